@@ -0,0 +1,261 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ageVerificationMethods are how a cashier establishes a customer's age: a date of birth typed
+// from the ID, a date of birth read by an ID scanner, or a manager vouching for the customer
+var ageVerificationMethods = []string{"dob_entered", "id_scanned", "manager_override"}
+
+var errAgeOverrideNotPermitted = errors.New("A manager override of the age check requires the pos.age_verifications.override permission")
+
+// ageVerificationRequest is the age check sent with a sale of age-restricted products
+type ageVerificationRequest struct {
+	Method         string  `json:"method" validate:"required"` // dob_entered, id_scanned, manager_override
+	DateOfBirth    *string `json:"date_of_birth"`              // YYYY-MM-DD
+	IDType         *string `json:"id_type"`                    // e.g. drivers_license, passport
+	IDExpiry       *string `json:"id_expiry"`                  // YYYY-MM-DD
+	OverrideReason *string `json:"override_reason"`
+}
+
+// itemMinimumAge returns the highest minimum age of a line's product and, for a kit, of its
+// components; 0 when none is restricted
+func itemMinimumAge(q rowQuerier, tenantID string, item *POSTransactionItem) (int, error) {
+	args := []interface{}{tenantID, item.ProductID}
+	placeholders := []string{"$2"}
+	for _, c := range item.Components {
+		args = append(args, c.ProductID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	var minimumAge int
+	err := q.QueryRow(`
+		SELECT COALESCE(MAX(minimum_age), 0) FROM pos_product_age_restrictions
+		WHERE tenant_id = $1 AND product_id IN (`+strings.Join(placeholders, ", ")+`)
+	`, args...).Scan(&minimumAge)
+	return minimumAge, err
+}
+
+// productMinimumAge returns the minimum customer age of a product, taking a kit's components
+// into account, for terminals to ask for ID as the item is scanned
+func productMinimumAge(q reportQuerier, tenantID string, productID int) (int, error) {
+	item := POSTransactionItem{ProductID: productID}
+	kit, err := loadKit(q, tenantID, productID)
+	if err != nil {
+		return 0, err
+	}
+	if kit != nil && kit.IsActive {
+		for _, c := range kit.Components {
+			item.Components = append(item.Components, POSTransactionItemComponent{ProductID: c.ProductID})
+		}
+	}
+	return itemMinimumAge(q, tenantID, &item)
+}
+
+// ageOn returns the age in whole years, on the date of at, of someone born on dob
+func ageOn(dob, at time.Time) int {
+	age := at.Year() - dob.Year()
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
+
+// verifyAge checks the age verification sent with a sale against the age its products require
+// and returns the record to keep, verified by the signed-in user at the sale time. A date of birth,
+// when given, must show the customer is old enough, even under a manager override; an override
+// needs a reason and a signed-in user allowed to override, who is recorded as the manager.
+func verifyAge(req *ageVerificationRequest, requiredAge, userID int, canOverride bool,
+	at time.Time) (*AgeVerification, error) {
+	if req == nil {
+		return nil, &itemOptionError{fmt.Sprintf("This sale includes items for customers aged %d or over; record an age verification",
+			requiredAge)}
+	}
+	known := false
+	for _, method := range ageVerificationMethods {
+		known = known || method == req.Method
+	}
+	if !known {
+		return nil, &itemOptionError{"Age verification method must be dob_entered, id_scanned or manager_override"}
+	}
+
+	v := &AgeVerification{
+		Method:      req.Method,
+		RequiredAge: requiredAge,
+		IDType:      req.IDType,
+		VerifiedBy:  userID,
+		VerifiedAt:  at,
+	}
+
+	if req.DateOfBirth != nil && *req.DateOfBirth != "" {
+		dob, err := time.Parse("2006-01-02", *req.DateOfBirth)
+		if err != nil {
+			return nil, &itemOptionError{"Invalid date of birth; use YYYY-MM-DD"}
+		}
+		if dob.After(at) {
+			return nil, &itemOptionError{"Date of birth is in the future"}
+		}
+		age := ageOn(dob, at)
+		if age < requiredAge {
+			return nil, &itemOptionError{fmt.Sprintf("Customer is %d; these items need a customer aged %d or over", age,
+				requiredAge)}
+		}
+		v.DateOfBirth = &dob
+		v.CustomerAge = &age
+	} else if req.Method != "manager_override" {
+		return nil, &itemOptionError{"A date of birth is required to verify the customer's age"}
+	}
+
+	if req.IDExpiry != nil && *req.IDExpiry != "" {
+		expiry, err := time.Parse("2006-01-02", *req.IDExpiry)
+		if err != nil {
+			return nil, &itemOptionError{"Invalid ID expiry date; use YYYY-MM-DD"}
+		}
+		if expiry.Before(time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)) {
+			return nil, &itemOptionError{"The customer's ID has expired"}
+		}
+		v.IDExpiry = &expiry
+	}
+
+	if req.Method == "manager_override" {
+		if !canOverride {
+			return nil, errAgeOverrideNotPermitted
+		}
+		if req.OverrideReason == nil || strings.TrimSpace(*req.OverrideReason) == "" {
+			return nil, &itemOptionError{"A manager override needs an override_reason"}
+		}
+		v.ManagerID = &userID
+		v.OverrideReason = req.OverrideReason
+	}
+	return v, nil
+}
+
+// blocks reports whether the restriction forbids sales at the given time, in its own time zone.
+// A window whose end is before its start runs past midnight and belongs to the day it starts on.
+func (r *AgeSaleRestriction) blocks(at time.Time) (bool, error) {
+	zone, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return false, err
+	}
+	from, err := time.Parse("15:04", r.TimeFrom)
+	if err != nil {
+		return false, err
+	}
+	to, err := time.Parse("15:04", r.TimeTo)
+	if err != nil {
+		return false, err
+	}
+
+	local := at.In(zone)
+	minute := local.Hour()*60 + local.Minute()
+	start, end := from.Hour()*60+from.Minute(), to.Hour()*60+to.Minute()
+	day := int(local.Weekday())
+	switch {
+	case start == end:
+		// the whole day
+	case start < end:
+		if minute < start || minute >= end {
+			return false, nil
+		}
+	case minute >= start:
+		// before midnight
+	case minute < end:
+		// after midnight, in the window that started the day before
+		day = (day + 6) % 7
+	default:
+		return false, nil
+	}
+	return r.DayOfWeek == nil || *r.DayOfWeek == day, nil
+}
+
+// checkAgeSaleHours refuses a sale of products restricted to requiredAge during a blocked window
+// of the register's location or of every location
+func checkAgeSaleHours(q reportQuerier, tenantID string, locationID *int, requiredAge int, at time.Time) error {
+	rows, err := q.Query(`
+		SELECT `+ageSaleRestrictionColumns+`
+		FROM pos_age_sale_restrictions
+		WHERE tenant_id = $1 AND is_active = true AND (location_id IS NULL OR location_id = $2)
+		  AND (minimum_age IS NULL OR minimum_age <= $3)
+		ORDER BY id
+	`, tenantID, locationID, requiredAge)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r AgeSaleRestriction
+		if err := scanAgeSaleRestriction(rows, &r); err != nil {
+			return err
+		}
+		blocked, err := r.blocks(at)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return &itemOptionError{fmt.Sprintf("Age-restricted items cannot be sold at this time (%s)", r.Name)}
+		}
+	}
+	return rows.Err()
+}
+
+const ageSaleRestrictionColumns = `id, tenant_id, location_id, name, minimum_age, day_of_week,
+	to_char(time_from, 'HH24:MI'), to_char(time_to, 'HH24:MI'), timezone, is_active, created_at, updated_at`
+
+func scanAgeSaleRestriction(row interface{ Scan(...interface{}) error }, r *AgeSaleRestriction) error {
+	return row.Scan(&r.ID, &r.TenantID, &r.LocationID, &r.Name, &r.MinimumAge, &r.DayOfWeek, &r.TimeFrom,
+		&r.TimeTo, &r.Timezone, &r.IsActive, &r.CreatedAt, &r.UpdatedAt)
+}
+
+// saveAgeVerification records the age check on a sale; an override's manager is also recorded
+// as the transaction's approving manager
+func saveAgeVerification(tx *sqlx.Tx, tenantID string, transactionID int, v *AgeVerification) error {
+	v.TransactionID = transactionID
+	err := tx.QueryRow(`
+		INSERT INTO pos_age_verifications (tenant_id, transaction_id, method, required_age, date_of_birth, customer_age,
+		                                   id_type, id_expiry, manager_id, override_reason, verified_by, verified_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`, tenantID, transactionID, v.Method, v.RequiredAge, v.DateOfBirth, v.CustomerAge, v.IDType, v.IDExpiry,
+		v.ManagerID, v.OverrideReason, v.VerifiedBy, v.VerifiedAt).Scan(&v.ID)
+	if err != nil || v.ManagerID == nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE pos_transactions SET manager_id = $1 WHERE id = $2", *v.ManagerID, transactionID)
+	return err
+}
+
+const ageVerificationColumns = `av.id, av.transaction_id, pt.transaction_number, pt.register_id, av.method,
+	av.required_age, av.date_of_birth, av.customer_age, av.id_type, av.id_expiry, av.manager_id, av.override_reason,
+	av.verified_by, av.verified_at`
+
+func scanAgeVerification(row interface{ Scan(...interface{}) error }, v *AgeVerification) error {
+	return row.Scan(&v.ID, &v.TransactionID, &v.TransactionNumber, &v.RegisterID, &v.Method, &v.RequiredAge,
+		&v.DateOfBirth, &v.CustomerAge, &v.IDType, &v.IDExpiry, &v.ManagerID, &v.OverrideReason, &v.VerifiedBy,
+		&v.VerifiedAt)
+}
+
+// loadAgeVerification returns the age check recorded on a transaction, or nil if it needed none
+func loadAgeVerification(q rowQuerier, transactionID int) (*AgeVerification, error) {
+	var v AgeVerification
+	err := scanAgeVerification(q.QueryRow(`
+		SELECT `+ageVerificationColumns+`
+		FROM pos_age_verifications av
+		JOIN pos_transactions pt ON pt.id = av.transaction_id
+		WHERE av.transaction_id = $1
+	`, transactionID), &v)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// AgeVerificationHandler manages the minimum ages of products, the hours when age-restricted
+// products may not be sold, and the audit of the age checks recorded on sales
+type AgeVerificationHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewAgeVerificationHandler creates a new age verification handler
+func NewAgeVerificationHandler(db *sqlx.DB, logger *zap.Logger) *AgeVerificationHandler {
+	return &AgeVerificationHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// GetProductAgeRestriction returns the minimum customer age of a product; 0 when it has none
+func (h *AgeVerificationHandler) GetProductAgeRestriction(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	restriction := ProductAgeRestriction{TenantID: tenantID, ProductID: productID}
+	err = h.db.QueryRow(`
+		SELECT minimum_age, updated_at FROM pos_product_age_restrictions
+		WHERE tenant_id = $1 AND product_id = $2
+	`, tenantID, productID).Scan(&restriction.MinimumAge, &restriction.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Failed to fetch product age restriction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restriction)
+}
+
+// SetProductAgeRestriction sets the minimum customer age of a product, or lifts it with 0.
+// Sales already recorded keep their verification.
+func (h *AgeVerificationHandler) SetProductAgeRestriction(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		MinimumAge int `json:"minimum_age"` // 0 lifts the restriction
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.MinimumAge < 0 || req.MinimumAge > 99 {
+		http.Error(w, "Minimum age must be between 1 and 99, or 0 for none", http.StatusBadRequest)
+		return
+	}
+
+	restriction := ProductAgeRestriction{TenantID: tenantID, ProductID: productID, MinimumAge: req.MinimumAge}
+	if req.MinimumAge == 0 {
+		_, err = h.db.Exec("DELETE FROM pos_product_age_restrictions WHERE tenant_id = $1 AND product_id = $2",
+			tenantID, productID)
+		restriction.UpdatedAt = time.Now()
+	} else {
+		var exists bool
+		err = h.db.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+		if err == nil && !exists {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		if err == nil {
+			err = h.db.QueryRow(`
+				INSERT INTO pos_product_age_restrictions (tenant_id, product_id, minimum_age)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (tenant_id, product_id) DO UPDATE SET minimum_age = EXCLUDED.minimum_age
+				RETURNING updated_at
+			`, tenantID, productID, req.MinimumAge).Scan(&restriction.UpdatedAt)
+		}
+	}
+	if err != nil {
+		http.Error(w, "Failed to update product age restriction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restriction)
+}
+
+// ageSaleRestrictionRequest is the body of a sale hours restriction create or update
+type ageSaleRestrictionRequest struct {
+	LocationID *int   `json:"location_id"`
+	Name       string `json:"name" validate:"required"`
+	MinimumAge *int   `json:"minimum_age"`
+	DayOfWeek  *int   `json:"day_of_week"`
+	TimeFrom   string `json:"time_from" validate:"required"` // HH:MM
+	TimeTo     string `json:"time_to" validate:"required"`
+	Timezone   string `json:"timezone"` // defaults to UTC
+	IsActive   *bool  `json:"is_active"`
+}
+
+func (req *ageSaleRestrictionRequest) validate() error {
+	if strings.TrimSpace(req.Name) == "" {
+		return errors.New("Restriction name is required")
+	}
+	if req.MinimumAge != nil && (*req.MinimumAge < 1 || *req.MinimumAge > 99) {
+		return errors.New("Minimum age must be between 1 and 99")
+	}
+	if req.DayOfWeek != nil && (*req.DayOfWeek < 0 || *req.DayOfWeek > 6) {
+		return errors.New("Day of week must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	if _, err := time.Parse("15:04", req.TimeFrom); err != nil {
+		return errors.New("Invalid time_from; use HH:MM")
+	}
+	if _, err := time.Parse("15:04", req.TimeTo); err != nil {
+		return errors.New("Invalid time_to; use HH:MM")
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return fmt.Errorf("Unknown time zone %s", req.Timezone)
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+	return nil
+}
+
+// GetAgeSaleRestrictions lists the sale hours restrictions, optionally those that apply at a
+// ?location_id=
+func (h *AgeVerificationHandler) GetAgeSaleRestrictions(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query := "SELECT " + ageSaleRestrictionColumns + " FROM pos_age_sale_restrictions WHERE tenant_id = $1"
+	args := []interface{}{tenantID}
+	if locationID := r.URL.Query().Get("location_id"); locationID != "" {
+		query += " AND (location_id IS NULL OR location_id = $2)"
+		args = append(args, locationID)
+	}
+	query += " ORDER BY location_id NULLS FIRST, day_of_week NULLS FIRST, time_from, id"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch age sale restrictions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	restrictions := []AgeSaleRestriction{}
+	for rows.Next() {
+		var restriction AgeSaleRestriction
+		if err := scanAgeSaleRestriction(rows, &restriction); err != nil {
+			http.Error(w, "Failed to scan age sale restriction", http.StatusInternalServerError)
+			return
+		}
+		restrictions = append(restrictions, restriction)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"restrictions": restrictions,
+		"count":        len(restrictions),
+	})
+}
+
+// CreateAgeSaleRestriction blocks sales of age-restricted products during a window of local time
+func (h *AgeVerificationHandler) CreateAgeSaleRestriction(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req ageSaleRestrictionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var restriction AgeSaleRestriction
+	err = scanAgeSaleRestriction(h.db.QueryRow(`
+		INSERT INTO pos_age_sale_restrictions (tenant_id, location_id, name, minimum_age, day_of_week, time_from,
+		                                       time_to, timezone, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+ageSaleRestrictionColumns,
+		tenantID, req.LocationID, req.Name, req.MinimumAge, req.DayOfWeek, req.TimeFrom, req.TimeTo, req.Timezone,
+		*req.IsActive), &restriction)
+	if err != nil {
+		http.Error(w, "Failed to create age sale restriction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(restriction)
+}
+
+// UpdateAgeSaleRestriction replaces a sale hours restriction
+func (h *AgeVerificationHandler) UpdateAgeSaleRestriction(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	restrictionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid restriction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ageSaleRestrictionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var restriction AgeSaleRestriction
+	err = scanAgeSaleRestriction(h.db.QueryRow(`
+		UPDATE pos_age_sale_restrictions
+		SET location_id = $3, name = $4, minimum_age = $5, day_of_week = $6, time_from = $7, time_to = $8,
+		    timezone = $9, is_active = $10
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING `+ageSaleRestrictionColumns,
+		restrictionID, tenantID, req.LocationID, req.Name, req.MinimumAge, req.DayOfWeek, req.TimeFrom, req.TimeTo,
+		req.Timezone, *req.IsActive), &restriction)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Age sale restriction not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update age sale restriction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restriction)
+}
+
+// DeleteAgeSaleRestriction removes a sale hours restriction
+func (h *AgeVerificationHandler) DeleteAgeSaleRestriction(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	restrictionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid restriction ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM pos_age_sale_restrictions WHERE id = $1 AND tenant_id = $2",
+		restrictionID, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to delete age sale restriction", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Age sale restriction not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAgeVerifications lists the age checks recorded on sales, newest first, for compliance
+// audits. Filters: ?start_date=, ?end_date=, ?register_id=, ?location_id=, ?method=
+func (h *AgeVerificationHandler) GetAgeVerifications(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conditions := " WHERE av.tenant_id = $1"
+	args := []interface{}{tenantID}
+	filter := func(condition, value string) {
+		if value != "" {
+			args = append(args, value)
+			conditions += fmt.Sprintf(" AND "+condition, len(args))
+		}
+	}
+	query := r.URL.Query()
+	filter("av.verified_at >= $%d", query.Get("start_date"))
+	filter("av.verified_at < $%d::date + 1", query.Get("end_date"))
+	filter("pt.register_id = $%d", query.Get("register_id"))
+	filter("pt.register_id IN (SELECT id FROM pos_registers WHERE location_id = $%d)", query.Get("location_id"))
+	filter("av.method = $%d", query.Get("method"))
+
+	from := `
+		FROM pos_age_verifications av
+		JOIN pos_transactions pt ON pt.id = av.transaction_id` + conditions
+
+	var totalCount int
+	if err := h.db.QueryRow("SELECT COUNT(*)"+from, args...).Scan(&totalCount); err != nil {
+		http.Error(w, "Failed to fetch age verifications", http.StatusInternalServerError)
+		return
+	}
+
+	page := parsePagination(r, 50, 500)
+	args = append(args, page.Limit, (page.Page-1)*page.Limit)
+	rows, err := h.db.Query("SELECT "+ageVerificationColumns+from+
+		fmt.Sprintf(" ORDER BY av.verified_at DESC, av.id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args)), args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch age verifications", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	verifications := []AgeVerification{}
+	for rows.Next() {
+		var v AgeVerification
+		if err := scanAgeVerification(rows, &v); err != nil {
+			http.Error(w, "Failed to scan age verification", http.StatusInternalServerError)
+			return
+		}
+		verifications = append(verifications, v)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"verifications": verifications,
+		"count":         len(verifications),
+		"pagination":    newPaginationResponse(page, totalCount),
+	})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAgeOn(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 12, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		dob, at time.Time
+		want    int
+	}{
+		{date(2008, time.October, 18), date(2026, time.October, 18), 18},
+		{date(2008, time.October, 19), date(2026, time.October, 18), 17},
+		{date(2008, time.November, 1), date(2026, time.October, 18), 17},
+		{date(2008, time.February, 29), date(2026, time.February, 28), 17},
+		{date(2008, time.February, 29), date(2026, time.March, 1), 18},
+		{date(2026, time.October, 18), date(2026, time.October, 18), 0},
+	}
+	for _, tt := range tests {
+		if got := ageOn(tt.dob, tt.at); got != tt.want {
+			t.Errorf("ageOn(%s, %s) = %d, want %d", tt.dob.Format("2006-01-02"), tt.at.Format("2006-01-02"), got,
+				tt.want)
+		}
+	}
+}
+
+func TestVerifyAge(t *testing.T) {
+	at := time.Date(2026, time.October, 18, 15, 0, 0, 0, time.UTC)
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name        string
+		req         *ageVerificationRequest
+		canOverride bool
+		wantErr     bool
+		wantForbid  bool
+		wantAge     int
+		wantManager bool
+	}{
+		{"no verification", nil, false, true, false, 0, false},
+		{"unknown method", &ageVerificationRequest{Method: "vibes"}, false, true, false, 0, false},
+		{"old enough", &ageVerificationRequest{Method: "dob_entered", DateOfBirth: str("2008-10-18")}, false, false,
+			false, 18, false},
+		{"a day too young", &ageVerificationRequest{Method: "id_scanned", DateOfBirth: str("2008-10-19")}, false,
+			true, false, 0, false},
+		{"date of birth required", &ageVerificationRequest{Method: "dob_entered"}, false, true, false, 0, false},
+		{"bad date of birth", &ageVerificationRequest{Method: "dob_entered", DateOfBirth: str("18/10/2008")}, false,
+			true, false, 0, false},
+		{"future date of birth", &ageVerificationRequest{Method: "dob_entered", DateOfBirth: str("2027-01-01")},
+			false, true, false, 0, false},
+		{"expired id", &ageVerificationRequest{Method: "id_scanned", DateOfBirth: str("1990-01-01"),
+			IDExpiry: str("2026-10-17")}, false, true, false, 0, false},
+		{"id expiring today", &ageVerificationRequest{Method: "id_scanned", DateOfBirth: str("1990-01-01"),
+			IDExpiry: str("2026-10-18")}, false, false, false, 36, false},
+		{"override without permission", &ageVerificationRequest{Method: "manager_override",
+			OverrideReason: str("known regular")}, false, true, true, 0, false},
+		{"override without reason", &ageVerificationRequest{Method: "manager_override", OverrideReason: str(" ")},
+			true, true, false, 0, false},
+		{"override", &ageVerificationRequest{Method: "manager_override", OverrideReason: str("known regular")},
+			true, false, false, 0, true},
+		{"override cannot pass an underage date of birth", &ageVerificationRequest{Method: "manager_override",
+			DateOfBirth: str("2010-01-01"), OverrideReason: str("looks old enough")}, true, true, false, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := verifyAge(tt.req, 18, 7, tt.canOverride, at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyAge error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var optionErr *itemOptionError
+				if forbidden := errors.Is(err, errAgeOverrideNotPermitted); forbidden != tt.wantForbid ||
+					(!forbidden && !errors.As(err, &optionErr)) {
+					t.Errorf("verifyAge error = %#v, want forbidden %v", err, tt.wantForbid)
+				}
+				return
+			}
+			if v.VerifiedBy != 7 || !v.VerifiedAt.Equal(at) || v.RequiredAge != 18 {
+				t.Errorf("verifyAge recorded %d at %s for age %d", v.VerifiedBy, v.VerifiedAt, v.RequiredAge)
+			}
+			if (v.CustomerAge == nil && tt.wantAge != 0) || (v.CustomerAge != nil && *v.CustomerAge != tt.wantAge) {
+				t.Errorf("verifyAge customer age = %v, want %d", v.CustomerAge, tt.wantAge)
+			}
+			if tt.wantManager != (v.ManagerID != nil) || (v.ManagerID != nil && *v.ManagerID != 7) {
+				t.Errorf("verifyAge manager = %v, want the signed-in user %v", v.ManagerID, tt.wantManager)
+			}
+		})
+	}
+}
+
+func TestAgeSaleRestrictionBlocks(t *testing.T) {
+	// 16 October 2026 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+	weekday := func(d time.Weekday) *int { n := int(d); return &n }
+
+	overnight := AgeSaleRestriction{TimeFrom: "22:00", TimeTo: "06:00", Timezone: "UTC"}
+	fridayNight := overnight
+	fridayNight.DayOfWeek = weekday(time.Friday)
+	sundayMorning := AgeSaleRestriction{TimeFrom: "09:00", TimeTo: "12:00", Timezone: "UTC",
+		DayOfWeek: weekday(time.Sunday)}
+	allSunday := AgeSaleRestriction{TimeFrom: "00:00", TimeTo: "00:00", Timezone: "UTC",
+		DayOfWeek: weekday(time.Sunday)}
+
+	tests := []struct {
+		name string
+		r    AgeSaleRestriction
+		at   time.Time
+		want bool
+	}{
+		{"overnight before midnight", overnight, at(16, 23, 0), true},
+		{"overnight after midnight", overnight, at(17, 5, 59), true},
+		{"overnight ends", overnight, at(17, 6, 0), false},
+		{"overnight not started", overnight, at(16, 21, 59), false},
+		{"friday night after midnight on saturday", fridayNight, at(17, 2, 0), true},
+		{"friday night window on friday", fridayNight, at(16, 22, 0), true},
+		{"saturday night is not friday night", fridayNight, at(17, 23, 0), false},
+		{"thursday night spills into friday", fridayNight, at(16, 2, 0), false},
+		{"sunday morning", sundayMorning, at(18, 10, 0), true},
+		{"saturday morning", sundayMorning, at(17, 10, 0), false},
+		{"sunday morning ends", sundayMorning, at(18, 12, 0), false},
+		{"whole sunday", allSunday, at(18, 23, 59), true},
+		{"whole sunday ends", allSunday, at(19, 0, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.r.blocks(tt.at)
+			if err != nil {
+				t.Fatalf("blocks: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("blocks(%s) = %v, want %v", tt.at.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+
+	for _, r := range []AgeSaleRestriction{
+		{TimeFrom: "22:00", TimeTo: "06:00", Timezone: "Not/AZone"},
+		{TimeFrom: "10pm", TimeTo: "06:00", Timezone: "UTC"},
+	} {
+		if _, err := r.blocks(at(16, 23, 0)); err == nil {
+			t.Errorf("blocks with %s-%s in %s did not fail", r.TimeFrom, r.TimeTo, r.Timezone)
+		}
+	}
+}
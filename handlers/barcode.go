@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	symbologyUPCA    = "upc_a"
+	symbologyEAN13   = "ean_13"
+	symbologyEAN8    = "ean_8"
+	symbologyCode128 = "code128"
+)
+
+var (
+	errBarcodeEmpty      = errors.New("barcode is empty")
+	errBarcodeCheckDigit = errors.New("barcode check digit is wrong")
+	errBarcodeCharacters = errors.New("barcode contains characters that cannot be encoded in Code 128")
+	errBarcodeNotGTIN    = errors.New("UPC and EAN barcodes have 8, 12 or 13 digits")
+	errBarcodeSymbology  = errors.New("symbology must be upc_a, ean_13, ean_8 or code128")
+)
+
+// scannedBarcode is a barcode as read by the scanner, validated and normalized
+type scannedBarcode struct {
+	Raw       string
+	Code      string // UPC-A is widened to EAN-13, so both forms of a product code match
+	Symbology string
+}
+
+// parseBarcode validates a scanned or entered code. The symbology is the one given, else the one
+// named by an AIM identifier the scanner put in front of the code (]E for UPC/EAN, ]C for Code
+// 128), else inferred: a code of 8, 12 or 13 digits whose last digit checks is EAN-8, UPC-A or
+// EAN-13, and anything else is Code 128. Only UPC and EAN codes carry a check digit to verify;
+// Code 128's check character has already been verified and stripped by the scanner.
+func parseBarcode(raw, symbology string) (*scannedBarcode, error) {
+	code := strings.TrimSpace(raw)
+	gtin := false
+	switch symbology {
+	case symbologyUPCA, symbologyEAN13, symbologyEAN8:
+		gtin = true
+	case symbologyCode128:
+	case "":
+		if len(code) > 3 && code[0] == ']' {
+			gtin = code[1] == 'E'
+			if !gtin {
+				symbology = symbologyCode128
+			}
+			code = code[3:]
+		}
+	default:
+		return nil, errBarcodeSymbology
+	}
+	if code == "" {
+		return nil, errBarcodeEmpty
+	}
+	b := &scannedBarcode{Raw: raw, Code: code}
+
+	lengthFits := isDigits(code) && (len(code) == 8 || len(code) == 12 || len(code) == 13)
+	if gtin || (symbology == "" && lengthFits && validGTIN(code)) {
+		if !lengthFits {
+			return nil, errBarcodeNotGTIN
+		}
+		if !validGTIN(code) {
+			return nil, errBarcodeCheckDigit
+		}
+		switch len(code) {
+		case 8:
+			b.Symbology = symbologyEAN8
+		case 12:
+			b.Symbology = symbologyUPCA
+			b.Code = "0" + code
+		case 13:
+			b.Symbology = symbologyEAN13
+		}
+		return b, nil
+	}
+
+	for _, c := range code {
+		if c < 32 || c > 126 {
+			return nil, errBarcodeCharacters
+		}
+	}
+	b.Symbology = symbologyCode128
+	return b, nil
+}
+
+// lookupCodes are the forms a product's barcode may be stored under
+func (b *scannedBarcode) lookupCodes() []string {
+	codes := []string{b.Code}
+	if b.Symbology != symbologyCode128 && len(b.Code) == 13 && b.Code[0] == '0' {
+		codes = append(codes, b.Code[1:])
+	}
+	return codes
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// gtinCheckDigit computes the GS1 mod-10 check digit for the digits before it
+func gtinCheckDigit(digits string) int {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// validGTIN reports whether a UPC/EAN code's last digit is its check digit
+func validGTIN(code string) bool {
+	body, check := code[:len(code)-1], int(code[len(code)-1]-'0')
+	return gtinCheckDigit(body) == check
+}
+
+// normalizeProductBarcode validates a barcode being assigned to a product and returns its stored
+// form and symbology; symbology may be empty to infer it
+func normalizeProductBarcode(raw, symbology string) (string, string, error) {
+	b, err := parseBarcode(raw, symbology)
+	if err != nil {
+		return "", "", err
+	}
+	return b.Code, b.Symbology, nil
+}
+
+// embeddedBarcodeSettings say which in-store EAN-13 prefixes carry a price or a weight
+type embeddedBarcodeSettings struct {
+	PricePrefixes  []string
+	WeightPrefixes []string
+	WeightDecimals int
+}
+
+// loadEmbeddedBarcodeSettings reads the embedded_* settings over the module defaults
+func loadEmbeddedBarcodeSettings(q rowQuerier, tenantID string) (embeddedBarcodeSettings, error) {
+	pricePrefixes := defaultSettings["embedded_price_prefixes"].(string)
+	weightPrefixes := defaultSettings["embedded_weight_prefixes"].(string)
+	s := embeddedBarcodeSettings{WeightDecimals: defaultSettings["embedded_weight_decimals"].(int)}
+
+	for key, dest := range map[string]interface{}{
+		"embedded_price_prefixes":  &pricePrefixes,
+		"embedded_weight_prefixes": &weightPrefixes,
+		"embedded_weight_decimals": &s.WeightDecimals,
+	} {
+		if _, err := loadTenantSetting(q, tenantID, key, dest); err != nil {
+			return s, err
+		}
+	}
+	s.PricePrefixes = splitSettingList(pricePrefixes)
+	s.WeightPrefixes = splitSettingList(weightPrefixes)
+	return s, nil
+}
+
+func splitSettingList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// embeddedBarcode is an in-store EAN-13 (prefix 20-29) laid out as 2P IIIII VVVVV C:
+// a two-digit prefix, a five-digit item code and a five-digit price or weight
+type embeddedBarcode struct {
+	ItemCode string
+	Kind     string // price, weight
+	Value    float64
+}
+
+// decodeEmbeddedBarcode splits a price- or weight-embedded EAN-13; it returns nil for other codes
+func decodeEmbeddedBarcode(b *scannedBarcode, s embeddedBarcodeSettings) *embeddedBarcode {
+	if b.Symbology != symbologyEAN13 || b.Code[0] != '2' {
+		return nil
+	}
+	prefix := b.Code[:2]
+	value, _ := strconv.Atoi(b.Code[7:12])
+
+	for _, p := range s.PricePrefixes {
+		if p == prefix {
+			return &embeddedBarcode{ItemCode: b.Code[2:7], Kind: "price", Value: float64(value) / 100}
+		}
+	}
+	for _, p := range s.WeightPrefixes {
+		if p == prefix {
+			return &embeddedBarcode{ItemCode: b.Code[2:7], Kind: "weight",
+				Value: float64(value) / math.Pow10(s.WeightDecimals)}
+		}
+	}
+	return nil
+}
+
+// lookupCodes are the forms the product behind an embedded code may be stored under: the
+// barcode with a zero price or weight, or the bare item code
+func (e *embeddedBarcode) lookupCodes(b *scannedBarcode) []string {
+	body := b.Code[:7] + "00000"
+	return []string{body + fmt.Sprint(gtinCheckDigit(body)), e.ItemCode}
+}
+
+// applyEmbeddedBarcode sets a line from the in-store code it was scanned from, whatever the
+// terminal sent: a price code sells one unit at the printed price, a weight code sells the printed
+// weight. It returns the price the line must sell at: the printed price, else listPrice.
+func applyEmbeddedBarcode(item *POSTransactionItem, embedded *embeddedBarcode, listPrice float64) float64 {
+	if embedded == nil {
+		return listPrice
+	}
+	switch embedded.Kind {
+	case "price":
+		item.Quantity = 1
+		item.UnitPrice = embedded.Value
+		listPrice = embedded.Value
+	case "weight":
+		item.Quantity = embedded.Value
+	}
+	priceLineItem(item)
+	return listPrice
+}
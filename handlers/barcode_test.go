@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGTINCheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   int
+	}{
+		{"400638133393", 1}, // EAN-13
+		{"03600029145", 2},  // UPC-A
+		{"9638507", 4},      // EAN-8
+		{"123456789012", 8},
+		{"201234500000", 1},
+	}
+	for _, tt := range tests {
+		if got := gtinCheckDigit(tt.digits); got != tt.want {
+			t.Errorf("gtinCheckDigit(%q) = %d, want %d", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestParseBarcode(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		symbology     string
+		wantCode      string
+		wantSymbology string
+		wantErr       error
+	}{
+		{"ean-13", "4006381333931", "", "4006381333931", symbologyEAN13, nil},
+		{"upc-a widened", "036000291452", "", "0036000291452", symbologyUPCA, nil},
+		{"ean-8", "96385074", "", "96385074", symbologyEAN8, nil},
+		{"surrounding spaces", " 4006381333931\n", "", "4006381333931", symbologyEAN13, nil},
+		{"code 128 text", "ABC-123", "", "ABC-123", symbologyCode128, nil},
+		{"digits of other lengths", "12345", "", "12345", symbologyCode128, nil},
+		{"digits failing the check are code 128", "1234567890120", "", "1234567890120", symbologyCode128, nil},
+		{"declared code 128", "4006381333931", symbologyCode128, "4006381333931", symbologyCode128, nil},
+		{"declared ean-13 with bad check digit", "4006381333932", symbologyEAN13, "", "", errBarcodeCheckDigit},
+		{"declared ean-13 of wrong length", "12345", symbologyEAN13, "", "", errBarcodeNotGTIN},
+		{"aim ean prefix", "]E04006381333931", "", "4006381333931", symbologyEAN13, nil},
+		{"aim ean prefix with bad check digit", "]E04006381333932", "", "", "", errBarcodeCheckDigit},
+		{"aim code 128 prefix", "]C01234567890120", "", "1234567890120", symbologyCode128, nil},
+		{"empty", "   ", "", "", "", errBarcodeEmpty},
+		{"control characters", "AB\x01C", "", "", "", errBarcodeCharacters},
+		{"unknown symbology", "ABC", "qr", "", "", errBarcodeSymbology},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := parseBarcode(tt.raw, tt.symbology)
+			if err != tt.wantErr {
+				t.Fatalf("parseBarcode(%q, %q) error = %v, want %v", tt.raw, tt.symbology, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if b.Code != tt.wantCode || b.Symbology != tt.wantSymbology {
+				t.Errorf("parseBarcode(%q, %q) = %s %q, want %s %q", tt.raw, tt.symbology, b.Symbology, b.Code,
+					tt.wantSymbology, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDecodeEmbeddedBarcode(t *testing.T) {
+	settings := embeddedBarcodeSettings{
+		PricePrefixes:  []string{"20", "21", "22"},
+		WeightPrefixes: []string{"23", "24", "25"},
+		WeightDecimals: 3,
+	}
+	tests := []struct {
+		name        string
+		code        string
+		want        *embeddedBarcode
+		wantLookups []string
+	}{
+		{"price", "2012345012349", &embeddedBarcode{ItemCode: "12345", Kind: "price", Value: 12.34},
+			[]string{"2012345000001", "12345"}},
+		{"weight", "2300042015009", &embeddedBarcode{ItemCode: "00042", Kind: "weight", Value: 1.5},
+			[]string{"2300042000005", "00042"}},
+		{"unconfigured in-store prefix", "2600042015000", nil, nil},
+		{"regular ean-13", "4006381333931", nil, nil},
+		{"ean-8", "96385074", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := parseBarcode(tt.code, "")
+			if err != nil {
+				t.Fatalf("parseBarcode(%q): %v", tt.code, err)
+			}
+			got := decodeEmbeddedBarcode(b, settings)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("decodeEmbeddedBarcode(%q) = %+v, want %+v", tt.code, got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if lookups := got.lookupCodes(b); !reflect.DeepEqual(lookups, tt.wantLookups) {
+				t.Errorf("lookupCodes(%q) = %v, want %v", tt.code, lookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestEmbeddedBarcodeLinePrice(t *testing.T) {
+	settings := embeddedBarcodeSettings{PricePrefixes: []string{"20"}, WeightPrefixes: []string{"23"}, WeightDecimals: 3}
+	tests := []struct {
+		name         string
+		code         string
+		item         POSTransactionItem // as sent by the terminal
+		listPrice    float64
+		wantQuantity float64
+		wantPrice    float64
+		wantErr      bool
+	}{
+		{"price code sells at the printed price", "2012345012349",
+			POSTransactionItem{ProductID: 1, Quantity: 1, UnitPrice: 12.34, TaxRate: 10}, 9.99, 1, 12.34, false},
+		{"price code ignores the terminal's price and quantity", "2012345012349",
+			POSTransactionItem{ProductID: 1, Quantity: 3, UnitPrice: 0.01}, 9.99, 1, 12.34, false},
+		{"weight code sells the printed weight at the list price", "2300042015009",
+			POSTransactionItem{ProductID: 2, Quantity: 1, UnitPrice: 4}, 4, 1.5, 4, false},
+		{"weight code at another price is an override", "2300042015009",
+			POSTransactionItem{ProductID: 2, Quantity: 1, UnitPrice: 1}, 4, 1.5, 1, true},
+		{"regular code at another price is an override", "4006381333931",
+			POSTransactionItem{ProductID: 3, Quantity: 1, UnitPrice: 1}, 2.5, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := parseBarcode(tt.code, "")
+			if err != nil {
+				t.Fatalf("parseBarcode(%q): %v", tt.code, err)
+			}
+			item := tt.item
+			price := applyEmbeddedBarcode(&item, decodeEmbeddedBarcode(b, settings), tt.listPrice)
+			err = checkLinePrice(&item, price, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkLinePrice error = %v, want error %v", err, tt.wantErr)
+			}
+			if item.Quantity != tt.wantQuantity || item.UnitPrice != tt.wantPrice {
+				t.Errorf("line = %v at %v, want %v at %v", item.Quantity, item.UnitPrice, tt.wantQuantity, tt.wantPrice)
+			}
+			if err == nil && checkLinePrice(&item, price, true) != nil {
+				t.Error("checkLinePrice refused a cashier who may override prices")
+			}
+		})
+	}
+
+	item := POSTransactionItem{ProductID: 1, Quantity: 1, UnitPrice: 12.34, TaxRate: 10}
+	applyEmbeddedBarcode(&item, &embeddedBarcode{Kind: "price", Value: 12.34}, 9.99)
+	if item.TaxAmount != 1.23 {
+		t.Errorf("price code line tax = %v, want 1.23", item.TaxAmount)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
@@ -162,3 +164,202 @@ func (h *CustomerHandler) UpdateLoyaltyPoints(w http.ResponseWriter, r *http.Req
 		"message":      "Loyalty points updated successfully",
 	})
 }
+
+// GetSMSConsent retrieves a customer's SMS receipt opt-in
+func (h *CustomerHandler) GetSMSConsent(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	customerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var consent SMSConsent
+	err = h.db.QueryRow(`
+		SELECT id, tenant_id, customer_id, phone_e164, opted_in, source, opted_in_at, opted_out_at,
+		       updated_by, created_at, updated_at
+		FROM pos_customer_sms_consent
+		WHERE tenant_id = $1 AND customer_id = $2
+	`, tenantID, customerID).Scan(&consent.ID, &consent.TenantID, &consent.CustomerID, &consent.PhoneE164,
+		&consent.OptedIn, &consent.Source, &consent.OptedInAt, &consent.OptedOutAt, &consent.UpdatedBy,
+		&consent.CreatedAt, &consent.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "No SMS consent recorded for customer", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch SMS consent", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(consent)
+}
+
+// UpdateSMSConsent records a customer opting in to or out of SMS receipts
+func (h *CustomerHandler) UpdateSMSConsent(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	customerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Phone   string  `json:"phone" validate:"required"`
+		OptedIn bool    `json:"opted_in"`
+		Source  *string `json:"source"` // pos, web, import
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	countryCode := defaultSettings["sms_default_country_code"].(string)
+	if _, err := loadTenantSetting(h.db, tenantID, "sms_default_country_code", &countryCode); err != nil {
+		http.Error(w, "Failed to load SMS settings", http.StatusInternalServerError)
+		return
+	}
+
+	phone, err := normalizePhoneE164(req.Phone, countryCode)
+	if err != nil {
+		http.Error(w, "Invalid phone number", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	var id int
+	err = h.db.QueryRow(`
+		INSERT INTO pos_customer_sms_consent (tenant_id, customer_id, phone_e164, opted_in, source,
+		                                      opted_in_at, opted_out_at, updated_by)
+		VALUES ($1, $2, $3, $4, $5,
+		        CASE WHEN $4 THEN NOW() END, CASE WHEN NOT $4 THEN NOW() END, $6)
+		ON CONFLICT (tenant_id, customer_id) DO UPDATE SET
+			phone_e164 = EXCLUDED.phone_e164,
+			opted_in = EXCLUDED.opted_in,
+			source = COALESCE(EXCLUDED.source, pos_customer_sms_consent.source),
+			opted_in_at = COALESCE(EXCLUDED.opted_in_at, pos_customer_sms_consent.opted_in_at),
+			opted_out_at = EXCLUDED.opted_out_at,
+			updated_by = EXCLUDED.updated_by
+		RETURNING id
+	`, tenantID, customerID, phone, req.OptedIn, req.Source, userID).Scan(&id)
+	if err != nil {
+		http.Error(w, "Failed to update SMS consent", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          id,
+		"customer_id": customerID,
+		"phone_e164":  phone,
+		"opted_in":    req.OptedIn,
+		"message":     "SMS consent updated",
+	})
+}
+
+// GetCustomerPriceGroup retrieves the customer group a customer's prices are resolved for
+func (h *CustomerHandler) GetCustomerPriceGroup(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	customerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var group CustomerPriceGroup
+	err = h.db.QueryRow(`
+		SELECT id, tenant_id, customer_id, customer_group_id, updated_by, created_at, updated_at
+		FROM pos_customer_price_groups
+		WHERE tenant_id = $1 AND customer_id = $2
+	`, tenantID, customerID).Scan(&group.ID, &group.TenantID, &group.CustomerID, &group.CustomerGroupID,
+		&group.UpdatedBy, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "No price group assigned to customer", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch price group", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// UpdateCustomerPriceGroup assigns a customer to a customer group, or clears the assignment when
+// customer_group_id is null
+func (h *CustomerHandler) UpdateCustomerPriceGroup(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	customerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		CustomerGroupID *int `json:"customer_group_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.CustomerGroupID == nil {
+		_, err = h.db.Exec("DELETE FROM pos_customer_price_groups WHERE tenant_id = $1 AND customer_id = $2",
+			tenantID, customerID)
+		if err != nil {
+			http.Error(w, "Failed to update price group", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	var id int
+	err = h.db.QueryRow(`
+		INSERT INTO pos_customer_price_groups (tenant_id, customer_id, customer_group_id, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, customer_id) DO UPDATE SET
+			customer_group_id = EXCLUDED.customer_group_id,
+			updated_by = EXCLUDED.updated_by
+		RETURNING id
+	`, tenantID, customerID, *req.CustomerGroupID, userID).Scan(&id)
+	if err != nil {
+		http.Error(w, "Failed to update price group", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":                id,
+		"customer_id":       customerID,
+		"customer_group_id": *req.CustomerGroupID,
+		"message":           "Price group updated",
+	})
+}
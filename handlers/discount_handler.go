@@ -115,6 +115,12 @@ func (h *DiscountHandler) CreateDiscountRule(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	// A bundle rule sets the price of the kits it names, or of the kits in its categories;
+	// discount_value is the price per kit
+	if req.DiscountType == "bundle" && len(req.ProductIDs) == 0 && len(req.CategoryIDs) == 0 {
+		http.Error(w, "A bundle rule needs the kit products or categories it prices", http.StatusBadRequest)
+		return
+	}
 
 	validFrom, err := time.Parse("2006-01-02 15:04:05", req.ValidFrom)
 	if err != nil {
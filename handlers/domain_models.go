@@ -62,6 +62,77 @@ type RegisterShift struct {
 	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 }
 
+// RegisterTransaction represents a cash movement in or out of a register
+type RegisterTransaction struct {
+	ID              int       `json:"id" db:"id"`
+	TenantID        string    `json:"tenant_id" db:"tenant_id"`
+	RegisterID      int       `json:"register_id" db:"register_id"`
+	ShiftID         *int      `json:"shift_id" db:"shift_id"`
+	TransactionType string    `json:"transaction_type" db:"transaction_type"` // opening, closing, cash_in, cash_out, cash_drop, payout, refund_cash
+	Amount          float64   `json:"amount" db:"amount"`
+	BalanceBefore   float64   `json:"balance_before" db:"balance_before"`
+	BalanceAfter    float64   `json:"balance_after" db:"balance_after"`
+	Reason          *string   `json:"reason" db:"reason"`
+	Notes           *string   `json:"notes" db:"notes"`
+	ReferenceNumber *string   `json:"reference_number" db:"reference_number"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	CreatedBy       *int      `json:"created_by" db:"created_by"`
+}
+
+// Safe represents the back-office safe for a location
+type Safe struct {
+	ID             int       `json:"id" db:"id"`
+	TenantID       string    `json:"tenant_id" db:"tenant_id"`
+	LocationID     *int      `json:"location_id" db:"location_id"`
+	Name           string    `json:"name" db:"name"`
+	CurrentBalance float64   `json:"current_balance" db:"current_balance"`
+	IsActive       bool      `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SafeTransaction represents an entry in a safe's ledger
+type SafeTransaction struct {
+	ID                    int       `json:"id" db:"id"`
+	TenantID              string    `json:"tenant_id" db:"tenant_id"`
+	SafeID                int       `json:"safe_id" db:"safe_id"`
+	TransactionType       string    `json:"transaction_type" db:"transaction_type"` // register_drop, change_fund, bank_deposit, deposit_cancelled, adjustment
+	Amount                float64   `json:"amount" db:"amount"`
+	BalanceBefore         float64   `json:"balance_before" db:"balance_before"`
+	BalanceAfter          float64   `json:"balance_after" db:"balance_after"`
+	RegisterID            *int      `json:"register_id" db:"register_id"`
+	RegisterTransactionID *int      `json:"register_transaction_id" db:"register_transaction_id"`
+	DepositID             *int      `json:"deposit_id" db:"deposit_id"`
+	ReferenceNumber       *string   `json:"reference_number" db:"reference_number"`
+	Notes                 *string   `json:"notes" db:"notes"`
+	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+	CreatedBy             *int      `json:"created_by" db:"created_by"`
+}
+
+// BankDeposit represents a batch of cash sent from a safe to the bank
+type BankDeposit struct {
+	ID              int        `json:"id" db:"id"`
+	TenantID        string     `json:"tenant_id" db:"tenant_id"`
+	SafeID          int        `json:"safe_id" db:"safe_id"`
+	DepositNumber   string     `json:"deposit_number" db:"deposit_number"`
+	SlipNumber      *string    `json:"slip_number" db:"slip_number"`
+	BagID           *string    `json:"bag_id" db:"bag_id"`
+	ExpectedAmount  float64    `json:"expected_amount" db:"expected_amount"`
+	ConfirmedAmount *float64   `json:"confirmed_amount" db:"confirmed_amount"`
+	Variance        *float64   `json:"variance" db:"variance"`
+	Status          string     `json:"status" db:"status"` // prepared, in_transit, confirmed, discrepancy, cancelled
+	BankReference   *string    `json:"bank_reference" db:"bank_reference"`
+	Notes           *string    `json:"notes" db:"notes"`
+	PreparedBy      *int       `json:"prepared_by" db:"prepared_by"`
+	PreparedAt      time.Time  `json:"prepared_at" db:"prepared_at"`
+	DispatchedBy    *int       `json:"dispatched_by" db:"dispatched_by"`
+	DispatchedAt    *time.Time `json:"dispatched_at" db:"dispatched_at"`
+	ConfirmedBy     *int       `json:"confirmed_by" db:"confirmed_by"`
+	ConfirmedAt     *time.Time `json:"confirmed_at" db:"confirmed_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
 // POSTerminal represents a POS device/terminal
 type POSTerminal struct {
 	ID              int        `json:"id" db:"id"`
@@ -140,24 +211,75 @@ type POSTransaction struct {
 	Cashier           *User                `json:"cashier,omitempty"`
 	Register          *POSRegister         `json:"register,omitempty"`
 	Session           *POSSession          `json:"session,omitempty"`
+	AgeVerification   *AgeVerification     `json:"age_verification,omitempty"`
 }
 
 // POSTransactionItem represents a line item in a transaction
 type POSTransactionItem struct {
-	ID              int       `json:"id" db:"id"`
-	TransactionID   int       `json:"transaction_id" db:"transaction_id"`
-	ProductID       int       `json:"product_id" db:"product_id"`
-	Quantity        int       `json:"quantity" db:"quantity"`
-	UnitPrice       float64   `json:"unit_price" db:"unit_price"`
-	DiscountPercent float64   `json:"discount_percent" db:"discount_percent"`
-	DiscountAmount  float64   `json:"discount_amount" db:"discount_amount"`
-	TaxRate         float64   `json:"tax_rate" db:"tax_rate"`
-	TaxAmount       float64   `json:"tax_amount" db:"tax_amount"`
-	LineTotal       float64   `json:"line_total" db:"line_total"`
-	Notes           *string   `json:"notes" db:"notes"`
-	Metadata        Metadata  `json:"metadata" db:"metadata"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	Product         *Product  `json:"product,omitempty"`
+	ID              int                           `json:"id" db:"id"`
+	TransactionID   int                           `json:"transaction_id" db:"transaction_id"`
+	ProductID       int                           `json:"product_id" db:"product_id"`
+	Quantity        float64                       `json:"quantity" db:"quantity"`
+	UnitPrice       float64                       `json:"unit_price" db:"unit_price"`
+	DiscountPercent float64                       `json:"discount_percent" db:"discount_percent"`
+	DiscountAmount  float64                       `json:"discount_amount" db:"discount_amount"`
+	TaxRate         float64                       `json:"tax_rate" db:"tax_rate"`
+	TaxAmount       float64                       `json:"tax_amount" db:"tax_amount"`
+	LineTotal       float64                       `json:"line_total" db:"line_total"`
+	Notes           *string                       `json:"notes" db:"notes"`
+	Metadata        Metadata                      `json:"metadata" db:"metadata"`
+	CreatedAt       time.Time                     `json:"created_at" db:"created_at"`
+	VariantID       *int                          `json:"variant_id" db:"variant_id"`
+	UnitOfMeasure   *string                       `json:"unit_of_measure" db:"unit_of_measure"`
+	GrossWeight     *float64                      `json:"gross_weight" db:"gross_weight"`
+	TareWeight      *float64                      `json:"tare_weight" db:"tare_weight"`
+	Scale           *ScaleReading                 `json:"scale,omitempty"`   // sent by the terminal; sets quantity
+	Barcode         *string                       `json:"barcode,omitempty"` // scanned code; an embedded price or weight sets the line
+	SerialNumbers   []string                      `json:"serial_numbers,omitempty"`
+	Lots            []ItemLot                     `json:"lots,omitempty"`
+	ListPrice       *float64                      `json:"list_price" db:"list_price"` // price book price before modifiers
+	PriceBookID     *int                          `json:"price_book_id" db:"price_book_id"`
+	DiscountRuleID  *int                          `json:"discount_rule_id" db:"discount_rule_id"` // bundle rule that priced a kit
+	Product         *Product                      `json:"product,omitempty"`
+	Variant         *ProductVariant               `json:"variant,omitempty"`
+	Modifiers       []POSTransactionItemModifier  `json:"modifiers,omitempty"`
+	Components      []POSTransactionItemComponent `json:"components,omitempty"`
+}
+
+// POSTransactionItemModifier is a modifier chosen on a line item. Its price and tax are
+// included in the line's unit price and tax amount.
+type POSTransactionItemModifier struct {
+	ID                int     `json:"id" db:"id"`
+	TransactionItemID int     `json:"transaction_item_id" db:"transaction_item_id"`
+	ModifierID        int     `json:"modifier_id" db:"modifier_id"`
+	GroupID           int     `json:"group_id" db:"group_id"`
+	Name              string  `json:"name" db:"name"`
+	Quantity          int     `json:"quantity" db:"quantity"` // per unit of the line
+	Price             float64 `json:"price" db:"price"`
+	Amount            float64 `json:"amount" db:"amount"` // price x quantity x line quantity
+	TaxRate           float64 `json:"tax_rate" db:"tax_rate"`
+	TaxAmount         float64 `json:"tax_amount" db:"tax_amount"`
+}
+
+// POSTransactionItemComponent is one component of a kit sold on a line. The line's discounted
+// amount and its tax are split across its components.
+type POSTransactionItemComponent struct {
+	ID                int     `json:"id" db:"id"`
+	TransactionItemID int     `json:"transaction_item_id" db:"transaction_item_id"`
+	ProductID         int     `json:"product_id" db:"product_id"`
+	VariantID         *int    `json:"variant_id" db:"variant_id"`
+	Name              string  `json:"name" db:"name"`
+	Quantity          float64 `json:"quantity" db:"quantity"` // per kit x line quantity
+	Amount            float64 `json:"amount" db:"amount"`
+	TaxRate           float64 `json:"tax_rate" db:"tax_rate"`
+	TaxAmount         float64 `json:"tax_amount" db:"tax_amount"`
+}
+
+// ItemLot is the quantity of a line taken from one lot
+type ItemLot struct {
+	LotNumber  string     `json:"lot_number"`
+	Quantity   float64    `json:"quantity"`
+	ExpiryDate *time.Time `json:"expiry_date,omitempty"`
 }
 
 // POSPayment represents a payment for a transaction
@@ -181,7 +303,7 @@ type POSReceipt struct {
 	TenantID      string     `json:"tenant_id" db:"tenant_id"`
 	TransactionID int        `json:"transaction_id" db:"transaction_id"`
 	ReceiptNumber string     `json:"receipt_number" db:"receipt_number"`
-	ReceiptType   string     `json:"receipt_type" db:"receipt_type"` // sale, refund, void
+	ReceiptType   string     `json:"receipt_type" db:"receipt_type"` // sale, refund, void, gift
 	PrintedAt     *time.Time `json:"printed_at" db:"printed_at"`
 	ReprintCount  int        `json:"reprint_count" db:"reprint_count"`
 	EmailSent     bool       `json:"email_sent" db:"email_sent"`
@@ -189,14 +311,444 @@ type POSReceipt struct {
 	SMSSent       bool       `json:"sms_sent" db:"sms_sent"`
 	SMSSentAt     *time.Time `json:"sms_sent_at" db:"sms_sent_at"`
 	ReceiptData   string     `json:"receipt_data" db:"receipt_data"`
+	RenderedText  *string    `json:"rendered_text" db:"rendered_text"`
+	RenderedAt    *time.Time `json:"rendered_at" db:"rendered_at"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 }
 
+// PrintJob is a rendered receipt queued for a register's printer
+type PrintJob struct {
+	ID           int        `json:"id" db:"id"`
+	TenantID     string     `json:"tenant_id" db:"tenant_id"`
+	ReceiptID    int        `json:"receipt_id" db:"receipt_id"`
+	RegisterID   int        `json:"register_id" db:"register_id"`
+	Format       string     `json:"format" db:"format"` // escpos
+	PaperWidth   int        `json:"paper_width" db:"paper_width"`
+	Payload      []byte     `json:"-" db:"payload"`
+	Status       string     `json:"status" db:"status"` // queued, printed, failed
+	ErrorMessage *string    `json:"error_message" db:"error_message"`
+	CreatedBy    *int       `json:"created_by" db:"created_by"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at" db:"completed_at"`
+}
+
+// ReceiptReprint records a copy of a receipt printed after the original
+type ReceiptReprint struct {
+	ID         int       `json:"id" db:"id"`
+	TenantID   string    `json:"tenant_id" db:"tenant_id"`
+	ReceiptID  int       `json:"receipt_id" db:"receipt_id"`
+	CopyNumber int       `json:"copy_number" db:"copy_number"`
+	Reason     string    `json:"reason" db:"reason"`
+	PrintJobID *int      `json:"print_job_id" db:"print_job_id"`
+	PrintedBy  *int      `json:"printed_by" db:"printed_by"`
+	PrintedAt  time.Time `json:"printed_at" db:"printed_at"`
+}
+
+// FiscalJournalEntry is one link in a register's hash-chained fiscal journal
+type FiscalJournalEntry struct {
+	ID             int64     `json:"id" db:"id"`
+	TenantID       string    `json:"tenant_id" db:"tenant_id"`
+	RegisterID     int       `json:"register_id" db:"register_id"`
+	SequenceNumber int64     `json:"sequence_number" db:"sequence_number"`
+	EntryType      string    `json:"entry_type" db:"entry_type"` // transaction, receipt
+	EntityID       int       `json:"entity_id" db:"entity_id"`
+	Payload        string    `json:"payload" db:"payload"`
+	PayloadHash    string    `json:"payload_hash" db:"payload_hash"`
+	PreviousHash   string    `json:"previous_hash" db:"previous_hash"`
+	EntryHash      string    `json:"entry_hash" db:"entry_hash"`
+	Signature      *string   `json:"signature" db:"signature"`
+	KeyVersion     *int      `json:"key_version" db:"key_version"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// FiscalJournalIssue is a gap, broken link or tampered record found while verifying a journal
+type FiscalJournalIssue struct {
+	Type           string `json:"type"`
+	SequenceNumber *int64 `json:"sequence_number,omitempty"`
+	EntryType      string `json:"entry_type,omitempty"`
+	EntityID       *int   `json:"entity_id,omitempty"`
+	Detail         string `json:"detail"`
+}
+
+// FiscalChainReport is the outcome of verifying one register's fiscal journal
+type FiscalChainReport struct {
+	RegisterID      int                  `json:"register_id"`
+	Valid           bool                 `json:"valid"`
+	EntriesChecked  int                  `json:"entries_checked"`
+	LastSequence    int64                `json:"last_sequence"`
+	UnsignedEntries int                  `json:"unsigned_entries"`
+	FirstEntryAt    *time.Time           `json:"first_entry_at"`
+	Issues          []FiscalJournalIssue `json:"issues"`
+}
+
+// GiftReceiptLookup resolves a gift receipt's return code to the original sale
+type GiftReceiptLookup struct {
+	ReceiptID         int               `json:"receipt_id"`
+	ReceiptNumber     string            `json:"receipt_number"`
+	TransactionID     int               `json:"transaction_id"`
+	TransactionNumber string            `json:"transaction_number"`
+	TransactionDate   time.Time         `json:"transaction_date"`
+	Lines             []GiftReceiptLine `json:"lines"`
+	RefundableTotal   float64           `json:"refundable_total"`
+}
+
+// GiftReceiptLine is an original sale line covered by a gift receipt
+type GiftReceiptLine struct {
+	TransactionItemID int     `json:"transaction_item_id"`
+	ProductID         int     `json:"product_id"`
+	SKU               string  `json:"sku"`
+	Description       string  `json:"description"`
+	Quantity          float64 `json:"quantity"`
+	UnitRefundValue   float64 `json:"unit_refund_value"`
+	RefundableValue   float64 `json:"refundable_value"`
+}
+
+// EmailDelivery is a queued or attempted receipt email
+type EmailDelivery struct {
+	ID            int        `json:"id" db:"id"`
+	TenantID      string     `json:"tenant_id" db:"tenant_id"`
+	ReceiptID     int        `json:"receipt_id" db:"receipt_id"`
+	Recipient     string     `json:"recipient" db:"recipient"`
+	Subject       string     `json:"subject" db:"subject"`
+	Status        string     `json:"status" db:"status"` // queued, sending, sent, failed, bounced
+	Attempts      int        `json:"attempts" db:"attempts"`
+	MaxAttempts   int        `json:"max_attempts" db:"max_attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	SentAt        *time.Time `json:"sent_at" db:"sent_at"`
+	BouncedAt     *time.Time `json:"bounced_at" db:"bounced_at"`
+	CreatedBy     *int       `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SMSConsent records whether a customer agreed to receipt texts
+type SMSConsent struct {
+	ID         int        `json:"id" db:"id"`
+	TenantID   string     `json:"tenant_id" db:"tenant_id"`
+	CustomerID int        `json:"customer_id" db:"customer_id"`
+	PhoneE164  string     `json:"phone_e164" db:"phone_e164"`
+	OptedIn    bool       `json:"opted_in" db:"opted_in"`
+	Source     *string    `json:"source" db:"source"` // pos, web, import
+	OptedInAt  *time.Time `json:"opted_in_at" db:"opted_in_at"`
+	OptedOutAt *time.Time `json:"opted_out_at" db:"opted_out_at"`
+	UpdatedBy  *int       `json:"updated_by" db:"updated_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CustomerPriceGroup assigns a customer to the customer group their prices are resolved for
+type CustomerPriceGroup struct {
+	ID              int       `json:"id" db:"id"`
+	TenantID        string    `json:"tenant_id" db:"tenant_id"`
+	CustomerID      int       `json:"customer_id" db:"customer_id"`
+	CustomerGroupID int       `json:"customer_group_id" db:"customer_group_id"`
+	UpdatedBy       *int      `json:"updated_by" db:"updated_by"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SMSMessage is a receipt text sent through the SMS provider
+type SMSMessage struct {
+	ID                int       `json:"id" db:"id"`
+	TenantID          string    `json:"tenant_id" db:"tenant_id"`
+	ReceiptID         int       `json:"receipt_id" db:"receipt_id"`
+	CustomerID        *int      `json:"customer_id" db:"customer_id"`
+	PhoneE164         string    `json:"phone_e164" db:"phone_e164"`
+	Body              string    `json:"body" db:"body"`
+	Status            string    `json:"status" db:"status"` // sent, failed
+	ProviderMessageID *string   `json:"provider_message_id" db:"provider_message_id"`
+	ErrorMessage      *string   `json:"error_message" db:"error_message"`
+	CreatedBy         *int      `json:"created_by" db:"created_by"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
+// Invoice is an issued tax invoice for a business customer's transaction
+type Invoice struct {
+	ID             int             `json:"id" db:"id"`
+	TenantID       string          `json:"tenant_id" db:"tenant_id"`
+	TransactionID  int             `json:"transaction_id" db:"transaction_id"`
+	SequenceNumber int             `json:"sequence_number" db:"sequence_number"`
+	InvoiceNumber  string          `json:"invoice_number" db:"invoice_number"`
+	CustomerID     *int            `json:"customer_id" db:"customer_id"`
+	BuyerName      string          `json:"buyer_name" db:"buyer_name"`
+	BuyerTaxID     *string         `json:"buyer_tax_id" db:"buyer_tax_id"`
+	TotalAmount    float64         `json:"total_amount" db:"total_amount"`
+	InvoiceData    json.RawMessage `json:"invoice_data" db:"invoice_data"`
+	IssuedBy       *int            `json:"issued_by" db:"issued_by"`
+	IssuedAt       time.Time       `json:"issued_at" db:"issued_at"`
+}
+
+// InvoiceDocument is the content of a tax invoice as issued
+type InvoiceDocument struct {
+	InvoiceNumber     string           `json:"invoice_number"`
+	IssuedAt          time.Time        `json:"issued_at"`
+	TransactionNumber string           `json:"transaction_number"`
+	TransactionDate   time.Time        `json:"transaction_date"`
+	Currency          string           `json:"currency"`
+	Seller            InvoiceParty     `json:"seller"`
+	Buyer             InvoiceParty     `json:"buyer"`
+	Lines             []ReceiptLine    `json:"lines"`
+	TaxSummary        []ReportTaxLine  `json:"tax_summary"`
+	Subtotal          float64          `json:"subtotal"`
+	DiscountTotal     float64          `json:"discount_total"`
+	TaxTotal          float64          `json:"tax_total"`
+	Total             float64          `json:"total"`
+	TotalInWords      string           `json:"total_in_words"`
+	Payments          []ReceiptPayment `json:"payments"`
+}
+
+// InvoiceParty is the seller or buyer on an invoice
+type InvoiceParty struct {
+	Name    string  `json:"name"`
+	Contact *string `json:"contact,omitempty"`
+	Address string  `json:"address,omitempty"`
+	TaxID   string  `json:"tax_id,omitempty"`
+	Email   *string `json:"email,omitempty"`
+	Phone   *string `json:"phone,omitempty"`
+}
+
+// ReceiptTemplate holds the tenant-configurable parts of a receipt, as resolved when the receipt was issued
+type ReceiptTemplate struct {
+	TemplateID      *int                         `json:"template_id,omitempty"`
+	Header          string                       `json:"header"`
+	Footer          string                       `json:"footer"`
+	LogoURL         string                       `json:"logo_url,omitempty"`
+	LegalText       string                       `json:"legal_text,omitempty"`
+	ReturnPolicy    string                       `json:"return_policy,omitempty"`
+	Locale          string                       `json:"locale,omitempty"`
+	SecondaryLocale string                       `json:"secondary_locale,omitempty"`
+	Currency        string                       `json:"currency,omitempty"`
+	Sections        *ReceiptSections             `json:"sections,omitempty"`
+	Translations    map[string]map[string]string `json:"translations,omitempty"` // locale -> key -> text
+}
+
+// ReceiptSections switches optional receipt sections on or off
+type ReceiptSections struct {
+	TaxSummary     bool `json:"tax_summary"`
+	LoyaltyBalance bool `json:"loyalty_balance"`
+	ReturnPolicy   bool `json:"return_policy"`
+}
+
+// POSReceiptTemplate is a receipt template for a tenant, location or register
+type POSReceiptTemplate struct {
+	ID              int                          `json:"id" db:"id"`
+	TenantID        string                       `json:"tenant_id" db:"tenant_id"`
+	Name            string                       `json:"name" db:"name"`
+	LocationID      *int                         `json:"location_id" db:"location_id"`
+	RegisterID      *int                         `json:"register_id" db:"register_id"`
+	Locale          string                       `json:"locale" db:"locale"`
+	SecondaryLocale *string                      `json:"secondary_locale" db:"secondary_locale"`
+	Currency        string                       `json:"currency" db:"currency"`
+	Header          string                       `json:"header" db:"header"`
+	Footer          string                       `json:"footer" db:"footer"`
+	LogoURL         string                       `json:"logo_url" db:"logo_url"`
+	LegalText       string                       `json:"legal_text" db:"legal_text"`
+	ReturnPolicy    string                       `json:"return_policy" db:"return_policy"`
+	Sections        ReceiptSections              `json:"sections" db:"sections"`
+	Translations    map[string]map[string]string `json:"translations" db:"translations"`
+	CreatedBy       *int                         `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time                    `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time                    `json:"updated_at" db:"updated_at"`
+}
+
+// ReceiptDocument is the canonical receipt built from a recorded transaction
+type ReceiptDocument struct {
+	ReceiptNumber     string           `json:"receipt_number"`
+	ReceiptType       string           `json:"receipt_type"` // sale, refund, void, gift
+	IssuedAt          time.Time        `json:"issued_at"`
+	Template          ReceiptTemplate  `json:"template"`
+	TransactionID     int              `json:"transaction_id"`
+	TransactionNumber string           `json:"transaction_number"`
+	TransactionType   string           `json:"transaction_type"`
+	TransactionDate   time.Time        `json:"transaction_date"`
+	Register          ReceiptRegister  `json:"register"`
+	Cashier           string           `json:"cashier"`
+	Customer          *ReceiptCustomer `json:"customer,omitempty"`
+	Lines             []ReceiptLine    `json:"lines"`
+	TaxSummary        []ReportTaxLine  `json:"tax_summary"`
+	Payments          []ReceiptPayment `json:"payments"`
+	Subtotal          float64          `json:"subtotal"`
+	DiscountTotal     float64          `json:"discount_total"`
+	TaxTotal          float64          `json:"tax_total"`
+	TipAmount         float64          `json:"tip_amount"`
+	Total             float64          `json:"total"`
+	AmountPaid        float64          `json:"amount_paid"`
+	ChangeAmount      float64          `json:"change_amount"`
+}
+
+// ReceiptRegister identifies the register a receipt was issued from
+type ReceiptRegister struct {
+	ID         int    `json:"id"`
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	LocationID *int   `json:"location_id,omitempty"`
+}
+
+// ReceiptCustomer is the customer printed on a receipt
+type ReceiptCustomer struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	CompanyName   *string `json:"company_name,omitempty"`
+	Email         *string `json:"email,omitempty"`
+	Phone         *string `json:"phone,omitempty"`
+	LoyaltyPoints *int    `json:"loyalty_points,omitempty"`
+}
+
+// ReceiptLine is one item line on a receipt
+type ReceiptLine struct {
+	ItemID         int                `json:"item_id"`
+	ProductID      int                `json:"product_id"`
+	SKU            string             `json:"sku"`
+	Description    string             `json:"description"`
+	Quantity       float64            `json:"quantity"`
+	UnitOfMeasure  string             `json:"unit_of_measure,omitempty"`
+	UnitPrice      float64            `json:"unit_price"`
+	DiscountAmount float64            `json:"discount_amount"`
+	TaxRate        float64            `json:"tax_rate"`
+	TaxAmount      float64            `json:"tax_amount"`
+	LineTotal      float64            `json:"line_total"`
+	Variant        string             `json:"variant,omitempty"`
+	Modifiers      []ReceiptModifier  `json:"modifiers,omitempty"`
+	Components     []ReceiptComponent `json:"components,omitempty"`
+	SerialNumbers  []string           `json:"serial_numbers,omitempty"`
+	LotNumbers     []string           `json:"lot_numbers,omitempty"`
+}
+
+// ReceiptModifier is a modifier printed under its item line
+type ReceiptModifier struct {
+	Name      string  `json:"name"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+	Amount    float64 `json:"amount"`
+	TaxRate   float64 `json:"tax_rate"`
+	TaxAmount float64 `json:"tax_amount"`
+}
+
+// ReceiptComponent is a kit component printed under its kit's line
+type ReceiptComponent struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	Amount      float64 `json:"amount"`
+	TaxRate     float64 `json:"tax_rate"`
+	TaxAmount   float64 `json:"tax_amount"`
+}
+
+// ReceiptPayment is one tender on a receipt; card references are masked
+type ReceiptPayment struct {
+	PaymentMethod string  `json:"payment_method"`
+	Amount        float64 `json:"amount"`
+	CardType      *string `json:"card_type,omitempty"`
+	Reference     *string `json:"reference,omitempty"`
+}
+
+// RegisterReport is an X- or Z-report for a register, session or shift
+type RegisterReport struct {
+	ReportType             string             `json:"report_type"` // X, Z
+	ZNumber                *int               `json:"z_number,omitempty"`
+	Scope                  string             `json:"scope"` // register, session, shift
+	RegisterID             int                `json:"register_id"`
+	SessionID              *int               `json:"session_id,omitempty"`
+	ShiftID                *int               `json:"shift_id,omitempty"`
+	PeriodStart            time.Time          `json:"period_start"`
+	PeriodEnd              time.Time          `json:"period_end"`
+	GeneratedAt            time.Time          `json:"generated_at"`
+	GeneratedBy            int                `json:"generated_by"`
+	GrossSales             float64            `json:"gross_sales"`
+	Discounts              float64            `json:"discounts"`
+	Returns                float64            `json:"returns"`
+	NetSales               float64            `json:"net_sales"`
+	TaxTotal               float64            `json:"tax_total"`
+	Tips                   float64            `json:"tips"`
+	SalesCount             int                `json:"sales_count"`
+	ReturnCount            int                `json:"return_count"`
+	VoidCount              int                `json:"void_count"`
+	VoidAmount             float64            `json:"void_amount"`
+	TaxByRate              []ReportTaxLine    `json:"tax_by_rate"`
+	Tenders                []ReportTenderLine `json:"tenders"`
+	CashMovements          []ReportCashLine   `json:"cash_movements"`
+	OpeningFloat           float64            `json:"opening_float"`
+	CashTendered           float64            `json:"cash_tendered"`
+	ChangeGiven            float64            `json:"change_given"`
+	ExpectedCash           float64            `json:"expected_cash"`
+	CountedCash            *float64           `json:"counted_cash"`
+	CashVariance           *float64           `json:"cash_variance"`
+	TransactionCount       int                `json:"transaction_count"`
+	FirstTransactionNumber *string            `json:"first_transaction_number"`
+	LastTransactionNumber  *string            `json:"last_transaction_number"`
+}
+
+// ReportTaxLine totals tax collected at one rate
+type ReportTaxLine struct {
+	Rate          float64 `json:"rate"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	TaxAmount     float64 `json:"tax_amount"`
+}
+
+// ReportTenderLine totals payments taken with one payment method
+type ReportTenderLine struct {
+	PaymentMethod string  `json:"payment_method"`
+	Count         int     `json:"count"`
+	Amount        float64 `json:"amount"`
+}
+
+// ReportCashLine totals one type of register cash movement
+type ReportCashLine struct {
+	TransactionType string  `json:"transaction_type"`
+	Count           int     `json:"count"`
+	Amount          float64 `json:"amount"`
+}
+
+// ReportItemLine totals one product, or one variant of it, before tax; amounts include modifiers
+type ReportItemLine struct {
+	ProductID        int     `json:"product_id"`
+	ProductName      string  `json:"product_name"`
+	VariantID        *int    `json:"variant_id,omitempty"`
+	VariantName      *string `json:"variant_name,omitempty"`
+	QuantitySold     float64 `json:"quantity_sold"`
+	QuantityReturned float64 `json:"quantity_returned"`
+	Sales            float64 `json:"sales"`
+	Returns          float64 `json:"returns"`
+	NetSales         float64 `json:"net_sales"`
+}
+
+// ReportModifierLine totals one modifier across every line it was chosen on
+type ReportModifierLine struct {
+	ModifierID       int     `json:"modifier_id"`
+	GroupID          int     `json:"group_id"`
+	GroupName        string  `json:"group_name"`
+	Name             string  `json:"name"`
+	QuantitySold     float64 `json:"quantity_sold"`
+	QuantityReturned float64 `json:"quantity_returned"`
+	Sales            float64 `json:"sales"`
+	Returns          float64 `json:"returns"`
+	NetSales         float64 `json:"net_sales"`
+}
+
+// ZReport is a persisted, numbered Z-report
+type ZReport struct {
+	ID               int             `json:"id" db:"id"`
+	TenantID         string          `json:"tenant_id" db:"tenant_id"`
+	RegisterID       int             `json:"register_id" db:"register_id"`
+	SessionID        *int            `json:"session_id" db:"session_id"`
+	ShiftID          *int            `json:"shift_id" db:"shift_id"`
+	Scope            string          `json:"scope" db:"scope"`
+	ZNumber          int             `json:"z_number" db:"z_number"`
+	PeriodStart      time.Time       `json:"period_start" db:"period_start"`
+	PeriodEnd        time.Time       `json:"period_end" db:"period_end"`
+	TransactionCount int             `json:"transaction_count" db:"transaction_count"`
+	NetSales         float64         `json:"net_sales" db:"net_sales"`
+	ReportData       json.RawMessage `json:"report_data,omitempty" db:"report_data"`
+	GeneratedBy      *int            `json:"generated_by" db:"generated_by"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+}
+
 // QuickSaleCategory represents a category for quick sale items
 type QuickSaleCategory struct {
 	ID           int       `json:"id" db:"id"`
 	TenantID     string    `json:"tenant_id" db:"tenant_id"`
 	LocationID   *int      `json:"location_id" db:"location_id"`
+	RegisterID   *int      `json:"register_id" db:"register_id"` // set on a register's own layout
 	CategoryCode string    `json:"category_code" db:"category_code"`
 	CategoryName string    `json:"category_name" db:"category_name"`
 	ColorCode    *string   `json:"color_code" db:"color_code"`
@@ -212,6 +764,7 @@ type QuickSaleItem struct {
 	ID           int                `json:"id" db:"id"`
 	TenantID     string             `json:"tenant_id" db:"tenant_id"`
 	LocationID   *int               `json:"location_id" db:"location_id"`
+	RegisterID   *int               `json:"register_id" db:"register_id"`
 	CategoryID   *int               `json:"category_id" db:"category_id"`
 	ProductID    int                `json:"product_id" db:"product_id"`
 	ButtonText   string             `json:"button_text" db:"button_text"`
@@ -224,6 +777,336 @@ type QuickSaleItem struct {
 	Category     *QuickSaleCategory `json:"category,omitempty"`
 }
 
+// QuickSaleLayout is the quick sale screen of one layout: the tenant default, a location's or a
+// register's own. It is also the export and import format.
+type QuickSaleLayout struct {
+	FormatVersion int                       `json:"format_version"`
+	Scope         string                    `json:"scope"` // default, location, register
+	LocationID    *int                      `json:"location_id"`
+	RegisterID    *int                      `json:"register_id"`
+	ExportedAt    *time.Time                `json:"exported_at,omitempty"`
+	Categories    []QuickSaleLayoutCategory `json:"categories"`
+	Items         []QuickSaleLayoutItem     `json:"items"` // buttons outside any category
+}
+
+// QuickSaleLayoutCategory is a category tab of a layout with its buttons in display order
+type QuickSaleLayoutCategory struct {
+	ID           int                   `json:"id,omitempty"`
+	CategoryCode string                `json:"category_code"`
+	CategoryName string                `json:"category_name"`
+	ColorCode    *string               `json:"color_code"`
+	Icon         *string               `json:"icon"`
+	DisplayOrder int                   `json:"display_order"`
+	IsActive     *bool                 `json:"is_active"` // nil imports as active
+	Items        []QuickSaleLayoutItem `json:"items"`
+}
+
+// QuickSaleLayoutItem is a product button of a layout. Imports find the product by SKU when given,
+// so layouts move between tenants whose product IDs differ.
+type QuickSaleLayoutItem struct {
+	ID           int     `json:"id,omitempty"`
+	ProductID    int     `json:"product_id"`
+	SKU          *string `json:"sku,omitempty"`
+	ButtonText   string  `json:"button_text"`
+	ButtonColor  *string `json:"button_color"`
+	DisplayOrder int     `json:"display_order"`
+	IsActive     *bool   `json:"is_active"` // nil imports as active
+}
+
+// ProductBarcode is an alternate barcode that resolves to a product
+type ProductBarcode struct {
+	ID          int       `json:"id" db:"id"`
+	TenantID    string    `json:"tenant_id" db:"tenant_id"`
+	ProductID   int       `json:"product_id" db:"product_id"`
+	Barcode     string    `json:"barcode" db:"barcode"`
+	Symbology   string    `json:"symbology" db:"symbology"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+	Description *string   `json:"description" db:"description"`
+	CreatedBy   *int      `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// ProductVariant is a sellable variation of a product, such as a size and colour
+type ProductVariant struct {
+	ID         int       `json:"id" db:"id"`
+	TenantID   string    `json:"tenant_id" db:"tenant_id"`
+	ProductID  int       `json:"product_id" db:"product_id"`
+	Name       string    `json:"name" db:"name"`
+	SKU        *string   `json:"sku" db:"sku"`
+	Barcode    *string   `json:"barcode" db:"barcode"`
+	Attributes Metadata  `json:"attributes" db:"attributes"`
+	Price      *float64  `json:"price" db:"price"` // nil sells at the product's price
+	IsActive   bool      `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ModifierGroup is a set of modifiers chosen together, such as milk options
+type ModifierGroup struct {
+	ID            int        `json:"id" db:"id"`
+	TenantID      string     `json:"tenant_id" db:"tenant_id"`
+	Name          string     `json:"name" db:"name"`
+	MinSelections int        `json:"min_selections" db:"min_selections"`
+	MaxSelections int        `json:"max_selections" db:"max_selections"` // 0 means no limit
+	IsActive      bool       `json:"is_active" db:"is_active"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	Modifiers     []Modifier `json:"modifiers"`
+}
+
+// Modifier is one option in a modifier group, with its own price and tax rate
+type Modifier struct {
+	ID        int       `json:"id" db:"id"`
+	TenantID  string    `json:"tenant_id" db:"tenant_id"`
+	GroupID   int       `json:"group_id" db:"group_id"`
+	Name      string    `json:"name" db:"name"`
+	Price     float64   `json:"price" db:"price"`
+	TaxRate   *float64  `json:"tax_rate" db:"tax_rate"` // nil is taxed at the item's rate
+	SortOrder int       `json:"sort_order" db:"sort_order"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ProductModifierGroup is a modifier group offered on a product, with the selection rules that apply to it
+type ProductModifierGroup struct {
+	ProductID     int        `json:"product_id" db:"product_id"`
+	GroupID       int        `json:"group_id" db:"group_id"`
+	Name          string     `json:"name" db:"name"`
+	MinSelections int        `json:"min_selections" db:"min_selections"`
+	MaxSelections int        `json:"max_selections" db:"max_selections"`
+	SortOrder     int        `json:"sort_order" db:"sort_order"`
+	Modifiers     []Modifier `json:"modifiers"`
+}
+
+// BarcodeScanResult is a scanned barcode resolved to a product and what to put on the sale
+type BarcodeScanResult struct {
+	Barcode     string          `json:"barcode"`
+	Symbology   string          `json:"symbology"`
+	Product     *Product        `json:"product"`
+	Variant     *ProductVariant `json:"variant,omitempty"`
+	Quantity    float64         `json:"quantity"`
+	UnitPrice   float64         `json:"unit_price"`
+	Weight      *float64        `json:"weight,omitempty"`
+	Price       float64         `json:"price"`                   // line price before discounts and tax
+	Embedded    *string         `json:"embedded,omitempty"`      // price or weight, for in-store codes
+	PriceBookID *int            `json:"price_book_id,omitempty"` // the book the unit price comes from
+	MinimumAge  int             `json:"minimum_age,omitempty"`   // the customer's age must be verified to sell it
+}
+
+// ProductSearchResult is a product found by a terminal search, with how it matched and how often it sells
+type ProductSearchResult struct {
+	ProductID   int     `json:"product_id"`
+	VariantID   *int    `json:"variant_id,omitempty"` // set when a variant's barcode matched
+	Name        string  `json:"name"`
+	SKU         *string `json:"sku"`
+	Barcode     *string `json:"barcode"`
+	CategoryID  *int    `json:"category_id"`
+	Price       float64 `json:"price"`
+	PriceBookID *int    `json:"price_book_id,omitempty"`
+	MatchType   string  `json:"match_type"`  // barcode, sku, sku_prefix, name_prefix, name, fuzzy, category
+	Similarity  float64 `json:"similarity"`  // trigram word similarity of the term to the name, 0-1
+	SalesCount  int     `json:"sales_count"` // sales including the product in the ranking window
+}
+
+// UnitOfMeasure is a unit products are sold in, with the precision and rounding of its quantities
+type UnitOfMeasure struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Decimals  int    `json:"decimals"`
+	Rounding  string `json:"rounding"` // half_up, down, up
+	IsWeighed bool   `json:"is_weighed"`
+	BuiltIn   bool   `json:"built_in"`
+}
+
+// ProductUnit is the unit a product is sold in and the tare taken off its scale readings
+type ProductUnit struct {
+	TenantID      string    `json:"tenant_id" db:"tenant_id"`
+	ProductID     int       `json:"product_id" db:"product_id"`
+	UnitCode      string    `json:"unit_code" db:"unit_code"`
+	TareWeight    float64   `json:"tare_weight" db:"tare_weight"`
+	RequiresScale bool      `json:"requires_scale" db:"requires_scale"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ScaleReading is a weight read by the terminal's scale for a line item
+type ScaleReading struct {
+	GrossWeight float64 `json:"gross_weight"`
+	Unit        string  `json:"unit"`   // unit of the reading; defaults to the product's unit
+	Tare        float64 `json:"tare"`   // tare keyed or set on the scale, on top of the product's
+	Stable      *bool   `json:"stable"` // omitted by scales that only report settled weights
+}
+
+// ProductTracking says whether a product's serial or lot numbers are captured when it is sold
+type ProductTracking struct {
+	TenantID     string    `json:"tenant_id" db:"tenant_id"`
+	ProductID    int       `json:"product_id" db:"product_id"`
+	Tracking     string    `json:"tracking" db:"tracking"` // serial, lot
+	WarrantyDays *int      `json:"warranty_days" db:"warranty_days"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SerialNumber is one serialized unit received into stock
+type SerialNumber struct {
+	ID           int       `json:"id" db:"id"`
+	TenantID     string    `json:"tenant_id" db:"tenant_id"`
+	ProductID    int       `json:"product_id" db:"product_id"`
+	SerialNumber string    `json:"serial_number" db:"serial_number"`
+	Status       string    `json:"status" db:"status"` // in_stock, sold, damaged
+	SoldItemID   *int      `json:"sold_item_id" db:"sold_item_id"`
+	ReceivedBy   *int      `json:"received_by" db:"received_by"`
+	ReceivedAt   time.Time `json:"received_at" db:"received_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Lot is a production lot of a product with the quantity still on hand
+type Lot struct {
+	ID             int        `json:"id" db:"id"`
+	TenantID       string     `json:"tenant_id" db:"tenant_id"`
+	ProductID      int        `json:"product_id" db:"product_id"`
+	LotNumber      string     `json:"lot_number" db:"lot_number"`
+	ExpiryDate     *time.Time `json:"expiry_date" db:"expiry_date"`
+	QuantityOnHand float64    `json:"quantity_on_hand" db:"quantity_on_hand"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SerialTransaction is a sale or return line a serial number was captured on
+type SerialTransaction struct {
+	TransactionID     int       `json:"transaction_id"`
+	TransactionNumber string    `json:"transaction_number"`
+	TransactionType   string    `json:"transaction_type"`
+	Status            string    `json:"status"`
+	TransactionDate   time.Time `json:"transaction_date"`
+	TransactionItemID int       `json:"transaction_item_id"`
+	RegisterID        int       `json:"register_id"`
+	CustomerID        *int      `json:"customer_id"`
+}
+
+// SerialLookup is a serial number with the sale it left on, for warranty returns
+type SerialLookup struct {
+	Serial            SerialNumber        `json:"serial"`
+	ProductName       string              `json:"product_name"`
+	OriginalSale      *SerialTransaction  `json:"original_sale"` // latest completed sale, if any
+	WarrantyExpiresAt *time.Time          `json:"warranty_expires_at,omitempty"`
+	InWarranty        *bool               `json:"in_warranty,omitempty"`
+	Transactions      []SerialTransaction `json:"transactions"`
+}
+
+// Kit is a product sold as one line whose components leave stock in its place
+type Kit struct {
+	ID               int            `json:"id" db:"id"`
+	TenantID         string         `json:"tenant_id" db:"tenant_id"`
+	ProductID        int            `json:"product_id" db:"product_id"`
+	AllocationMethod string         `json:"allocation_method" db:"allocation_method"` // list_price, quantity, fixed
+	IsActive         bool           `json:"is_active" db:"is_active"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at" db:"updated_at"`
+	Components       []KitComponent `json:"components"`
+}
+
+// KitComponent is a product, or one variant of it, in a kit
+type KitComponent struct {
+	ID             int      `json:"id" db:"id"`
+	ProductID      int      `json:"product_id" db:"product_id"`
+	VariantID      *int     `json:"variant_id" db:"variant_id"`
+	Name           string   `json:"name"`
+	Quantity       float64  `json:"quantity" db:"quantity"`               // per kit
+	AllocatedPrice *float64 `json:"allocated_price" db:"allocated_price"` // per unit, for the fixed method
+	TaxRate        *float64 `json:"tax_rate" db:"tax_rate"`               // nil is taxed at the kit line's rate
+	SortOrder      int      `json:"sort_order" db:"sort_order"`
+}
+
+// ProductAgeRestriction is the minimum age a customer must be to buy a product
+type ProductAgeRestriction struct {
+	TenantID   string    `json:"tenant_id" db:"tenant_id"`
+	ProductID  int       `json:"product_id" db:"product_id"`
+	MinimumAge int       `json:"minimum_age" db:"minimum_age"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AgeSaleRestriction is a window of local time when age-restricted products may not be sold
+type AgeSaleRestriction struct {
+	ID         int       `json:"id" db:"id"`
+	TenantID   string    `json:"tenant_id" db:"tenant_id"`
+	LocationID *int      `json:"location_id" db:"location_id"` // nil for every location
+	Name       string    `json:"name" db:"name"`
+	MinimumAge *int      `json:"minimum_age" db:"minimum_age"` // nil for every restricted product
+	DayOfWeek  *int      `json:"day_of_week" db:"day_of_week"` // 0 = Sunday; nil for every day
+	TimeFrom   string    `json:"time_from" db:"time_from"`     // HH:MM
+	TimeTo     string    `json:"time_to" db:"time_to"`
+	Timezone   string    `json:"timezone" db:"timezone"`
+	IsActive   bool      `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AgeVerification is the age check recorded on a sale of age-restricted products
+type AgeVerification struct {
+	ID                int        `json:"id" db:"id"`
+	TransactionID     int        `json:"transaction_id" db:"transaction_id"`
+	TransactionNumber string     `json:"transaction_number,omitempty"`
+	RegisterID        int        `json:"register_id,omitempty"`
+	Method            string     `json:"method" db:"method"` // dob_entered, id_scanned, manager_override
+	RequiredAge       int        `json:"required_age" db:"required_age"`
+	DateOfBirth       *time.Time `json:"date_of_birth" db:"date_of_birth"`
+	CustomerAge       *int       `json:"customer_age" db:"customer_age"`
+	IDType            *string    `json:"id_type" db:"id_type"`
+	IDExpiry          *time.Time `json:"id_expiry" db:"id_expiry"`
+	ManagerID         *int       `json:"manager_id" db:"manager_id"`
+	OverrideReason    *string    `json:"override_reason" db:"override_reason"`
+	VerifiedBy        int        `json:"verified_by" db:"verified_by"`
+	VerifiedAt        time.Time  `json:"verified_at" db:"verified_at"`
+}
+
+// PriceBook is a set of prices that applies to every register, a location, a register or a customer group
+type PriceBook struct {
+	ID          int        `json:"id" db:"id"`
+	TenantID    string     `json:"tenant_id" db:"tenant_id"`
+	Name        string     `json:"name" db:"name"`
+	ScopeType   string     `json:"scope_type" db:"scope_type"` // all, location, register, customer_group
+	ScopeID     *int       `json:"scope_id" db:"scope_id"`
+	Priority    int        `json:"priority" db:"priority"`
+	ValidFrom   *time.Time `json:"valid_from" db:"valid_from"`
+	ValidTo     *time.Time `json:"valid_to" db:"valid_to"`
+	IsActive    bool       `json:"is_active" db:"is_active"`
+	Version     int        `json:"version" db:"version"`
+	PublishedAt *time.Time `json:"published_at" db:"published_at"`
+	CreatedBy   *int       `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// PriceBookEntry is a product or variant price in a price book from its effective date
+type PriceBookEntry struct {
+	ID            int        `json:"id" db:"id"`
+	PriceBookID   int        `json:"price_book_id" db:"price_book_id"`
+	ProductID     int        `json:"product_id" db:"product_id"`
+	VariantID     *int       `json:"variant_id" db:"variant_id"`
+	Price         float64    `json:"price" db:"price"`
+	EffectiveFrom time.Time  `json:"effective_from" db:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to" db:"effective_to"`
+	CreatedBy     *int       `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PublishedPriceBook is the snapshot of a price book terminals download, with scheduled entries
+type PublishedPriceBook struct {
+	PriceBook
+	Entries []PriceBookEntry `json:"entries"`
+}
+
+// ResolvedPrice is the price a product or variant sells at in a pricing context, and where it came from
+type ResolvedPrice struct {
+	ProductID     int     `json:"product_id"`
+	VariantID     *int    `json:"variant_id,omitempty"`
+	Price         float64 `json:"price"`
+	Source        string  `json:"source"` // price_book, variant, product
+	PriceBookID   *int    `json:"price_book_id,omitempty"`
+	PriceBookName *string `json:"price_book_name,omitempty"`
+	EntryID       *int    `json:"entry_id,omitempty"`
+}
+
 // POSProduct represents POS-specific product settings
 type POSProduct struct {
 	ID           int       `json:"id" db:"id"`
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const (
+	// emailDeliveryInterval is how often the worker sends due emails
+	emailDeliveryInterval = 30 * time.Second
+	// emailDeliveryBatchSize caps the emails claimed in one pass
+	emailDeliveryBatchSize = 20
+	// emailSendingTimeout releases deliveries left in 'sending' by a crashed worker
+	emailSendingTimeout = 10 * time.Minute
+)
+
+// EmailMessage is a rendered email ready for a transport
+type EmailMessage struct {
+	From     string
+	To       string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// EmailTransport sends email messages
+type EmailTransport interface {
+	Send(ctx context.Context, msg EmailMessage) error
+}
+
+// errPermanentEmailFailure wraps failures that retrying will not fix, such as a rejected recipient
+var errPermanentEmailFailure = errors.New("permanent delivery failure")
+
+// smtpTransport sends through an SMTP relay, upgrading to STARTTLS when offered
+type smtpTransport struct {
+	addr string
+	auth smtp.Auth
+}
+
+// Send delivers the message over SMTP
+func (t *smtpTransport) Send(ctx context.Context, msg EmailMessage) error {
+	body, err := buildMIMEMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	err = smtp.SendMail(t.addr, t.auth, msg.From, []string{msg.To}, body)
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return fmt.Errorf("%w: %v", errPermanentEmailFailure, err)
+	}
+	return err
+}
+
+// fileTransport writes each message as an .eml file, for local development and tests
+type fileTransport struct {
+	dir string
+}
+
+// Send writes the message to the transport directory
+func (t *fileTransport) Send(ctx context.Context, msg EmailMessage) error {
+	body, err := buildMIMEMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(msg.To))
+	return os.WriteFile(filepath.Join(t.dir, name), body, 0o644)
+}
+
+// errEmailNotConfigured is returned for every send while no email transport is configured
+var errEmailNotConfigured = errors.New("no email transport configured: set POS_SMTP_HOST, or POS_EMAIL_TRANSPORT=file for local development")
+
+// unconfiguredTransport fails every send, so deliveries are retried and then fail instead of being marked sent
+type unconfiguredTransport struct{}
+
+// Send always returns errEmailNotConfigured
+func (unconfiguredTransport) Send(ctx context.Context, msg EmailMessage) error {
+	return errEmailNotConfigured
+}
+
+// newEmailTransportFromEnv uses the file transport when POS_EMAIL_TRANSPORT=file and SMTP when
+// POS_SMTP_HOST is set. Without either, sends fail and emails are never reported as sent.
+func newEmailTransportFromEnv(logger *zap.Logger) EmailTransport {
+	if os.Getenv("POS_EMAIL_TRANSPORT") == "file" {
+		dir := os.Getenv("POS_MAIL_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "pos-mail")
+		}
+		logger.Info("POS_EMAIL_TRANSPORT=file; receipt emails will be written to disk", zap.String("dir", dir))
+		return &fileTransport{dir: dir}
+	}
+
+	host := os.Getenv("POS_SMTP_HOST")
+	if host == "" {
+		logger.Warn("POS_SMTP_HOST not set; receipt emails will not be delivered")
+		return unconfiguredTransport{}
+	}
+
+	port := os.Getenv("POS_SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("POS_SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("POS_SMTP_PASSWORD"), host)
+	}
+
+	return &smtpTransport{addr: host + ":" + port, auth: auth}
+}
+
+// emailSender returns the From address for receipt emails
+func emailSender() string {
+	if from := os.Getenv("POS_SMTP_FROM"); from != "" {
+		return from
+	}
+	return "receipts@localhost"
+}
+
+// buildMIMEMessage encodes the message as multipart/alternative with text and HTML parts
+func buildMIMEMessage(msg EmailMessage) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.TextBody},
+		{"text/html; charset=utf-8", msg.HTMLBody},
+	}
+	for _, part := range parts {
+		if part.content == "" {
+			continue
+		}
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&out, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&out, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&out, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	out.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&out, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// =================================================================
+// DELIVERY WORKER
+// =================================================================
+
+// EmailWorker sends queued receipt emails and retries failures with backoff
+type EmailWorker struct {
+	db        *sqlx.DB
+	logger    *zap.Logger
+	transport EmailTransport
+	interval  time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewEmailWorker creates an email worker using the transport configured in the environment
+func NewEmailWorker(db *sqlx.DB, logger *zap.Logger) *EmailWorker {
+	return &EmailWorker{
+		db:        db,
+		logger:    logger,
+		transport: newEmailTransportFromEnv(logger),
+		interval:  emailDeliveryInterval,
+	}
+}
+
+// Start runs the worker until Stop is called
+func (w *EmailWorker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.runOnce(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop signals the worker to exit and waits for the current pass to finish
+func (w *EmailWorker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+// claimedDelivery is a delivery the worker has marked as sending
+type claimedDelivery struct {
+	ID          int
+	TenantID    string
+	ReceiptID   int
+	Recipient   string
+	Subject     string
+	Attempts    int
+	MaxAttempts int
+}
+
+// runOnce claims due deliveries and sends them
+func (w *EmailWorker) runOnce(ctx context.Context) {
+	// Release deliveries abandoned mid-send
+	_, err := w.db.ExecContext(ctx, `
+		UPDATE pos_email_deliveries SET status = 'queued'
+		WHERE status = 'sending' AND updated_at < $1
+	`, time.Now().Add(-emailSendingTimeout))
+	if err != nil && ctx.Err() == nil {
+		w.logger.Error("Failed to release stuck email deliveries", zap.Error(err))
+	}
+
+	rows, err := w.db.QueryContext(ctx, `
+		UPDATE pos_email_deliveries SET status = 'sending', attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM pos_email_deliveries
+			WHERE status = 'queued' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, tenant_id, receipt_id, recipient, subject, attempts, max_attempts
+	`, emailDeliveryBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("Failed to claim email deliveries", zap.Error(err))
+		}
+		return
+	}
+
+	var claimed []claimedDelivery
+	for rows.Next() {
+		var d claimedDelivery
+		if err := rows.Scan(&d.ID, &d.TenantID, &d.ReceiptID, &d.Recipient, &d.Subject, &d.Attempts, &d.MaxAttempts); err == nil {
+			claimed = append(claimed, d)
+		}
+	}
+	rows.Close()
+
+	for _, d := range claimed {
+		if ctx.Err() != nil {
+			return
+		}
+		w.deliver(ctx, d)
+	}
+}
+
+// deliveryRetry decides what happens to a delivery after a failed attempt: permanent failures
+// bounce, the last allowed attempt fails, and anything else is queued again after backing off
+// 1, 2, 4, 8... minutes
+func deliveryRetry(err error, attempts, maxAttempts int, now time.Time) (status string, nextAttempt time.Time) {
+	status = "queued"
+	switch {
+	case errors.Is(err, errPermanentEmailFailure):
+		status = "bounced"
+	case attempts >= maxAttempts:
+		status = "failed"
+	}
+	return status, now.Add(time.Minute << uint(attempts-1))
+}
+
+// deliver renders and sends one receipt email and records the outcome
+func (w *EmailWorker) deliver(ctx context.Context, d claimedDelivery) {
+	err := w.send(ctx, d)
+	if err == nil {
+		if err := w.markSent(ctx, d); err != nil {
+			w.logger.Error("Failed to record sent email", zap.Int("delivery_id", d.ID), zap.Error(err))
+		}
+		return
+	}
+
+	status, nextAttempt := deliveryRetry(err, d.Attempts, d.MaxAttempts, time.Now())
+
+	_, updateErr := w.db.ExecContext(ctx, `
+		UPDATE pos_email_deliveries
+		SET status = $1, last_error = $2, next_attempt_at = $3,
+		    bounced_at = CASE WHEN $1 = 'bounced' THEN NOW() ELSE bounced_at END
+		WHERE id = $4
+	`, status, err.Error(), nextAttempt, d.ID)
+	if updateErr != nil {
+		w.logger.Error("Failed to record email failure", zap.Int("delivery_id", d.ID), zap.Error(updateErr))
+	}
+
+	w.logger.Warn("Receipt email not delivered",
+		zap.Int("delivery_id", d.ID),
+		zap.Int("attempt", d.Attempts),
+		zap.String("status", status),
+		zap.Error(err),
+	)
+}
+
+// send renders the stored receipt and hands it to the transport
+func (w *EmailWorker) send(ctx context.Context, d claimedDelivery) error {
+	_, doc, err := loadStoredReceipt(w.db, d.TenantID, d.ReceiptID)
+	if err != nil {
+		return err
+	}
+
+	paperWidth, err := registerPaperWidth(w.db, d.TenantID, doc.Register.ID)
+	if err != nil {
+		return err
+	}
+
+	htmlBody, err := renderReceiptHTML(doc)
+	if err != nil {
+		return err
+	}
+
+	return w.transport.Send(ctx, EmailMessage{
+		From:     emailSender(),
+		To:       d.Recipient,
+		Subject:  d.Subject,
+		TextBody: renderReceiptText(doc, receiptWidthForPaper(paperWidth)),
+		HTMLBody: htmlBody,
+	})
+}
+
+// markSent records a successful delivery and flags the receipt as emailed
+func (w *EmailWorker) markSent(ctx context.Context, d claimedDelivery) error {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE pos_email_deliveries SET status = 'sent', sent_at = NOW(), last_error = NULL
+		WHERE id = $1
+	`, d.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE pos_receipts SET email_sent = true, email_sent_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`, d.ReceiptID, d.TenantID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestDeliveryRetry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	timeout := errors.New("dial tcp: i/o timeout")
+	rejected := fmt.Errorf("%w: 550 mailbox unavailable", errPermanentEmailFailure)
+
+	tests := []struct {
+		name        string
+		err         error
+		attempts    int
+		wantStatus  string
+		wantBackoff time.Duration
+	}{
+		{"first failure", timeout, 1, "queued", time.Minute},
+		{"third failure", timeout, 3, "queued", 4 * time.Minute},
+		{"last attempt", timeout, 5, "failed", 16 * time.Minute},
+		{"rejected recipient", rejected, 1, "bounced", time.Minute},
+		{"not configured", errEmailNotConfigured, 5, "failed", 16 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, next := deliveryRetry(tt.err, tt.attempts, 5, now)
+			if status != tt.wantStatus {
+				t.Errorf("deliveryRetry() status = %q, want %q", status, tt.wantStatus)
+			}
+			if backoff := next.Sub(now); backoff != tt.wantBackoff {
+				t.Errorf("deliveryRetry() backoff = %v, want %v", backoff, tt.wantBackoff)
+			}
+		})
+	}
+}
+
+func TestNewEmailTransportFromEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport string
+		host      string
+		want      string
+	}{
+		{"nothing configured", "", "", "main.unconfiguredTransport"},
+		{"file opt-in", "file", "", "*main.fileTransport"},
+		{"smtp host", "", "smtp.example.com", "*main.smtpTransport"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("POS_EMAIL_TRANSPORT", tt.transport)
+			t.Setenv("POS_SMTP_HOST", tt.host)
+			got := fmt.Sprintf("%T", newEmailTransportFromEnv(zap.NewNop()))
+			if got != tt.want {
+				t.Errorf("newEmailTransportFromEnv() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMIMEMessage(t *testing.T) {
+	body, err := buildMIMEMessage(EmailMessage{
+		From:     "receipts@shop.example",
+		To:       "customer@example.com",
+		Subject:  "Your receipt — Café",
+		TextBody: "Total 3.50",
+		HTMLBody: "<p>Total 3.50</p>",
+	})
+	if err != nil {
+		t.Fatalf("buildMIMEMessage() error = %v", err)
+	}
+	msg := string(body)
+	for _, want := range []string{
+		"From: receipts@shop.example\r\n",
+		"To: customer@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+		"<p>Total 3.50</p>",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("buildMIMEMessage() is missing %q", want)
+		}
+	}
+}
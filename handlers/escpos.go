@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+)
+
+// ESC/POS control bytes
+const (
+	escposESC = 0x1B
+	escposGS  = 0x1D
+)
+
+// escposOptions controls the extras printed around a receipt
+type escposOptions struct {
+	Code       string // barcode, qr or none
+	QRContent  string // encoded in the QR code; defaults to the receipt number
+	Marker     string // printed above and below the receipt on copies, e.g. "DUPLICATE - COPY #2"
+	OpenDrawer bool
+}
+
+// escposWriter accumulates an ESC/POS byte stream
+type escposWriter struct {
+	buf bytes.Buffer
+}
+
+// newESCPOSWriter starts a stream with a printer reset and selects the WPC1252 code page
+func newESCPOSWriter() *escposWriter {
+	w := &escposWriter{}
+	w.buf.Write([]byte{escposESC, '@'})
+	w.buf.Write([]byte{escposESC, 't', 16})
+	return w
+}
+
+// align sets justification: 0 left, 1 center, 2 right
+func (w *escposWriter) align(n byte) {
+	w.buf.Write([]byte{escposESC, 'a', n})
+}
+
+// bold toggles emphasized printing
+func (w *escposWriter) bold(on bool) {
+	w.buf.Write([]byte{escposESC, 'E', escposFlag(on)})
+}
+
+// tall toggles double-height characters, which keeps the column layout intact
+func (w *escposWriter) tall(on bool) {
+	var size byte
+	if on {
+		size = 0x01
+	}
+	w.buf.Write([]byte{escposGS, '!', size})
+}
+
+// line writes text followed by a line feed
+func (w *escposWriter) line(text string) {
+	w.buf.Write(escposEncode(text))
+	w.buf.WriteByte('\n')
+}
+
+// markerLine prints a centered, bold, double-height notice such as a copy marker
+func (w *escposWriter) markerLine(text string) {
+	w.align(1)
+	w.bold(true)
+	w.tall(true)
+	w.line(text)
+	w.tall(false)
+	w.bold(false)
+	w.align(0)
+}
+
+// feed advances the paper n lines
+func (w *escposWriter) feed(n byte) {
+	w.buf.Write([]byte{escposESC, 'd', n})
+}
+
+// barcode prints data as CODE128 with the human-readable text below
+func (w *escposWriter) barcode(data string) {
+	payload := append([]byte("{B"), data...)
+	if len(payload) > 255 {
+		return
+	}
+	w.buf.Write([]byte{escposGS, 'h', 80}) // height in dots
+	w.buf.Write([]byte{escposGS, 'w', 2})  // module width
+	w.buf.Write([]byte{escposGS, 'H', 2})  // HRI below
+	w.buf.Write([]byte{escposGS, 'k', 73, byte(len(payload))})
+	w.buf.Write(payload)
+	w.buf.WriteByte('\n')
+}
+
+// qrCode prints data as a model 2 QR code
+func (w *escposWriter) qrCode(data string) {
+	n := len(data) + 3
+	w.buf.Write([]byte{escposGS, '(', 'k', 4, 0, 49, 65, 50, 0})                      // model 2
+	w.buf.Write([]byte{escposGS, '(', 'k', 3, 0, 49, 67, 6})                          // module size
+	w.buf.Write([]byte{escposGS, '(', 'k', 3, 0, 49, 69, 49})                         // error correction M
+	w.buf.Write([]byte{escposGS, '(', 'k', byte(n % 256), byte(n / 256), 49, 80, 48}) // store
+	w.buf.WriteString(data)
+	w.buf.Write([]byte{escposGS, '(', 'k', 3, 0, 49, 81, 48}) // print
+	w.buf.WriteByte('\n')
+}
+
+// kickDrawer pulses the cash drawer connected to pin 2
+func (w *escposWriter) kickDrawer() {
+	w.buf.Write([]byte{escposESC, 'p', 0, 25, 250})
+}
+
+// cut feeds past the tear bar and performs a partial cut
+func (w *escposWriter) cut() {
+	w.buf.Write([]byte{escposGS, 'V', 66, 0})
+}
+
+// Bytes returns the stream written so far
+func (w *escposWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+func escposFlag(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
+
+// renderReceiptESCPOS renders the receipt as an ESC/POS byte stream for a thermal printer
+func renderReceiptESCPOS(doc *ReceiptDocument, width int, opts escposOptions) []byte {
+	w := newESCPOSWriter()
+
+	if opts.OpenDrawer {
+		w.kickDrawer()
+	}
+
+	if opts.Marker != "" {
+		w.markerLine(opts.Marker)
+	}
+
+	// Lines come pre-padded to the paper width, so everything prints left-aligned
+	for _, line := range layoutReceipt(doc, width) {
+		if line.Bold {
+			w.bold(true)
+		}
+		if line.Tall {
+			w.tall(true)
+		}
+		w.line(line.Text)
+		if line.Tall {
+			w.tall(false)
+		}
+		if line.Bold {
+			w.bold(false)
+		}
+	}
+
+	switch opts.Code {
+	case "barcode":
+		w.feed(1)
+		w.align(1)
+		w.barcode(doc.ReceiptNumber)
+		w.align(0)
+	case "qr":
+		content := opts.QRContent
+		if content == "" {
+			content = doc.ReceiptNumber
+		}
+		w.feed(1)
+		w.align(1)
+		w.qrCode(content)
+		w.align(0)
+	}
+
+	if opts.Marker != "" {
+		w.feed(1)
+		w.markerLine(opts.Marker)
+	}
+
+	w.feed(3)
+	w.cut()
+
+	return w.Bytes()
+}
+
+// escposWindows1252 maps the characters WPC1252 places in 0x80-0x9F
+var escposWindows1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// escposEncode converts text to WPC1252; characters it lacks print as '?'
+func escposEncode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			out = append(out, byte(r))
+		case escposWindows1252[r] != 0:
+			out = append(out, escposWindows1252[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
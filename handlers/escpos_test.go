@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestESCPOSEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []byte
+	}{
+		{"ascii", "Total 12.50", []byte("Total 12.50")},
+		{"latin-1", "Café Müller", []byte{'C', 'a', 'f', 0xE9, ' ', 'M', 0xFC, 'l', 'l', 'e', 'r'}},
+		{"euro sign", "€5", []byte{0x80, '5'}},
+		{"typographic quotes and dashes", "“a”–b—", []byte{0x93, 'a', 0x94, 0x96, 'b', 0x97}},
+		{"characters outside the code page", "Łódź 日本", []byte{'?', 0xF3, 'd', '?', ' ', '?', '?'}},
+		{"c1 controls", "\u0081", []byte{'?'}},
+		{"empty", "", []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escposEncode(tt.text); !bytes.Equal(got, tt.want) {
+				t.Errorf("escposEncode(%q) = % x, want % x", tt.text, got, tt.want)
+			}
+		})
+	}
+}
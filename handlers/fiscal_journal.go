@@ -0,0 +1,576 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	fiscalEntryTransaction = "transaction"
+	fiscalEntryReceipt     = "receipt"
+
+	// fiscalKeyVersion is the version new entries are signed with; bump it to rotate every tenant's key
+	fiscalKeyVersion = 1
+)
+
+// fiscalGenesisHash is the previous hash of a register's first journal entry
+var fiscalGenesisHash = strings.Repeat("0", 64)
+
+var errFiscalEntityNotFound = errors.New("journaled record not found")
+
+// errFiscalKeyMismatch means POS_FISCAL_SIGNING_KEY changed under a key version already in use.
+// Entries signed before can no longer be verified; restore the key, or bump fiscalKeyVersion.
+var errFiscalKeyMismatch = errors.New("fiscal signing key does not match the key published for its version")
+
+// fiscalSigningKey derives a tenant's Ed25519 signing key from POS_FISCAL_SIGNING_KEY.
+// Without the variable entries are still hash-chained but left unsigned.
+func fiscalSigningKey(tenantID string, version int) (ed25519.PrivateKey, bool) {
+	master := os.Getenv("POS_FISCAL_SIGNING_KEY")
+	if master == "" {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, []byte(master))
+	mac.Write([]byte(fmt.Sprintf("pos-fiscal:%s:%d", tenantID, version)))
+	return ed25519.NewKeyFromSeed(mac.Sum(nil)), true
+}
+
+// fiscalTransactionPayload is the journaled snapshot of a transaction. Only fields that
+// must never change after the sale are included; status moves on with refunds and voids.
+type fiscalTransactionPayload struct {
+	ID                int                 `json:"id"`
+	TransactionNumber string              `json:"transaction_number"`
+	RegisterID        int                 `json:"register_id"`
+	TransactionType   string              `json:"transaction_type"`
+	TransactionDate   string              `json:"transaction_date"`
+	CustomerID        *int                `json:"customer_id"`
+	CashierID         int                 `json:"cashier_id"`
+	Subtotal          string              `json:"subtotal"`
+	TaxAmount         string              `json:"tax_amount"`
+	DiscountAmount    string              `json:"discount_amount"`
+	TipAmount         string              `json:"tip_amount"`
+	TotalAmount       string              `json:"total_amount"`
+	ChangeAmount      string              `json:"change_amount"`
+	Items             []fiscalItemPayload `json:"items"`
+	Payments          []fiscalPaymentLine `json:"payments"`
+}
+
+type fiscalItemPayload struct {
+	ID             int    `json:"id"`
+	ProductID      int    `json:"product_id"`
+	Quantity       string `json:"quantity"`
+	UnitPrice      string `json:"unit_price"`
+	DiscountAmount string `json:"discount_amount"`
+	TaxRate        string `json:"tax_rate"`
+	TaxAmount      string `json:"tax_amount"`
+	LineTotal      string `json:"line_total"`
+	// Lines without a variant, modifiers, unit or kit components serialize exactly as before either existed
+	VariantID     *int                  `json:"variant_id,omitempty"`
+	Modifiers     []fiscalModifierLine  `json:"modifiers,omitempty"`
+	UnitOfMeasure *string               `json:"unit_of_measure,omitempty"`
+	GrossWeight   *string               `json:"gross_weight,omitempty"`
+	TareWeight    *string               `json:"tare_weight,omitempty"`
+	Components    []fiscalComponentLine `json:"components,omitempty"`
+}
+
+type fiscalModifierLine struct {
+	ModifierID int    `json:"modifier_id"`
+	Name       string `json:"name"`
+	Quantity   int    `json:"quantity"`
+	Price      string `json:"price"`
+	Amount     string `json:"amount"`
+	TaxRate    string `json:"tax_rate"`
+	TaxAmount  string `json:"tax_amount"`
+}
+
+type fiscalComponentLine struct {
+	ProductID int    `json:"product_id"`
+	VariantID *int   `json:"variant_id,omitempty"`
+	Name      string `json:"name"`
+	Quantity  string `json:"quantity"`
+	Amount    string `json:"amount"`
+	TaxRate   string `json:"tax_rate"`
+	TaxAmount string `json:"tax_amount"`
+}
+
+type fiscalPaymentLine struct {
+	ID            int    `json:"id"`
+	PaymentMethod string `json:"payment_method"`
+	Amount        string `json:"amount"`
+}
+
+// fiscalReceiptPayload is the journaled snapshot of a receipt; the receipt content is covered by its hash
+type fiscalReceiptPayload struct {
+	ID              int    `json:"id"`
+	TransactionID   int    `json:"transaction_id"`
+	ReceiptNumber   string `json:"receipt_number"`
+	ReceiptType     string `json:"receipt_type"`
+	ReceiptDataHash string `json:"receipt_data_hash"`
+	CreatedAt       string `json:"created_at"`
+}
+
+// fiscalQuantityText drops the trailing zeros of a decimal quantity, so whole quantities read
+// "2" as they did when quantities were integers and earlier entries still verify
+func fiscalQuantityText(quantity string) string {
+	if !strings.Contains(quantity, ".") {
+		return quantity
+	}
+	return strings.TrimRight(strings.TrimRight(quantity, "0"), ".")
+}
+
+// fiscalEntityPayload reads a transaction or receipt as it is stored now and returns its
+// canonical payload and register. Amounts and timestamps are read as text so the same row
+// always produces the same bytes.
+func fiscalEntityPayload(q reportQuerier, tenantID, entryType string, entityID int) ([]byte, int, error) {
+	switch entryType {
+	case fiscalEntryTransaction:
+		return fiscalTransactionSnapshot(q, tenantID, entityID)
+	case fiscalEntryReceipt:
+		return fiscalReceiptSnapshot(q, tenantID, entityID)
+	}
+	return nil, 0, fmt.Errorf("unknown fiscal entry type %q", entryType)
+}
+
+func fiscalTransactionSnapshot(q reportQuerier, tenantID string, transactionID int) ([]byte, int, error) {
+	var p fiscalTransactionPayload
+	err := q.QueryRow(`
+		SELECT id, transaction_number, register_id, transaction_type, COALESCE(transaction_date::text, ''),
+		       customer_id, cashier_id, COALESCE(subtotal, 0)::text, COALESCE(tax_amount, 0)::text,
+		       COALESCE(discount_amount, 0)::text, COALESCE(tip_amount, 0)::text,
+		       COALESCE(total_amount, 0)::text, COALESCE(change_amount, 0)::text
+		FROM pos_transactions
+		WHERE id = $1 AND tenant_id = $2
+	`, transactionID, tenantID).Scan(&p.ID, &p.TransactionNumber, &p.RegisterID, &p.TransactionType, &p.TransactionDate,
+		&p.CustomerID, &p.CashierID, &p.Subtotal, &p.TaxAmount, &p.DiscountAmount, &p.TipAmount,
+		&p.TotalAmount, &p.ChangeAmount)
+	if err == sql.ErrNoRows {
+		return nil, 0, errFiscalEntityNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	itemRows, err := q.Query(`
+		SELECT id, product_id, quantity::text, unit_price::text, COALESCE(discount_amount, 0)::text,
+		       COALESCE(tax_rate, 0)::text, COALESCE(tax_amount, 0)::text, COALESCE(line_total, 0)::text,
+		       variant_id, unit_of_measure, gross_weight::text, tare_weight::text
+		FROM pos_transaction_items
+		WHERE transaction_id = $1
+		ORDER BY id
+	`, transactionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer itemRows.Close()
+
+	p.Items = []fiscalItemPayload{}
+	for itemRows.Next() {
+		var item fiscalItemPayload
+		if err := itemRows.Scan(&item.ID, &item.ProductID, &item.Quantity, &item.UnitPrice, &item.DiscountAmount,
+			&item.TaxRate, &item.TaxAmount, &item.LineTotal, &item.VariantID, &item.UnitOfMeasure,
+			&item.GrossWeight, &item.TareWeight); err != nil {
+			return nil, 0, err
+		}
+		item.Quantity = fiscalQuantityText(item.Quantity)
+		p.Items = append(p.Items, item)
+	}
+	if err := itemRows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	modifierRows, err := q.Query(`
+		SELECT m.transaction_item_id, m.modifier_id, m.name, m.quantity, m.price::text, m.amount::text,
+		       m.tax_rate::text, m.tax_amount::text
+		FROM pos_transaction_item_modifiers m
+		JOIN pos_transaction_items pti ON pti.id = m.transaction_item_id
+		WHERE pti.transaction_id = $1
+		ORDER BY m.transaction_item_id, m.id
+	`, transactionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer modifierRows.Close()
+
+	itemIndex := make(map[int]int, len(p.Items))
+	for i, item := range p.Items {
+		itemIndex[item.ID] = i
+	}
+	for modifierRows.Next() {
+		var itemID int
+		var modifier fiscalModifierLine
+		if err := modifierRows.Scan(&itemID, &modifier.ModifierID, &modifier.Name, &modifier.Quantity, &modifier.Price,
+			&modifier.Amount, &modifier.TaxRate, &modifier.TaxAmount); err != nil {
+			return nil, 0, err
+		}
+		if i, ok := itemIndex[itemID]; ok {
+			p.Items[i].Modifiers = append(p.Items[i].Modifiers, modifier)
+		}
+	}
+	if err := modifierRows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	componentRows, err := q.Query(`
+		SELECT c.transaction_item_id, c.product_id, c.variant_id, c.name, c.quantity::text, c.amount::text,
+		       c.tax_rate::text, c.tax_amount::text
+		FROM pos_transaction_item_components c
+		JOIN pos_transaction_items pti ON pti.id = c.transaction_item_id
+		WHERE pti.transaction_id = $1
+		ORDER BY c.transaction_item_id, c.id
+	`, transactionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer componentRows.Close()
+
+	for componentRows.Next() {
+		var itemID int
+		var component fiscalComponentLine
+		if err := componentRows.Scan(&itemID, &component.ProductID, &component.VariantID, &component.Name,
+			&component.Quantity, &component.Amount, &component.TaxRate, &component.TaxAmount); err != nil {
+			return nil, 0, err
+		}
+		component.Quantity = fiscalQuantityText(component.Quantity)
+		if i, ok := itemIndex[itemID]; ok {
+			p.Items[i].Components = append(p.Items[i].Components, component)
+		}
+	}
+	if err := componentRows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	paymentRows, err := q.Query(`
+		SELECT id, payment_method, amount::text
+		FROM pos_payments
+		WHERE transaction_id = $1
+		ORDER BY id
+	`, transactionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer paymentRows.Close()
+
+	p.Payments = []fiscalPaymentLine{}
+	for paymentRows.Next() {
+		var payment fiscalPaymentLine
+		if err := paymentRows.Scan(&payment.ID, &payment.PaymentMethod, &payment.Amount); err != nil {
+			return nil, 0, err
+		}
+		p.Payments = append(p.Payments, payment)
+	}
+	if err := paymentRows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	payload, err := json.Marshal(p)
+	return payload, p.RegisterID, err
+}
+
+func fiscalReceiptSnapshot(q reportQuerier, tenantID string, receiptID int) ([]byte, int, error) {
+	var p fiscalReceiptPayload
+	var registerID int
+	var receiptData string
+	err := q.QueryRow(`
+		SELECT r.id, r.transaction_id, t.register_id, r.receipt_number, COALESCE(r.receipt_type, 'sale'),
+		       COALESCE(r.receipt_data, ''), COALESCE(r.created_at::text, '')
+		FROM pos_receipts r
+		JOIN pos_transactions t ON t.id = r.transaction_id
+		WHERE r.id = $1 AND r.tenant_id = $2
+	`, receiptID, tenantID).Scan(&p.ID, &p.TransactionID, &registerID, &p.ReceiptNumber, &p.ReceiptType,
+		&receiptData, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, 0, errFiscalEntityNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	p.ReceiptDataHash = sha256Hex([]byte(receiptData))
+
+	payload, err := json.Marshal(p)
+	return payload, registerID, err
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fiscalEntryHash chains an entry to its predecessor: it covers the entry's position, the record
+// it journals, the payload hash and the previous entry's hash
+func fiscalEntryHash(tenantID string, registerID int, sequence int64, entryType string, entityID int, payloadHash, previousHash string) string {
+	return sha256Hex([]byte(fmt.Sprintf("%s|%d|%d|%s|%d|%s|%s",
+		tenantID, registerID, sequence, entryType, entityID, payloadHash, previousHash)))
+}
+
+// appendFiscalEntry journals a transaction or receipt inside the transaction that records it.
+// The register row is locked so entries on one register are numbered without gaps.
+func appendFiscalEntry(tx *sqlx.Tx, tenantID, entryType string, entityID int) error {
+	payload, registerID, err := fiscalEntityPayload(tx, tenantID, entryType, entityID)
+	if err != nil {
+		return err
+	}
+
+	var lockedID int
+	err = tx.QueryRow("SELECT id FROM pos_registers WHERE id = $1 AND tenant_id = $2 FOR UPDATE",
+		registerID, tenantID).Scan(&lockedID)
+	if err != nil {
+		return err
+	}
+
+	var lastSequence int64
+	previousHash := fiscalGenesisHash
+	err = tx.QueryRow(`
+		SELECT sequence_number, entry_hash FROM pos_fiscal_journal
+		WHERE register_id = $1
+		ORDER BY sequence_number DESC
+		LIMIT 1
+	`, registerID).Scan(&lastSequence, &previousHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	sequence := lastSequence + 1
+	payloadHash := sha256Hex(payload)
+	entryHash := fiscalEntryHash(tenantID, registerID, sequence, entryType, entityID, payloadHash, previousHash)
+
+	var signature *string
+	var keyVersion *int
+	if key, ok := fiscalSigningKey(tenantID, fiscalKeyVersion); ok {
+		// Publish the key on first use; a different key already published for the version means
+		// the signing key was changed without rotating the version
+		publicKey := base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+		var published string
+		err = tx.QueryRow(`
+			INSERT INTO pos_fiscal_keys (tenant_id, key_version, public_key)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (tenant_id, key_version) DO UPDATE SET public_key = pos_fiscal_keys.public_key
+			RETURNING public_key
+		`, tenantID, fiscalKeyVersion, publicKey).Scan(&published)
+		if err != nil {
+			return err
+		}
+		if published != publicKey {
+			return errFiscalKeyMismatch
+		}
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(entryHash)))
+		version := fiscalKeyVersion
+		signature, keyVersion = &sig, &version
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO pos_fiscal_journal (tenant_id, register_id, sequence_number, entry_type, entity_id,
+		                                payload, payload_hash, previous_hash, entry_hash, signature, key_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, tenantID, registerID, sequence, entryType, entityID, string(payload), payloadHash, previousHash,
+		entryHash, signature, keyVersion)
+	return err
+}
+
+// fiscalVerifyBatch is how many journal entries are checked per query
+const fiscalVerifyBatch = 500
+
+// verifyFiscalChain walks a register's journal from its first entry and reports every broken link,
+// gap, bad signature, and transaction or receipt that was changed, deleted or never journaled
+func verifyFiscalChain(q reportQuerier, tenantID string, registerID int) (*FiscalChainReport, error) {
+	report := &FiscalChainReport{RegisterID: registerID, Issues: []FiscalJournalIssue{}}
+
+	publicKeys := make(map[int]ed25519.PublicKey)
+
+	addIssue := func(issue FiscalJournalIssue) {
+		report.Issues = append(report.Issues, issue)
+	}
+
+	var lastSequence int64
+	previousHash := fiscalGenesisHash
+	journaled := map[string]map[int]bool{fiscalEntryTransaction: {}, fiscalEntryReceipt: {}}
+
+	for {
+		entries, err := loadFiscalJournalBatch(q, tenantID, registerID, lastSequence)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			report.EntriesChecked++
+			if report.FirstEntryAt == nil {
+				createdAt := e.CreatedAt
+				report.FirstEntryAt = &createdAt
+			}
+			journaled[e.EntryType][e.EntityID] = true
+			seq := e.SequenceNumber
+
+			if seq != lastSequence+1 {
+				addIssue(FiscalJournalIssue{Type: "sequence_gap", SequenceNumber: &seq,
+					Detail: fmt.Sprintf("entries %d to %d are missing", lastSequence+1, seq-1)})
+			}
+			if e.PreviousHash != previousHash {
+				addIssue(FiscalJournalIssue{Type: "broken_chain", SequenceNumber: &seq,
+					Detail: "previous hash does not match the preceding entry"})
+			}
+			if sha256Hex([]byte(e.Payload)) != e.PayloadHash {
+				addIssue(FiscalJournalIssue{Type: "payload_hash_mismatch", SequenceNumber: &seq,
+					Detail: "journaled payload does not match its hash"})
+			}
+			if fiscalEntryHash(tenantID, e.RegisterID, seq, e.EntryType, e.EntityID, e.PayloadHash, e.PreviousHash) != e.EntryHash {
+				addIssue(FiscalJournalIssue{Type: "entry_hash_mismatch", SequenceNumber: &seq,
+					Detail: "entry hash does not match its contents"})
+			}
+
+			if e.Signature == nil || e.KeyVersion == nil {
+				report.UnsignedEntries++
+			}
+			if issue := fiscalSignatureIssue(tenantID, &e, publicKeys); issue != nil {
+				addIssue(*issue)
+			}
+
+			// The record must still match what was journaled
+			entityID := e.EntityID
+			current, _, err := fiscalEntityPayload(q, tenantID, e.EntryType, e.EntityID)
+			switch {
+			case err == errFiscalEntityNotFound:
+				addIssue(FiscalJournalIssue{Type: "record_missing", SequenceNumber: &seq, EntryType: e.EntryType,
+					EntityID: &entityID, Detail: "journaled record no longer exists"})
+			case err != nil:
+				return nil, err
+			case string(current) != e.Payload:
+				addIssue(FiscalJournalIssue{Type: "record_modified", SequenceNumber: &seq, EntryType: e.EntryType,
+					EntityID: &entityID, Detail: "record differs from its journaled snapshot"})
+			}
+
+			lastSequence = seq
+			previousHash = e.EntryHash
+		}
+
+		if len(entries) < fiscalVerifyBatch {
+			break
+		}
+	}
+	report.LastSequence = lastSequence
+
+	// Everything recorded on the register since the journal started must be in it
+	if report.FirstEntryAt != nil {
+		missing, err := unjournaledFiscalRecords(q, tenantID, registerID, journaled)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, missing...)
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
+
+// fiscalSignatureIssue checks an entry's signature and returns the problem with it, if any.
+// Signatures are checked against keys derived from POS_FISCAL_SIGNING_KEY, never against the
+// published keys, which live in the database the journal protects. Derived public keys are
+// cached in keys by version.
+func fiscalSignatureIssue(tenantID string, e *FiscalJournalEntry, keys map[int]ed25519.PublicKey) *FiscalJournalIssue {
+	seq := e.SequenceNumber
+	_, signing := fiscalSigningKey(tenantID, fiscalKeyVersion)
+	switch {
+	case e.Signature == nil || e.KeyVersion == nil:
+		if signing {
+			return &FiscalJournalIssue{Type: "unsigned_entry", SequenceNumber: &seq,
+				Detail: "entry is not signed although a signing key is configured"}
+		}
+		return nil
+	case !signing:
+		return &FiscalJournalIssue{Type: "unknown_key", SequenceNumber: &seq,
+			Detail: "no signing key is configured to verify the signature"}
+	case *e.KeyVersion < 1 || *e.KeyVersion > fiscalKeyVersion:
+		return &FiscalJournalIssue{Type: "unknown_key", SequenceNumber: &seq,
+			Detail: fmt.Sprintf("no signing key for key version %d", *e.KeyVersion)}
+	}
+
+	key := keys[*e.KeyVersion]
+	if key == nil {
+		private, _ := fiscalSigningKey(tenantID, *e.KeyVersion)
+		key = private.Public().(ed25519.PublicKey)
+		keys[*e.KeyVersion] = key
+	}
+	sig, err := base64.StdEncoding.DecodeString(*e.Signature)
+	if err != nil || !ed25519.Verify(key, []byte(e.EntryHash), sig) {
+		return &FiscalJournalIssue{Type: "invalid_signature", SequenceNumber: &seq,
+			Detail: "signature does not verify against the tenant key"}
+	}
+	return nil
+}
+
+// loadFiscalJournalBatch returns the register's entries after a sequence number
+func loadFiscalJournalBatch(q reportQuerier, tenantID string, registerID int, afterSequence int64) ([]FiscalJournalEntry, error) {
+	rows, err := q.Query(`
+		SELECT id, tenant_id, register_id, sequence_number, entry_type, entity_id, payload, payload_hash,
+		       previous_hash, entry_hash, signature, key_version, created_at
+		FROM pos_fiscal_journal
+		WHERE tenant_id = $1 AND register_id = $2 AND sequence_number > $3
+		ORDER BY sequence_number
+		LIMIT $4
+	`, tenantID, registerID, afterSequence, fiscalVerifyBatch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []FiscalJournalEntry
+	for rows.Next() {
+		var e FiscalJournalEntry
+		err := rows.Scan(&e.ID, &e.TenantID, &e.RegisterID, &e.SequenceNumber, &e.EntryType, &e.EntityID, &e.Payload,
+			&e.PayloadHash, &e.PreviousHash, &e.EntryHash, &e.Signature, &e.KeyVersion, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// unjournaledFiscalRecords finds transactions and receipts on the register created since its first
+// journal entry that have no entry of their own. Older records predate the journal.
+func unjournaledFiscalRecords(q reportQuerier, tenantID string, registerID int, journaled map[string]map[int]bool) ([]FiscalJournalIssue, error) {
+	rows, err := q.Query(`
+		WITH journal_start AS (
+			SELECT created_at FROM pos_fiscal_journal
+			WHERE tenant_id = $1 AND register_id = $2
+			ORDER BY sequence_number
+			LIMIT 1
+		)
+		SELECT 'transaction', t.id
+		FROM pos_transactions t, journal_start s
+		WHERE t.tenant_id = $1 AND t.register_id = $2 AND t.created_at >= s.created_at
+		UNION ALL
+		SELECT 'receipt', r.id
+		FROM pos_receipts r
+		JOIN pos_transactions t ON t.id = r.transaction_id, journal_start s
+		WHERE r.tenant_id = $1 AND t.register_id = $2 AND r.created_at >= s.created_at
+	`, tenantID, registerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []FiscalJournalIssue
+	for rows.Next() {
+		var entryType string
+		var entityID int
+		if err := rows.Scan(&entryType, &entityID); err != nil {
+			return nil, err
+		}
+		if journaled[entryType][entityID] {
+			continue
+		}
+		id := entityID
+		issues = append(issues, FiscalJournalIssue{Type: "not_journaled", EntryType: entryType, EntityID: &id,
+			Detail: fmt.Sprintf("%s was recorded without a journal entry", entryType)})
+	}
+	return issues, rows.Err()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// FiscalJournalHandler exposes the fiscal journal and its verification
+type FiscalJournalHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewFiscalJournalHandler creates a new fiscal journal handler
+func NewFiscalJournalHandler(db *sqlx.DB, logger *zap.Logger) *FiscalJournalHandler {
+	if _, ok := fiscalSigningKey("", fiscalKeyVersion); !ok {
+		logger.Warn("POS_FISCAL_SIGNING_KEY is not set; fiscal journal entries will be hash-chained but unsigned")
+	}
+	return &FiscalJournalHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// GetFiscalJournal lists a register's journal entries in sequence order
+func (h *FiscalJournalHandler) GetFiscalJournal(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	registerID, err := strconv.Atoi(r.URL.Query().Get("register_id"))
+	if err != nil {
+		http.Error(w, "register_id is required", http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT id, tenant_id, register_id, sequence_number, entry_type, entity_id, payload, payload_hash,
+		       previous_hash, entry_hash, signature, key_version, created_at
+		FROM pos_fiscal_journal
+		WHERE tenant_id = $1 AND register_id = $2
+	`
+	args := []interface{}{tenantID, registerID}
+	argIndex := 3
+
+	if from := r.URL.Query().Get("from_sequence"); from != "" {
+		query += " AND sequence_number >= $" + strconv.Itoa(argIndex)
+		args = append(args, from)
+		argIndex++
+	}
+
+	limit := 100
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+	query += " ORDER BY sequence_number LIMIT $" + strconv.Itoa(argIndex)
+	args = append(args, limit)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch fiscal journal", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var entries []FiscalJournalEntry
+	for rows.Next() {
+		var e FiscalJournalEntry
+		err := rows.Scan(&e.ID, &e.TenantID, &e.RegisterID, &e.SequenceNumber, &e.EntryType, &e.EntityID, &e.Payload,
+			&e.PayloadHash, &e.PreviousHash, &e.EntryHash, &e.Signature, &e.KeyVersion, &e.CreatedAt)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// VerifyFiscalJournal walks the journal of one register, or of every register with entries,
+// and reports gaps, broken links, bad signatures and transactions or receipts that were
+// changed, deleted or recorded without an entry
+func (h *FiscalJournalHandler) VerifyFiscalJournal(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var registerIDs []int
+	if param := r.URL.Query().Get("register_id"); param != "" {
+		registerID, err := strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid register ID", http.StatusBadRequest)
+			return
+		}
+		registerIDs = append(registerIDs, registerID)
+	} else {
+		rows, err := h.db.Query(`
+			SELECT DISTINCT register_id FROM pos_fiscal_journal WHERE tenant_id = $1 ORDER BY register_id
+		`, tenantID)
+		if err != nil {
+			http.Error(w, "Failed to fetch fiscal journal", http.StatusInternalServerError)
+			return
+		}
+		for rows.Next() {
+			var registerID int
+			if err := rows.Scan(&registerID); err != nil {
+				continue
+			}
+			registerIDs = append(registerIDs, registerID)
+		}
+		rows.Close()
+	}
+
+	valid := true
+	reports := []*FiscalChainReport{}
+	for _, registerID := range registerIDs {
+		report, err := verifyFiscalChain(h.db, tenantID, registerID)
+		if err != nil {
+			h.logger.Error("Failed to verify fiscal journal", zap.Int("register_id", registerID), zap.Error(err))
+			http.Error(w, "Failed to verify fiscal journal", http.StatusInternalServerError)
+			return
+		}
+		if !report.Valid {
+			valid = false
+			h.logger.Warn("Fiscal journal verification failed",
+				zap.String("tenant_id", tenantID),
+				zap.Int("register_id", registerID),
+				zap.Int("issues", len(report.Issues)))
+		}
+		reports = append(reports, report)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"valid":     valid,
+		"registers": reports,
+		"count":     len(reports),
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestFiscalEntryHash(t *testing.T) {
+	payloadHash := strings.Repeat("ab", 32)
+	base := fiscalEntryHash("t1", 3, 1, fiscalEntryTransaction, 42, payloadHash, fiscalGenesisHash)
+	if want := "cb1130c4a4938cf9e4e07ac5b80ca95c9c9445d97d40ca6f6875fca878d42d5d"; base != want {
+		t.Fatalf("fiscalEntryHash = %s, want %s", base, want)
+	}
+
+	// Every field the hash covers must change it
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"tenant", fiscalEntryHash("t2", 3, 1, fiscalEntryTransaction, 42, payloadHash, fiscalGenesisHash)},
+		{"register", fiscalEntryHash("t1", 4, 1, fiscalEntryTransaction, 42, payloadHash, fiscalGenesisHash)},
+		{"sequence", fiscalEntryHash("t1", 3, 2, fiscalEntryTransaction, 42, payloadHash, fiscalGenesisHash)},
+		{"entry type", fiscalEntryHash("t1", 3, 1, fiscalEntryReceipt, 42, payloadHash, fiscalGenesisHash)},
+		{"entity", fiscalEntryHash("t1", 3, 1, fiscalEntryTransaction, 43, payloadHash, fiscalGenesisHash)},
+		{"payload hash", fiscalEntryHash("t1", 3, 1, fiscalEntryTransaction, 42, strings.Repeat("cd", 32), fiscalGenesisHash)},
+		{"previous hash", fiscalEntryHash("t1", 3, 1, fiscalEntryTransaction, 42, payloadHash, base)},
+	}
+	for _, tt := range tests {
+		if tt.hash == base {
+			t.Errorf("changing the %s does not change the entry hash", tt.name)
+		}
+	}
+}
+
+func TestFiscalSignatureIssue(t *testing.T) {
+	const tenantID = "t1"
+	entryHash := fiscalEntryHash(tenantID, 3, 1, fiscalEntryTransaction, 42, strings.Repeat("ab", 32), fiscalGenesisHash)
+
+	sign := func(master string, version int, message string) *string {
+		t.Setenv("POS_FISCAL_SIGNING_KEY", master)
+		key, _ := fiscalSigningKey(tenantID, version)
+		sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(message)))
+		return &sig
+	}
+	version := func(v int) *int { return &v }
+	garbage := "not base64!"
+
+	tests := []struct {
+		name       string
+		master     string // POS_FISCAL_SIGNING_KEY while verifying
+		signature  *string
+		keyVersion *int
+		wantIssue  string // "" for none
+	}{
+		{"valid signature", "secret", sign("secret", 1, entryHash), version(1), ""},
+		{"signed with another master key", "secret", sign("other", 1, entryHash), version(1), "invalid_signature"},
+		{"signature over other content", "secret", sign("secret", 1, "tampered"), version(1), "invalid_signature"},
+		{"signature that does not decode", "secret", &garbage, version(1), "invalid_signature"},
+		{"signature for another key version", "secret", sign("secret", 2, entryHash), version(1), "invalid_signature"},
+		{"unknown key version", "secret", sign("secret", 1, entryHash), version(fiscalKeyVersion + 1), "unknown_key"},
+		{"zero key version", "secret", sign("secret", 1, entryHash), version(0), "unknown_key"},
+		{"signed but no key configured", "", sign("secret", 1, entryHash), version(1), "unknown_key"},
+		{"unsigned with a key configured", "secret", nil, nil, "unsigned_entry"},
+		{"unsigned without a key", "", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("POS_FISCAL_SIGNING_KEY", tt.master)
+			e := FiscalJournalEntry{SequenceNumber: 1, EntryHash: entryHash, Signature: tt.signature,
+				KeyVersion: tt.keyVersion}
+			issue := fiscalSignatureIssue(tenantID, &e, make(map[int]ed25519.PublicKey))
+			got := ""
+			if issue != nil {
+				got = issue.Type
+			}
+			if got != tt.wantIssue {
+				t.Errorf("fiscalSignatureIssue = %q, want %q", got, tt.wantIssue)
+			}
+		})
+	}
+}
+
+func TestFiscalSigningKeyDerivation(t *testing.T) {
+	t.Setenv("POS_FISCAL_SIGNING_KEY", "")
+	if _, ok := fiscalSigningKey("t1", 1); ok {
+		t.Fatal("fiscalSigningKey returned a key with POS_FISCAL_SIGNING_KEY unset")
+	}
+
+	t.Setenv("POS_FISCAL_SIGNING_KEY", "secret")
+	a, _ := fiscalSigningKey("t1", 1)
+	again, _ := fiscalSigningKey("t1", 1)
+	if !a.Equal(again) {
+		t.Error("the same tenant and version derive different keys")
+	}
+	for _, other := range []struct {
+		tenantID string
+		version  int
+	}{{"t2", 1}, {"t1", 2}} {
+		key, _ := fiscalSigningKey(other.tenantID, other.version)
+		if a.Equal(key) {
+			t.Errorf("tenant %s version %d derives the same key as t1 version 1", other.tenantID, other.version)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// StockMovement is one change to a product's stock made by the POS
+type StockMovement struct {
+	TenantID          string
+	TransactionID     int
+	TransactionItemID int
+	ProductID         int
+	LocationID        *int
+	Quantity          float64 // negative leaves stock, positive comes back
+	MovementType      string  // sale, void, return
+	Disposition       string  // restock, damaged, write_off; empty for sales
+	CreatedBy         int
+}
+
+// InventoryAdapter applies POS stock movements to an inventory store. Movements are applied
+// inside the caller's transaction, so a failed sale or return never leaves stock changed.
+type InventoryAdapter interface {
+	Name() string
+	ApplyMovement(tx *sqlx.Tx, m StockMovement) error
+}
+
+// stockDispositions are what can happen to stock coming back on a void or return: restocked,
+// kept aside as damaged, or written off entirely
+var stockDispositions = map[string]bool{
+	"restock":   true,
+	"damaged":   true,
+	"write_off": true,
+}
+
+// inventoryAdapters are the stores selectable through the inventory_adapter setting
+var inventoryAdapters = map[string]InventoryAdapter{
+	"core":  coreInventoryAdapter{},
+	"local": localInventoryAdapter{},
+}
+
+// coreInventoryAdapter writes to the ERP's shared inventory tables
+type coreInventoryAdapter struct{}
+
+func (coreInventoryAdapter) Name() string { return "core" }
+
+func (coreInventoryAdapter) ApplyMovement(tx *sqlx.Tx, m StockMovement) error {
+	movementType := m.MovementType
+	if m.Disposition == "damaged" || m.Disposition == "write_off" {
+		movementType = m.Disposition
+	}
+	_, err := tx.Exec(`
+		INSERT INTO inventory_movements (tenant_id, product_id, location_id, quantity, movement_type,
+		                                 reference_type, reference_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, 'pos_transaction', $6, $7)
+	`, m.TenantID, m.ProductID, m.LocationID, m.Quantity, movementType, m.TransactionID, m.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	// Damaged and written-off returns are recorded but never become sellable stock again
+	if m.Disposition == "damaged" || m.Disposition == "write_off" {
+		return nil
+	}
+	result, err := tx.Exec(`
+		UPDATE inventory_levels
+		SET quantity_on_hand = quantity_on_hand + $1, updated_at = NOW()
+		WHERE tenant_id = $2 AND product_id = $3 AND location_id IS NOT DISTINCT FROM $4
+	`, m.Quantity, m.TenantID, m.ProductID, m.LocationID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+	_, err = tx.Exec(`
+		INSERT INTO inventory_levels (tenant_id, product_id, location_id, quantity_on_hand)
+		VALUES ($1, $2, $3, $4)
+	`, m.TenantID, m.ProductID, m.LocationID, m.Quantity)
+	return err
+}
+
+// localInventoryAdapter keeps on-hand stock in pos_stock_levels, for tenants without the ERP inventory
+type localInventoryAdapter struct{}
+
+func (localInventoryAdapter) Name() string { return "local" }
+
+func (localInventoryAdapter) ApplyMovement(tx *sqlx.Tx, m StockMovement) error {
+	// Written-off stock leaves the books, so no level changes
+	if m.Disposition == "write_off" {
+		return nil
+	}
+	onHand, damaged := m.Quantity, 0.0
+	if m.Disposition == "damaged" {
+		onHand, damaged = 0, m.Quantity
+	}
+	_, err := tx.Exec(`
+		INSERT INTO pos_stock_levels (tenant_id, product_id, location_id, quantity_on_hand, quantity_damaged)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, product_id, COALESCE(location_id, 0)) DO UPDATE SET
+			quantity_on_hand = pos_stock_levels.quantity_on_hand + EXCLUDED.quantity_on_hand,
+			quantity_damaged = pos_stock_levels.quantity_damaged + EXCLUDED.quantity_damaged,
+			updated_at = NOW()
+	`, m.TenantID, m.ProductID, m.LocationID, onHand, damaged)
+	return err
+}
+
+// tenantInventoryAdapter returns the tenant's inventory store, or nil when inventory tracking is off
+func tenantInventoryAdapter(q rowQuerier, tenantID string) (InventoryAdapter, error) {
+	enabled := defaultSettings["enable_inventory_tracking"].(bool)
+	if _, err := loadTenantSetting(q, tenantID, "enable_inventory_tracking", &enabled); err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	name := defaultSettings["inventory_adapter"].(string)
+	if _, err := loadTenantSetting(q, tenantID, "inventory_adapter", &name); err != nil {
+		return nil, err
+	}
+	adapter, ok := inventoryAdapters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown inventory adapter %q", name)
+	}
+	return adapter, nil
+}
+
+// registerLocationID returns the location a register's stock moves against
+func registerLocationID(q rowQuerier, tenantID string, registerID int) (*int, error) {
+	var locationID sql.NullInt64
+	err := q.QueryRow("SELECT location_id FROM pos_registers WHERE id = $1 AND tenant_id = $2",
+		registerID, tenantID).Scan(&locationID)
+	if err != nil || !locationID.Valid {
+		return nil, err
+	}
+	id := int(locationID.Int64)
+	return &id, nil
+}
+
+// recordStockMovement logs a movement in pos_stock_movements and applies it through the adapter
+func recordStockMovement(tx *sqlx.Tx, adapter InventoryAdapter, m StockMovement) error {
+	var disposition *string
+	if m.Disposition != "" {
+		disposition = &m.Disposition
+	}
+	_, err := tx.Exec(`
+		INSERT INTO pos_stock_movements (tenant_id, transaction_id, transaction_item_id, product_id, location_id,
+		                                 quantity, movement_type, disposition, adapter, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, m.TenantID, m.TransactionID, m.TransactionItemID, m.ProductID, m.LocationID, m.Quantity,
+		m.MovementType, disposition, adapter.Name(), m.CreatedBy)
+	if err != nil {
+		return err
+	}
+	return adapter.ApplyMovement(tx, m)
+}
+
+// deductSaleStock takes every item of a sale out of stock at the register's location. A kit
+// line takes out its components rather than the kit product.
+func deductSaleStock(tx *sqlx.Tx, tenantID string, transactionID, registerID, userID int) error {
+	adapter, err := tenantInventoryAdapter(tx, tenantID)
+	if err != nil || adapter == nil {
+		return err
+	}
+	locationID, err := registerLocationID(tx, tenantID, registerID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT pti.id, COALESCE(c.product_id, pti.product_id), COALESCE(c.quantity, pti.quantity)
+		FROM pos_transaction_items pti
+		LEFT JOIN pos_transaction_item_components c ON c.transaction_item_id = pti.id
+		WHERE pti.transaction_id = $1
+		ORDER BY pti.id, c.id
+	`, transactionID)
+	if err != nil {
+		return err
+	}
+	var movements []StockMovement
+	for rows.Next() {
+		m := StockMovement{TenantID: tenantID, TransactionID: transactionID, LocationID: locationID,
+			MovementType: "sale", CreatedBy: userID}
+		if err := rows.Scan(&m.TransactionItemID, &m.ProductID, &m.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		m.Quantity = -m.Quantity
+		movements = append(movements, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range movements {
+		if err := recordStockMovement(tx, adapter, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reverseVoidedSale puts back exactly what a sale took out, through the store it was taken from,
+// restocked or booked as damaged or written off according to the disposition.
+// Sales made while inventory tracking was off moved no stock and have nothing to put back.
+func reverseVoidedSale(tx *sqlx.Tx, tenantID string, transactionID, userID int, disposition string) error {
+	rows, err := tx.Query(`
+		SELECT transaction_item_id, product_id, location_id, quantity, adapter
+		FROM pos_stock_movements
+		WHERE tenant_id = $1 AND transaction_id = $2 AND movement_type = 'sale'
+		ORDER BY id
+	`, tenantID, transactionID)
+	if err != nil {
+		return err
+	}
+	var movements []StockMovement
+	var adapters []string
+	for rows.Next() {
+		m := StockMovement{TenantID: tenantID, TransactionID: transactionID, MovementType: "void",
+			Disposition: disposition, CreatedBy: userID}
+		var adapter string
+		if err := rows.Scan(&m.TransactionItemID, &m.ProductID, &m.LocationID, &m.Quantity, &adapter); err != nil {
+			rows.Close()
+			return err
+		}
+		m.Quantity = -m.Quantity
+		movements = append(movements, m)
+		adapters = append(adapters, adapter)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, m := range movements {
+		adapter, ok := inventoryAdapters[adapters[i]]
+		if !ok {
+			return fmt.Errorf("unknown inventory adapter %q", adapters[i])
+		}
+		if err := recordStockMovement(tx, adapter, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,596 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// InvoiceHandler handles tax invoices for business customers
+type InvoiceHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewInvoiceHandler creates a new invoice handler
+func NewInvoiceHandler(db *sqlx.DB, logger *zap.Logger) *InvoiceHandler {
+	return &InvoiceHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+var (
+	errInvoiceNotB2B     = errors.New("invoices are only issued for sales to business customers")
+	errInvoiceNotIssued  = errors.New("no invoice has been issued for this transaction")
+	errInvoiceNotAllowed = errors.New("invoices can only be issued for sales")
+	errInvoiceNotFinal   = errors.New("invoices can only be issued for completed sales")
+)
+
+// invoiceSettings are the tenant's seller details and invoice format
+type invoiceSettings struct {
+	Seller    InvoiceParty
+	Prefix    string
+	PaperSize string
+	Currency  string
+}
+
+// loadInvoiceSettings reads the invoice_* settings over the module defaults
+func loadInvoiceSettings(q rowQuerier, tenantID string) (invoiceSettings, error) {
+	s := invoiceSettings{
+		Seller: InvoiceParty{
+			Name:    defaultSettings["invoice_seller_name"].(string),
+			Address: defaultSettings["invoice_seller_address"].(string),
+			TaxID:   defaultSettings["invoice_seller_tax_id"].(string),
+		},
+		Prefix:    defaultSettings["invoice_number_prefix"].(string),
+		PaperSize: defaultSettings["invoice_paper_size"].(string),
+		Currency:  defaultSettings["invoice_currency"].(string),
+	}
+
+	fields := map[string]*string{
+		"invoice_seller_name":    &s.Seller.Name,
+		"invoice_seller_address": &s.Seller.Address,
+		"invoice_seller_tax_id":  &s.Seller.TaxID,
+		"invoice_number_prefix":  &s.Prefix,
+		"invoice_paper_size":     &s.PaperSize,
+		"invoice_currency":       &s.Currency,
+	}
+	for key, dest := range fields {
+		if _, err := loadTenantSetting(q, tenantID, key, dest); err != nil {
+			return s, err
+		}
+	}
+
+	return s, nil
+}
+
+// nextInvoiceNumber allocates the tenant's next invoice sequence number; it rolls back with the transaction
+func nextInvoiceNumber(tx *sqlx.Tx, tenantID string) (int, error) {
+	var next int
+	err := tx.QueryRow(`
+		INSERT INTO pos_invoice_sequences (tenant_id, last_number) VALUES ($1, 1)
+		ON CONFLICT (tenant_id) DO UPDATE SET last_number = pos_invoice_sequences.last_number + 1
+		RETURNING last_number
+	`, tenantID).Scan(&next)
+	return next, err
+}
+
+// invoiceErrorStatus maps invoice errors to HTTP status codes
+func invoiceErrorStatus(err error) int {
+	switch err {
+	case errReceiptTransactionNotFound, errInvoiceNotIssued:
+		return http.StatusNotFound
+	case errReceiptTransactionNotFinal, errInvoiceNotB2B, errInvoiceNotAllowed, errInvoiceNotFinal:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// IssueInvoice issues the numbered tax invoice for a business customer's sale.
+// Issuing again returns the existing invoice.
+func (h *InvoiceHandler) IssueInvoice(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	transactionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		BuyerTaxID   *string `json:"buyer_tax_id"`
+		BuyerAddress *string `json:"buyer_address"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the sale so concurrent requests wait here instead of racing for the invoice
+	var status string
+	err = tx.QueryRow(`
+		SELECT status FROM pos_transactions WHERE id = $1 AND tenant_id = $2 FOR UPDATE
+	`, transactionID, tenantID).Scan(&status)
+	if err == sql.ErrNoRows {
+		http.Error(w, errReceiptTransactionNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+		return
+	}
+
+	var existing Invoice
+	err = tx.QueryRow(`
+		SELECT id, invoice_number, issued_at FROM pos_invoices WHERE tenant_id = $1 AND transaction_id = $2
+	`, tenantID, transactionID).Scan(&existing.ID, &existing.InvoiceNumber, &existing.IssuedAt)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":             existing.ID,
+			"invoice_number": existing.InvoiceNumber,
+			"issued_at":      existing.IssuedAt,
+			"message":        "Invoice already issued",
+		})
+		return
+	}
+	if err != sql.ErrNoRows {
+		http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+		return
+	}
+
+	if status != "completed" {
+		http.Error(w, errInvoiceNotFinal.Error(), http.StatusConflict)
+		return
+	}
+
+	receipt, err := buildReceiptDocument(tx, tenantID, transactionID)
+	if err == nil && receipt.ReceiptType != "sale" {
+		err = errInvoiceNotAllowed
+	}
+	if err == nil && (receipt.Customer == nil || receipt.Customer.CompanyName == nil) {
+		err = errInvoiceNotB2B
+	}
+	if err != nil {
+		status := invoiceErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			http.Error(w, "Failed to issue invoice", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	settings, err := loadInvoiceSettings(tx, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to load invoice settings", http.StatusInternalServerError)
+		return
+	}
+
+	sequence, err := nextInvoiceNumber(tx, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to allocate invoice number", http.StatusInternalServerError)
+		return
+	}
+
+	buyer := InvoiceParty{
+		Name:  *receipt.Customer.CompanyName,
+		Email: receipt.Customer.Email,
+		Phone: receipt.Customer.Phone,
+	}
+	if receipt.Customer.Name != "" {
+		buyer.Contact = &receipt.Customer.Name
+	}
+	if req.BuyerTaxID != nil {
+		buyer.TaxID = *req.BuyerTaxID
+	}
+	if req.BuyerAddress != nil {
+		buyer.Address = *req.BuyerAddress
+	}
+
+	doc := InvoiceDocument{
+		InvoiceNumber:     fmt.Sprintf("%s%06d", settings.Prefix, sequence),
+		IssuedAt:          time.Now(),
+		TransactionNumber: receipt.TransactionNumber,
+		TransactionDate:   receipt.TransactionDate,
+		Currency:          settings.Currency,
+		Seller:            settings.Seller,
+		Buyer:             buyer,
+		Lines:             receipt.Lines,
+		TaxSummary:        receipt.TaxSummary,
+		Subtotal:          receipt.Subtotal,
+		DiscountTotal:     receipt.DiscountTotal,
+		TaxTotal:          receipt.TaxTotal,
+		Total:             receipt.Total,
+		TotalInWords:      amountInWords(receipt.Total, settings.Currency),
+		Payments:          receipt.Payments,
+	}
+
+	invoiceData, err := json.Marshal(doc)
+	if err != nil {
+		http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+		return
+	}
+
+	var invoiceID int
+	err = tx.QueryRow(`
+		INSERT INTO pos_invoices (tenant_id, transaction_id, sequence_number, invoice_number, customer_id,
+		                          buyer_name, buyer_tax_id, total_amount, invoice_data, issued_by, issued_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`, tenantID, transactionID, sequence, doc.InvoiceNumber, receipt.Customer.ID, buyer.Name, req.BuyerTaxID,
+		doc.Total, invoiceData, userID, doc.IssuedAt).Scan(&invoiceID)
+	if err != nil {
+		http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":             invoiceID,
+		"invoice_number": doc.InvoiceNumber,
+		"invoice":        doc,
+		"message":        "Invoice issued successfully",
+	})
+}
+
+// DownloadInvoicePDF returns the issued invoice for a transaction as a PDF
+func (h *InvoiceHandler) DownloadInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	transactionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var invoiceData []byte
+	err = h.db.QueryRow("SELECT invoice_data FROM pos_invoices WHERE tenant_id = $1 AND transaction_id = $2",
+		tenantID, transactionID).Scan(&invoiceData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, errInvoiceNotIssued.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch invoice", http.StatusInternalServerError)
+		return
+	}
+
+	var doc InvoiceDocument
+	if err := json.Unmarshal(invoiceData, &doc); err != nil {
+		http.Error(w, "Failed to read invoice", http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := loadInvoiceSettings(h.db, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to load invoice settings", http.StatusInternalServerError)
+		return
+	}
+	paperSize := settings.PaperSize
+	if paper := r.URL.Query().Get("paper"); paper != "" {
+		paperSize = paper
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.InvoiceNumber+".pdf"))
+	w.Write(renderInvoicePDF(&doc, paperSize))
+}
+
+// =================================================================
+// PDF LAYOUT
+// =================================================================
+
+// renderInvoicePDF lays the invoice out on A4 or Letter pages
+func renderInvoicePDF(doc *InvoiceDocument, paperSize string) []byte {
+	width, height := pdfA4Width, pdfA4Height
+	if strings.EqualFold(paperSize, "letter") {
+		width, height = pdfLetterWidth, pdfLetterHeight
+	}
+
+	const margin = 40.0
+	right := width - margin
+	pdf := newPDFDocument(width, height)
+	pageNumber := 0
+
+	newPage := func() float64 {
+		pdf.addPage()
+		pageNumber++
+		pdf.textRight(right, height-25, 8, false, fmt.Sprintf("%s - Page %d", doc.InvoiceNumber, pageNumber))
+		return 50
+	}
+
+	// Item table columns: right edges of the numeric columns
+	cols := []struct {
+		title string
+		right float64
+	}{
+		{"Qty", right - 335},
+		{"Unit Price", right - 275},
+		{"Discount", right - 215},
+		{"Net", right - 160},
+		{"Tax %", right - 115},
+		{"Tax", right - 60},
+		{"Total", right},
+	}
+	descWidth := cols[0].right - 30 - margin
+
+	tableHeader := func(y float64) float64 {
+		pdf.text(margin, y, 9, true, "Description")
+		for _, c := range cols {
+			pdf.textRight(c.right, y, 9, true, c.title)
+		}
+		pdf.line(margin, y+4, right, y+4)
+		return y + 16
+	}
+
+	y := newPage()
+
+	// Title and invoice details
+	pdf.text(margin, y+10, 18, true, "TAX INVOICE")
+	pdf.textRight(right, y, 9, false, "Invoice No: "+doc.InvoiceNumber)
+	pdf.textRight(right, y+12, 9, false, "Date: "+doc.IssuedAt.Format("2006-01-02"))
+	pdf.textRight(right, y+24, 9, false, "Transaction: "+doc.TransactionNumber)
+	pdf.textRight(right, y+36, 9, false, "Sale Date: "+doc.TransactionDate.Format("2006-01-02"))
+	y += 60
+
+	// Seller and buyer blocks
+	half := margin + (right-margin)/2
+	partyBlock := func(x, y float64, title string, p InvoiceParty) float64 {
+		pdf.text(x, y, 9, true, title)
+		y += 13
+		pdf.text(x, y, 10, true, pdfFit(p.Name, half-margin-10, 10, true))
+		y += 13
+		if p.Contact != nil {
+			pdf.text(x, y, 9, false, "Attn: "+*p.Contact)
+			y += 12
+		}
+		for _, line := range strings.Split(p.Address, "\n") {
+			if line == "" {
+				continue
+			}
+			pdf.text(x, y, 9, false, pdfFit(line, half-margin-10, 9, false))
+			y += 12
+		}
+		if p.TaxID != "" {
+			pdf.text(x, y, 9, false, "Tax ID: "+p.TaxID)
+			y += 12
+		}
+		if p.Email != nil {
+			pdf.text(x, y, 9, false, *p.Email)
+			y += 12
+		}
+		if p.Phone != nil {
+			pdf.text(x, y, 9, false, *p.Phone)
+			y += 12
+		}
+		return y
+	}
+	sellerEnd := partyBlock(margin, y, "Seller", doc.Seller)
+	buyerEnd := partyBlock(half, y, "Bill To", doc.Buyer)
+	y = math.Max(sellerEnd, buyerEnd) + 20
+
+	// Line items with line-level tax
+	y = tableHeader(y)
+	for _, line := range doc.Lines {
+		if y > height-100 {
+			y = tableHeader(newPage())
+		}
+		net := line.Quantity*line.UnitPrice - line.DiscountAmount
+		pdf.text(margin, y, 9, false, pdfFit(line.Description, descWidth, 9, false))
+		values := []string{
+			formatReceiptQuantity(line.Quantity, line.UnitOfMeasure),
+			formatReceiptMoney(line.UnitPrice),
+			formatReceiptMoney(line.DiscountAmount),
+			formatReceiptMoney(net),
+			fmt.Sprintf("%.2f", line.TaxRate),
+			formatReceiptMoney(line.TaxAmount),
+			formatReceiptMoney(line.LineTotal),
+		}
+		for i, c := range cols {
+			pdf.textRight(c.right, y, 9, false, values[i])
+		}
+		y += 14
+
+		// Modifiers are included in the line above; list them with their own tax rate
+		for _, m := range line.Modifiers {
+			text := fmt.Sprintf("  %s - %s, tax %.2f%%", m.Name, formatReceiptMoney(m.Amount), m.TaxRate)
+			pdf.text(margin, y-2, 8, false, pdfFit(text, descWidth, 8, false))
+			y += 11
+		}
+		// A kit's amount and tax are split across its components
+		for _, c := range line.Components {
+			text := fmt.Sprintf("  %s x %s - %s, tax %.2f%%", formatReceiptQuantity(c.Quantity, ""), c.Description,
+				formatReceiptMoney(c.Amount), c.TaxRate)
+			pdf.text(margin, y-2, 8, false, pdfFit(text, descWidth, 8, false))
+			y += 11
+		}
+	}
+	pdf.line(margin, y-8, right, y-8)
+	y += 10
+
+	// Keep the summary together on one page
+	if y > height-220 {
+		y = newPage()
+	}
+
+	// Tax breakdown by rate
+	pdf.text(margin, y, 9, true, "Tax Rate")
+	pdf.textRight(margin+150, y, 9, true, "Taxable")
+	pdf.textRight(margin+230, y, 9, true, "Tax")
+	summaryY := y + 14
+	for _, tax := range doc.TaxSummary {
+		pdf.text(margin, summaryY, 9, false, fmt.Sprintf("%.2f%%", tax.Rate))
+		pdf.textRight(margin+150, summaryY, 9, false, formatReceiptMoney(tax.TaxableAmount))
+		pdf.textRight(margin+230, summaryY, 9, false, formatReceiptMoney(tax.TaxAmount))
+		summaryY += 12
+	}
+
+	// Totals
+	totals := []struct {
+		label string
+		value float64
+		bold  bool
+	}{
+		{"Subtotal", doc.Subtotal, false},
+		{"Discount", -doc.DiscountTotal, false},
+		{"Tax", doc.TaxTotal, false},
+		{"Total " + doc.Currency, doc.Total, true},
+	}
+	totalsY := y
+	for _, t := range totals {
+		size := 9.0
+		if t.bold {
+			size = 11
+		}
+		pdf.text(right-200, totalsY, size, t.bold, t.label)
+		pdf.textRight(right, totalsY, size, t.bold, formatReceiptMoney(t.value))
+		totalsY += 14
+	}
+	y = math.Max(summaryY, totalsY) + 16
+
+	// Amount in words
+	pdf.text(margin, y, 9, true, "Amount in words:")
+	y += 12
+	for _, line := range wrapPDFText(doc.TotalInWords, right-margin, 9) {
+		pdf.text(margin, y, 9, false, line)
+		y += 12
+	}
+
+	if len(doc.Payments) > 0 {
+		y += 8
+		pdf.text(margin, y, 9, true, "Payments:")
+		y += 12
+		for _, p := range doc.Payments {
+			label := strings.ToUpper(strings.ReplaceAll(p.PaymentMethod, "_", " "))
+			if p.Reference != nil {
+				label += " " + *p.Reference
+			}
+			pdf.text(margin, y, 9, false, label)
+			pdf.textRight(margin+230, y, 9, false, formatReceiptMoney(p.Amount))
+			y += 12
+		}
+	}
+
+	return pdf.Bytes()
+}
+
+// wrapPDFText word-wraps text to a width in points
+func wrapPDFText(text string, maxWidth, size float64) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if current != "" && pdfTextWidth(candidate, size, false) > maxWidth {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current = candidate
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// =================================================================
+// AMOUNT IN WORDS
+// =================================================================
+
+var (
+	wordOnes = []string{"", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	wordTens   = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	wordScales = []string{"", "thousand", "million", "billion", "trillion"}
+)
+
+// amountInWords spells out an amount as on a cheque, e.g. "One hundred five and 20/100 USD"
+func amountInWords(amount float64, currency string) string {
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	words := numberToWords(cents / 100)
+	if amount < 0 && cents > 0 {
+		words = "minus " + words
+	}
+	words = strings.ToUpper(words[:1]) + words[1:]
+	return strings.TrimSpace(fmt.Sprintf("%s and %02d/100 %s", words, cents%100, currency))
+}
+
+// numberToWords spells out a non-negative integer in English
+func numberToWords(n int64) string {
+	if n == 0 {
+		return "zero"
+	}
+
+	var groups []string
+	for scale := 0; n > 0 && scale < len(wordScales); scale++ {
+		chunk := n % 1000
+		n /= 1000
+		if chunk == 0 {
+			continue
+		}
+		words := hundredsToWords(int(chunk))
+		if wordScales[scale] != "" {
+			words += " " + wordScales[scale]
+		}
+		groups = append([]string{words}, groups...)
+	}
+	return strings.Join(groups, " ")
+}
+
+// hundredsToWords spells out 1-999
+func hundredsToWords(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, wordOnes[n/100]+" hundred")
+		n %= 100
+	}
+	switch {
+	case n >= 20:
+		word := wordTens[n/10]
+		if n%10 != 0 {
+			word += "-" + wordOnes[n%10]
+		}
+		parts = append(parts, word)
+	case n > 0:
+		parts = append(parts, wordOnes[n])
+	}
+	return strings.Join(parts, " ")
+}
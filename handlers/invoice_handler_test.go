@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestAmountInWords(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     string
+	}{
+		{105.20, "USD", "One hundred five and 20/100 USD"},
+		{0, "EUR", "Zero and 00/100 EUR"},
+		{0.99, "USD", "Zero and 99/100 USD"},
+		{1, "", "One and 00/100"},
+		{19.999, "USD", "Twenty and 00/100 USD"},
+		{21.05, "GBP", "Twenty-one and 05/100 GBP"},
+		{1000, "USD", "One thousand and 00/100 USD"},
+		{1001000.5, "USD", "One million one thousand and 50/100 USD"},
+		{2000000017, "USD", "Two billion seventeen and 00/100 USD"},
+		{123456.78, "USD", "One hundred twenty-three thousand four hundred fifty-six and 78/100 USD"},
+		{-42.1, "USD", "Minus forty-two and 10/100 USD"},
+		{-0.001, "USD", "Zero and 00/100 USD"},
+	}
+	for _, tt := range tests {
+		if got := amountInWords(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("amountInWords(%v, %q) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestHundredsToWords(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{7, "seven"},
+		{13, "thirteen"},
+		{40, "forty"},
+		{99, "ninety-nine"},
+		{100, "one hundred"},
+		{310, "three hundred ten"},
+		{999, "nine hundred ninety-nine"},
+	}
+	for _, tt := range tests {
+		if got := hundredsToWords(tt.n); got != tt.want {
+			t.Errorf("hundredsToWords(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// itemOptionError is a line item that cannot be sold as given, such as an unavailable variant, a
+// modifier choice or a quantity its unit does not allow; its message is shown to the cashier
+type itemOptionError struct {
+	msg string
+}
+
+func (e *itemOptionError) Error() string { return e.msg }
+
+// loadProductModifierGroups returns the active modifier groups offered on a product with their
+// active modifiers, applying the product's selection rules over the group's own
+func loadProductModifierGroups(q reportQuerier, tenantID string, productID int) ([]ProductModifierGroup, error) {
+	rows, err := q.Query(`
+		SELECT pmg.product_id, g.id, g.name, COALESCE(pmg.min_selections, g.min_selections),
+		       COALESCE(pmg.max_selections, g.max_selections), COALESCE(pmg.sort_order, 0)
+		FROM pos_product_modifier_groups pmg
+		JOIN pos_modifier_groups g ON g.id = pmg.group_id AND g.tenant_id = pmg.tenant_id
+		WHERE pmg.tenant_id = $1 AND pmg.product_id = $2 AND g.is_active = true
+		ORDER BY pmg.sort_order, g.name
+	`, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	var groups []ProductModifierGroup
+	index := make(map[int]int)
+	for rows.Next() {
+		var g ProductModifierGroup
+		if err := rows.Scan(&g.ProductID, &g.GroupID, &g.Name, &g.MinSelections, &g.MaxSelections, &g.SortOrder); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		g.Modifiers = []Modifier{}
+		index[g.GroupID] = len(groups)
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(groups) == 0 {
+		return groups, err
+	}
+
+	modifierRows, err := q.Query(`
+		SELECT m.id, m.tenant_id, m.group_id, m.name, m.price, m.tax_rate, COALESCE(m.sort_order, 0),
+		       m.is_active, m.created_at, m.updated_at
+		FROM pos_modifiers m
+		JOIN pos_product_modifier_groups pmg ON pmg.group_id = m.group_id
+		WHERE pmg.tenant_id = $1 AND pmg.product_id = $2 AND m.tenant_id = $1 AND m.is_active = true
+		ORDER BY m.sort_order, m.name
+	`, tenantID, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer modifierRows.Close()
+	for modifierRows.Next() {
+		var m Modifier
+		if err := modifierRows.Scan(&m.ID, &m.TenantID, &m.GroupID, &m.Name, &m.Price, &m.TaxRate, &m.SortOrder,
+			&m.IsActive, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if i, ok := index[m.GroupID]; ok {
+			groups[i].Modifiers = append(groups[i].Modifiers, m)
+		}
+	}
+	return groups, modifierRows.Err()
+}
+
+// applyItemOptions checks a line's variant and modifiers against the product's configuration and
+// prices the modifiers from it. The client sends unit_price and tax_amount for the product or
+// variant alone; each modifier's price and tax are added to them here, so line_total and every
+// report built on it include the modifiers.
+func applyItemOptions(q reportQuerier, tenantID string, item *POSTransactionItem) error {
+	if item.VariantID != nil {
+		var productID int
+		var active bool
+		err := q.QueryRow(`
+			SELECT product_id, is_active FROM pos_product_variants WHERE id = $1 AND tenant_id = $2
+		`, *item.VariantID, tenantID).Scan(&productID, &active)
+		if err == sql.ErrNoRows || (err == nil && productID != item.ProductID) {
+			return &itemOptionError{fmt.Sprintf("Variant %d is not a variant of product %d", *item.VariantID, item.ProductID)}
+		}
+		if err != nil {
+			return err
+		}
+		if !active {
+			return &itemOptionError{fmt.Sprintf("Variant %d is no longer sold", *item.VariantID)}
+		}
+	}
+
+	groups, err := loadProductModifierGroups(q, tenantID, item.ProductID)
+	if err != nil {
+		return err
+	}
+	return priceItemModifiers(item, groups)
+}
+
+// priceItemModifiers checks a line's modifiers against the groups offered on its product and
+// each group's selection limits, then adds their price and tax to the line
+func priceItemModifiers(item *POSTransactionItem, groups []ProductModifierGroup) error {
+	offered := make(map[int]Modifier)
+	for _, g := range groups {
+		for _, m := range g.Modifiers {
+			offered[m.ID] = m
+		}
+	}
+
+	selected := make(map[int]int)
+	var unitExtra, taxExtra float64
+	for i := range item.Modifiers {
+		m := &item.Modifiers[i]
+		if m.Quantity == 0 {
+			m.Quantity = 1
+		}
+		modifier, ok := offered[m.ModifierID]
+		if !ok {
+			return &itemOptionError{fmt.Sprintf("Modifier %d is not offered on product %d", m.ModifierID, item.ProductID)}
+		}
+		if m.Quantity < 0 {
+			return &itemOptionError{fmt.Sprintf("Quantity of modifier %q must be positive", modifier.Name)}
+		}
+
+		m.GroupID = modifier.GroupID
+		m.Name = modifier.Name
+		m.Price = modifier.Price
+		m.TaxRate = item.TaxRate
+		if modifier.TaxRate != nil {
+			m.TaxRate = *modifier.TaxRate
+		}
+		m.Amount = math.Round(modifier.Price*float64(m.Quantity)*item.Quantity*100) / 100
+		m.TaxAmount = math.Round(m.Amount*m.TaxRate) / 100
+
+		selected[m.GroupID] += m.Quantity
+		unitExtra += modifier.Price * float64(m.Quantity)
+		taxExtra += m.TaxAmount
+	}
+
+	for _, g := range groups {
+		n := selected[g.GroupID]
+		if n < g.MinSelections {
+			return &itemOptionError{fmt.Sprintf("%s needs at least %d selection(s)", g.Name, g.MinSelections)}
+		}
+		if g.MaxSelections > 0 && n > g.MaxSelections {
+			return &itemOptionError{fmt.Sprintf("%s allows at most %d selection(s)", g.Name, g.MaxSelections)}
+		}
+	}
+
+	item.UnitPrice = math.Round((item.UnitPrice+unitExtra)*100) / 100
+	item.TaxAmount = math.Round((item.TaxAmount+taxExtra)*100) / 100
+	return nil
+}
+
+// saveItemModifiers records the modifiers priced by applyItemOptions against their line
+func saveItemModifiers(tx *sqlx.Tx, itemID int, modifiers []POSTransactionItemModifier) error {
+	for _, m := range modifiers {
+		_, err := tx.Exec(`
+			INSERT INTO pos_transaction_item_modifiers (transaction_item_id, modifier_id, group_id, name, quantity,
+			                                            price, amount, tax_rate, tax_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, itemID, m.ModifierID, m.GroupID, m.Name, m.Quantity, m.Price, m.Amount, m.TaxRate, m.TaxAmount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyItemModifiers carries a sold line's modifiers onto a return line, with amounts and tax
+// prorated to the quantity returned
+func copyItemModifiers(tx *sqlx.Tx, fromItemID, toItemID int, ratio float64) error {
+	_, err := tx.Exec(`
+		INSERT INTO pos_transaction_item_modifiers (transaction_item_id, modifier_id, group_id, name, quantity,
+		                                            price, amount, tax_rate, tax_amount)
+		SELECT $1, modifier_id, group_id, name, quantity, price, ROUND(amount * $3, 2), tax_rate,
+		       ROUND(tax_amount * $3, 2)
+		FROM pos_transaction_item_modifiers
+		WHERE transaction_item_id = $2
+		ORDER BY id
+	`, toItemID, fromItemID, ratio)
+	return err
+}
+
+// loadItemModifiers returns the modifiers of every line of a transaction, keyed by line
+func loadItemModifiers(q reportQuerier, transactionID int) (map[int][]POSTransactionItemModifier, error) {
+	rows, err := q.Query(`
+		SELECT m.id, m.transaction_item_id, m.modifier_id, m.group_id, m.name, m.quantity, m.price, m.amount,
+		       m.tax_rate, m.tax_amount
+		FROM pos_transaction_item_modifiers m
+		JOIN pos_transaction_items pti ON pti.id = m.transaction_item_id
+		WHERE pti.transaction_id = $1
+		ORDER BY m.transaction_item_id, m.id
+	`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modifiers := make(map[int][]POSTransactionItemModifier)
+	for rows.Next() {
+		var m POSTransactionItemModifier
+		if err := rows.Scan(&m.ID, &m.TransactionItemID, &m.ModifierID, &m.GroupID, &m.Name, &m.Quantity, &m.Price,
+			&m.Amount, &m.TaxRate, &m.TaxAmount); err != nil {
+			return nil, err
+		}
+		modifiers[m.TransactionItemID] = append(modifiers[m.TransactionItemID], m)
+	}
+	return modifiers, rows.Err()
+}
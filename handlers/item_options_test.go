@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceItemModifiers(t *testing.T) {
+	zero := 0.0
+	groups := []ProductModifierGroup{
+		{GroupID: 1, Name: "Milk", MinSelections: 1, MaxSelections: 1, Modifiers: []Modifier{
+			{ID: 10, GroupID: 1, Name: "Oat milk", Price: 0.5},
+			{ID: 11, GroupID: 1, Name: "Soy milk", Price: 0.4},
+		}},
+		{GroupID: 2, Name: "Extras", MaxSelections: 2, Modifiers: []Modifier{
+			{ID: 20, GroupID: 2, Name: "Extra shot", Price: 0.6, TaxRate: &zero},
+		}},
+	}
+
+	tests := []struct {
+		name          string
+		modifiers     []POSTransactionItemModifier
+		wantErr       string
+		wantUnitPrice float64
+		wantTax       float64
+	}{
+		{"one required choice",
+			[]POSTransactionItemModifier{{ModifierID: 10}},
+			"", 3.5, 0.7},
+		{"extras taxed at their own rate",
+			[]POSTransactionItemModifier{{ModifierID: 11}, {ModifierID: 20, Quantity: 2}},
+			"", 4.6, 0.68},
+		{"required group left empty",
+			[]POSTransactionItemModifier{{ModifierID: 20}},
+			"Milk needs at least 1 selection(s)", 0, 0},
+		{"too many in a group",
+			[]POSTransactionItemModifier{{ModifierID: 10}, {ModifierID: 11}},
+			"Milk allows at most 1 selection(s)", 0, 0},
+		{"quantity counts towards the limit",
+			[]POSTransactionItemModifier{{ModifierID: 10}, {ModifierID: 20, Quantity: 3}},
+			"Extras allows at most 2 selection(s)", 0, 0},
+		{"modifier not offered",
+			[]POSTransactionItemModifier{{ModifierID: 10}, {ModifierID: 99}},
+			"Modifier 99 is not offered on product 5", 0, 0},
+		{"negative quantity",
+			[]POSTransactionItemModifier{{ModifierID: 10, Quantity: -1}},
+			`Quantity of modifier "Oat milk" must be positive`, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := POSTransactionItem{ProductID: 5, Quantity: 2, UnitPrice: 3, TaxRate: 10, TaxAmount: 0.6,
+				Modifiers: tt.modifiers}
+			err := priceItemModifiers(&item, groups)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("priceItemModifiers() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("priceItemModifiers() error = %v", err)
+			}
+			if math.Abs(item.UnitPrice-tt.wantUnitPrice) > 1e-9 || math.Abs(item.TaxAmount-tt.wantTax) > 1e-9 {
+				t.Errorf("priceItemModifiers() unit price %v, tax %v, want %v, %v", item.UnitPrice, item.TaxAmount,
+					tt.wantUnitPrice, tt.wantTax)
+			}
+		})
+	}
+}
+
+func TestValidSelections(t *testing.T) {
+	tests := []struct {
+		min, max int
+		wantErr  bool
+	}{
+		{0, 0, false},
+		{1, 1, false},
+		{2, 0, false},
+		{0, 3, false},
+		{3, 2, true},
+		{-1, 0, true},
+		{0, -1, true},
+	}
+	for _, tt := range tests {
+		if err := validSelections(tt.min, tt.max); (err != nil) != tt.wantErr {
+			t.Errorf("validSelections(%d, %d) error = %v, want error %v", tt.min, tt.max, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// KitHandler manages kits: products sold as one line whose components leave stock in their place
+type KitHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewKitHandler creates a new kit handler
+func NewKitHandler(db *sqlx.DB, logger *zap.Logger) *KitHandler {
+	return &KitHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// kitRequest is the body of a kit definition
+type kitRequest struct {
+	AllocationMethod string `json:"allocation_method"` // list_price (default), quantity, fixed
+	IsActive         *bool  `json:"is_active"`
+	Components       []struct {
+		ProductID      int      `json:"product_id" validate:"required"`
+		VariantID      *int     `json:"variant_id"`
+		Quantity       float64  `json:"quantity"` // per kit; defaults to 1
+		AllocatedPrice *float64 `json:"allocated_price"`
+		TaxRate        *float64 `json:"tax_rate"`
+		SortOrder      int      `json:"sort_order"`
+	} `json:"components"`
+}
+
+func (req *kitRequest) validate(productID int) error {
+	if req.AllocationMethod == "" {
+		req.AllocationMethod = "list_price"
+	}
+	known := false
+	for _, method := range kitAllocationMethods {
+		known = known || method == req.AllocationMethod
+	}
+	if !known {
+		return errors.New("Allocation method must be list_price, quantity or fixed")
+	}
+	if len(req.Components) == 0 {
+		return errors.New("A kit needs at least one component")
+	}
+	for i := range req.Components {
+		c := &req.Components[i]
+		if c.ProductID == productID {
+			return errors.New("A kit cannot contain itself")
+		}
+		if c.Quantity == 0 {
+			c.Quantity = 1
+		}
+		if c.Quantity < 0 {
+			return fmt.Errorf("Quantity of component %d must be positive", c.ProductID)
+		}
+		if c.AllocatedPrice != nil && *c.AllocatedPrice < 0 {
+			return fmt.Errorf("Allocated price of component %d cannot be negative", c.ProductID)
+		}
+		if req.AllocationMethod == "fixed" && c.AllocatedPrice == nil {
+			return fmt.Errorf("Component %d needs an allocated_price for the fixed allocation method", c.ProductID)
+		}
+		if c.TaxRate != nil && (*c.TaxRate < 0 || *c.TaxRate > 100) {
+			return fmt.Errorf("Tax rate of component %d must be between 0 and 100", c.ProductID)
+		}
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+	return nil
+}
+
+// GetKits lists the tenant's kits with their components
+func (h *KitHandler) GetKits(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query := "SELECT product_id FROM pos_kits WHERE tenant_id = $1"
+	if r.URL.Query().Get("is_active") == "true" {
+		query += " AND is_active = true"
+	}
+	query += " ORDER BY product_id"
+
+	var productIDs []int
+	if err := h.db.Select(&productIDs, query, tenantID); err != nil {
+		http.Error(w, "Failed to fetch kits", http.StatusInternalServerError)
+		return
+	}
+
+	kits := []Kit{}
+	for _, productID := range productIDs {
+		kit, err := loadKit(h.db, tenantID, productID)
+		if err != nil {
+			http.Error(w, "Failed to fetch kits", http.StatusInternalServerError)
+			return
+		}
+		if kit != nil {
+			kits = append(kits, *kit)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"kits":  kits,
+		"count": len(kits),
+	})
+}
+
+// GetProductKit returns the kit defined on a product
+func (h *KitHandler) GetProductKit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	kit, err := loadKit(h.db, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to fetch kit", http.StatusInternalServerError)
+		return
+	}
+	if kit == nil {
+		http.Error(w, "Product is not a kit", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kit)
+}
+
+// SetProductKit makes a product a kit, or replaces its components and allocation method.
+// Components must be plain products: kits do not nest. Lines already sold keep the components
+// they were sold with.
+func (h *KitHandler) SetProductKit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req kitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(productID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var exists, isComponent bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM products WHERE id = $2),
+		       EXISTS (SELECT 1 FROM pos_kit_components c JOIN pos_kits k ON k.id = c.kit_id
+		               WHERE k.tenant_id = $1 AND c.product_id = $2)
+	`, tenantID, productID).Scan(&exists, &isComponent)
+	if err != nil {
+		http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if isComponent {
+		http.Error(w, "Product is a component of another kit; kits cannot be nested", http.StatusConflict)
+		return
+	}
+
+	for _, c := range req.Components {
+		var componentExists, componentIsKit bool
+		err := tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM products WHERE id = $2),
+			       EXISTS (SELECT 1 FROM pos_kits WHERE tenant_id = $1 AND product_id = $2)
+		`, tenantID, c.ProductID).Scan(&componentExists, &componentIsKit)
+		if err != nil {
+			http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+			return
+		}
+		if !componentExists {
+			http.Error(w, fmt.Sprintf("Component product %d not found", c.ProductID), http.StatusBadRequest)
+			return
+		}
+		if componentIsKit {
+			http.Error(w, fmt.Sprintf("Component product %d is a kit; kits cannot be nested", c.ProductID),
+				http.StatusConflict)
+			return
+		}
+		if c.VariantID != nil {
+			var variantProductID int
+			err := tx.QueryRow("SELECT product_id FROM pos_product_variants WHERE id = $1 AND tenant_id = $2",
+				*c.VariantID, tenantID).Scan(&variantProductID)
+			if err == sql.ErrNoRows || (err == nil && variantProductID != c.ProductID) {
+				http.Error(w, fmt.Sprintf("Variant %d is not a variant of product %d", *c.VariantID, c.ProductID),
+					http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	var kitID int
+	err = tx.QueryRow(`
+		INSERT INTO pos_kits (tenant_id, product_id, allocation_method, is_active)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, product_id) DO UPDATE SET
+			allocation_method = EXCLUDED.allocation_method, is_active = EXCLUDED.is_active
+		RETURNING id
+	`, tenantID, productID, req.AllocationMethod, *req.IsActive).Scan(&kitID)
+	if err != nil {
+		http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err = tx.Exec("DELETE FROM pos_kit_components WHERE kit_id = $1", kitID); err != nil {
+		http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+		return
+	}
+	for _, c := range req.Components {
+		_, err = tx.Exec(`
+			INSERT INTO pos_kit_components (kit_id, product_id, variant_id, quantity, allocated_price, tax_rate, sort_order)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, kitID, c.ProductID, c.VariantID, c.Quantity, c.AllocatedPrice, c.TaxRate, c.SortOrder)
+		if err != nil {
+			http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	kit, err := loadKit(tx, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to update kit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kit)
+}
+
+// DeleteProductKit stops a product being a kit; it sells as a plain product from then on
+func (h *KitHandler) DeleteProductKit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM pos_kits WHERE tenant_id = $1 AND product_id = $2", tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to delete kit", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Product is not a kit", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
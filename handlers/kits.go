@@ -0,0 +1,317 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// kitAllocationMethods are how a kit line's amount is split across its components: by the
+// components' own selling prices, by their quantities, or by the fixed prices set on the kit
+var kitAllocationMethods = []string{"list_price", "quantity", "fixed"}
+
+// loadKit returns the kit defined on a product with its components in order, or nil if the
+// product is not a kit
+func loadKit(q reportQuerier, tenantID string, productID int) (*Kit, error) {
+	var k Kit
+	err := q.QueryRow(`
+		SELECT id, tenant_id, product_id, allocation_method, is_active, created_at, updated_at
+		FROM pos_kits
+		WHERE tenant_id = $1 AND product_id = $2
+	`, tenantID, productID).Scan(&k.ID, &k.TenantID, &k.ProductID, &k.AllocationMethod, &k.IsActive,
+		&k.CreatedAt, &k.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT c.id, c.product_id, c.variant_id, COALESCE(p.name, ''), COALESCE(v.name, ''), c.quantity,
+		       c.allocated_price, c.tax_rate, COALESCE(c.sort_order, 0)
+		FROM pos_kit_components c
+		LEFT JOIN products p ON p.id = c.product_id
+		LEFT JOIN pos_product_variants v ON v.id = c.variant_id
+		WHERE c.kit_id = $1
+		ORDER BY c.sort_order, c.id
+	`, k.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	k.Components = []KitComponent{}
+	for rows.Next() {
+		var c KitComponent
+		var variantName string
+		if err := rows.Scan(&c.ID, &c.ProductID, &c.VariantID, &c.Name, &variantName, &c.Quantity,
+			&c.AllocatedPrice, &c.TaxRate, &c.SortOrder); err != nil {
+			return nil, err
+		}
+		if variantName != "" {
+			c.Name += " - " + variantName
+		}
+		k.Components = append(k.Components, c)
+	}
+	return &k, rows.Err()
+}
+
+// loadBundleRule returns the bundle discount rule that prices a kit at the given time, with the
+// price per kit it sets. Bundle rules target kit products or their categories; rules that need
+// a manager's approval or have used up their limit are never applied at the till.
+func loadBundleRule(q rowQuerier, tenantID string, ctx priceContext, productID int, at time.Time) (*int, float64, error) {
+	var ruleID int
+	var price float64
+	err := q.QueryRow(`
+		SELECT r.id, r.discount_value
+		FROM discount_rules r
+		WHERE r.tenant_id = $1 AND r.discount_type = 'bundle' AND r.is_active = true
+		  AND r.requires_approval = false
+		  AND r.valid_from <= $2 AND (r.valid_to IS NULL OR r.valid_to > $2)
+		  AND (r.usage_limit IS NULL OR r.usage_count < r.usage_limit)
+		  AND (r.customer_group_id IS NULL OR r.customer_group_id = $4)
+		  AND (r.days_of_week IS NULL OR EXTRACT(DOW FROM $2)::int = ANY(r.days_of_week))
+		  AND (r.time_from IS NULL OR $2::time >= r.time_from) AND (r.time_to IS NULL OR $2::time < r.time_to)
+		  AND EXISTS (
+			SELECT 1 FROM discount_rule_products drp
+			WHERE drp.discount_rule_id = r.id
+			  AND (drp.product_id = $3
+			       OR drp.category_id = (SELECT category_id FROM products WHERE id = $3)))
+		ORDER BY r.priority DESC, r.discount_value, r.id
+		LIMIT 1
+	`, tenantID, at, productID, ctx.CustomerGroupID).Scan(&ruleID, &price)
+	if err == sql.ErrNoRows {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return &ruleID, price, nil
+}
+
+// explodeKitLine splits a kit line into its components. A bundle rule prices the kit when the
+// cashier gave no discount of their own. The line's discounted amount is then allocated to the
+// components by the kit's method, the last component taking the rounding, and each share is taxed
+// at the component's rate, so the line's tax is the sum of its components'. Lines of products
+// that are not active kits are left as they are.
+func explodeKitLine(q reportQuerier, tenantID string, ctx priceContext, item *POSTransactionItem, at time.Time) error {
+	// Components and the bundle rule are only ever set here, never taken from the client
+	item.Components = nil
+	item.DiscountRuleID = nil
+	kit, err := loadKit(q, tenantID, item.ProductID)
+	if err != nil || kit == nil || !kit.IsActive {
+		return err
+	}
+	if len(kit.Components) == 0 {
+		return &itemOptionError{fmt.Sprintf("Kit product %d has no components", item.ProductID)}
+	}
+	if len(item.Modifiers) > 0 {
+		return &itemOptionError{fmt.Sprintf("Kit product %d cannot take modifiers", item.ProductID)}
+	}
+
+	if item.DiscountAmount == 0 && item.DiscountPercent == 0 {
+		ruleID, price, err := loadBundleRule(q, tenantID, ctx, item.ProductID, at)
+		if err != nil {
+			return err
+		}
+		if ruleID != nil && price < item.UnitPrice {
+			item.DiscountAmount = math.Round((item.UnitPrice-price)*item.Quantity*100) / 100
+			item.DiscountRuleID = ruleID
+		}
+	}
+
+	weights := make([]float64, len(kit.Components))
+	for i, c := range kit.Components {
+		switch kit.AllocationMethod {
+		case "list_price":
+			price, err := resolvePrice(q, tenantID, ctx, c.ProductID, c.VariantID, at)
+			if err != nil {
+				return err
+			}
+			weights[i] = price.Price * c.Quantity
+		case "fixed":
+			if c.AllocatedPrice != nil {
+				weights[i] = *c.AllocatedPrice * c.Quantity
+			}
+		}
+	}
+	allocateKitLine(item, kit.Components, weights)
+	return nil
+}
+
+// allocateKitLine splits the line's discounted amount across the components in proportion to
+// their weights, the last component taking the rounding, and taxes each share at the component's
+// rate. Components that are all free, or a quantity split, share the line by quantity.
+func allocateKitLine(item *POSTransactionItem, components []KitComponent, weights []float64) {
+	var totalWeight float64
+	for _, w := range weights {
+		totalWeight += w
+	}
+	if totalWeight <= 0 {
+		totalWeight = 0
+		for i, c := range components {
+			weights[i] = c.Quantity
+			totalWeight += weights[i]
+		}
+	}
+
+	net := item.Quantity*item.UnitPrice - item.DiscountAmount
+	var allocated, tax float64
+	item.Components = make([]POSTransactionItemComponent, len(components))
+	for i, c := range components {
+		amount := math.Round(net*weights[i]/totalWeight*100) / 100
+		if i == len(components)-1 {
+			amount = math.Round((net-allocated)*100) / 100
+		}
+		allocated += amount
+
+		rate := item.TaxRate
+		if c.TaxRate != nil {
+			rate = *c.TaxRate
+		}
+		item.Components[i] = POSTransactionItemComponent{
+			ProductID: c.ProductID,
+			VariantID: c.VariantID,
+			Name:      c.Name,
+			Quantity:  math.Round(c.Quantity*item.Quantity*1000) / 1000,
+			Amount:    amount,
+			TaxRate:   rate,
+			TaxAmount: math.Round(amount*rate) / 100,
+		}
+		tax += item.Components[i].TaxAmount
+	}
+	item.TaxAmount = math.Round(tax*100) / 100
+}
+
+// errBundleLimitReached is returned when a concurrent sale used up a bundle rule's last use
+// after the rule priced the line
+var errBundleLimitReached = errors.New("bundle price has reached its usage limit")
+
+// saveKitLine records the components explodeKitLine split a line into and counts a use of the
+// bundle rule that priced it. The use is only counted while the rule is under its limit.
+func saveKitLine(tx *sqlx.Tx, itemID int, item *POSTransactionItem) error {
+	for _, c := range item.Components {
+		_, err := tx.Exec(`
+			INSERT INTO pos_transaction_item_components (transaction_item_id, product_id, variant_id, name, quantity,
+			                                             amount, tax_rate, tax_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, itemID, c.ProductID, c.VariantID, c.Name, c.Quantity, c.Amount, c.TaxRate, c.TaxAmount)
+		if err != nil {
+			return err
+		}
+	}
+	if item.DiscountRuleID == nil {
+		return nil
+	}
+	result, err := tx.Exec(`
+		UPDATE discount_rules SET usage_count = usage_count + 1
+		WHERE id = $1 AND (usage_limit IS NULL OR usage_count < usage_limit)
+	`, *item.DiscountRuleID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errBundleLimitReached
+	}
+	return nil
+}
+
+// releaseVoidedBundleUses gives back the bundle rule uses counted by saveKitLine for a voided sale,
+// one per kit line the rule priced
+func releaseVoidedBundleUses(tx *sqlx.Tx, transactionID int) error {
+	_, err := tx.Exec(`
+		UPDATE discount_rules dr
+		SET usage_count = GREATEST(dr.usage_count - uses.count, 0)
+		FROM (
+			SELECT discount_rule_id, COUNT(*) AS count
+			FROM pos_transaction_items
+			WHERE transaction_id = $1 AND discount_rule_id IS NOT NULL
+			GROUP BY discount_rule_id
+		) uses
+		WHERE dr.id = uses.discount_rule_id
+	`, transactionID)
+	return err
+}
+
+// copyItemComponents carries a sold kit line's components onto a return line, with quantities,
+// amounts and tax prorated to the quantity returned
+func copyItemComponents(tx *sqlx.Tx, fromItemID, toItemID int, ratio float64) error {
+	_, err := tx.Exec(`
+		INSERT INTO pos_transaction_item_components (transaction_item_id, product_id, variant_id, name, quantity,
+		                                             amount, tax_rate, tax_amount)
+		SELECT $1, product_id, variant_id, name, ROUND(quantity * $3, 3), ROUND(amount * $3, 2), tax_rate,
+		       ROUND(tax_amount * $3, 2)
+		FROM pos_transaction_item_components
+		WHERE transaction_item_id = $2
+		ORDER BY id
+	`, toItemID, fromItemID, ratio)
+	return err
+}
+
+// loadItemComponents returns the kit components of every line of a transaction, keyed by line
+func loadItemComponents(q reportQuerier, transactionID int) (map[int][]POSTransactionItemComponent, error) {
+	rows, err := q.Query(`
+		SELECT c.id, c.transaction_item_id, c.product_id, c.variant_id, c.name, c.quantity, c.amount,
+		       c.tax_rate, c.tax_amount
+		FROM pos_transaction_item_components c
+		JOIN pos_transaction_items pti ON pti.id = c.transaction_item_id
+		WHERE pti.transaction_id = $1
+		ORDER BY c.transaction_item_id, c.id
+	`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := make(map[int][]POSTransactionItemComponent)
+	for rows.Next() {
+		var c POSTransactionItemComponent
+		if err := rows.Scan(&c.ID, &c.TransactionItemID, &c.ProductID, &c.VariantID, &c.Name, &c.Quantity,
+			&c.Amount, &c.TaxRate, &c.TaxAmount); err != nil {
+			return nil, err
+		}
+		components[c.TransactionItemID] = append(components[c.TransactionItemID], c)
+	}
+	return components, rows.Err()
+}
+
+// stockPart is a product and quantity a line moves in stock
+type stockPart struct {
+	ProductID int
+	Quantity  float64
+}
+
+// itemStockParts returns what a line moves in stock: a kit line's components, or the line's
+// own product
+func itemStockParts(q reportQuerier, itemID, productID int, quantity float64) ([]stockPart, error) {
+	rows, err := q.Query(`
+		SELECT product_id, quantity FROM pos_transaction_item_components
+		WHERE transaction_item_id = $1
+		ORDER BY id
+	`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []stockPart
+	for rows.Next() {
+		var part stockPart
+		if err := rows.Scan(&part.ProductID, &part.Quantity); err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		parts = append(parts, stockPart{ProductID: productID, Quantity: quantity})
+	}
+	return parts, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAllocateKitLine(t *testing.T) {
+	zero := 0.0
+	tests := []struct {
+		name        string
+		item        POSTransactionItem
+		components  []KitComponent
+		weights     []float64
+		wantAmounts []float64
+		wantTaxes   []float64
+		wantTax     float64
+	}{
+		{"by weight",
+			POSTransactionItem{Quantity: 2, UnitPrice: 10, TaxRate: 10},
+			[]KitComponent{{Quantity: 1}, {Quantity: 1}}, []float64{30, 10},
+			[]float64{15, 5}, []float64{1.5, 0.5}, 2},
+		{"last component takes the rounding",
+			POSTransactionItem{Quantity: 1, UnitPrice: 10},
+			[]KitComponent{{Quantity: 1}, {Quantity: 1}, {Quantity: 1}}, []float64{1, 1, 1},
+			[]float64{3.33, 3.33, 3.34}, []float64{0, 0, 0}, 0},
+		{"discount is allocated",
+			POSTransactionItem{Quantity: 1, UnitPrice: 30, DiscountAmount: 6},
+			[]KitComponent{{Quantity: 1}, {Quantity: 2}}, []float64{5, 10},
+			[]float64{8, 16}, []float64{0, 0}, 0},
+		{"free components share by quantity",
+			POSTransactionItem{Quantity: 1, UnitPrice: 8},
+			[]KitComponent{{Quantity: 1}, {Quantity: 3}}, []float64{0, 0},
+			[]float64{2, 6}, []float64{0, 0}, 0},
+		{"component tax rate overrides the line's",
+			POSTransactionItem{Quantity: 1, UnitPrice: 10, TaxRate: 20},
+			[]KitComponent{{Quantity: 1}, {Quantity: 1, TaxRate: &zero}}, []float64{1, 1},
+			[]float64{5, 5}, []float64{1, 0}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := tt.item
+			allocateKitLine(&item, tt.components, tt.weights)
+			var amounts, taxes []float64
+			var total float64
+			for _, c := range item.Components {
+				amounts = append(amounts, c.Amount)
+				taxes = append(taxes, c.TaxAmount)
+				total += c.Amount
+			}
+			if !reflect.DeepEqual(amounts, tt.wantAmounts) || !reflect.DeepEqual(taxes, tt.wantTaxes) {
+				t.Errorf("allocateKitLine amounts %v taxes %v, want %v and %v", amounts, taxes, tt.wantAmounts,
+					tt.wantTaxes)
+			}
+			if net := item.Quantity*item.UnitPrice - item.DiscountAmount; math.Abs(total-net) > 1e-9 {
+				t.Errorf("components add up to %v, want the line's %v", total, net)
+			}
+			if item.TaxAmount != tt.wantTax {
+				t.Errorf("line tax = %v, want %v", item.TaxAmount, tt.wantTax)
+			}
+		})
+	}
+}
+
+func TestAllocateKitLineQuantities(t *testing.T) {
+	item := POSTransactionItem{Quantity: 3, UnitPrice: 5}
+	allocateKitLine(&item, []KitComponent{{Quantity: 2}, {Quantity: 0.25}}, []float64{1, 1})
+	if got := []float64{item.Components[0].Quantity, item.Components[1].Quantity}; !reflect.DeepEqual(got,
+		[]float64{6, 0.75}) {
+		t.Errorf("component quantities = %v, want [6 0.75]", got)
+	}
+}
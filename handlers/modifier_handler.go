@@ -0,0 +1,494 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ModifierHandler manages modifier groups, their modifiers and the groups offered on each product
+type ModifierHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewModifierHandler creates a new modifier handler
+func NewModifierHandler(db *sqlx.DB, logger *zap.Logger) *ModifierHandler {
+	return &ModifierHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// modifierRequest is the body of a modifier create or update
+type modifierRequest struct {
+	Name      string   `json:"name" validate:"required"`
+	Price     float64  `json:"price"`
+	TaxRate   *float64 `json:"tax_rate"` // omit to tax at the item's rate
+	SortOrder int      `json:"sort_order"`
+	IsActive  *bool    `json:"is_active"`
+}
+
+func (req *modifierRequest) validate() error {
+	if req.Name == "" {
+		return errors.New("Modifier name is required")
+	}
+	if req.TaxRate != nil && (*req.TaxRate < 0 || *req.TaxRate > 100) {
+		return errors.New("Tax rate must be between 0 and 100")
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+	return nil
+}
+
+// validSelections checks a min/max selection rule; a max of 0 means no limit
+func validSelections(minSelections, maxSelections int) error {
+	if minSelections < 0 || maxSelections < 0 {
+		return errors.New("Selections cannot be negative")
+	}
+	if maxSelections > 0 && maxSelections < minSelections {
+		return errors.New("Maximum selections cannot be less than minimum selections")
+	}
+	return nil
+}
+
+// GetModifierGroups lists the tenant's modifier groups with their modifiers
+func (h *ModifierHandler) GetModifierGroups(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, tenant_id, name, min_selections, max_selections, is_active, created_at, updated_at
+		FROM pos_modifier_groups
+		WHERE tenant_id = $1
+		ORDER BY name
+	`, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch modifier groups", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var groups []ModifierGroup
+	index := make(map[int]int)
+	for rows.Next() {
+		var g ModifierGroup
+		err := rows.Scan(&g.ID, &g.TenantID, &g.Name, &g.MinSelections, &g.MaxSelections, &g.IsActive,
+			&g.CreatedAt, &g.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		g.Modifiers = []Modifier{}
+		index[g.ID] = len(groups)
+		groups = append(groups, g)
+	}
+
+	modifierRows, err := h.db.Query(`
+		SELECT id, tenant_id, group_id, name, price, tax_rate, COALESCE(sort_order, 0), is_active, created_at, updated_at
+		FROM pos_modifiers
+		WHERE tenant_id = $1
+		ORDER BY sort_order, name
+	`, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch modifiers", http.StatusInternalServerError)
+		return
+	}
+	defer modifierRows.Close()
+
+	for modifierRows.Next() {
+		var m Modifier
+		err := modifierRows.Scan(&m.ID, &m.TenantID, &m.GroupID, &m.Name, &m.Price, &m.TaxRate, &m.SortOrder,
+			&m.IsActive, &m.CreatedAt, &m.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		if i, ok := index[m.GroupID]; ok {
+			groups[i].Modifiers = append(groups[i].Modifiers, m)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"modifier_groups": groups,
+		"count":           len(groups),
+	})
+}
+
+// CreateModifierGroup creates a modifier group, optionally with its modifiers
+func (h *ModifierHandler) CreateModifierGroup(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Name          string            `json:"name" validate:"required"`
+		MinSelections int               `json:"min_selections"`
+		MaxSelections *int              `json:"max_selections"` // defaults to 1; 0 means no limit
+		Modifiers     []modifierRequest `json:"modifiers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	maxSelections := 1
+	if req.MaxSelections != nil {
+		maxSelections = *req.MaxSelections
+	}
+	if err := validSelections(req.MinSelections, maxSelections); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range req.Modifiers {
+		if err := req.Modifiers[i].validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to create modifier group", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var id int
+	var createdAt time.Time
+	err = tx.QueryRow(`
+		INSERT INTO pos_modifier_groups (tenant_id, name, min_selections, max_selections)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, tenantID, req.Name, req.MinSelections, maxSelections).Scan(&id, &createdAt)
+	if err != nil {
+		http.Error(w, "Failed to create modifier group; group names must be unique", http.StatusConflict)
+		return
+	}
+
+	for _, m := range req.Modifiers {
+		_, err = tx.Exec(`
+			INSERT INTO pos_modifiers (tenant_id, group_id, name, price, tax_rate, sort_order, is_active)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, tenantID, id, m.Name, m.Price, m.TaxRate, m.SortOrder, *m.IsActive)
+		if err != nil {
+			http.Error(w, "Failed to create modifier; modifier names must be unique within a group", http.StatusConflict)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to create modifier group", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"created_at": createdAt,
+		"message":    "Modifier group created successfully",
+	})
+}
+
+// UpdateModifierGroup renames a modifier group, changes its default selection rule or deactivates it
+func (h *ModifierHandler) UpdateModifierGroup(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid modifier group ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Name          string `json:"name" validate:"required"`
+		MinSelections int    `json:"min_selections"`
+		MaxSelections int    `json:"max_selections"`
+		IsActive      *bool  `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if err := validSelections(req.MinSelections, req.MaxSelections); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var updatedAt time.Time
+	err = h.db.QueryRow(`
+		UPDATE pos_modifier_groups
+		SET name = $1, min_selections = $2, max_selections = $3, is_active = $4
+		WHERE id = $5 AND tenant_id = $6
+		RETURNING updated_at
+	`, req.Name, req.MinSelections, req.MaxSelections, isActive, groupID, tenantID).Scan(&updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Modifier group not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update modifier group; group names must be unique", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         groupID,
+		"updated_at": updatedAt,
+		"message":    "Modifier group updated successfully",
+	})
+}
+
+// CreateModifier adds a modifier to a group
+func (h *ModifierHandler) CreateModifier(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid modifier group ID", http.StatusBadRequest)
+		return
+	}
+
+	var req modifierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var exists bool
+	err = h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM pos_modifier_groups WHERE id = $1 AND tenant_id = $2)",
+		groupID, tenantID).Scan(&exists)
+	if err != nil {
+		http.Error(w, "Failed to create modifier", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Modifier group not found", http.StatusNotFound)
+		return
+	}
+
+	var id int
+	var createdAt time.Time
+	err = h.db.QueryRow(`
+		INSERT INTO pos_modifiers (tenant_id, group_id, name, price, tax_rate, sort_order, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, tenantID, groupID, req.Name, req.Price, req.TaxRate, req.SortOrder, *req.IsActive).Scan(&id, &createdAt)
+	if err != nil {
+		http.Error(w, "Failed to create modifier; modifier names must be unique within a group", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"created_at": createdAt,
+		"message":    "Modifier created successfully",
+	})
+}
+
+// UpdateModifier changes a modifier's name, price, tax rate or order, or deactivates it. Lines
+// already sold keep the name, price and tax they were sold with.
+func (h *ModifierHandler) UpdateModifier(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	modifierID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid modifier ID", http.StatusBadRequest)
+		return
+	}
+
+	var req modifierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var updatedAt time.Time
+	err = h.db.QueryRow(`
+		UPDATE pos_modifiers
+		SET name = $1, price = $2, tax_rate = $3, sort_order = $4, is_active = $5
+		WHERE id = $6 AND tenant_id = $7
+		RETURNING updated_at
+	`, req.Name, req.Price, req.TaxRate, req.SortOrder, *req.IsActive, modifierID, tenantID).Scan(&updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Modifier not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update modifier; modifier names must be unique within a group", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         modifierID,
+		"updated_at": updatedAt,
+		"message":    "Modifier updated successfully",
+	})
+}
+
+// GetProductModifierGroups lists the active modifier groups offered on a product, with the
+// selection rules that apply to the product
+func (h *ModifierHandler) GetProductModifierGroups(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	groups, err := loadProductModifierGroups(h.db, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to fetch modifier groups", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"modifier_groups": groups,
+		"count":           len(groups),
+	})
+}
+
+// SetProductModifierGroups replaces the modifier groups offered on a product. A group's
+// min_selections and max_selections may be overridden for this product; omit them to use the group's.
+func (h *ModifierHandler) SetProductModifierGroups(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Groups []struct {
+			GroupID       int  `json:"group_id" validate:"required"`
+			MinSelections *int `json:"min_selections"`
+			MaxSelections *int `json:"max_selections"`
+			SortOrder     int  `json:"sort_order"`
+		} `json:"groups"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to update product modifier groups", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM pos_product_modifier_groups WHERE tenant_id = $1 AND product_id = $2",
+		tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to update product modifier groups", http.StatusInternalServerError)
+		return
+	}
+
+	for _, g := range req.Groups {
+		var minSelections, maxSelections int
+		err := tx.QueryRow(`
+			SELECT min_selections, max_selections FROM pos_modifier_groups WHERE id = $1 AND tenant_id = $2
+		`, g.GroupID, tenantID).Scan(&minSelections, &maxSelections)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Modifier group "+strconv.Itoa(g.GroupID)+" not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to update product modifier groups", http.StatusInternalServerError)
+			return
+		}
+		if g.MinSelections != nil {
+			minSelections = *g.MinSelections
+		}
+		if g.MaxSelections != nil {
+			maxSelections = *g.MaxSelections
+		}
+		if err := validSelections(minSelections, maxSelections); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO pos_product_modifier_groups (tenant_id, product_id, group_id, min_selections, max_selections, sort_order)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, tenantID, productID, g.GroupID, g.MinSelections, g.MaxSelections, g.SortOrder)
+		if err != nil {
+			http.Error(w, "Failed to update product modifier groups; each group can be offered once", http.StatusConflict)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to update product modifier groups", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"product_id": productID,
+		"count":      len(req.Groups),
+		"message":    "Product modifier groups updated successfully",
+	})
+}
@@ -12,10 +12,29 @@ import (
 
 // POSPlugin implements the ModulePlugin interface
 type POSPlugin struct {
-	db           *sqlx.DB
-	logger       *zap.Logger
-	handler      *POSHandler
-	shiftHandler *ShiftHandler
+	db                     *sqlx.DB
+	logger                 *zap.Logger
+	handler                *POSHandler
+	shiftHandler           *ShiftHandler
+	registerHandler        *RegisterHandler
+	reportHandler          *ReportHandler
+	settingsHandler        *SettingsHandler
+	safeHandler            *SafeHandler
+	receiptHandler         *ReceiptHandler
+	customerHandler        *CustomerHandler
+	invoiceHandler         *InvoiceHandler
+	receiptTemplateHandler *ReceiptTemplateHandler
+	fiscalJournalHandler   *FiscalJournalHandler
+	returnHandler          *ReturnHandler
+	productHandler         *ProductHandler
+	modifierHandler        *ModifierHandler
+	unitHandler            *UnitHandler
+	trackingHandler        *TrackingHandler
+	priceBookHandler       *PriceBookHandler
+	kitHandler             *KitHandler
+	ageVerificationHandler *AgeVerificationHandler
+	timeoutWorker          *TimeoutWorker
+	emailWorker            *EmailWorker
 }
 
 // NewPOSPlugin creates a new plugin instance
@@ -29,6 +48,27 @@ func (p *POSPlugin) Initialize(db *sqlx.DB, logger *zap.Logger) error {
 	p.logger = logger
 	p.handler = NewPOSHandler(db, logger)
 	p.shiftHandler = NewShiftHandler(db, logger)
+	p.registerHandler = NewRegisterHandler(db, logger)
+	p.reportHandler = NewReportHandler(db, logger)
+	p.settingsHandler = NewSettingsHandler(db, logger)
+	p.safeHandler = NewSafeHandler(db, logger)
+	p.receiptHandler = NewReceiptHandler(db, logger)
+	p.customerHandler = NewCustomerHandler(db, logger)
+	p.invoiceHandler = NewInvoiceHandler(db, logger)
+	p.receiptTemplateHandler = NewReceiptTemplateHandler(db, logger)
+	p.fiscalJournalHandler = NewFiscalJournalHandler(db, logger)
+	p.returnHandler = NewReturnHandler(db, logger)
+	p.productHandler = NewProductHandler(db, logger)
+	p.modifierHandler = NewModifierHandler(db, logger)
+	p.unitHandler = NewUnitHandler(db, logger)
+	p.trackingHandler = NewTrackingHandler(db, logger)
+	p.priceBookHandler = NewPriceBookHandler(db, logger)
+	p.kitHandler = NewKitHandler(db, logger)
+	p.ageVerificationHandler = NewAgeVerificationHandler(db, logger)
+	p.timeoutWorker = NewTimeoutWorker(db, logger)
+	p.timeoutWorker.Start()
+	p.emailWorker = NewEmailWorker(db, logger)
+	p.emailWorker.Start()
 	p.logger.Info("POS module initialized")
 	return nil
 }
@@ -46,6 +86,12 @@ func (p *POSPlugin) GetModuleVersion() string {
 // Cleanup performs cleanup
 func (p *POSPlugin) Cleanup() error {
 	p.logger.Info("Cleaning up POS module")
+	if p.timeoutWorker != nil {
+		p.timeoutWorker.Stop()
+	}
+	if p.emailWorker != nil {
+		p.emailWorker.Stop()
+	}
 	return nil
 }
 
@@ -55,15 +101,136 @@ func (p *POSPlugin) GetHandler(route string, method string) (http.HandlerFunc, e
 	method = strings.ToUpper(method)
 
 	handlers := map[string]http.HandlerFunc{
-		"GET /transactions":  p.handler.GetPOSTransactions,
-		"POST /transactions": p.handler.CreatePOSTransaction,
-		"GET /shifts":        p.shiftHandler.GetPOSShifts,
-		"POST /shifts":       p.shiftHandler.CreatePOSShift,
-		"GET /sessions":      p.handler.GetPOSSessions,
-		"POST /sessions":     p.handler.CreatePOSSession,
-		"GET /registers":     p.handler.GetPOSRegisters,
-		"POST /registers":    p.handler.CreatePOSRegister,
-		"GET /analytics":     p.handler.GetPOSAnalytics,
+		"GET /transactions":                      p.handler.GetPOSTransactions,
+		"POST /transactions":                     p.handler.CreatePOSTransaction,
+		"GET /shifts":                            p.shiftHandler.GetPOSShifts,
+		"POST /shifts":                           p.shiftHandler.CreatePOSShift,
+		"POST /shifts/{id}/close":                p.shiftHandler.ClosePOSShift,
+		"GET /sessions":                          p.handler.GetPOSSessions,
+		"POST /sessions":                         p.handler.CreatePOSSession,
+		"POST /sessions/{id}/close":              p.handler.ClosePOSSession,
+		"POST /receipts":                         p.receiptHandler.CreateReceipt,
+		"GET /receipts/{id}":                     p.receiptHandler.GetReceipt,
+		"POST /receipts/gift":                    p.receiptHandler.CreateGiftReceipt,
+		"GET /gift-receipts/{code}":              p.receiptHandler.LookupGiftReceipt,
+		"GET /receipts/{id}/escpos":              p.receiptHandler.DownloadReceiptESCPOS,
+		"GET /print-jobs":                        p.receiptHandler.GetPrintJobs,
+		"GET /print-jobs/{id}/payload":           p.receiptHandler.DownloadPrintJob,
+		"POST /print-jobs/{id}/complete":         p.receiptHandler.CompletePrintJob,
+		"POST /receipts/{id}/email":              p.receiptHandler.EmailReceipt,
+		"GET /receipts/{id}/email-deliveries":    p.receiptHandler.GetReceiptEmailDeliveries,
+		"POST /email-deliveries/{id}/bounce":     p.receiptHandler.RecordEmailBounce,
+		"POST /receipts/{id}/sms":                p.receiptHandler.SMSReceipt,
+		"POST /receipts/{id}/link":               p.receiptHandler.CreateReceiptLink,
+		"DELETE /receipts/{id}/link":             p.receiptHandler.RevokeReceiptLink,
+		"GET /public/receipts/{token}":           p.receiptHandler.GetPublicReceipt,
+		"GET /customers/{id}/sms-consent":        p.customerHandler.GetSMSConsent,
+		"PUT /customers/{id}/sms-consent":        p.customerHandler.UpdateSMSConsent,
+		"GET /customers/{id}/price-group":        p.customerHandler.GetCustomerPriceGroup,
+		"PUT /customers/{id}/price-group":        p.customerHandler.UpdateCustomerPriceGroup,
+		"POST /receipts/{id}/print":              p.receiptHandler.PrintReceipt,
+		"GET /receipt-templates":                 p.receiptTemplateHandler.GetReceiptTemplates,
+		"POST /receipt-templates":                p.receiptTemplateHandler.CreateReceiptTemplate,
+		"GET /receipt-templates/{id}":            p.receiptTemplateHandler.GetReceiptTemplate,
+		"PUT /receipt-templates/{id}":            p.receiptTemplateHandler.UpdateReceiptTemplate,
+		"DELETE /receipt-templates/{id}":         p.receiptTemplateHandler.DeleteReceiptTemplate,
+		"POST /receipt-templates/{id}/preview":   p.receiptTemplateHandler.PreviewReceiptTemplate,
+		"GET /receipts/{id}/reprints":            p.receiptHandler.GetReceiptReprints,
+		"POST /transactions/{id}/invoice":        p.invoiceHandler.IssueInvoice,
+		"GET /transactions/{id}/invoice":         p.invoiceHandler.DownloadInvoicePDF,
+		"POST /transactions/{id}/void":           p.returnHandler.VoidTransaction,
+		"POST /transactions/{id}/return":         p.returnHandler.ReturnTransaction,
+		"GET /fiscal-journal":                    p.fiscalJournalHandler.GetFiscalJournal,
+		"GET /fiscal-journal/verify":             p.fiscalJournalHandler.VerifyFiscalJournal,
+		"GET /registers":                         p.handler.GetPOSRegisters,
+		"POST /registers":                        p.handler.CreatePOSRegister,
+		"PUT /registers/{id}":                    p.registerHandler.UpdatePOSRegister,
+		"DELETE /registers/{id}":                 p.registerHandler.DeactivatePOSRegister,
+		"POST /registers/{id}/open":              p.registerHandler.OpenRegister,
+		"POST /registers/{id}/close":             p.registerHandler.CloseRegister,
+		"POST /registers/{id}/suspend":           p.registerHandler.SuspendRegister,
+		"POST /registers/{id}/resume":            p.registerHandler.ResumeRegister,
+		"POST /registers/{id}/maintenance/start": p.registerHandler.StartRegisterMaintenance,
+		"POST /registers/{id}/maintenance/end":   p.registerHandler.EndRegisterMaintenance,
+		"GET /registers/{id}/cash-movements":     p.registerHandler.GetRegisterTransactions,
+		"POST /registers/{id}/cash-movements":    p.registerHandler.CreateCashMovement,
+		"GET /reports/x":                         p.reportHandler.GetXReport,
+		"GET /reports/z":                         p.reportHandler.GetZReports,
+		"POST /reports/z":                        p.reportHandler.CreateZReport,
+		"GET /reports/z/{id}":                    p.reportHandler.GetZReport,
+		"GET /safes":                             p.safeHandler.GetSafes,
+		"POST /safes":                            p.safeHandler.CreateSafe,
+		"GET /safes/{id}/transactions":           p.safeHandler.GetSafeTransactions,
+		"POST /safes/{id}/drops":                 p.safeHandler.CreateSafeDrop,
+		"POST /safes/{id}/change-fund":           p.safeHandler.CreateChangeFund,
+		"POST /safes/{id}/deposits":              p.safeHandler.CreateBankDeposit,
+		"GET /deposits":                          p.safeHandler.GetBankDeposits,
+		"POST /deposits/{id}/dispatch":           p.safeHandler.DispatchBankDeposit,
+		"POST /deposits/{id}/reconcile":          p.safeHandler.ReconcileBankDeposit,
+		"POST /deposits/{id}/cancel":             p.safeHandler.CancelBankDeposit,
+		"GET /products/search":                   p.productHandler.SearchProducts,
+		"GET /barcodes/scan":                     p.productHandler.ScanBarcode,
+		"GET /products/{id}/barcodes":            p.productHandler.GetProductBarcodes,
+		"GET /quick-sale/categories":             p.productHandler.GetQuickSaleCategories,
+		"POST /quick-sale/categories":            p.productHandler.CreateQuickSaleCategory,
+		"PUT /quick-sale/categories/{id}":        p.productHandler.UpdateQuickSaleCategory,
+		"DELETE /quick-sale/categories/{id}":     p.productHandler.DeleteQuickSaleCategory,
+		"GET /quick-sale/items":                  p.productHandler.GetQuickSaleItems,
+		"POST /quick-sale/items":                 p.productHandler.CreateQuickSaleItem,
+		"PUT /quick-sale/items/{id}":             p.productHandler.UpdateQuickSaleItem,
+		"DELETE /quick-sale/items/{id}":          p.productHandler.DeleteQuickSaleItem,
+		"GET /quick-sale/layout":                 p.productHandler.GetQuickSaleLayout,
+		"PUT /quick-sale/layout/order":           p.productHandler.ReorderQuickSaleLayout,
+		"POST /quick-sale/layout/clone":          p.productHandler.CloneQuickSaleLayout,
+		"GET /quick-sale/layout/export":          p.productHandler.ExportQuickSaleLayout,
+		"POST /quick-sale/layout/import":         p.productHandler.ImportQuickSaleLayout,
+		"POST /products/{id}/barcodes":           p.productHandler.CreateProductBarcode,
+		"DELETE /barcodes/{id}":                  p.productHandler.DeleteProductBarcode,
+		"GET /products/{id}/variants":            p.productHandler.GetProductVariants,
+		"POST /products/{id}/variants":           p.productHandler.CreateProductVariant,
+		"PUT /variants/{id}":                     p.productHandler.UpdateProductVariant,
+		"GET /modifier-groups":                   p.modifierHandler.GetModifierGroups,
+		"POST /modifier-groups":                  p.modifierHandler.CreateModifierGroup,
+		"PUT /modifier-groups/{id}":              p.modifierHandler.UpdateModifierGroup,
+		"POST /modifier-groups/{id}/modifiers":   p.modifierHandler.CreateModifier,
+		"PUT /modifiers/{id}":                    p.modifierHandler.UpdateModifier,
+		"GET /products/{id}/modifier-groups":     p.modifierHandler.GetProductModifierGroups,
+		"PUT /products/{id}/modifier-groups":     p.modifierHandler.SetProductModifierGroups,
+		"GET /units":                             p.unitHandler.GetUnits,
+		"PUT /units/{code}":                      p.unitHandler.SetUnit,
+		"GET /products/{id}/unit":                p.unitHandler.GetProductUnit,
+		"PUT /products/{id}/unit":                p.unitHandler.SetProductUnit,
+		"POST /items/price":                      p.unitHandler.PriceLineItem,
+		"GET /products/{id}/tracking":            p.trackingHandler.GetProductTracking,
+		"PUT /products/{id}/tracking":            p.trackingHandler.SetProductTracking,
+		"GET /products/{id}/serials":             p.trackingHandler.GetProductSerials,
+		"POST /products/{id}/serials":            p.trackingHandler.ReceiveProductSerials,
+		"GET /products/{id}/lots":                p.trackingHandler.GetProductLots,
+		"POST /products/{id}/lots":               p.trackingHandler.ReceiveProductLot,
+		"GET /serials/{serial}":                  p.trackingHandler.LookupSerial,
+		"GET /kits":                              p.kitHandler.GetKits,
+		"GET /products/{id}/kit":                 p.kitHandler.GetProductKit,
+		"PUT /products/{id}/kit":                 p.kitHandler.SetProductKit,
+		"DELETE /products/{id}/kit":              p.kitHandler.DeleteProductKit,
+		"GET /products/{id}/age-restriction":     p.ageVerificationHandler.GetProductAgeRestriction,
+		"PUT /products/{id}/age-restriction":     p.ageVerificationHandler.SetProductAgeRestriction,
+		"GET /age-sale-restrictions":             p.ageVerificationHandler.GetAgeSaleRestrictions,
+		"POST /age-sale-restrictions":            p.ageVerificationHandler.CreateAgeSaleRestriction,
+		"PUT /age-sale-restrictions/{id}":        p.ageVerificationHandler.UpdateAgeSaleRestriction,
+		"DELETE /age-sale-restrictions/{id}":     p.ageVerificationHandler.DeleteAgeSaleRestriction,
+		"GET /age-verifications":                 p.ageVerificationHandler.GetAgeVerifications,
+		"GET /price-books":                       p.priceBookHandler.GetPriceBooks,
+		"POST /price-books":                      p.priceBookHandler.CreatePriceBook,
+		"PUT /price-books/{id}":                  p.priceBookHandler.UpdatePriceBook,
+		"GET /price-books/{id}/entries":          p.priceBookHandler.GetPriceBookEntries,
+		"POST /price-books/{id}/entries":         p.priceBookHandler.CreatePriceBookEntry,
+		"DELETE /price-book-entries/{id}":        p.priceBookHandler.DeletePriceBookEntry,
+		"POST /price-books/{id}/publish":         p.priceBookHandler.PublishPriceBook,
+		"GET /price-books/published":             p.priceBookHandler.GetPublishedPriceBooks,
+		"GET /prices/resolve":                    p.priceBookHandler.ResolvePrice,
+		"GET /settings":                          p.settingsHandler.GetSettings,
+		"PUT /settings":                          p.settingsHandler.UpdateSettings,
+		"GET /analytics":                         p.handler.GetPOSAnalytics,
 	}
 
 	key := method + " " + route
@@ -3,9 +3,12 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -53,6 +56,48 @@ func (h *POSHandler) getUserID(r *http.Request) (int, error) {
 	return userID, nil
 }
 
+func (h *POSHandler) hasPermission(r *http.Request, permission string) bool {
+	// This should check the user's permissions from JWT or auth context
+	for _, granted := range strings.Split(r.Header.Get("X-User-Permissions"), ",") {
+		if strings.TrimSpace(granted) == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// isUniqueViolation reports whether err is PostgreSQL's unique_violation (SQLSTATE 23505), on the
+// named constraint or index when one is given
+func isUniqueViolation(err error, constraint string) bool {
+	if err == nil {
+		return false
+	}
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		if state.SQLState() != "23505" {
+			return false
+		}
+	} else if !strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+		return false
+	}
+	return constraint == "" || strings.Contains(err.Error(), `"`+constraint+`"`)
+}
+
+// nextDocumentNumber allocates the tenant's next number for a document type, formatted with the
+// type as prefix (DEP-000042); it rolls back with the transaction, so numbers never collide
+func nextDocumentNumber(tx *sqlx.Tx, tenantID, documentType string) (string, error) {
+	var next int
+	err := tx.QueryRow(`
+		INSERT INTO pos_document_sequences (tenant_id, document_type, last_number) VALUES ($1, $2, 1)
+		ON CONFLICT (tenant_id, document_type) DO UPDATE SET last_number = pos_document_sequences.last_number + 1
+		RETURNING last_number
+	`, tenantID, documentType).Scan(&next)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%06d", documentType, next), nil
+}
+
 // =================================================================
 // SESSION MANAGEMENT
 // =================================================================
@@ -67,6 +112,7 @@ func (h *POSHandler) GetPOSSessions(w http.ResponseWriter, r *http.Request) {
 
 	status := r.URL.Query().Get("status")
 	registerID := r.URL.Query().Get("register_id")
+	shiftID := r.URL.Query().Get("shift_id")
 	limit := r.URL.Query().Get("limit")
 
 	if limit == "" {
@@ -97,6 +143,12 @@ func (h *POSHandler) GetPOSSessions(w http.ResponseWriter, r *http.Request) {
 		argIndex++
 	}
 
+	if shiftID != "" {
+		query += fmt.Sprintf(" AND ps.shift_id = $%d", argIndex)
+		args = append(args, shiftID)
+		argIndex++
+	}
+
 	query += fmt.Sprintf(" ORDER BY ps.session_start DESC LIMIT $%d", argIndex)
 	args = append(args, limit)
 
@@ -198,19 +250,44 @@ func (h *POSHandler) CreatePOSSession(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
+	// Sessions run on an open register inside its open shift
+	if err := requireRegisterOpen(tx, tenantID, req.RegisterID); err != nil {
+		status := saleContextErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			http.Error(w, "Failed to look up register", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	shiftID, err := getOpenShiftID(tx, tenantID, req.RegisterID)
+	if err != nil {
+		if err == errNoOpenShift {
+			http.Error(w, "No open shift for this register; start a shift first", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to look up shift", http.StatusInternalServerError)
+		return
+	}
+
 	// Create POS session
 	sessionQuery := `
-		INSERT INTO pos_sessions (tenant_id, register_id, user_id, opening_amount, session_number, notes)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO pos_sessions (tenant_id, register_id, shift_id, user_id, opening_amount, session_number, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, session_start, created_at, updated_at
 	`
 
 	var sessionID int
 	var sessionStart, createdAt, updatedAt time.Time
 
-	err = tx.QueryRow(sessionQuery, tenantID, req.RegisterID, userID, req.OpeningAmount, sessionNumber, req.Notes).
+	err = tx.QueryRow(sessionQuery, tenantID, req.RegisterID, shiftID, userID, req.OpeningAmount, sessionNumber, req.Notes).
 		Scan(&sessionID, &sessionStart, &createdAt, &updatedAt)
 
+	if isUniqueViolation(err, "idx_pos_sessions_one_active") {
+		http.Error(w, "There is already an active session for this register", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to create POS session", http.StatusInternalServerError)
 		return
@@ -250,6 +327,7 @@ func (h *POSHandler) CreatePOSSession(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"session_id":     sessionID,
 		"session_number": sessionNumber,
+		"shift_id":       shiftID,
 		"session_start":  sessionStart,
 		"created_at":     createdAt,
 		"updated_at":     updatedAt,
@@ -361,6 +439,7 @@ func (h *POSHandler) GetPOSTransactions(w http.ResponseWriter, r *http.Request)
 
 	sessionID := r.URL.Query().Get("session_id")
 	registerID := r.URL.Query().Get("register_id")
+	shiftID := r.URL.Query().Get("shift_id")
 	status := r.URL.Query().Get("status")
 	limit := r.URL.Query().Get("limit")
 
@@ -392,6 +471,12 @@ func (h *POSHandler) GetPOSTransactions(w http.ResponseWriter, r *http.Request)
 		argIndex++
 	}
 
+	if shiftID != "" {
+		query += fmt.Sprintf(" AND pt.shift_id = $%d", argIndex)
+		args = append(args, shiftID)
+		argIndex++
+	}
+
 	if status != "" {
 		query += fmt.Sprintf(" AND pt.status = $%d", argIndex)
 		args = append(args, status)
@@ -475,19 +560,20 @@ func (h *POSHandler) CreatePOSTransaction(w http.ResponseWriter, r *http.Request
 	}
 
 	var req struct {
-		SessionID      int                    `json:"session_id" validate:"required"`
-		RegisterID     int                    `json:"register_id" validate:"required"`
-		CustomerID     *int                   `json:"customer_id"`
-		Subtotal       float64                `json:"subtotal" validate:"required"`
-		TaxAmount      float64                `json:"tax_amount"`
-		DiscountAmount float64                `json:"discount_amount"`
-		TipAmount      float64                `json:"tip_amount"`
-		TotalAmount    float64                `json:"total_amount" validate:"required"`
-		ChangeAmount   float64                `json:"change_amount"`
-		Notes          *string                `json:"notes"`
-		Items          []POSTransactionItem   `json:"items" validate:"required"`
-		Payments       []POSPayment           `json:"payments" validate:"required"`
-		CustomFields   map[string]interface{} `json:"custom_fields"`
+		SessionID       int                     `json:"session_id" validate:"required"`
+		RegisterID      int                     `json:"register_id" validate:"required"`
+		CustomerID      *int                    `json:"customer_id"`
+		Subtotal        float64                 `json:"subtotal" validate:"required"`
+		TaxAmount       float64                 `json:"tax_amount"`
+		DiscountAmount  float64                 `json:"discount_amount"`
+		TipAmount       float64                 `json:"tip_amount"`
+		TotalAmount     float64                 `json:"total_amount" validate:"required"`
+		ChangeAmount    float64                 `json:"change_amount"`
+		Notes           *string                 `json:"notes"`
+		Items           []POSTransactionItem    `json:"items" validate:"required"`
+		Payments        []POSPayment            `json:"payments" validate:"required"`
+		CustomFields    map[string]interface{}  `json:"custom_fields"`
+		AgeVerification *ageVerificationRequest `json:"age_verification"` // required when an item has a minimum age
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -518,12 +604,24 @@ func (h *POSHandler) CreatePOSTransaction(w http.ResponseWriter, r *http.Request
 	}
 	defer tx.Rollback()
 
+	// Sales may only be posted to an active session on an open shift of the same register
+	shiftID, err := validateSaleContext(tx, tenantID, req.SessionID, req.RegisterID)
+	if err != nil {
+		status := saleContextErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			http.Error(w, "Failed to validate session", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
 	// Create POS transaction
 	transactionQuery := `
-		INSERT INTO pos_transactions (tenant_id, transaction_number, session_id, register_id, customer_id,
+		INSERT INTO pos_transactions (tenant_id, transaction_number, session_id, register_id, shift_id, customer_id,
 		                             subtotal, tax_amount, discount_amount, tip_amount, total_amount,
 		                             change_amount, cashier_id, notes, custom_fields)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		RETURNING id, transaction_date, created_at, updated_at
 	`
 
@@ -532,7 +630,7 @@ func (h *POSHandler) CreatePOSTransaction(w http.ResponseWriter, r *http.Request
 
 	customFieldsJSON, _ := json.Marshal(req.CustomFields)
 
-	err = tx.QueryRow(transactionQuery, tenantID, transactionNumber, req.SessionID, req.RegisterID, req.CustomerID,
+	err = tx.QueryRow(transactionQuery, tenantID, transactionNumber, req.SessionID, req.RegisterID, shiftID, req.CustomerID,
 		req.Subtotal, req.TaxAmount, req.DiscountAmount, req.TipAmount, req.TotalAmount,
 		req.ChangeAmount, userID, req.Notes, customFieldsJSON).Scan(&transactionID, &transactionDate, &createdAt, &updatedAt)
 
@@ -541,25 +639,185 @@ func (h *POSHandler) CreatePOSTransaction(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Create transaction items
+	// Lines record the price the price books give them, so cashier price overrides can be reported.
+	// Selling at any other price is an override and needs its own permission.
+	priceCtx, err := loadPriceContext(tx, tenantID, &req.RegisterID, req.CustomerID)
+	if err != nil {
+		http.Error(w, "Failed to load prices", http.StatusInternalServerError)
+		return
+	}
+	canOverridePrice := h.hasPermission(r, "pos.prices.override")
+	barcodeSettings, err := loadEmbeddedBarcodeSettings(tx, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to load barcode settings", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+
+	// Create transaction items. The server reprices lines, so the totals are summed from the lines
+	// as saved rather than taken from the client.
+	var subtotal, discountTotal, taxTotal float64
+	requiredAge := 0
 	for _, item := range req.Items {
+		// Settle the quantity in the product's unit, then check the variant and modifiers and add
+		// the modifiers' price and tax to the line; a kit is split into its components
+		// A line scanned from an in-store code takes its price or weight from the code itself
+		var embedded *embeddedBarcode
+		if item.Barcode != nil {
+			embedded, err = resolveLineBarcode(tx, tenantID, barcodeSettings, &item)
+		}
+		var listPrice *ResolvedPrice
+		if err == nil {
+			listPrice, err = resolvePrice(tx, tenantID, priceCtx, item.ProductID, item.VariantID, now)
+		}
+		if err == nil {
+			price := applyEmbeddedBarcode(&item, embedded, listPrice.Price)
+			item.ListPrice = &price
+			item.PriceBookID = listPrice.PriceBookID
+			if embedded != nil && embedded.Kind == "price" {
+				item.PriceBookID = nil
+			}
+			err = checkLinePrice(&item, price, canOverridePrice)
+		}
+		if err == nil {
+			err = resolveLineQuantity(tx, tenantID, &item)
+		}
+		if err == nil {
+			err = applyItemOptions(tx, tenantID, &item)
+		}
+		if err == nil {
+			err = explodeKitLine(tx, tenantID, priceCtx, &item, now)
+		}
+		minimumAge := 0
+		if err == nil {
+			minimumAge, err = itemMinimumAge(tx, tenantID, &item)
+		}
+		if err != nil {
+			var optionErr *itemOptionError
+			if errors.As(err, &optionErr) {
+				http.Error(w, optionErr.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to load product options", http.StatusInternalServerError)
+			return
+		}
+		if minimumAge > requiredAge {
+			requiredAge = minimumAge
+		}
+		subtotal += item.Quantity * item.UnitPrice
+		discountTotal += item.DiscountAmount
+		taxTotal += item.TaxAmount
+
 		itemQuery := `
-			INSERT INTO pos_transaction_items (transaction_id, product_id, quantity, unit_price,
-			                                   discount_percent, discount_amount, tax_rate, tax_amount, notes, metadata)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			INSERT INTO pos_transaction_items (transaction_id, product_id, variant_id, quantity, unit_price,
+			                                   discount_percent, discount_amount, tax_rate, tax_amount, notes, metadata,
+			                                   unit_of_measure, gross_weight, tare_weight, list_price, price_book_id,
+			                                   discount_rule_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			RETURNING id
 		`
 
+		var itemID int
 		itemMetadata, _ := json.Marshal(map[string]interface{}{})
-		_, err = tx.Exec(itemQuery, transactionID, item.ProductID, item.Quantity, item.UnitPrice,
-			item.DiscountPercent, item.DiscountAmount, item.TaxRate, item.TaxAmount, item.Notes, itemMetadata)
+		err = tx.QueryRow(itemQuery, transactionID, item.ProductID, item.VariantID, item.Quantity, item.UnitPrice,
+			item.DiscountPercent, item.DiscountAmount, item.TaxRate, item.TaxAmount, item.Notes, itemMetadata,
+			item.UnitOfMeasure, item.GrossWeight, item.TareWeight, item.ListPrice, item.PriceBookID,
+			item.DiscountRuleID).Scan(&itemID)
 		if err != nil {
 			http.Error(w, "Failed to create transaction item", http.StatusInternalServerError)
 			return
 		}
+
+		if err = saveItemModifiers(tx, itemID, item.Modifiers); err != nil {
+			http.Error(w, "Failed to create transaction item", http.StatusInternalServerError)
+			return
+		}
+		if err = saveKitLine(tx, itemID, &item); err != nil {
+			if errors.Is(err, errBundleLimitReached) {
+				http.Error(w, fmt.Sprintf("The bundle price for kit %d has reached its usage limit; price the sale again",
+					item.ProductID), http.StatusConflict)
+				return
+			}
+			http.Error(w, "Failed to create transaction item", http.StatusInternalServerError)
+			return
+		}
+
+		// Serialized and lot-tracked products leave stock under the numbers captured at the till
+		if err = captureItemTracking(tx, tenantID, itemID, &item); err != nil {
+			var optionErr *itemOptionError
+			if errors.As(err, &optionErr) {
+				http.Error(w, optionErr.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to record serial and lot numbers", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	subtotal = math.Round(subtotal*100) / 100
+	discountTotal = math.Round(discountTotal*100) / 100
+	taxTotal = math.Round(taxTotal*100) / 100
+	total := math.Round((subtotal-discountTotal+taxTotal+req.TipAmount)*100) / 100
+
+	// Payments less the change handed back must cover the total
+	var paid float64
+	for _, payment := range req.Payments {
+		paid += payment.Amount
+	}
+	if math.Round((paid-req.ChangeAmount)*100) < math.Round(total*100) {
+		http.Error(w, fmt.Sprintf("Payments of %.2f less change of %.2f do not cover the total of %.2f",
+			paid, req.ChangeAmount, total), http.StatusBadRequest)
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pos_transactions
+		SET subtotal = $1, discount_amount = $2, tax_amount = $3, total_amount = $4
+		WHERE id = $5
+	`, subtotal, discountTotal, taxTotal, total, transactionID)
+	if err != nil {
+		http.Error(w, "Failed to create POS transaction", http.StatusInternalServerError)
+		return
+	}
+
+	// Age-restricted items are only sold outside the location's blocked hours, and with the
+	// customer's age check recorded on the transaction
+	if requiredAge > 0 {
+		err = checkAgeSaleHours(tx, tenantID, priceCtx.LocationID, requiredAge, now)
+		var verification *AgeVerification
+		if err == nil {
+			verification, err = verifyAge(req.AgeVerification, requiredAge, userID,
+				h.hasPermission(r, "pos.age_verifications.override"), now)
+		}
+		if err == nil {
+			err = saveAgeVerification(tx, tenantID, transactionID, verification)
+		}
+		if err == errAgeOverrideNotPermitted {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			var optionErr *itemOptionError
+			if errors.As(err, &optionErr) {
+				http.Error(w, optionErr.Error(), http.StatusBadRequest)
+				return
+			}
+			h.logger.Error("Failed to record age verification", zap.Int("transaction_id", transactionID), zap.Error(err))
+			http.Error(w, "Failed to record age verification", http.StatusInternalServerError)
+			return
+		}
 	}
 
 	// Create payments
+	var cashSales, cardSales float64
 	for _, payment := range req.Payments {
+		switch payment.PaymentMethod {
+		case "cash":
+			cashSales += payment.Amount
+		case "card":
+			cardSales += payment.Amount
+		}
+
 		paymentQuery := `
 			INSERT INTO pos_payments (transaction_id, payment_method, amount, reference_number, status, card_type, notes, metadata)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -574,17 +832,59 @@ func (h *POSHandler) CreatePOSTransaction(w http.ResponseWriter, r *http.Request
 		}
 	}
 
+	// Change is handed back from the cash drawer
+	cashSales -= req.ChangeAmount
+
 	// Update session totals
 	_, err = tx.Exec(`
 		UPDATE pos_sessions 
 		SET total_sales = total_sales + $1, total_transactions = total_transactions + 1
 		WHERE id = $2
-	`, req.TotalAmount, req.SessionID)
+	`, total, req.SessionID)
 	if err != nil {
 		http.Error(w, "Failed to update session", http.StatusInternalServerError)
 		return
 	}
 
+	// Update shift totals
+	_, err = tx.Exec(`
+		UPDATE register_shifts
+		SET total_sales = total_sales + $1, total_cash_sales = total_cash_sales + $2,
+		    total_card_sales = total_card_sales + $3, transaction_count = transaction_count + 1
+		WHERE id = $4
+	`, total, cashSales, cardSales, shiftID)
+	if err != nil {
+		http.Error(w, "Failed to update shift", http.StatusInternalServerError)
+		return
+	}
+
+	// Keep the register's cash position in step with cash taken
+	if cashSales != 0 {
+		_, err = tx.Exec(`
+			UPDATE pos_registers
+			SET current_balance = current_balance + $1, expected_balance = expected_balance + $1
+			WHERE id = $2
+		`, cashSales, req.RegisterID)
+		if err != nil {
+			http.Error(w, "Failed to update register balance", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Take the sold items out of stock at the register's location
+	if err = deductSaleStock(tx, tenantID, transactionID, req.RegisterID, userID); err != nil {
+		h.logger.Error("Failed to record stock movements", zap.Int("transaction_id", transactionID), zap.Error(err))
+		http.Error(w, "Failed to update inventory", http.StatusInternalServerError)
+		return
+	}
+
+	// Sales are recorded completed, so they go straight into the register's fiscal journal
+	if err = appendFiscalEntry(tx, tenantID, fiscalEntryTransaction, transactionID); err != nil {
+		h.logger.Error("Failed to journal transaction", zap.Int("transaction_id", transactionID), zap.Error(err))
+		http.Error(w, "Failed to journal transaction", http.StatusInternalServerError)
+		return
+	}
+
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
 		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
@@ -596,7 +896,12 @@ func (h *POSHandler) CreatePOSTransaction(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"transaction_id":     transactionID,
 		"transaction_number": transactionNumber,
+		"shift_id":           shiftID,
 		"transaction_date":   transactionDate,
+		"subtotal":           subtotal,
+		"discount_amount":    discountTotal,
+		"tax_amount":         taxTotal,
+		"total_amount":       total,
 		"created_at":         createdAt,
 		"updated_at":         updatedAt,
 		"message":            "POS transaction created successfully",
@@ -683,13 +988,34 @@ func (h *POSHandler) GetPOSTransaction(w http.ResponseWriter, r *http.Request) {
 
 	// Get transaction items
 	itemsQuery := `
-		SELECT pti.*, p.name as product_name, p.sku
+		SELECT pti.id, pti.transaction_id, pti.product_id, pti.quantity, pti.unit_price, pti.discount_percent,
+		       pti.discount_amount, pti.tax_rate, pti.tax_amount, pti.line_total, pti.notes, pti.metadata,
+		       pti.created_at, p.name as product_name, p.sku, pti.variant_id, v.name as variant_name, v.sku,
+		       pti.unit_of_measure, pti.gross_weight, pti.tare_weight, pti.list_price, pti.price_book_id,
+		       pti.discount_rule_id
 		FROM pos_transaction_items pti
 		JOIN products p ON pti.product_id = p.id
+		LEFT JOIN pos_product_variants v ON v.id = pti.variant_id
 		WHERE pti.transaction_id = $1
 		ORDER BY pti.id
 	`
 
+	modifiers, err := loadItemModifiers(h.db, id)
+	if err != nil {
+		http.Error(w, "Failed to fetch transaction items", http.StatusInternalServerError)
+		return
+	}
+	components, err := loadItemComponents(h.db, id)
+	if err != nil {
+		http.Error(w, "Failed to fetch transaction items", http.StatusInternalServerError)
+		return
+	}
+	serials, lots, err := loadItemTracking(h.db, id)
+	if err != nil {
+		http.Error(w, "Failed to fetch transaction items", http.StatusInternalServerError)
+		return
+	}
+
 	itemRows, err := h.db.Query(itemsQuery, id)
 	if err == nil {
 		defer itemRows.Close()
@@ -698,12 +1024,17 @@ func (h *POSHandler) GetPOSTransaction(w http.ResponseWriter, r *http.Request) {
 			var item POSTransactionItem
 			var productName, sku sql.NullString
 			var metadataJSON sql.NullString
+			var variantName sql.NullString
+			var variantSKU *string
 
 			err := itemRows.Scan(
 				&item.ID, &item.TransactionID, &item.ProductID, &item.Quantity,
 				&item.UnitPrice, &item.DiscountPercent, &item.DiscountAmount,
 				&item.TaxRate, &item.TaxAmount, &item.LineTotal, &item.Notes,
 				&metadataJSON, &item.CreatedAt, &productName, &sku,
+				&item.VariantID, &variantName, &variantSKU,
+				&item.UnitOfMeasure, &item.GrossWeight, &item.TareWeight, &item.ListPrice, &item.PriceBookID,
+				&item.DiscountRuleID,
 			)
 			if err != nil {
 				continue
@@ -714,6 +1045,18 @@ func (h *POSHandler) GetPOSTransaction(w http.ResponseWriter, r *http.Request) {
 				Name: productName.String,
 				SKU:  sku.String,
 			}
+			if item.VariantID != nil {
+				item.Variant = &ProductVariant{
+					ID:        *item.VariantID,
+					ProductID: item.ProductID,
+					Name:      variantName.String,
+					SKU:       variantSKU,
+				}
+			}
+			item.Modifiers = modifiers[item.ID]
+			item.Components = components[item.ID]
+			item.SerialNumbers = serials[item.ID]
+			item.Lots = lots[item.ID]
 
 			if metadataJSON.Valid {
 				json.Unmarshal([]byte(metadataJSON.String), &item.Metadata)
@@ -756,100 +1099,15 @@ func (h *POSHandler) GetPOSTransaction(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(transaction)
-}
-
-// =================================================================
-// RECEIPT MANAGEMENT
-// =================================================================
-
-// CreateReceipt creates a receipt for a transaction
-func (h *POSHandler) CreateReceipt(w http.ResponseWriter, r *http.Request) {
-	tenantID, err := h.getTenantID(r)
+	// Get the age verification, if the sale needed one
+	transaction.AgeVerification, err = loadAgeVerification(h.db, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
-		return
-	}
-
-	var req struct {
-		TransactionID int    `json:"transaction_id" validate:"required"`
-		ReceiptType   string `json:"receipt_type" validate:"required"`
-		ReceiptData   string `json:"receipt_data" validate:"required"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Generate receipt number
-	receiptNumber := fmt.Sprintf("RCP-%d", time.Now().Unix())
-
-	query := `
-		INSERT INTO pos_receipts (tenant_id, transaction_id, receipt_number, receipt_type, receipt_data)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at
-	`
-
-	var receiptID int
-	var createdAt time.Time
-
-	err = h.db.QueryRow(query, tenantID, req.TransactionID, receiptNumber, req.ReceiptType, req.ReceiptData).
-		Scan(&receiptID, &createdAt)
-
-	if err != nil {
-		http.Error(w, "Failed to create receipt", http.StatusInternalServerError)
+		http.Error(w, "Failed to fetch age verification", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"receipt_id":     receiptID,
-		"receipt_number": receiptNumber,
-		"created_at":     createdAt,
-		"message":        "Receipt created successfully",
-	})
-}
-
-// PrintReceipt marks a receipt as printed
-func (h *POSHandler) PrintReceipt(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	receiptID, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
-		return
-	}
-
-	query := `
-		UPDATE pos_receipts 
-		SET printed_at = $1, reprint_count = reprint_count + 1
-		WHERE id = $2
-		RETURNING receipt_number, printed_at, reprint_count
-	`
-
-	var receiptNumber string
-	var printedAt time.Time
-	var reprintCount int
-
-	err = h.db.QueryRow(query, time.Now(), receiptID).Scan(&receiptNumber, &printedAt, &reprintCount)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Receipt not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to print receipt", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"receipt_number": receiptNumber,
-		"printed_at":     printedAt,
-		"reprint_count":  reprintCount,
-		"message":        "Receipt printed successfully",
-	})
+	json.NewEncoder(w).Encode(transaction)
 }
 
 // =================================================================
@@ -997,7 +1255,7 @@ func (h *POSHandler) GetPOSAnalytics(w http.ResponseWriter, r *http.Request) {
 			SUM(pt.discount_amount) as total_discounts
 		FROM pos_transactions pt
 		WHERE pt.tenant_id = $1 AND pt.created_at BETWEEN $2 AND $3
-		  AND pt.status = 'completed'
+		  AND pt.status IN ('completed', 'refunded')
 	`
 
 	args := []interface{}{tenantID, startDate, endDate}
@@ -1019,13 +1277,15 @@ func (h *POSHandler) GetPOSAnalytics(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 
 	var analytics struct {
-		Period                  string    `json:"period"`
-		GeneratedAt             time.Time `json:"generated_at"`
-		TotalTransactions       int       `json:"total_transactions"`
-		TotalSales              float64   `json:"total_sales"`
-		TotalTax                float64   `json:"total_tax"`
-		TotalDiscounts          float64   `json:"total_discounts"`
-		AverageTransactionValue float64   `json:"average_transaction_value"`
+		Period                  string               `json:"period"`
+		GeneratedAt             time.Time            `json:"generated_at"`
+		TotalTransactions       int                  `json:"total_transactions"`
+		TotalSales              float64              `json:"total_sales"`
+		TotalTax                float64              `json:"total_tax"`
+		TotalDiscounts          float64              `json:"total_discounts"`
+		AverageTransactionValue float64              `json:"average_transaction_value"`
+		ItemSales               []ReportItemLine     `json:"item_sales"`
+		ModifierSales           []ReportModifierLine `json:"modifier_sales"`
 	}
 
 	for rows.Next() {
@@ -1048,6 +1308,17 @@ func (h *POSHandler) GetPOSAnalytics(w http.ResponseWriter, r *http.Request) {
 		analytics.AverageTransactionValue = analytics.TotalSales / float64(analytics.TotalTransactions)
 	}
 
+	// Sales by product and variant, and by modifier, over the same period and register
+	itemFilter := "pt.tenant_id = $1 AND pt.created_at BETWEEN $2 AND $3"
+	if registerID != "" {
+		itemFilter += " AND pt.register_id = $4"
+	}
+	analytics.ItemSales, analytics.ModifierSales, err = itemSalesBreakdown(h.db, itemFilter, args)
+	if err != nil {
+		http.Error(w, "Failed to fetch POS analytics", http.StatusInternalServerError)
+		return
+	}
+
 	analytics.Period = fmt.Sprintf("%s to %s", startDate, endDate)
 	analytics.GeneratedAt = time.Now()
 
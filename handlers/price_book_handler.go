@@ -0,0 +1,672 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// PriceBookHandler manages price books and their entries, publishes them for terminals to cache,
+// and resolves the price a product sells at
+type PriceBookHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewPriceBookHandler creates a new price book handler
+func NewPriceBookHandler(db *sqlx.DB, logger *zap.Logger) *PriceBookHandler {
+	return &PriceBookHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+const priceBookColumns = `id, tenant_id, name, scope_type, scope_id, priority, valid_from, valid_to, is_active,
+	version, published_at, created_by, created_at, updated_at`
+
+const priceBookEntryColumns = `id, price_book_id, product_id, variant_id, price, effective_from, effective_to,
+	created_by, created_at`
+
+// priceBookRequest is the body of a price book create or update
+type priceBookRequest struct {
+	Name      string     `json:"name" validate:"required"`
+	ScopeType string     `json:"scope_type"` // all, location, register, customer_group
+	ScopeID   *int       `json:"scope_id"`
+	Priority  int        `json:"priority"`
+	ValidFrom *time.Time `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+	IsActive  *bool      `json:"is_active"`
+}
+
+func (req *priceBookRequest) validate() error {
+	if req.Name == "" {
+		return errors.New("Price book name is required")
+	}
+	if req.ScopeType == "" {
+		req.ScopeType = "all"
+	}
+	known := false
+	for _, scope := range priceBookScopes {
+		known = known || scope == req.ScopeType
+	}
+	if !known {
+		return errors.New("Scope type must be all, location, register or customer_group")
+	}
+	if req.ScopeType == "all" && req.ScopeID != nil {
+		return errors.New("A price book for all registers takes no scope_id")
+	}
+	if req.ScopeType != "all" && req.ScopeID == nil {
+		return errors.New("scope_id is required for this scope type")
+	}
+	if req.ValidFrom != nil && req.ValidTo != nil && !req.ValidTo.After(*req.ValidFrom) {
+		return errors.New("valid_to must be after valid_from")
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+	return nil
+}
+
+func scanPriceBook(row interface{ Scan(...interface{}) error }, b *PriceBook) error {
+	return row.Scan(&b.ID, &b.TenantID, &b.Name, &b.ScopeType, &b.ScopeID, &b.Priority, &b.ValidFrom,
+		&b.ValidTo, &b.IsActive, &b.Version, &b.PublishedAt, &b.CreatedBy, &b.CreatedAt, &b.UpdatedAt)
+}
+
+func scanPriceBookEntry(row interface{ Scan(...interface{}) error }, e *PriceBookEntry) error {
+	return row.Scan(&e.ID, &e.PriceBookID, &e.ProductID, &e.VariantID, &e.Price, &e.EffectiveFrom,
+		&e.EffectiveTo, &e.CreatedBy, &e.CreatedAt)
+}
+
+// loadPriceBook returns a tenant's price book, or nil if it doesn't exist
+func loadPriceBook(q rowQuerier, tenantID string, id int) (*PriceBook, error) {
+	var b PriceBook
+	err := scanPriceBook(q.QueryRow("SELECT "+priceBookColumns+" FROM pos_price_books WHERE id = $1 AND tenant_id = $2",
+		id, tenantID), &b)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetPriceBooks lists the tenant's price books, most specific scope first
+func (h *PriceBookHandler) GetPriceBooks(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query := "SELECT " + priceBookColumns + " FROM pos_price_books WHERE tenant_id = $1"
+	args := []interface{}{tenantID}
+	if scopeType := r.URL.Query().Get("scope_type"); scopeType != "" {
+		query += " AND scope_type = $2"
+		args = append(args, scopeType)
+	}
+	query += ` ORDER BY CASE scope_type WHEN 'customer_group' THEN 0 WHEN 'register' THEN 1 WHEN 'location' THEN 2 ELSE 3 END,
+		priority DESC, name`
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch price books", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	books := []PriceBook{}
+	for rows.Next() {
+		var b PriceBook
+		if err := scanPriceBook(rows, &b); err != nil {
+			http.Error(w, "Failed to scan price book", http.StatusInternalServerError)
+			return
+		}
+		books = append(books, b)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"price_books": books,
+		"count":       len(books),
+	})
+}
+
+// CreatePriceBook creates a price book for every register, a location, a register or a customer group
+func (h *PriceBookHandler) CreatePriceBook(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.price_books.manage") {
+		http.Error(w, "Managing price books requires the pos.price_books.manage permission", http.StatusForbidden)
+		return
+	}
+
+	var req priceBookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+	var book PriceBook
+	err = scanPriceBook(h.db.QueryRow(`
+		INSERT INTO pos_price_books (tenant_id, name, scope_type, scope_id, priority, valid_from, valid_to,
+		                             is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+priceBookColumns,
+		tenantID, req.Name, req.ScopeType, req.ScopeID, req.Priority, req.ValidFrom, req.ValidTo,
+		*req.IsActive, userID), &book)
+	if err != nil {
+		http.Error(w, "Failed to create price book; price book names must be unique", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(book)
+}
+
+// UpdatePriceBook replaces a price book's name, scope, priority and validity. Terminals see the
+// change once the book is published again.
+func (h *PriceBookHandler) UpdatePriceBook(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.price_books.manage") {
+		http.Error(w, "Managing price books requires the pos.price_books.manage permission", http.StatusForbidden)
+		return
+	}
+
+	bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid price book ID", http.StatusBadRequest)
+		return
+	}
+
+	var req priceBookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing, err := loadPriceBook(h.db, tenantID, bookID)
+	if err != nil {
+		http.Error(w, "Failed to fetch price book", http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Price book not found", http.StatusNotFound)
+		return
+	}
+
+	var book PriceBook
+	err = scanPriceBook(h.db.QueryRow(`
+		UPDATE pos_price_books
+		SET name = $3, scope_type = $4, scope_id = $5, priority = $6, valid_from = $7, valid_to = $8, is_active = $9
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING `+priceBookColumns,
+		bookID, tenantID, req.Name, req.ScopeType, req.ScopeID, req.Priority, req.ValidFrom, req.ValidTo,
+		*req.IsActive), &book)
+	if err != nil {
+		http.Error(w, "Failed to update price book; price book names must be unique", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
+
+// GetPriceBookEntries lists a price book's entries, including expired and scheduled ones
+func (h *PriceBookHandler) GetPriceBookEntries(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid price book ID", http.StatusBadRequest)
+		return
+	}
+
+	book, err := loadPriceBook(h.db, tenantID, bookID)
+	if err != nil {
+		http.Error(w, "Failed to fetch price book", http.StatusInternalServerError)
+		return
+	}
+	if book == nil {
+		http.Error(w, "Price book not found", http.StatusNotFound)
+		return
+	}
+
+	query := "SELECT " + priceBookEntryColumns + " FROM pos_price_book_entries WHERE price_book_id = $1"
+	args := []interface{}{bookID}
+	if productID := r.URL.Query().Get("product_id"); productID != "" {
+		id, err := strconv.Atoi(productID)
+		if err != nil {
+			http.Error(w, "Invalid product ID", http.StatusBadRequest)
+			return
+		}
+		query += " AND product_id = $2"
+		args = append(args, id)
+	}
+	query += " ORDER BY product_id, variant_id NULLS FIRST, effective_from"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch price book entries", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []PriceBookEntry{}
+	for rows.Next() {
+		var e PriceBookEntry
+		if err := scanPriceBookEntry(rows, &e); err != nil {
+			http.Error(w, "Failed to scan price book entry", http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// CreatePriceBookEntry adds a product or variant price to a price book. Without effective_from the
+// price applies now; a future effective_from schedules a price change, and effective_to ends a
+// temporary price so the entry before it applies again.
+func (h *PriceBookHandler) CreatePriceBookEntry(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.price_books.manage") {
+		http.Error(w, "Managing price books requires the pos.price_books.manage permission", http.StatusForbidden)
+		return
+	}
+
+	bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid price book ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		ProductID     int        `json:"product_id" validate:"required"`
+		VariantID     *int       `json:"variant_id"`
+		Price         float64    `json:"price"`
+		EffectiveFrom *time.Time `json:"effective_from"`
+		EffectiveTo   *time.Time `json:"effective_to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ProductID == 0 {
+		http.Error(w, "Product is required", http.StatusBadRequest)
+		return
+	}
+	if req.Price < 0 {
+		http.Error(w, "Price cannot be negative", http.StatusBadRequest)
+		return
+	}
+	effectiveFrom := time.Now()
+	if req.EffectiveFrom != nil {
+		effectiveFrom = *req.EffectiveFrom
+	}
+	if req.EffectiveTo != nil && !req.EffectiveTo.After(effectiveFrom) {
+		http.Error(w, "effective_to must be after effective_from", http.StatusBadRequest)
+		return
+	}
+
+	book, err := loadPriceBook(h.db, tenantID, bookID)
+	if err != nil {
+		http.Error(w, "Failed to fetch price book", http.StatusInternalServerError)
+		return
+	}
+	if book == nil {
+		http.Error(w, "Price book not found", http.StatusNotFound)
+		return
+	}
+
+	if req.VariantID != nil {
+		var variantProductID int
+		err := h.db.QueryRow("SELECT product_id FROM pos_product_variants WHERE id = $1 AND tenant_id = $2",
+			*req.VariantID, tenantID).Scan(&variantProductID)
+		if err == sql.ErrNoRows || (err == nil && variantProductID != req.ProductID) {
+			http.Error(w, "Variant not found for this product", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch variant", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+	var entry PriceBookEntry
+	err = scanPriceBookEntry(h.db.QueryRow(`
+		INSERT INTO pos_price_book_entries (price_book_id, product_id, variant_id, price, effective_from,
+		                                    effective_to, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+priceBookEntryColumns,
+		bookID, req.ProductID, req.VariantID, req.Price, effectiveFrom, req.EffectiveTo, userID), &entry)
+	if err != nil {
+		http.Error(w, "Failed to create price book entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// DeletePriceBookEntry cancels a scheduled price. Entries already in effect stay for the record;
+// end them with a new entry or an effective_to instead.
+func (h *PriceBookHandler) DeletePriceBookEntry(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.price_books.manage") {
+		http.Error(w, "Managing price books requires the pos.price_books.manage permission", http.StatusForbidden)
+		return
+	}
+
+	entryID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid price book entry ID", http.StatusBadRequest)
+		return
+	}
+
+	var effectiveFrom time.Time
+	err = h.db.QueryRow(`
+		SELECT e.effective_from
+		FROM pos_price_book_entries e
+		JOIN pos_price_books b ON b.id = e.price_book_id
+		WHERE e.id = $1 AND b.tenant_id = $2
+	`, entryID, tenantID).Scan(&effectiveFrom)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Price book entry not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch price book entry", http.StatusInternalServerError)
+		return
+	}
+	if !effectiveFrom.After(time.Now()) {
+		http.Error(w, "Price book entry is already in effect; add a new entry or set an effective_to", http.StatusConflict)
+		return
+	}
+
+	if _, err := h.db.Exec("DELETE FROM pos_price_book_entries WHERE id = $1", entryID); err != nil {
+		http.Error(w, "Failed to delete price book entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PublishPriceBook snapshots a price book with its current and scheduled entries as a new version.
+// Terminals download the latest snapshot and apply each entry from its effective date, so scheduled
+// changes take effect offline.
+func (h *PriceBookHandler) PublishPriceBook(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.price_books.manage") {
+		http.Error(w, "Managing price books requires the pos.price_books.manage permission", http.StatusForbidden)
+		return
+	}
+
+	bookID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid price book ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var published PublishedPriceBook
+	err = scanPriceBook(tx.QueryRow(`
+		UPDATE pos_price_books SET version = version + 1, published_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING `+priceBookColumns, bookID, tenantID), &published.PriceBook)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Price book not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to publish price book", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := tx.Query(`
+		SELECT `+priceBookEntryColumns+` FROM pos_price_book_entries
+		WHERE price_book_id = $1 AND (effective_to IS NULL OR effective_to > CURRENT_TIMESTAMP)
+		ORDER BY product_id, variant_id NULLS FIRST, effective_from
+	`, bookID)
+	if err != nil {
+		http.Error(w, "Failed to fetch price book entries", http.StatusInternalServerError)
+		return
+	}
+	published.Entries = []PriceBookEntry{}
+	for rows.Next() {
+		var e PriceBookEntry
+		if err := scanPriceBookEntry(rows, &e); err != nil {
+			rows.Close()
+			http.Error(w, "Failed to scan price book entry", http.StatusInternalServerError)
+			return
+		}
+		published.Entries = append(published.Entries, e)
+	}
+	rows.Close()
+
+	snapshot, err := json.Marshal(published)
+	if err != nil {
+		http.Error(w, "Failed to encode price book", http.StatusInternalServerError)
+		return
+	}
+	userID, _ := h.baseHandler.getUserID(r)
+	_, err = tx.Exec(`
+		INSERT INTO pos_price_book_publications (price_book_id, version, snapshot, snapshot_hash, published_by)
+		VALUES ($1, $2, $3, $4, $5)
+	`, bookID, published.Version, snapshot, sha256Hex(snapshot), userID)
+	if err != nil {
+		http.Error(w, "Failed to publish price book", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Price book published", zap.Int("price_book_id", bookID), zap.Int("version", published.Version),
+		zap.Int("entries", len(published.Entries)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(published)
+}
+
+// GetPublishedPriceBooks returns the latest published version of every active book a register can
+// use: books for all registers, its location and itself, and every customer group's books, since
+// the customer is only known at sale. The ETag changes whenever any of them is republished, so
+// terminals poll with If-None-Match and keep their cache on 304.
+func (h *PriceBookHandler) GetPublishedPriceBooks(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var registerID *int
+	if param := r.URL.Query().Get("register_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "Invalid register ID", http.StatusBadRequest)
+			return
+		}
+		registerID = &id
+	}
+	ctx, err := loadPriceContext(h.db, tenantID, registerID, nil)
+	if err != nil {
+		http.Error(w, "Failed to load register", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT DISTINCT ON (b.id) b.id, p.version, p.snapshot, p.snapshot_hash
+		FROM pos_price_books b
+		JOIN pos_price_book_publications p ON p.price_book_id = b.id
+		WHERE b.tenant_id = $1 AND b.is_active = true
+		  AND (b.valid_to IS NULL OR b.valid_to > CURRENT_TIMESTAMP)
+		  AND (b.scope_type IN ('all', 'customer_group')
+		       OR (b.scope_type = 'location' AND b.scope_id = $2)
+		       OR (b.scope_type = 'register' AND b.scope_id = $3))
+		ORDER BY b.id, p.version DESC
+	`, tenantID, ctx.LocationID, ctx.RegisterID)
+	if err != nil {
+		http.Error(w, "Failed to fetch published price books", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	books := []json.RawMessage{}
+	digest := sha256.New()
+	for rows.Next() {
+		var bookID, version int
+		var snapshot []byte
+		var hash string
+		if err := rows.Scan(&bookID, &version, &snapshot, &hash); err != nil {
+			http.Error(w, "Failed to scan published price book", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(digest, "%d:%d:%s\n", bookID, version, hash)
+		books = append(books, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to fetch published price books", http.StatusInternalServerError)
+		return
+	}
+
+	etag := `"` + hex.EncodeToString(digest.Sum(nil)) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"register_id":      ctx.RegisterID,
+		"location_id":      ctx.LocationID,
+		"resolution_order": priceBookScopes,
+		"price_books":      books,
+		"count":            len(books),
+	})
+}
+
+// ResolvePrice returns the price a product or variant sells at for a register and customer, at a
+// given time or now, and the price book it comes from
+func (h *PriceBookHandler) ResolvePrice(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	productID, err := strconv.Atoi(query.Get("product_id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	ids := map[string]*int{}
+	for _, param := range []string{"variant_id", "register_id", "customer_id", "customer_group_id"} {
+		if value := query.Get(param); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			ids[param] = &id
+		}
+	}
+	at := time.Now()
+	if value := query.Get("at"); value != "" {
+		at, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "Invalid time; use RFC 3339", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, err := loadPriceContext(h.db, tenantID, ids["register_id"], ids["customer_id"])
+	if err != nil {
+		http.Error(w, "Failed to load prices", http.StatusInternalServerError)
+		return
+	}
+	if ids["customer_group_id"] != nil {
+		ctx.CustomerGroupID = ids["customer_group_id"]
+	}
+
+	price, err := resolvePrice(h.db, tenantID, ctx, productID, ids["variant_id"], at)
+	if err != nil {
+		var optionErr *itemOptionError
+		if errors.As(err, &optionErr) {
+			http.Error(w, optionErr.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to resolve price", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(price)
+}
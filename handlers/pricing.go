@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// priceBookScopes are the scopes a price book can have, most specific first. A customer group's
+// price beats the register's, the register's beats its location's, and those beat books for
+// every register.
+var priceBookScopes = []string{"customer_group", "register", "location", "all"}
+
+// priceContext is where and for whom a price is resolved
+type priceContext struct {
+	RegisterID      *int
+	LocationID      *int
+	CustomerGroupID *int
+}
+
+// loadPriceContext finds the location of a register and the price group of a customer; either may be nil
+func loadPriceContext(q rowQuerier, tenantID string, registerID, customerID *int) (priceContext, error) {
+	ctx := priceContext{RegisterID: registerID}
+	if registerID != nil {
+		locationID, err := registerLocationID(q, tenantID, *registerID)
+		if err != nil && err != sql.ErrNoRows {
+			return ctx, err
+		}
+		ctx.LocationID = locationID
+	}
+	if customerID != nil {
+		var groupID int
+		err := q.QueryRow(`
+			SELECT customer_group_id FROM pos_customer_price_groups WHERE tenant_id = $1 AND customer_id = $2
+		`, tenantID, *customerID).Scan(&groupID)
+		if err != nil && err != sql.ErrNoRows {
+			return ctx, err
+		}
+		if err == nil {
+			ctx.CustomerGroupID = &groupID
+		}
+	}
+	return ctx, nil
+}
+
+// priceBookCandidate is the best entry one product or variant key has in the applicable books
+type priceBookCandidate struct {
+	entryID   int
+	bookID    int
+	bookName  string
+	price     float64
+	scopeRank int
+	priority  int
+}
+
+// better reports whether c wins over other: the more specific scope first, then the higher priority
+func (c *priceBookCandidate) better(other *priceBookCandidate) bool {
+	if other == nil {
+		return true
+	}
+	if c.scopeRank != other.scopeRank {
+		return c.scopeRank < other.scopeRank
+	}
+	return c.priority > other.priority
+}
+
+// priceBookKey identifies a product, or one variant of it, in the price map
+type priceBookKey struct {
+	productID int
+	variantID int // 0 for entries that price the product and its variants
+}
+
+// loadPriceBookCandidates returns, per product and variant, the entry that applies at the given
+// time in the books active for the context. Within a key the most specific scope wins, then the
+// book's priority, then the entry that took effect last. productID 0 loads every product.
+func loadPriceBookCandidates(q reportQuerier, tenantID string, ctx priceContext, productID int,
+	at time.Time) (map[priceBookKey]*priceBookCandidate, error) {
+	query := `
+		SELECT DISTINCT ON (e.product_id, COALESCE(e.variant_id, 0))
+		       e.product_id, COALESCE(e.variant_id, 0), e.id, b.id, b.name, e.price, b.priority,
+		       CASE b.scope_type WHEN 'customer_group' THEN 0 WHEN 'register' THEN 1 WHEN 'location' THEN 2 ELSE 3 END AS scope_rank
+		FROM pos_price_book_entries e
+		JOIN pos_price_books b ON b.id = e.price_book_id
+		WHERE b.tenant_id = $1 AND b.is_active = true
+		  AND (b.valid_from IS NULL OR b.valid_from <= $2) AND (b.valid_to IS NULL OR b.valid_to > $2)
+		  AND e.effective_from <= $2 AND (e.effective_to IS NULL OR e.effective_to > $2)
+		  AND (b.scope_type = 'all'
+		       OR (b.scope_type = 'location' AND b.scope_id = $3)
+		       OR (b.scope_type = 'register' AND b.scope_id = $4)
+		       OR (b.scope_type = 'customer_group' AND b.scope_id = $5))
+	`
+	args := []interface{}{tenantID, at, ctx.LocationID, ctx.RegisterID, ctx.CustomerGroupID}
+	if productID != 0 {
+		query += " AND e.product_id = $6"
+		args = append(args, productID)
+	}
+	query += " ORDER BY e.product_id, COALESCE(e.variant_id, 0), scope_rank, b.priority DESC, e.effective_from DESC, e.id DESC"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make(map[priceBookKey]*priceBookCandidate)
+	for rows.Next() {
+		var key priceBookKey
+		var c priceBookCandidate
+		if err := rows.Scan(&key.productID, &key.variantID, &c.entryID, &c.bookID, &c.bookName, &c.price,
+			&c.priority, &c.scopeRank); err != nil {
+			return nil, err
+		}
+		candidates[key] = &c
+	}
+	return candidates, rows.Err()
+}
+
+// pickPriceBookCandidate chooses between a variant's own entry and its product's entry. The more
+// specific book wins; within the same scope and priority the variant's entry wins.
+func pickPriceBookCandidate(candidates map[priceBookKey]*priceBookCandidate, productID int, variantID *int) *priceBookCandidate {
+	productEntry := candidates[priceBookKey{productID: productID}]
+	if variantID == nil {
+		return productEntry
+	}
+	variantEntry := candidates[priceBookKey{productID: productID, variantID: *variantID}]
+	if variantEntry == nil {
+		return productEntry
+	}
+	if productEntry != nil && productEntry.better(variantEntry) {
+		return productEntry
+	}
+	return variantEntry
+}
+
+// resolvePrice returns what a product or variant sells at in a context: the applicable price
+// book entry, else the variant's own price, else the product's selling price
+func resolvePrice(q reportQuerier, tenantID string, ctx priceContext, productID int, variantID *int,
+	at time.Time) (*ResolvedPrice, error) {
+	candidates, err := loadPriceBookCandidates(q, tenantID, ctx, productID, at)
+	if err != nil {
+		return nil, err
+	}
+	resolved := &ResolvedPrice{ProductID: productID, VariantID: variantID}
+	if c := pickPriceBookCandidate(candidates, productID, variantID); c != nil {
+		resolved.Price = c.price
+		resolved.Source = "price_book"
+		resolved.PriceBookID = &c.bookID
+		resolved.PriceBookName = &c.bookName
+		resolved.EntryID = &c.entryID
+		return resolved, nil
+	}
+
+	if variantID != nil {
+		var price sql.NullFloat64
+		err := q.QueryRow("SELECT price FROM pos_product_variants WHERE id = $1 AND tenant_id = $2",
+			*variantID, tenantID).Scan(&price)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if price.Valid {
+			resolved.Price = price.Float64
+			resolved.Source = "variant"
+			return resolved, nil
+		}
+	}
+
+	var price sql.NullFloat64
+	err = q.QueryRow("SELECT selling_price FROM products WHERE id = $1", productID).Scan(&price)
+	if err == sql.ErrNoRows {
+		return nil, &itemOptionError{fmt.Sprintf("Product %d not found", productID)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	resolved.Price = price.Float64
+	resolved.Source = "product"
+	return resolved, nil
+}
+
+// checkLinePrice refuses a line sold at other than its price, to the cent, unless the cashier may
+// override prices
+func checkLinePrice(item *POSTransactionItem, price float64, canOverride bool) error {
+	if canOverride || math.Round(item.UnitPrice*100) == math.Round(price*100) {
+		return nil
+	}
+	return &itemOptionError{fmt.Sprintf("Product %d sells at %.2f; selling it at %.2f needs the pos.prices.override permission",
+		item.ProductID, price, item.UnitPrice)}
+}
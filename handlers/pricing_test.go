@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestPickPriceBookCandidate(t *testing.T) {
+	variantID := 3
+	entry := func(id int, scope string, priority int) *priceBookCandidate {
+		rank := map[string]int{"customer_group": 0, "register": 1, "location": 2, "all": 3}[scope]
+		return &priceBookCandidate{entryID: id, scopeRank: rank, priority: priority}
+	}
+	product := priceBookKey{productID: 7}
+	variant := priceBookKey{productID: 7, variantID: variantID}
+
+	tests := []struct {
+		name       string
+		candidates map[priceBookKey]*priceBookCandidate
+		variantID  *int
+		wantEntry  int // 0 for no entry
+	}{
+		{"no entries", map[priceBookKey]*priceBookCandidate{}, &variantID, 0},
+		{"product entry for the product", map[priceBookKey]*priceBookCandidate{
+			product: entry(1, "all", 0), variant: entry(2, "customer_group", 0)}, nil, 1},
+		{"product entry covers its variants", map[priceBookKey]*priceBookCandidate{
+			product: entry(1, "location", 0)}, &variantID, 1},
+		{"variant entry alone", map[priceBookKey]*priceBookCandidate{
+			variant: entry(2, "all", 0)}, &variantID, 2},
+		{"customer group beats register", map[priceBookKey]*priceBookCandidate{
+			product: entry(1, "customer_group", 0), variant: entry(2, "register", 10)}, &variantID, 1},
+		{"register beats location", map[priceBookKey]*priceBookCandidate{
+			product: entry(1, "location", 10), variant: entry(2, "register", 0)}, &variantID, 2},
+		{"location beats all", map[priceBookKey]*priceBookCandidate{
+			product: entry(1, "location", 0), variant: entry(2, "all", 10)}, &variantID, 1},
+		{"same scope, higher priority wins", map[priceBookKey]*priceBookCandidate{
+			product: entry(1, "register", 5), variant: entry(2, "register", 1)}, &variantID, 1},
+		{"tie goes to the variant", map[priceBookKey]*priceBookCandidate{
+			product: entry(1, "location", 5), variant: entry(2, "location", 5)}, &variantID, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickPriceBookCandidate(tt.candidates, 7, tt.variantID)
+			gotEntry := 0
+			if got != nil {
+				gotEntry = got.entryID
+			}
+			if gotEntry != tt.wantEntry {
+				t.Errorf("pickPriceBookCandidate() = entry %d, want entry %d", gotEntry, tt.wantEntry)
+			}
+		})
+	}
+}
+
+func TestCheckLinePrice(t *testing.T) {
+	tests := []struct {
+		unitPrice, price float64
+		canOverride      bool
+		wantErr          bool
+	}{
+		{2.5, 2.5, false, false},
+		{0.1 + 0.2, 0.3, false, false},
+		{2.49, 2.5, false, true},
+		{2.49, 2.5, true, false},
+		{0, 2.5, false, true},
+	}
+	for _, tt := range tests {
+		item := POSTransactionItem{ProductID: 7, UnitPrice: tt.unitPrice}
+		err := checkLinePrice(&item, tt.price, tt.canOverride)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkLinePrice(%v, %v, %v) error = %v, want error %v", tt.unitPrice, tt.price, tt.canOverride,
+				err, tt.wantErr)
+		}
+	}
+}
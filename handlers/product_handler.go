@@ -3,10 +3,15 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
@@ -29,7 +34,7 @@ func NewProductHandler(db *sqlx.DB, logger *zap.Logger) *ProductHandler {
 
 // GetPOSProducts retrieves POS-specific product settings
 func (h *ProductHandler) GetPOSProducts(w http.ResponseWriter, r *http.Request) {
-	_, err := h.baseHandler.getTenantID(r)
+	tenantID, err := h.baseHandler.getTenantID(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
@@ -92,6 +97,25 @@ func (h *ProductHandler) GetPOSProducts(w http.ResponseWriter, r *http.Request)
 		products = append(products, product)
 	}
 
+	// A register sees the prices its price books give, not the catalog price
+	if id, err := strconv.Atoi(registerID); err == nil {
+		ctx, err := loadPriceContext(h.db, tenantID, &id, nil)
+		if err == nil {
+			var candidates map[priceBookKey]*priceBookCandidate
+			candidates, err = loadPriceBookCandidates(h.db, tenantID, ctx, 0, time.Now())
+			for i := range products {
+				c := pickPriceBookCandidate(candidates, products[i].ProductID, nil)
+				if c != nil && products[i].Product != nil {
+					products[i].Product.Price = c.price
+				}
+			}
+		}
+		if err != nil {
+			http.Error(w, "Failed to load prices", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"products": products,
@@ -99,6 +123,76 @@ func (h *ProductHandler) GetPOSProducts(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// SearchProducts finds products available at a register by barcode, SKU or partial or misspelled
+// name, optionally within a category, ranked by how well they match and then by how often they sell
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	search := productSearch{
+		Term: strings.TrimSpace(r.URL.Query().Get("q")),
+		Page: parsePagination(r, defaultSearchLimit, maxSearchLimit),
+	}
+	var customerID *int
+	for param, dest := range map[string]**int{"register_id": &search.RegisterID, "category_id": &search.CategoryID,
+		"customer_id": &customerID} {
+		if value := r.URL.Query().Get(param); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			*dest = &id
+		}
+	}
+	if search.Term == "" && search.CategoryID == nil {
+		http.Error(w, "A search term or category_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if search.RegisterID != nil {
+		var exists bool
+		err := h.db.QueryRow("SELECT EXISTS (SELECT 1 FROM pos_registers WHERE id = $1 AND tenant_id = $2)",
+			*search.RegisterID, tenantID).Scan(&exists)
+		if err != nil {
+			http.Error(w, "Failed to fetch register", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, "Register not found", http.StatusNotFound)
+			return
+		}
+	}
+	if search.Prices, err = loadPriceContext(h.db, tenantID, search.RegisterID, customerID); err != nil {
+		http.Error(w, "Failed to load prices", http.StatusInternalServerError)
+		return
+	}
+
+	salesDays := defaultSettings["product_search_sales_days"].(int)
+	if _, err := loadTenantSetting(h.db, tenantID, "product_search_sales_days", &salesDays); err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+	search.SalesSince = time.Now().AddDate(0, 0, -salesDays)
+
+	results, total, err := searchProducts(h.db, tenantID, search)
+	if err != nil {
+		h.logger.Error("Product search failed", zap.Error(err))
+		http.Error(w, "Failed to search products", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"products":   results,
+		"count":      len(results),
+		"pagination": newPaginationResponse(search.Page, total),
+	})
+}
+
 // CreatePOSProduct links a product to POS
 func (h *ProductHandler) CreatePOSProduct(w http.ResponseWriter, r *http.Request) {
 	_, err := h.baseHandler.getTenantID(r)
@@ -157,6 +251,38 @@ func (h *ProductHandler) CreatePOSProduct(w http.ResponseWriter, r *http.Request
 // QUICK SALE MANAGEMENT
 // =================================================================
 
+// quickSaleScopeFromQuery reads the layout a request targets from location_id and register_id,
+// writing the error response and returning false when they are invalid
+func (h *ProductHandler) quickSaleScopeFromQuery(w http.ResponseWriter, r *http.Request, tenantID string) (quickSaleScope, bool) {
+	var locationID, registerID *int
+	for param, dest := range map[string]**int{"location_id": &locationID, "register_id": &registerID} {
+		if value := r.URL.Query().Get(param); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return quickSaleScope{}, false
+			}
+			*dest = &id
+		}
+	}
+	return h.quickSaleScope(w, tenantID, locationID, registerID)
+}
+
+// quickSaleScope resolves a layout scope, writing the error response and returning false for an
+// unknown register
+func (h *ProductHandler) quickSaleScope(w http.ResponseWriter, tenantID string, locationID, registerID *int) (quickSaleScope, bool) {
+	scope, err := resolveQuickSaleScope(h.db, tenantID, locationID, registerID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Register not found", http.StatusNotFound)
+		return scope, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch register", http.StatusInternalServerError)
+		return scope, false
+	}
+	return scope, true
+}
+
 // GetQuickSaleCategories retrieves all quick sale categories
 func (h *ProductHandler) GetQuickSaleCategories(w http.ResponseWriter, r *http.Request) {
 	tenantID, err := h.baseHandler.getTenantID(r)
@@ -165,7 +291,11 @@ func (h *ProductHandler) GetQuickSaleCategories(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	query := `SELECT * FROM quick_sale_categories WHERE tenant_id = $1 AND is_active = true ORDER BY display_order, category_name`
+	query := `
+		SELECT id, tenant_id, location_id, register_id, category_code, category_name, color_code, icon,
+		       display_order, is_active, created_at, updated_at
+		FROM quick_sale_categories WHERE tenant_id = $1 AND is_active = true ORDER BY display_order, category_name
+	`
 	rows, err := h.db.Query(query, tenantID)
 	if err != nil {
 		http.Error(w, "Failed to fetch categories", http.StatusInternalServerError)
@@ -176,9 +306,9 @@ func (h *ProductHandler) GetQuickSaleCategories(w http.ResponseWriter, r *http.R
 	var categories []QuickSaleCategory
 	for rows.Next() {
 		var category QuickSaleCategory
-		err := rows.Scan(&category.ID, &category.TenantID, &category.LocationID, &category.CategoryCode,
-			&category.CategoryName, &category.ColorCode, &category.Icon, &category.DisplayOrder,
-			&category.IsActive, &category.CreatedAt, &category.UpdatedAt)
+		err := rows.Scan(&category.ID, &category.TenantID, &category.LocationID, &category.RegisterID,
+			&category.CategoryCode, &category.CategoryName, &category.ColorCode, &category.Icon,
+			&category.DisplayOrder, &category.IsActive, &category.CreatedAt, &category.UpdatedAt)
 		if err != nil {
 			continue
 		}
@@ -192,7 +322,8 @@ func (h *ProductHandler) GetQuickSaleCategories(w http.ResponseWriter, r *http.R
 	})
 }
 
-// CreateQuickSaleCategory creates a new quick sale category
+// CreateQuickSaleCategory creates a new quick sale category in the default, a location's or a
+// register's layout
 func (h *ProductHandler) CreateQuickSaleCategory(w http.ResponseWriter, r *http.Request) {
 	tenantID, err := h.baseHandler.getTenantID(r)
 	if err != nil {
@@ -200,12 +331,11 @@ func (h *ProductHandler) CreateQuickSaleCategory(w http.ResponseWriter, r *http.
 		return
 	}
 
-	_ = tenantID // Suppress unused variable warning
-
 	var req struct {
 		CategoryCode string  `json:"category_code" validate:"required"`
 		CategoryName string  `json:"category_name" validate:"required"`
 		LocationID   *int    `json:"location_id"`
+		RegisterID   *int    `json:"register_id"`
 		ColorCode    *string `json:"color_code"`
 		Icon         *string `json:"icon"`
 		DisplayOrder int     `json:"display_order"`
@@ -215,20 +345,29 @@ func (h *ProductHandler) CreateQuickSaleCategory(w http.ResponseWriter, r *http.
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.CategoryCode == "" || req.CategoryName == "" {
+		http.Error(w, "Category code and name are required", http.StatusBadRequest)
+		return
+	}
+
+	scope, ok := h.quickSaleScope(w, tenantID, req.LocationID, req.RegisterID)
+	if !ok {
+		return
+	}
 
 	query := `
-		INSERT INTO quick_sale_categories (tenant_id, location_id, category_code, category_name, color_code, icon, display_order)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO quick_sale_categories (tenant_id, location_id, register_id, category_code, category_name, color_code, icon, display_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at
 	`
 
 	var id int
 	var createdAt time.Time
 
-	err = h.db.QueryRow(query, tenantID, req.LocationID, req.CategoryCode, req.CategoryName,
+	err = h.db.QueryRow(query, tenantID, scope.LocationID, scope.RegisterID, req.CategoryCode, req.CategoryName,
 		req.ColorCode, req.Icon, req.DisplayOrder).Scan(&id, &createdAt)
 	if err != nil {
-		http.Error(w, "Failed to create category", http.StatusInternalServerError)
+		http.Error(w, "Failed to create category; category codes must be unique within a layout", http.StatusConflict)
 		return
 	}
 
@@ -241,6 +380,93 @@ func (h *ProductHandler) CreateQuickSaleCategory(w http.ResponseWriter, r *http.
 	})
 }
 
+// UpdateQuickSaleCategory replaces a category's code, name, colour, icon, position and active flag
+func (h *ProductHandler) UpdateQuickSaleCategory(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	categoryID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		CategoryCode string  `json:"category_code" validate:"required"`
+		CategoryName string  `json:"category_name" validate:"required"`
+		ColorCode    *string `json:"color_code"`
+		Icon         *string `json:"icon"`
+		DisplayOrder int     `json:"display_order"`
+		IsActive     *bool   `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.CategoryCode == "" || req.CategoryName == "" {
+		http.Error(w, "Category code and name are required", http.StatusBadRequest)
+		return
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+
+	var category QuickSaleCategory
+	err = h.db.QueryRow(`
+		UPDATE quick_sale_categories
+		SET category_code = $3, category_name = $4, color_code = $5, icon = $6, display_order = $7,
+		    is_active = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING id, tenant_id, location_id, register_id, category_code, category_name, color_code, icon,
+		          display_order, is_active, created_at, updated_at
+	`, categoryID, tenantID, req.CategoryCode, req.CategoryName, req.ColorCode, req.Icon, req.DisplayOrder,
+		*req.IsActive).Scan(&category.ID, &category.TenantID, &category.LocationID, &category.RegisterID,
+		&category.CategoryCode, &category.CategoryName, &category.ColorCode, &category.Icon,
+		&category.DisplayOrder, &category.IsActive, &category.CreatedAt, &category.UpdatedAt)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update category; category codes must be unique within a layout", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// DeleteQuickSaleCategory removes a category and the buttons in it
+func (h *ProductHandler) DeleteQuickSaleCategory(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	categoryID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM quick_sale_categories WHERE id = $1 AND tenant_id = $2", categoryID, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to delete category", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // GetQuickSaleItems retrieves quick sale items
 func (h *ProductHandler) GetQuickSaleItems(w http.ResponseWriter, r *http.Request) {
 	tenantID, err := h.baseHandler.getTenantID(r)
@@ -253,7 +479,9 @@ func (h *ProductHandler) GetQuickSaleItems(w http.ResponseWriter, r *http.Reques
 	locationID := r.URL.Query().Get("location_id")
 
 	query := `
-		SELECT qsi.*, p.name as product_name, p.sku, p.selling_price
+		SELECT qsi.id, qsi.tenant_id, qsi.location_id, qsi.register_id, qsi.category_id, qsi.product_id,
+		       qsi.button_text, qsi.button_color, qsi.display_order, qsi.is_active, qsi.created_at, qsi.updated_at,
+		       p.name as product_name, p.sku, p.selling_price
 		FROM quick_sale_items qsi
 		JOIN products p ON qsi.product_id = p.id
 		WHERE qsi.tenant_id = $1 AND qsi.is_active = true
@@ -288,7 +516,7 @@ func (h *ProductHandler) GetQuickSaleItems(w http.ResponseWriter, r *http.Reques
 		var productName, sku sql.NullString
 		var sellingPrice sql.NullFloat64
 
-		err := rows.Scan(&item.ID, &item.TenantID, &item.LocationID, &item.CategoryID,
+		err := rows.Scan(&item.ID, &item.TenantID, &item.LocationID, &item.RegisterID, &item.CategoryID,
 			&item.ProductID, &item.ButtonText, &item.ButtonColor, &item.DisplayOrder,
 			&item.IsActive, &item.CreatedAt, &item.UpdatedAt, &productName, &sku, &sellingPrice)
 		if err != nil {
@@ -316,7 +544,15 @@ func (h *ProductHandler) GetQuickSaleItems(w http.ResponseWriter, r *http.Reques
 	})
 }
 
-// CreateQuickSaleItem creates a new quick sale item
+// quickSaleCategoryInScope reports whether a category belongs to the layout of scope
+func quickSaleCategoryInScope(q rowQuerier, tenantID string, scope quickSaleScope, categoryID int) (bool, error) {
+	var exists bool
+	err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM quick_sale_categories WHERE "+quickSaleLayoutCondition+" AND id = $4)",
+		tenantID, scope.LocationID, scope.RegisterID, categoryID).Scan(&exists)
+	return exists, err
+}
+
+// CreateQuickSaleItem creates a new quick sale item, or updates the product's button in the layout
 func (h *ProductHandler) CreateQuickSaleItem(w http.ResponseWriter, r *http.Request) {
 	tenantID, err := h.baseHandler.getTenantID(r)
 	if err != nil {
@@ -328,6 +564,7 @@ func (h *ProductHandler) CreateQuickSaleItem(w http.ResponseWriter, r *http.Requ
 		ProductID    int     `json:"product_id" validate:"required"`
 		CategoryID   *int    `json:"category_id"`
 		LocationID   *int    `json:"location_id"`
+		RegisterID   *int    `json:"register_id"`
 		ButtonText   string  `json:"button_text" validate:"required"`
 		ButtonColor  *string `json:"button_color"`
 		DisplayOrder int     `json:"display_order"`
@@ -338,10 +575,27 @@ func (h *ProductHandler) CreateQuickSaleItem(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	scope, ok := h.quickSaleScope(w, tenantID, req.LocationID, req.RegisterID)
+	if !ok {
+		return
+	}
+	if req.CategoryID != nil {
+		inScope, err := quickSaleCategoryInScope(h.db, tenantID, scope, *req.CategoryID)
+		if err != nil {
+			http.Error(w, "Failed to fetch category", http.StatusInternalServerError)
+			return
+		}
+		if !inScope {
+			http.Error(w, "Category is not in this layout", http.StatusBadRequest)
+			return
+		}
+	}
+
 	query := `
-		INSERT INTO quick_sale_items (tenant_id, location_id, category_id, product_id, button_text, button_color, display_order)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		ON CONFLICT (tenant_id, product_id, location_id) DO UPDATE SET
+		INSERT INTO quick_sale_items (tenant_id, location_id, register_id, category_id, product_id, button_text, button_color, display_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tenant_id, (COALESCE(location_id, 0)), (COALESCE(register_id, 0)), product_id) DO UPDATE SET
+			category_id = EXCLUDED.category_id,
 			button_text = EXCLUDED.button_text,
 			button_color = EXCLUDED.button_color,
 			display_order = EXCLUDED.display_order,
@@ -352,7 +606,7 @@ func (h *ProductHandler) CreateQuickSaleItem(w http.ResponseWriter, r *http.Requ
 	var id int
 	var createdAt, updatedAt time.Time
 
-	err = h.db.QueryRow(query, tenantID, req.LocationID, req.CategoryID, req.ProductID,
+	err = h.db.QueryRow(query, tenantID, scope.LocationID, scope.RegisterID, req.CategoryID, req.ProductID,
 		req.ButtonText, req.ButtonColor, req.DisplayOrder).Scan(&id, &createdAt, &updatedAt)
 	if err != nil {
 		http.Error(w, "Failed to create quick sale item", http.StatusInternalServerError)
@@ -368,3 +622,918 @@ func (h *ProductHandler) CreateQuickSaleItem(w http.ResponseWriter, r *http.Requ
 		"message":    "Quick sale item created successfully",
 	})
 }
+
+// UpdateQuickSaleItem replaces a button's category, label, colour, position and active flag. The
+// category must be in the button's layout.
+func (h *ProductHandler) UpdateQuickSaleItem(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid quick sale item ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		CategoryID   *int    `json:"category_id"`
+		ButtonText   string  `json:"button_text" validate:"required"`
+		ButtonColor  *string `json:"button_color"`
+		DisplayOrder int     `json:"display_order"`
+		IsActive     *bool   `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ButtonText == "" {
+		http.Error(w, "Button text is required", http.StatusBadRequest)
+		return
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+
+	var scope quickSaleScope
+	err = h.db.QueryRow("SELECT location_id, register_id FROM quick_sale_items WHERE id = $1 AND tenant_id = $2",
+		itemID, tenantID).Scan(&scope.LocationID, &scope.RegisterID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Quick sale item not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch quick sale item", http.StatusInternalServerError)
+		return
+	}
+	if req.CategoryID != nil {
+		inScope, err := quickSaleCategoryInScope(h.db, tenantID, scope, *req.CategoryID)
+		if err != nil {
+			http.Error(w, "Failed to fetch category", http.StatusInternalServerError)
+			return
+		}
+		if !inScope {
+			http.Error(w, "Category is not in this layout", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var item QuickSaleItem
+	err = h.db.QueryRow(`
+		UPDATE quick_sale_items
+		SET category_id = $3, button_text = $4, button_color = $5, display_order = $6, is_active = $7
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING id, tenant_id, location_id, register_id, category_id, product_id, button_text, button_color,
+		          display_order, is_active, created_at, updated_at
+	`, itemID, tenantID, req.CategoryID, req.ButtonText, req.ButtonColor, req.DisplayOrder, *req.IsActive).Scan(
+		&item.ID, &item.TenantID, &item.LocationID, &item.RegisterID, &item.CategoryID, &item.ProductID,
+		&item.ButtonText, &item.ButtonColor, &item.DisplayOrder, &item.IsActive, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		http.Error(w, "Failed to update quick sale item", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// DeleteQuickSaleItem removes a button from its layout
+func (h *ProductHandler) DeleteQuickSaleItem(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	itemID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid quick sale item ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM quick_sale_items WHERE id = $1 AND tenant_id = $2", itemID, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to delete quick sale item", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Quick sale item not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetQuickSaleLayout returns the layout a register or location shows, falling back from the
+// register's own layout to its location's and then the tenant default. With exact=true it returns
+// the requested layout itself, for the designer.
+func (h *ProductHandler) GetQuickSaleLayout(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	scope, ok := h.quickSaleScopeFromQuery(w, r, tenantID)
+	if !ok {
+		return
+	}
+
+	var layout *QuickSaleLayout
+	if r.URL.Query().Get("exact") == "true" {
+		layout, err = loadQuickSaleLayout(h.db, tenantID, scope)
+	} else {
+		layout, err = loadEffectiveQuickSaleLayout(h.db, tenantID, scope)
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch quick sale layout", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(layout)
+}
+
+// ReorderQuickSaleLayout applies a drag-and-drop arrangement to a layout: the category IDs in
+// their new order, and for each category (or none) the button IDs it now holds, in order
+func (h *ProductHandler) ReorderQuickSaleLayout(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		LocationID *int  `json:"location_id"`
+		RegisterID *int  `json:"register_id"`
+		Categories []int `json:"categories"`
+		Items      []struct {
+			CategoryID *int  `json:"category_id"` // null for buttons outside any category
+			ItemIDs    []int `json:"item_ids"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	scope, ok := h.quickSaleScope(w, tenantID, req.LocationID, req.RegisterID)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	layoutArgs := []interface{}{tenantID, scope.LocationID, scope.RegisterID}
+	for i, categoryID := range req.Categories {
+		result, err := tx.Exec("UPDATE quick_sale_categories SET display_order = $5, updated_at = CURRENT_TIMESTAMP WHERE "+
+			quickSaleLayoutCondition+" AND id = $4", append(layoutArgs, categoryID, i)...)
+		if err != nil {
+			http.Error(w, "Failed to reorder categories", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			http.Error(w, fmt.Sprintf("Category %d is not in this layout", categoryID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, group := range req.Items {
+		if group.CategoryID != nil {
+			inScope, err := quickSaleCategoryInScope(tx, tenantID, scope, *group.CategoryID)
+			if err != nil {
+				http.Error(w, "Failed to fetch category", http.StatusInternalServerError)
+				return
+			}
+			if !inScope {
+				http.Error(w, fmt.Sprintf("Category %d is not in this layout", *group.CategoryID), http.StatusBadRequest)
+				return
+			}
+		}
+		for i, itemID := range group.ItemIDs {
+			result, err := tx.Exec("UPDATE quick_sale_items SET category_id = $5, display_order = $6 WHERE "+
+				quickSaleLayoutCondition+" AND id = $4", append(layoutArgs, itemID, group.CategoryID, i)...)
+			if err != nil {
+				http.Error(w, "Failed to reorder quick sale items", http.StatusInternalServerError)
+				return
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				http.Error(w, fmt.Sprintf("Quick sale item %d is not in this layout", itemID), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	layout, err := loadQuickSaleLayout(tx, tenantID, scope)
+	if err != nil {
+		http.Error(w, "Failed to fetch quick sale layout", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(layout)
+}
+
+// replaceQuickSaleLayout saves layout as the layout of scope in one transaction. Unless replace is
+// set, a scope that already has a layout is left alone and the response is 409.
+func (h *ProductHandler) replaceQuickSaleLayout(w http.ResponseWriter, tenantID string, scope quickSaleScope,
+	layout *QuickSaleLayout, replace bool) {
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	existing, err := loadQuickSaleLayout(tx, tenantID, scope)
+	if err != nil {
+		http.Error(w, "Failed to fetch quick sale layout", http.StatusInternalServerError)
+		return
+	}
+	if !existing.empty() && !replace {
+		http.Error(w, "The target already has a quick sale layout; pass replace=true to overwrite it", http.StatusConflict)
+		return
+	}
+
+	if err := saveQuickSaleLayout(tx, tenantID, scope, layout); err != nil {
+		http.Error(w, "Failed to save quick sale layout", http.StatusInternalServerError)
+		return
+	}
+	saved, err := loadQuickSaleLayout(tx, tenantID, scope)
+	if err != nil {
+		http.Error(w, "Failed to fetch quick sale layout", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Quick sale layout saved", zap.String("tenant_id", tenantID), zap.String("scope", scope.name()),
+		zap.Int("categories", len(saved.Categories)))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// CloneQuickSaleLayout copies a layout, such as one location's, to another location or register
+func (h *ProductHandler) CloneQuickSaleLayout(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		FromLocationID *int `json:"from_location_id"`
+		FromRegisterID *int `json:"from_register_id"`
+		ToLocationID   *int `json:"to_location_id"`
+		ToRegisterID   *int `json:"to_register_id"`
+		Replace        bool `json:"replace"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	from, ok := h.quickSaleScope(w, tenantID, req.FromLocationID, req.FromRegisterID)
+	if !ok {
+		return
+	}
+	to, ok := h.quickSaleScope(w, tenantID, req.ToLocationID, req.ToRegisterID)
+	if !ok {
+		return
+	}
+	if from.equal(to) {
+		http.Error(w, "Source and target layouts are the same", http.StatusBadRequest)
+		return
+	}
+
+	layout, err := loadQuickSaleLayout(h.db, tenantID, from)
+	if err != nil {
+		http.Error(w, "Failed to fetch quick sale layout", http.StatusInternalServerError)
+		return
+	}
+	if layout.empty() {
+		http.Error(w, "The source has no quick sale layout", http.StatusNotFound)
+		return
+	}
+
+	h.replaceQuickSaleLayout(w, tenantID, to, layout, req.Replace)
+}
+
+// ExportQuickSaleLayout downloads a layout as JSON, with product SKUs so it can be imported
+// elsewhere
+func (h *ProductHandler) ExportQuickSaleLayout(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	scope, ok := h.quickSaleScopeFromQuery(w, r, tenantID)
+	if !ok {
+		return
+	}
+
+	layout, err := loadQuickSaleLayout(h.db, tenantID, scope)
+	if err != nil {
+		http.Error(w, "Failed to fetch quick sale layout", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	layout.ExportedAt = &now
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="quick-sale-layout-%s.json"`, scope.name()))
+	json.NewEncoder(w).Encode(layout)
+}
+
+// ImportQuickSaleLayout replaces the layout given by location_id and register_id with an exported
+// layout. Buttons find their product by SKU when the export has one, else by product ID.
+func (h *ProductHandler) ImportQuickSaleLayout(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	scope, ok := h.quickSaleScopeFromQuery(w, r, tenantID)
+	if !ok {
+		return
+	}
+
+	var layout QuickSaleLayout
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	missing, err := resolveLayoutProducts(h.db, &layout)
+	if err != nil {
+		http.Error(w, "Failed to fetch products", http.StatusInternalServerError)
+		return
+	}
+	if len(missing) > 0 {
+		http.Error(w, "Products not found: "+strings.Join(missing, ", "), http.StatusBadRequest)
+		return
+	}
+	if err := layout.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.replaceQuickSaleLayout(w, tenantID, scope, &layout, r.URL.Query().Get("replace") == "true")
+}
+
+// =================================================================
+// VARIANTS
+// =================================================================
+
+// productVariantRequest is the body of a variant create or update
+type productVariantRequest struct {
+	Name       string                 `json:"name" validate:"required"`
+	SKU        *string                `json:"sku"`
+	Barcode    *string                `json:"barcode"`
+	Attributes map[string]interface{} `json:"attributes"` // e.g. {"size": "M", "color": "Red"}
+	Price      *float64               `json:"price"`      // omit to sell at the product's price
+	IsActive   *bool                  `json:"is_active"`
+}
+
+func (req *productVariantRequest) validate() error {
+	if req.Name == "" {
+		return errors.New("Variant name is required")
+	}
+	if req.SKU != nil && *req.SKU == "" {
+		req.SKU = nil
+	}
+	if req.Barcode != nil && *req.Barcode == "" {
+		req.Barcode = nil
+	}
+	if req.Barcode != nil {
+		code, _, err := normalizeProductBarcode(*req.Barcode, "")
+		if err != nil {
+			return fmt.Errorf("Invalid barcode: %v", err)
+		}
+		req.Barcode = &code
+	}
+	if req.Price != nil && *req.Price < 0 {
+		return errors.New("Price cannot be negative")
+	}
+	if req.Attributes == nil {
+		req.Attributes = map[string]interface{}{}
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+	return nil
+}
+
+// loadProductVariant reads one of the tenant's variants
+func loadProductVariant(q rowQuerier, tenantID string, variantID int) (*ProductVariant, error) {
+	var v ProductVariant
+	var attributesJSON sql.NullString
+	err := q.QueryRow(`
+		SELECT id, tenant_id, product_id, name, sku, barcode, attributes, price, is_active, created_at, updated_at
+		FROM pos_product_variants
+		WHERE id = $1 AND tenant_id = $2
+	`, variantID, tenantID).Scan(&v.ID, &v.TenantID, &v.ProductID, &v.Name, &v.SKU, &v.Barcode, &attributesJSON,
+		&v.Price, &v.IsActive, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if attributesJSON.Valid {
+		json.Unmarshal([]byte(attributesJSON.String), &v.Attributes)
+	}
+	return &v, nil
+}
+
+// GetProductVariants lists a product's variants
+func (h *ProductHandler) GetProductVariants(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT id, tenant_id, product_id, name, sku, barcode, attributes, price, is_active, created_at, updated_at
+		FROM pos_product_variants
+		WHERE tenant_id = $1 AND product_id = $2
+	`
+	if r.URL.Query().Get("active") == "true" {
+		query += " AND is_active = true"
+	}
+	query += " ORDER BY name"
+
+	rows, err := h.db.Query(query, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to fetch variants", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var variants []ProductVariant
+	for rows.Next() {
+		var v ProductVariant
+		var attributesJSON sql.NullString
+		err := rows.Scan(&v.ID, &v.TenantID, &v.ProductID, &v.Name, &v.SKU, &v.Barcode, &attributesJSON,
+			&v.Price, &v.IsActive, &v.CreatedAt, &v.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		if attributesJSON.Valid {
+			json.Unmarshal([]byte(attributesJSON.String), &v.Attributes)
+		}
+		variants = append(variants, v)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"variants": variants,
+		"count":    len(variants),
+	})
+}
+
+// CreateProductVariant adds a variant to a product
+func (h *ProductHandler) CreateProductVariant(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req productVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	attributes, _ := json.Marshal(req.Attributes)
+
+	var id int
+	var createdAt time.Time
+	err = h.db.QueryRow(`
+		INSERT INTO pos_product_variants (tenant_id, product_id, name, sku, barcode, attributes, price, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, tenantID, productID, req.Name, req.SKU, req.Barcode, attributes, req.Price, *req.IsActive).Scan(&id, &createdAt)
+	if err != nil {
+		http.Error(w, "Failed to create variant; names must be unique per product and SKUs and barcodes per tenant", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"created_at": createdAt,
+		"message":    "Variant created successfully",
+	})
+}
+
+// UpdateProductVariant replaces a variant's details; set is_active to false to stop selling it.
+// Variants are never deleted, since sold lines keep referring to them.
+func (h *ProductHandler) UpdateProductVariant(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	variantID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid variant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req productVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	attributes, _ := json.Marshal(req.Attributes)
+
+	var updatedAt time.Time
+	err = h.db.QueryRow(`
+		UPDATE pos_product_variants
+		SET name = $1, sku = $2, barcode = $3, attributes = $4, price = $5, is_active = $6
+		WHERE id = $7 AND tenant_id = $8
+		RETURNING updated_at
+	`, req.Name, req.SKU, req.Barcode, attributes, req.Price, *req.IsActive, variantID, tenantID).Scan(&updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Variant not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update variant; names must be unique per product and SKUs and barcodes per tenant", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         variantID,
+		"updated_at": updatedAt,
+		"message":    "Variant updated successfully",
+	})
+}
+
+// =================================================================
+// BARCODES
+// =================================================================
+
+// resolveBarcodeProduct finds the product, variant and pack quantity behind a barcode: the
+// tenant's alternate barcodes first, then variant barcodes, then the product's own barcode
+func resolveBarcodeProduct(q rowQuerier, tenantID string, codes []string) (int, *int, int, error) {
+	for _, code := range codes {
+		var productID, quantity int
+		err := q.QueryRow(`
+			SELECT product_id, quantity FROM pos_product_barcodes
+			WHERE tenant_id = $1 AND barcode = $2
+		`, tenantID, code).Scan(&productID, &quantity)
+		if err == nil {
+			return productID, nil, quantity, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, nil, 0, err
+		}
+	}
+	for _, code := range codes {
+		var productID, variantID int
+		err := q.QueryRow(`
+			SELECT product_id, id FROM pos_product_variants
+			WHERE tenant_id = $1 AND barcode = $2 AND is_active = true
+		`, tenantID, code).Scan(&productID, &variantID)
+		if err == nil {
+			return productID, &variantID, 1, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, nil, 0, err
+		}
+	}
+	for _, code := range codes {
+		var productID int
+		err := q.QueryRow("SELECT id FROM products WHERE barcode = $1 ORDER BY id LIMIT 1", code).Scan(&productID)
+		if err == nil {
+			return productID, nil, 1, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, nil, 0, err
+		}
+	}
+	return 0, nil, 0, sql.ErrNoRows
+}
+
+// resolveLineBarcode checks that the barcode sent on a sale line belongs to the line's product
+// and returns the price or weight embedded in it, if any
+func resolveLineBarcode(q rowQuerier, tenantID string, settings embeddedBarcodeSettings,
+	item *POSTransactionItem) (*embeddedBarcode, error) {
+	scanned, err := parseBarcode(*item.Barcode, "")
+	if err != nil {
+		return nil, &itemOptionError{fmt.Sprintf("Invalid barcode on product %d: %v", item.ProductID, err)}
+	}
+	codes := scanned.lookupCodes()
+	embedded := decodeEmbeddedBarcode(scanned, settings)
+	if embedded != nil {
+		codes = embedded.lookupCodes(scanned)
+	}
+	productID, _, _, err := resolveBarcodeProduct(q, tenantID, codes)
+	if err == sql.ErrNoRows || (err == nil && productID != item.ProductID) {
+		return nil, &itemOptionError{fmt.Sprintf("Barcode %s is not a barcode of product %d", scanned.Code, item.ProductID)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return embedded, nil
+}
+
+// ScanBarcode resolves a scanned UPC-A, EAN-13, EAN-8 or Code 128 barcode to a product. In-store
+// EAN-13 codes with a price or weight prefix resolve to their product with the embedded price or weight.
+func (h *ProductHandler) ScanBarcode(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	scanned, err := parseBarcode(r.URL.Query().Get("code"), r.URL.Query().Get("symbology"))
+	if err != nil {
+		http.Error(w, "Invalid barcode: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := loadEmbeddedBarcodeSettings(h.db, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to load barcode settings", http.StatusInternalServerError)
+		return
+	}
+
+	codes := scanned.lookupCodes()
+	embedded := decodeEmbeddedBarcode(scanned, settings)
+	if embedded != nil {
+		codes = embedded.lookupCodes(scanned)
+	}
+
+	productID, variantID, quantity, err := resolveBarcodeProduct(h.db, tenantID, codes)
+	if err == sql.ErrNoRows {
+		http.Error(w, "No product found for barcode", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to resolve barcode", http.StatusInternalServerError)
+		return
+	}
+
+	product := &Product{ID: productID}
+	var sku sql.NullString
+	var sellingPrice sql.NullFloat64
+	err = h.db.QueryRow("SELECT name, sku, selling_price, barcode FROM products WHERE id = $1", productID).
+		Scan(&product.Name, &sku, &sellingPrice, &product.Barcode)
+	if err != nil {
+		http.Error(w, "Failed to fetch product", http.StatusInternalServerError)
+		return
+	}
+	product.SKU = sku.String
+	product.Price = sellingPrice.Float64
+
+	var variant *ProductVariant
+	if variantID != nil {
+		variant, err = loadProductVariant(h.db, tenantID, *variantID)
+		if err != nil {
+			http.Error(w, "Failed to fetch variant", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The register's and customer's price books set the price; without one a variant with its own
+	// price sells at that price
+	var registerID, customerID *int
+	if id, err := strconv.Atoi(r.URL.Query().Get("register_id")); err == nil {
+		registerID = &id
+	}
+	if id, err := strconv.Atoi(r.URL.Query().Get("customer_id")); err == nil {
+		customerID = &id
+	}
+	ctx, err := loadPriceContext(h.db, tenantID, registerID, customerID)
+	if err != nil {
+		http.Error(w, "Failed to load prices", http.StatusInternalServerError)
+		return
+	}
+	resolved, err := resolvePrice(h.db, tenantID, ctx, productID, variantID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to load prices", http.StatusInternalServerError)
+		return
+	}
+	product.Price = resolved.Price
+
+	minimumAge, err := productMinimumAge(h.db, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to fetch product age restriction", http.StatusInternalServerError)
+		return
+	}
+
+	result := BarcodeScanResult{
+		Barcode:     scanned.Code,
+		Symbology:   scanned.Symbology,
+		Product:     product,
+		Variant:     variant,
+		Quantity:    float64(quantity),
+		UnitPrice:   product.Price,
+		Price:       math.Round(product.Price*float64(quantity)*100) / 100,
+		PriceBookID: resolved.PriceBookID,
+		MinimumAge:  minimumAge,
+	}
+	if embedded != nil {
+		result.Embedded = &embedded.Kind
+		switch embedded.Kind {
+		case "price":
+			result.Quantity = 1
+			result.PriceBookID = nil
+			result.UnitPrice = embedded.Value
+			result.Price = embedded.Value
+		case "weight":
+			// The product's price is per unit of weight, so the weight is the line's quantity
+			weight := embedded.Value
+			result.Quantity = weight
+			result.Weight = &weight
+			result.Price = math.Round(product.Price*weight*100) / 100
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetProductBarcodes lists a product's alternate barcodes
+func (h *ProductHandler) GetProductBarcodes(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, tenant_id, product_id, barcode, symbology, quantity, description, created_by, created_at
+		FROM pos_product_barcodes
+		WHERE tenant_id = $1 AND product_id = $2
+		ORDER BY id
+	`, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to fetch barcodes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var barcodes []ProductBarcode
+	for rows.Next() {
+		var barcode ProductBarcode
+		err := rows.Scan(&barcode.ID, &barcode.TenantID, &barcode.ProductID, &barcode.Barcode, &barcode.Symbology,
+			&barcode.Quantity, &barcode.Description, &barcode.CreatedBy, &barcode.CreatedAt)
+		if err != nil {
+			continue
+		}
+		barcodes = append(barcodes, barcode)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"barcodes": barcodes,
+		"count":    len(barcodes),
+	})
+}
+
+// CreateProductBarcode adds an alternate barcode to a product
+func (h *ProductHandler) CreateProductBarcode(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Barcode     string  `json:"barcode" validate:"required"`
+		Symbology   string  `json:"symbology"` // upc_a, ean_13, ean_8 or code128; inferred when empty
+		Quantity    int     `json:"quantity"`  // units one scan adds; defaults to 1
+		Description *string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 {
+		http.Error(w, "Quantity must be positive", http.StatusBadRequest)
+		return
+	}
+
+	code, symbology, err := normalizeProductBarcode(req.Barcode, req.Symbology)
+	if err != nil {
+		http.Error(w, "Invalid barcode: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	var id int
+	var createdAt time.Time
+	err = h.db.QueryRow(`
+		INSERT INTO pos_product_barcodes (tenant_id, product_id, barcode, symbology, quantity, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, tenantID, productID, code, symbology, req.Quantity, req.Description, userID).Scan(&id, &createdAt)
+	if isUniqueViolation(err, "") {
+		http.Error(w, "A barcode can only belong to one product", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create barcode", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"barcode":    code,
+		"symbology":  symbology,
+		"created_at": createdAt,
+		"message":    "Barcode created successfully",
+	})
+}
+
+// DeleteProductBarcode removes an alternate barcode from its product
+func (h *ProductHandler) DeleteProductBarcode(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	barcodeID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid barcode ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM pos_product_barcodes WHERE id = $1 AND tenant_id = $2", barcodeID, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to delete barcode", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Barcode not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Barcode deleted successfully",
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchMatchTypes names how a product matched a search, best first; the query ranks by index
+var searchMatchTypes = []string{"barcode", "sku", "sku_prefix", "name_prefix", "name", "fuzzy", "category"}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// productSearch is a terminal product search. An empty Term lists the category by sales.
+type productSearch struct {
+	Term       string
+	RegisterID *int
+	CategoryID *int
+	Prices     priceContext // the register's and customer's price books
+	SalesSince time.Time
+	Page       PaginationRequest
+}
+
+// parsePagination reads page and limit query parameters, defaulting to the first page
+func parsePagination(r *http.Request, defaultLimit, maxLimit int) PaginationRequest {
+	p := PaginationRequest{Page: 1, Limit: defaultLimit}
+	if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && page > 0 {
+		p.Page = page
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		p.Limit = limit
+	}
+	if p.Limit > maxLimit {
+		p.Limit = maxLimit
+	}
+	return p
+}
+
+// newPaginationResponse describes the page p of totalCount results
+func newPaginationResponse(p PaginationRequest, totalCount int) PaginationResponse {
+	totalPages := (totalCount + p.Limit - 1) / p.Limit
+	return PaginationResponse{
+		CurrentPage: p.Page,
+		TotalPages:  totalPages,
+		TotalCount:  totalCount,
+		Limit:       p.Limit,
+		HasNext:     p.Page < totalPages,
+		HasPrev:     p.Page > 1,
+	}
+}
+
+// escapeLike escapes the LIKE wildcards in a search term
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
+
+// searchProducts finds the products available at the register (or anywhere, without one) by
+// barcode, SKU, name prefix, name substring or trigram similarity to the name. Results rank by how
+// they matched, then by how many sales included them since SalesSince. Prices resolve as on a
+// sale: the applicable price book entry, else the variant's own price, else the selling price. It
+// returns one page and the total number of matches.
+func searchProducts(q reportQuerier, tenantID string, s productSearch) ([]ProductSearchResult, int, error) {
+	args := []interface{}{tenantID, s.SalesSince}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// A register's own pos_products row overrides the row for every register
+	available := "EXISTS (SELECT 1 FROM pos_products pp WHERE pp.product_id = p.id AND COALESCE(pp.is_available, true))"
+	if s.RegisterID != nil {
+		registerID := arg(*s.RegisterID)
+		available = fmt.Sprintf(`COALESCE(
+			(SELECT COALESCE(pp.is_available, true) FROM pos_products pp WHERE pp.product_id = p.id AND pp.register_id = %[1]s),
+			(SELECT COALESCE(pp.is_available, true) FROM pos_products pp WHERE pp.product_id = p.id AND pp.register_id IS NULL),
+			false)`, registerID)
+	}
+	where := []string{available}
+	if s.CategoryID != nil {
+		where = append(where, "p.category_id = "+arg(*s.CategoryID))
+	}
+
+	rank := "6"
+	similarity := "0"
+	variantBarcodes := "NULL"
+	if s.Term != "" {
+		codes := []string{s.Term}
+		if scanned, err := parseBarcode(s.Term, ""); err == nil {
+			codes = scanned.lookupCodes()
+		}
+		codeList := arg(codes[0]) + ", " + arg(codes[len(codes)-1])
+		term := arg(s.Term) + "::text"
+		prefix := arg(escapeLike(s.Term) + "%")
+		contains := arg("%" + escapeLike(s.Term) + "%")
+
+		variantBarcodes = codeList
+		barcodeMatch := fmt.Sprintf(`(p.barcode IN (%[1]s) OR v.id IS NOT NULL OR EXISTS (
+			SELECT 1 FROM pos_product_barcodes b WHERE b.tenant_id = $1 AND b.product_id = p.id AND b.barcode IN (%[1]s)))`,
+			codeList)
+		rank = fmt.Sprintf(`CASE
+			WHEN %[1]s THEN 0
+			WHEN lower(p.sku) = lower(%[2]s) THEN 1
+			WHEN p.sku ILIKE %[3]s THEN 2
+			WHEN p.name ILIKE %[3]s THEN 3
+			WHEN p.name ILIKE %[4]s THEN 4
+			ELSE 5 END`, barcodeMatch, term, prefix, contains)
+		similarity = fmt.Sprintf("word_similarity(%s, p.name)", term)
+		// <% is pg_trgm's word similarity operator, served by the trigram index on the name
+		where = append(where, fmt.Sprintf("(%s OR p.sku ILIKE %s OR p.name ILIKE %s OR %s <%% p.name)",
+			barcodeMatch, prefix, contains, term))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT p.id, v.id, p.name, p.sku, p.barcode, p.category_id,
+		       COALESCE(v.price, p.selling_price, 0), %[1]s AS match_rank, %[2]s AS similarity,
+		       COALESCE(s.sales, 0) AS sales, COUNT(*) OVER ()
+		FROM products p
+		LEFT JOIN LATERAL (
+			SELECT id, price FROM pos_product_variants
+			WHERE tenant_id = $1 AND product_id = p.id AND is_active = true AND barcode IN (%[3]s)
+			LIMIT 1
+		) v ON true
+		LEFT JOIN (
+			SELECT pti.product_id, COUNT(DISTINCT pti.transaction_id) AS sales
+			FROM pos_transaction_items pti
+			JOIN pos_transactions pt ON pt.id = pti.transaction_id
+			WHERE pt.tenant_id = $1 AND pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded')
+			  AND pt.transaction_date >= $2
+			GROUP BY pti.product_id
+		) s ON s.product_id = p.id
+		WHERE %[4]s
+		ORDER BY match_rank, sales DESC, similarity DESC, p.name, p.id
+		LIMIT %[5]s OFFSET %[6]s
+	`, rank, similarity, variantBarcodes, strings.Join(where, " AND "),
+		arg(s.Page.Limit), arg((s.Page.Page-1)*s.Page.Limit))
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := []ProductSearchResult{}
+	total := 0
+	for rows.Next() {
+		var res ProductSearchResult
+		var matchRank int
+		if err := rows.Scan(&res.ProductID, &res.VariantID, &res.Name, &res.SKU, &res.Barcode, &res.CategoryID,
+			&res.Price, &matchRank, &res.Similarity, &res.SalesCount, &total); err != nil {
+			return nil, 0, err
+		}
+		res.MatchType = searchMatchTypes[matchRank]
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil || len(results) == 0 {
+		return results, total, err
+	}
+
+	candidates, err := loadPriceBookCandidates(q, tenantID, s.Prices, 0, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+	applySearchPrices(results, candidates)
+	return results, total, nil
+}
+
+// applySearchPrices replaces the variant or selling price of each result with its price book
+// price, where one applies
+func applySearchPrices(results []ProductSearchResult, candidates map[priceBookKey]*priceBookCandidate) {
+	for i := range results {
+		if c := pickPriceBookCandidate(candidates, results[i].ProductID, results[i].VariantID); c != nil {
+			results[i].Price = c.price
+			results[i].PriceBookID = &c.bookID
+		}
+	}
+}
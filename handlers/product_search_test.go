@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplySearchPrices(t *testing.T) {
+	variantID := 3
+	candidates := map[priceBookKey]*priceBookCandidate{
+		{productID: 1}:               {bookID: 10, price: 4.5, scopeRank: 3},
+		{productID: 1, variantID: 3}: {bookID: 11, price: 4.25, scopeRank: 3},
+		{productID: 2, variantID: 8}: {bookID: 12, price: 9, scopeRank: 0},
+	}
+	results := []ProductSearchResult{
+		{ProductID: 1, Price: 5},
+		{ProductID: 1, VariantID: &variantID, Price: 5.5},
+		{ProductID: 2, Price: 12},
+		{ProductID: 4, Price: 2},
+	}
+	want := []struct {
+		price float64
+		book  int // 0 for no price book
+	}{
+		{4.5, 10},
+		{4.25, 11},
+		{12, 0}, // another variant's entry does not price the product
+		{2, 0},
+	}
+
+	applySearchPrices(results, candidates)
+	for i, w := range want {
+		book := 0
+		if results[i].PriceBookID != nil {
+			book = *results[i].PriceBookID
+		}
+		if results[i].Price != w.price || book != w.book {
+			t.Errorf("result %d priced %v from book %d, want %v from book %d", i, results[i].Price, book, w.price, w.book)
+		}
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query string
+		want  PaginationRequest
+	}{
+		{"", PaginationRequest{Page: 1, Limit: 20}},
+		{"page=3&limit=50", PaginationRequest{Page: 3, Limit: 50}},
+		{"page=0&limit=-5", PaginationRequest{Page: 1, Limit: 20}},
+		{"page=two&limit=ten", PaginationRequest{Page: 1, Limit: 20}},
+		{"limit=500", PaginationRequest{Page: 1, Limit: 100}},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/products/search?"+tt.query, nil)
+		if got := parsePagination(r, defaultSearchLimit, maxSearchLimit); got != tt.want {
+			t.Errorf("parsePagination(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNewPaginationResponse(t *testing.T) {
+	got := newPaginationResponse(PaginationRequest{Page: 2, Limit: 20}, 45)
+	want := PaginationResponse{CurrentPage: 2, TotalPages: 3, TotalCount: 45, Limit: 20, HasNext: true, HasPrev: true}
+	if got != want {
+		t.Errorf("newPaginationResponse() = %+v, want %+v", got, want)
+	}
+	got = newPaginationResponse(PaginationRequest{Page: 1, Limit: 20}, 0)
+	want = PaginationResponse{CurrentPage: 1, TotalPages: 0, TotalCount: 0, Limit: 20}
+	if got != want {
+		t.Errorf("newPaginationResponse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := map[string]string{
+		"coffee":     "coffee",
+		"100%":       `100\%`,
+		"sku_1":      `sku\_1`,
+		`back\slash`: `back\\slash`,
+	}
+	for term, want := range tests {
+		if got := escapeLike(term); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", term, got, want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// quickSaleLayoutFormatVersion is the version of the layout export format
+const quickSaleLayoutFormatVersion = 1
+
+// quickSaleLayoutCondition matches the rows of one layout, with the tenant, location and register as
+// $1 to $3
+const quickSaleLayoutCondition = "tenant_id = $1 AND location_id IS NOT DISTINCT FROM $2 AND register_id IS NOT DISTINCT FROM $3"
+
+// quickSaleScope identifies a layout: neither ID for the tenant default, a location's, or a
+// register's own layout, which also carries the register's location
+type quickSaleScope struct {
+	LocationID *int
+	RegisterID *int
+}
+
+func (s quickSaleScope) name() string {
+	switch {
+	case s.RegisterID != nil:
+		return "register"
+	case s.LocationID != nil:
+		return "location"
+	}
+	return "default"
+}
+
+func (s quickSaleScope) equal(other quickSaleScope) bool {
+	sameID := func(a, b *int) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+	}
+	return sameID(s.LocationID, other.LocationID) && sameID(s.RegisterID, other.RegisterID)
+}
+
+// fallbacks returns the scope and the layouts a register or location falls back to, most specific first
+func (s quickSaleScope) fallbacks() []quickSaleScope {
+	scopes := []quickSaleScope{s}
+	if s.RegisterID != nil {
+		scopes = append(scopes, quickSaleScope{LocationID: s.LocationID})
+	}
+	if s.LocationID != nil {
+		scopes = append(scopes, quickSaleScope{})
+	}
+	return scopes
+}
+
+// resolveQuickSaleScope builds a layout scope. A register's layout belongs to the register's
+// location, whatever location is given. It returns sql.ErrNoRows for an unknown register.
+func resolveQuickSaleScope(q rowQuerier, tenantID string, locationID, registerID *int) (quickSaleScope, error) {
+	if registerID == nil {
+		return quickSaleScope{LocationID: locationID}, nil
+	}
+	registerLocation, err := registerLocationID(q, tenantID, *registerID)
+	if err != nil {
+		return quickSaleScope{}, err
+	}
+	return quickSaleScope{LocationID: registerLocation, RegisterID: registerID}, nil
+}
+
+// loadQuickSaleLayout reads one layout's categories and buttons in display order
+func loadQuickSaleLayout(q reportQuerier, tenantID string, scope quickSaleScope) (*QuickSaleLayout, error) {
+	layout := &QuickSaleLayout{
+		FormatVersion: quickSaleLayoutFormatVersion,
+		Scope:         scope.name(),
+		LocationID:    scope.LocationID,
+		RegisterID:    scope.RegisterID,
+		Categories:    []QuickSaleLayoutCategory{},
+		Items:         []QuickSaleLayoutItem{},
+	}
+
+	rows, err := q.Query(`
+		SELECT id, category_code, category_name, color_code, icon, display_order, is_active
+		FROM quick_sale_categories
+		WHERE `+quickSaleLayoutCondition+`
+		ORDER BY display_order, category_name
+	`, tenantID, scope.LocationID, scope.RegisterID)
+	if err != nil {
+		return nil, err
+	}
+	categoryIndex := make(map[int]int)
+	for rows.Next() {
+		c := QuickSaleLayoutCategory{Items: []QuickSaleLayoutItem{}}
+		if err := rows.Scan(&c.ID, &c.CategoryCode, &c.CategoryName, &c.ColorCode, &c.Icon, &c.DisplayOrder,
+			&c.IsActive); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		categoryIndex[c.ID] = len(layout.Categories)
+		layout.Categories = append(layout.Categories, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(`
+		SELECT qsi.id, qsi.category_id, qsi.product_id, p.sku, qsi.button_text, qsi.button_color,
+		       qsi.display_order, qsi.is_active
+		FROM quick_sale_items qsi
+		LEFT JOIN products p ON p.id = qsi.product_id
+		WHERE qsi.tenant_id = $1 AND qsi.location_id IS NOT DISTINCT FROM $2 AND qsi.register_id IS NOT DISTINCT FROM $3
+		ORDER BY qsi.display_order, qsi.id
+	`, tenantID, scope.LocationID, scope.RegisterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item QuickSaleLayoutItem
+		var categoryID *int
+		if err := rows.Scan(&item.ID, &categoryID, &item.ProductID, &item.SKU, &item.ButtonText, &item.ButtonColor,
+			&item.DisplayOrder, &item.IsActive); err != nil {
+			return nil, err
+		}
+		if categoryID != nil {
+			if i, ok := categoryIndex[*categoryID]; ok {
+				layout.Categories[i].Items = append(layout.Categories[i].Items, item)
+				continue
+			}
+		}
+		layout.Items = append(layout.Items, item)
+	}
+	return layout, rows.Err()
+}
+
+// loadEffectiveQuickSaleLayout returns the layout a register or location shows: its own, else its
+// location's, else the tenant default. An empty layout of the requested scope means none is set up.
+func loadEffectiveQuickSaleLayout(q reportQuerier, tenantID string, scope quickSaleScope) (*QuickSaleLayout, error) {
+	var requested *QuickSaleLayout
+	for _, candidate := range scope.fallbacks() {
+		layout, err := loadQuickSaleLayout(q, tenantID, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if !layout.empty() {
+			return layout, nil
+		}
+		if requested == nil {
+			requested = layout
+		}
+	}
+	return requested, nil
+}
+
+func (l *QuickSaleLayout) empty() bool {
+	return len(l.Categories) == 0 && len(l.Items) == 0
+}
+
+// eachItem calls fn with every button of the layout
+func (l *QuickSaleLayout) eachItem(fn func(item *QuickSaleLayoutItem)) {
+	for i := range l.Categories {
+		for j := range l.Categories[i].Items {
+			fn(&l.Categories[i].Items[j])
+		}
+	}
+	for i := range l.Items {
+		fn(&l.Items[i])
+	}
+}
+
+// resolveLayoutProducts sets each button's product from its SKU when given, and returns the SKUs
+// and product IDs that match no product
+func resolveLayoutProducts(q rowQuerier, layout *QuickSaleLayout) ([]string, error) {
+	var missing []string
+	var err error
+	layout.eachItem(func(item *QuickSaleLayoutItem) {
+		if err != nil {
+			return
+		}
+		if item.SKU != nil && *item.SKU != "" {
+			var productID int
+			lookupErr := q.QueryRow("SELECT id FROM products WHERE sku = $1 ORDER BY id LIMIT 1", *item.SKU).Scan(&productID)
+			if lookupErr == sql.ErrNoRows {
+				missing = append(missing, "SKU "+*item.SKU)
+				return
+			}
+			if lookupErr != nil {
+				err = lookupErr
+				return
+			}
+			item.ProductID = productID
+			return
+		}
+		var exists bool
+		if err = q.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)", item.ProductID).Scan(&exists); err == nil && !exists {
+			missing = append(missing, fmt.Sprintf("product %d", item.ProductID))
+		}
+	})
+	return missing, err
+}
+
+// validate checks a layout before it is saved: named categories with unique codes, labelled
+// buttons, and each product on one button only
+func (l *QuickSaleLayout) validate() error {
+	if l.FormatVersion != 0 && l.FormatVersion != quickSaleLayoutFormatVersion {
+		return fmt.Errorf("Unsupported layout format version %d", l.FormatVersion)
+	}
+	codes := make(map[string]bool)
+	for _, c := range l.Categories {
+		if c.CategoryCode == "" || c.CategoryName == "" {
+			return errors.New("Each category needs a category_code and category_name")
+		}
+		if codes[c.CategoryCode] {
+			return fmt.Errorf("Category code %s is used more than once", c.CategoryCode)
+		}
+		codes[c.CategoryCode] = true
+	}
+	var err error
+	products := make(map[int]bool)
+	l.eachItem(func(item *QuickSaleLayoutItem) {
+		switch {
+		case err != nil:
+		case item.ButtonText == "":
+			err = errors.New("Each button needs its button_text")
+		case products[item.ProductID]:
+			err = fmt.Errorf("Product %d has more than one button", item.ProductID)
+		}
+		products[item.ProductID] = true
+	})
+	return err
+}
+
+// activeOrDefault treats a category or button without is_active, as in hand-written layouts, as active
+func activeOrDefault(isActive *bool) bool {
+	return isActive == nil || *isActive
+}
+
+// saveQuickSaleLayout replaces the layout of a scope with the given categories and buttons
+func saveQuickSaleLayout(tx *sqlx.Tx, tenantID string, scope quickSaleScope, layout *QuickSaleLayout) error {
+	args := []interface{}{tenantID, scope.LocationID, scope.RegisterID}
+	if _, err := tx.Exec("DELETE FROM quick_sale_items WHERE "+quickSaleLayoutCondition, args...); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM quick_sale_categories WHERE "+quickSaleLayoutCondition, args...); err != nil {
+		return err
+	}
+
+	insertItem := func(categoryID *int, item QuickSaleLayoutItem) error {
+		_, err := tx.Exec(`
+			INSERT INTO quick_sale_items (tenant_id, location_id, register_id, category_id, product_id, button_text,
+			                              button_color, display_order, is_active)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, tenantID, scope.LocationID, scope.RegisterID, categoryID, item.ProductID, item.ButtonText, item.ButtonColor,
+			item.DisplayOrder, activeOrDefault(item.IsActive))
+		return err
+	}
+	for _, c := range layout.Categories {
+		var categoryID int
+		err := tx.QueryRow(`
+			INSERT INTO quick_sale_categories (tenant_id, location_id, register_id, category_code, category_name,
+			                                   color_code, icon, display_order, is_active)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, tenantID, scope.LocationID, scope.RegisterID, c.CategoryCode, c.CategoryName, c.ColorCode, c.Icon,
+			c.DisplayOrder, activeOrDefault(c.IsActive)).Scan(&categoryID)
+		if err != nil {
+			return err
+		}
+		for _, item := range c.Items {
+			if err := insertItem(&categoryID, item); err != nil {
+				return err
+			}
+		}
+	}
+	for _, item := range layout.Items {
+		if err := insertItem(nil, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLayoutImportActiveDefaults(t *testing.T) {
+	raw := `{
+		"categories": [
+			{"category_code": "HOT", "category_name": "Hot drinks", "items": [
+				{"product_id": 1, "button_text": "Latte"},
+				{"product_id": 2, "button_text": "Mocha", "is_active": false}
+			]},
+			{"category_code": "OLD", "category_name": "Retired", "is_active": false, "items": []}
+		],
+		"items": [{"product_id": 3, "button_text": "Bag", "is_active": true}]
+	}`
+	var layout QuickSaleLayout
+	if err := json.Unmarshal([]byte(raw), &layout); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		isActive *bool
+		want     bool
+	}{
+		{"category without is_active", layout.Categories[0].IsActive, true},
+		{"inactive category", layout.Categories[1].IsActive, false},
+		{"button without is_active", layout.Categories[0].Items[0].IsActive, true},
+		{"inactive button", layout.Categories[0].Items[1].IsActive, false},
+		{"active button", layout.Items[0].IsActive, true},
+	}
+	for _, tt := range tests {
+		if got := activeOrDefault(tt.isActive); got != tt.want {
+			t.Errorf("%s: activeOrDefault() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuickSaleLayoutValidate(t *testing.T) {
+	button := func(productID int, text string) QuickSaleLayoutItem {
+		return QuickSaleLayoutItem{ProductID: productID, ButtonText: text}
+	}
+	tests := []struct {
+		name    string
+		layout  QuickSaleLayout
+		wantErr string
+	}{
+		{"valid", QuickSaleLayout{
+			Categories: []QuickSaleLayoutCategory{{CategoryCode: "HOT", CategoryName: "Hot drinks",
+				Items: []QuickSaleLayoutItem{button(1, "Latte")}}},
+			Items: []QuickSaleLayoutItem{button(2, "Bag")}}, ""},
+		{"unsupported version", QuickSaleLayout{FormatVersion: 99},
+			"Unsupported layout format version 99"},
+		{"unnamed category", QuickSaleLayout{
+			Categories: []QuickSaleLayoutCategory{{CategoryCode: "HOT"}}},
+			"Each category needs a category_code and category_name"},
+		{"duplicate category code", QuickSaleLayout{
+			Categories: []QuickSaleLayoutCategory{{CategoryCode: "HOT", CategoryName: "Hot"},
+				{CategoryCode: "HOT", CategoryName: "Also hot"}}},
+			"Category code HOT is used more than once"},
+		{"unlabelled button", QuickSaleLayout{Items: []QuickSaleLayoutItem{button(1, "")}},
+			"Each button needs its button_text"},
+		{"product on two buttons", QuickSaleLayout{
+			Categories: []QuickSaleLayoutCategory{{CategoryCode: "HOT", CategoryName: "Hot drinks",
+				Items: []QuickSaleLayoutItem{button(1, "Latte")}}},
+			Items: []QuickSaleLayoutItem{button(1, "Latte again")}},
+			"Product 1 has more than one button"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.layout.validate()
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.wantErr {
+				t.Errorf("validate() error = %q, want %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuickSaleScopeFallbacks(t *testing.T) {
+	locationID, registerID := 4, 9
+	tests := []struct {
+		scope    quickSaleScope
+		wantName string
+		want     []quickSaleScope
+	}{
+		{quickSaleScope{}, "default", []quickSaleScope{{}}},
+		{quickSaleScope{LocationID: &locationID}, "location",
+			[]quickSaleScope{{LocationID: &locationID}, {}}},
+		{quickSaleScope{LocationID: &locationID, RegisterID: &registerID}, "register",
+			[]quickSaleScope{{LocationID: &locationID, RegisterID: &registerID}, {LocationID: &locationID}, {}}},
+	}
+	for _, tt := range tests {
+		if got := tt.scope.name(); got != tt.wantName {
+			t.Errorf("name() = %q, want %q", got, tt.wantName)
+		}
+		if got := tt.scope.fallbacks(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s fallbacks() = %+v, want %+v", tt.wantName, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1524 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ReceiptHandler handles receipt issuing, rendering and printing
+type ReceiptHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+	sms         SMSNotifier
+}
+
+// NewReceiptHandler creates a new receipt handler
+func NewReceiptHandler(db *sqlx.DB, logger *zap.Logger) *ReceiptHandler {
+	if _, ok := receiptLinkSecret(); !ok {
+		logger.Warn("POS_RECEIPT_LINK_SECRET not set; digital receipt links, QR receipt codes and SMS receipts are disabled")
+	}
+
+	return &ReceiptHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+		sms:         &logSMSNotifier{logger: logger},
+	}
+}
+
+var (
+	errReceiptNotFound    = errors.New("receipt not found")
+	errReceiptNotRendered = errors.New("receipt has no server-built receipt data; reissue it")
+)
+
+// receiptErrorStatus maps receipt build errors to HTTP status codes
+func receiptErrorStatus(err error) int {
+	switch err {
+	case errReceiptTransactionNotFound, errReceiptNotFound:
+		return http.StatusNotFound
+	case errReceiptTransactionNotFinal, errReceiptNotRendered:
+		return http.StatusConflict
+	case errReceiptLinkNoKey:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeReceiptError writes a mapped receipt error, hiding internal error details
+func writeReceiptError(w http.ResponseWriter, err error, fallback string) {
+	status := receiptErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		http.Error(w, fallback, status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// loadStoredReceipt loads a tenant's receipt and decodes its structured receipt data
+func loadStoredReceipt(q rowQuerier, tenantID string, receiptID int) (*POSReceipt, *ReceiptDocument, error) {
+	var receipt POSReceipt
+	var receiptData sql.NullString
+	err := q.QueryRow(`
+		SELECT id, tenant_id, transaction_id, receipt_number, receipt_type, printed_at, reprint_count,
+		       email_sent, email_sent_at, sms_sent, sms_sent_at, receipt_data, rendered_text, rendered_at, created_at
+		FROM pos_receipts
+		WHERE id = $1 AND tenant_id = $2
+	`, receiptID, tenantID).Scan(&receipt.ID, &receipt.TenantID, &receipt.TransactionID, &receipt.ReceiptNumber,
+		&receipt.ReceiptType, &receipt.PrintedAt, &receipt.ReprintCount, &receipt.EmailSent, &receipt.EmailSentAt,
+		&receipt.SMSSent, &receipt.SMSSentAt, &receiptData, &receipt.RenderedText, &receipt.RenderedAt,
+		&receipt.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, errReceiptNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	receipt.ReceiptData = receiptData.String
+
+	var doc ReceiptDocument
+	if err := json.Unmarshal([]byte(receipt.ReceiptData), &doc); err != nil {
+		return &receipt, nil, errReceiptNotRendered
+	}
+	return &receipt, &doc, nil
+}
+
+// CreateReceipt builds a receipt from the recorded transaction and stores it
+func (h *ReceiptHandler) CreateReceipt(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TransactionID int `json:"transaction_id" validate:"required"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to create receipt", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	receiptNumber, err := nextDocumentNumber(tx, tenantID, "RCP")
+	if err != nil {
+		http.Error(w, "Failed to allocate receipt number", http.StatusInternalServerError)
+		return
+	}
+
+	doc, err := newReceiptDocument(tx, tenantID, req.TransactionID, receiptNumber)
+	if err != nil {
+		status := receiptErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to build receipt", zap.Error(err))
+			http.Error(w, "Failed to build receipt", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	receiptData, err := json.Marshal(doc)
+	if err != nil {
+		http.Error(w, "Failed to build receipt", http.StatusInternalServerError)
+		return
+	}
+
+	paperWidth, err := registerPaperWidth(tx, tenantID, doc.Register.ID)
+	if err != nil {
+		http.Error(w, "Failed to load register settings", http.StatusInternalServerError)
+		return
+	}
+	renderedText := renderReceiptText(doc, receiptWidthForPaper(paperWidth))
+
+	query := `
+		INSERT INTO pos_receipts (tenant_id, transaction_id, receipt_number, receipt_type, receipt_data,
+		                          rendered_text, rendered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	var receiptID int
+	var createdAt time.Time
+
+	err = tx.QueryRow(query, tenantID, req.TransactionID, receiptNumber, doc.ReceiptType, string(receiptData),
+		renderedText, doc.IssuedAt).Scan(&receiptID, &createdAt)
+
+	if err != nil {
+		http.Error(w, "Failed to create receipt", http.StatusInternalServerError)
+		return
+	}
+
+	if err = appendFiscalEntry(tx, tenantID, fiscalEntryReceipt, receiptID); err != nil {
+		h.logger.Error("Failed to journal receipt", zap.Int("receipt_id", receiptID), zap.Error(err))
+		http.Error(w, "Failed to journal receipt", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to create receipt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"receipt_id":     receiptID,
+		"receipt_number": receiptNumber,
+		"receipt_type":   doc.ReceiptType,
+		"receipt":        doc,
+		"rendered_text":  renderedText,
+		"created_at":     createdAt,
+		"message":        "Receipt created successfully",
+	})
+}
+
+// GetReceipt retrieves a stored receipt with its structured data and rendered text
+func (h *ReceiptHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	receipt, _, err := loadStoredReceipt(h.db, tenantID, receiptID)
+	if err != nil && err != errReceiptNotRendered {
+		if err == errReceiptNotFound {
+			http.Error(w, "Receipt not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch receipt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// receiptPrintOptions resolves the paper width and ESC/POS extras for a receipt.
+// A QR code links to the public digital receipt.
+func receiptPrintOptions(tx *sqlx.Tx, tenantID string, receiptID int, doc *ReceiptDocument, r *http.Request) (int, escposOptions, error) {
+	var opts escposOptions
+
+	paperWidth, err := registerPaperWidth(tx, tenantID, doc.Register.ID)
+	if err != nil {
+		return 0, opts, err
+	}
+	if width, err := strconv.Atoi(r.URL.Query().Get("paper_width")); err == nil && (width == 58 || width == 80) {
+		paperWidth = width
+	}
+
+	opts.Code = defaultSettings["receipt_code_type"].(string)
+	if _, err := loadTenantSetting(tx, tenantID, "receipt_code_type", &opts.Code); err != nil {
+		return 0, opts, err
+	}
+	if code := r.URL.Query().Get("code"); code != "" {
+		opts.Code = code
+	}
+
+	// Gift receipts always carry the return-lookup barcode
+	if doc.ReceiptType == "gift" {
+		opts.Code = "barcode"
+	}
+
+	if opts.Code == "qr" {
+		token, _, err := ensureReceiptViewToken(tx, tenantID, receiptID)
+		if err != nil {
+			return 0, opts, err
+		}
+		opts.QRContent = receiptViewURL(token)
+	}
+
+	return paperWidth, opts, nil
+}
+
+// hasCashTender reports whether cash was taken or handed back on the receipt
+func hasCashTender(doc *ReceiptDocument) bool {
+	for _, payment := range doc.Payments {
+		if payment.PaymentMethod == "cash" {
+			return true
+		}
+	}
+	return false
+}
+
+// receiptPrintRefused is a print the receipt's reprint policy refuses, with the HTTP status to answer
+type receiptPrintRefused struct {
+	status int
+	msg    string
+}
+
+func (e *receiptPrintRefused) Error() string { return e.msg }
+
+// receiptPrint is a receipt rendered for a printer and the print job recorded for it
+type receiptPrint struct {
+	Receipt      *POSReceipt
+	JobID        int
+	PaperWidth   int
+	Payload      []byte
+	PrintedAt    *time.Time
+	ReprintCount int
+	CopyNumber   int // 0 for the original
+}
+
+// reprintCopyNumber checks a reprint against the reprint controls and returns its copy number
+func reprintCopyNumber(canReprint bool, reason string, reprintCount, maxReprints int) (int, error) {
+	if !canReprint {
+		return 0, &receiptPrintRefused{http.StatusForbidden,
+			"Reprinting a receipt requires the pos.receipts.print permission"}
+	}
+	if reason == "" {
+		return 0, &receiptPrintRefused{http.StatusBadRequest, "A reason is required to reprint a receipt"}
+	}
+	if reprintCount >= maxReprints {
+		return 0, &receiptPrintRefused{http.StatusConflict,
+			fmt.Sprintf("Reprint limit of %d reached for this receipt", maxReprints)}
+	}
+	return reprintCount + 1, nil
+}
+
+// printReceipt renders a receipt for a printer and records the print job. Every way a receipt
+// reaches a printer goes through here: the first print is the original; any later one is a
+// reprint, which needs pos.receipts.print and a reason, is capped by the max_receipt_reprints
+// setting, is audited, and carries a "DUPLICATE - COPY #n" marker. A job that is downloaded
+// rather than queued for the register's print agent is recorded as already printed.
+func (h *ReceiptHandler) printReceipt(tx *sqlx.Tx, r *http.Request, tenantID string, receiptID int, reason string,
+	openDrawer *bool, downloaded bool) (*receiptPrint, error) {
+	userID, _ := h.baseHandler.getUserID(r)
+
+	// Lock the receipt so concurrent reprints get distinct copy numbers
+	printed := &receiptPrint{}
+	var reprintCount int
+	err := tx.QueryRow("SELECT printed_at, reprint_count FROM pos_receipts WHERE id = $1 AND tenant_id = $2 FOR UPDATE",
+		receiptID, tenantID).Scan(&printed.PrintedAt, &reprintCount)
+	if err == sql.ErrNoRows {
+		return nil, errReceiptNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	isReprint := printed.PrintedAt != nil
+	reason = strings.TrimSpace(reason)
+	if isReprint {
+		maxReprints := defaultSettings["max_receipt_reprints"].(int)
+		if _, err := loadTenantSetting(tx, tenantID, "max_receipt_reprints", &maxReprints); err != nil {
+			return nil, err
+		}
+		printed.CopyNumber, err = reprintCopyNumber(h.baseHandler.hasPermission(r, "pos.receipts.print"), reason,
+			reprintCount, maxReprints)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	receipt, doc, err := loadStoredReceipt(tx, tenantID, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	printed.Receipt = receipt
+
+	paperWidth, opts, err := receiptPrintOptions(tx, tenantID, receiptID, doc, r)
+	if err != nil {
+		return nil, err
+	}
+	printed.PaperWidth = paperWidth
+
+	if isReprint {
+		// Copies never open the drawer
+		opts.Marker = fmt.Sprintf("DUPLICATE - COPY #%d", printed.CopyNumber)
+	} else {
+		// Kick the drawer on the original print of a cash sale unless told otherwise
+		opts.OpenDrawer = hasCashTender(doc)
+		if openDrawer != nil {
+			opts.OpenDrawer = *openDrawer
+		}
+	}
+
+	printed.Payload = renderReceiptESCPOS(doc, receiptWidthForPaper(paperWidth), opts)
+
+	status := "queued"
+	var completedAt, downloadedAt *time.Time
+	if downloaded {
+		now := time.Now()
+		status, completedAt, downloadedAt = "printed", &now, &now
+	}
+	err = tx.QueryRow(`
+		INSERT INTO pos_print_jobs (tenant_id, receipt_id, register_id, format, paper_width, payload, status,
+		                            created_by, completed_at, downloaded_at)
+		VALUES ($1, $2, $3, 'escpos', $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, tenantID, receiptID, doc.Register.ID, paperWidth, printed.Payload, status, userID, completedAt,
+		downloadedAt).Scan(&printed.JobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isReprint {
+		_, err = tx.Exec(`
+			INSERT INTO pos_receipt_reprints (tenant_id, receipt_id, copy_number, reason, print_job_id, printed_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, tenantID, receiptID, printed.CopyNumber, reason, printed.JobID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.QueryRow(`
+			UPDATE pos_receipts SET reprint_count = reprint_count + 1
+			WHERE id = $1 AND tenant_id = $2
+			RETURNING printed_at, reprint_count
+		`, receiptID, tenantID).Scan(&printed.PrintedAt, &printed.ReprintCount)
+	} else {
+		err = tx.QueryRow(`
+			UPDATE pos_receipts SET printed_at = $1
+			WHERE id = $2 AND tenant_id = $3
+			RETURNING printed_at, reprint_count
+		`, time.Now(), receiptID, tenantID).Scan(&printed.PrintedAt, &printed.ReprintCount)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if isReprint {
+		h.logger.Info("Receipt reprinted",
+			zap.String("tenant_id", tenantID),
+			zap.Int("receipt_id", receiptID),
+			zap.Int("copy_number", printed.CopyNumber),
+			zap.Int("user_id", userID),
+			zap.String("reason", reason),
+		)
+	}
+	return printed, nil
+}
+
+// writePrintError writes a refused print with its status, and other print errors as receipt errors
+func writePrintError(w http.ResponseWriter, err error, fallback string) {
+	var refused *receiptPrintRefused
+	if errors.As(err, &refused) {
+		http.Error(w, refused.msg, refused.status)
+		return
+	}
+	writeReceiptError(w, err, fallback)
+}
+
+// writePrintPayload sends a print's ESC/POS bytes as a download
+func writePrintPayload(w http.ResponseWriter, printed *receiptPrint) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", printed.Receipt.ReceiptNumber+".bin"))
+	w.Header().Set("X-Print-Job-ID", strconv.Itoa(printed.JobID))
+	w.Header().Set("X-Copy-Number", strconv.Itoa(printed.CopyNumber))
+	w.Write(printed.Payload)
+}
+
+// DownloadReceiptESCPOS returns the receipt as a raw ESC/POS byte stream. A download prints the
+// receipt, so after the first print it is a reprint and needs ?reason=.
+func (h *ReceiptHandler) DownloadReceiptESCPOS(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to print receipt", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	printed, err := h.printReceipt(tx, r, tenantID, receiptID, r.URL.Query().Get("reason"), nil, true)
+	if err != nil {
+		writePrintError(w, err, "Failed to print receipt")
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to print receipt", http.StatusInternalServerError)
+		return
+	}
+
+	writePrintPayload(w, printed)
+}
+
+// PrintReceipt renders the receipt for the register's printer and queues a print job.
+// Printing after the original is a reprint: it needs pos.receipts.print and a reason, is capped by
+// the max_receipt_reprints setting, is audited, and prints with a "DUPLICATE - COPY #n" marker.
+func (h *ReceiptHandler) PrintReceipt(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		OpenDrawer *bool  `json:"open_drawer"`
+		Reason     string `json:"reason"` // required for reprints
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to print receipt", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	printed, err := h.printReceipt(tx, r, tenantID, receiptID, req.Reason, req.OpenDrawer, false)
+	if err != nil {
+		writePrintError(w, err, "Failed to print receipt")
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to print receipt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"receipt_number": printed.Receipt.ReceiptNumber,
+		"print_job_id":   printed.JobID,
+		"paper_width":    printed.PaperWidth,
+		"escpos":         printed.Payload, // base64 encoded
+		"printed_at":     printed.PrintedAt,
+		"duplicate":      printed.CopyNumber > 0,
+		"copy_number":    printed.CopyNumber,
+		"reprint_count":  printed.ReprintCount,
+		"message":        "Receipt sent to printer",
+	})
+}
+
+// GetReceiptReprints lists the audited reprints of a receipt
+func (h *ReceiptHandler) GetReceiptReprints(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, tenant_id, receipt_id, copy_number, reason, print_job_id, printed_by, printed_at
+		FROM pos_receipt_reprints
+		WHERE tenant_id = $1 AND receipt_id = $2
+		ORDER BY copy_number
+	`, tenantID, receiptID)
+	if err != nil {
+		http.Error(w, "Failed to fetch reprints", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var reprints []ReceiptReprint
+	for rows.Next() {
+		var reprint ReceiptReprint
+		err := rows.Scan(&reprint.ID, &reprint.TenantID, &reprint.ReceiptID, &reprint.CopyNumber, &reprint.Reason,
+			&reprint.PrintJobID, &reprint.PrintedBy, &reprint.PrintedAt)
+		if err != nil {
+			continue
+		}
+		reprints = append(reprints, reprint)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reprints": reprints,
+		"count":    len(reprints),
+	})
+}
+
+// =================================================================
+// PRINT JOBS
+// =================================================================
+
+// GetPrintJobs lists print jobs, typically polled by a register's print agent
+func (h *ReceiptHandler) GetPrintJobs(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	registerID := r.URL.Query().Get("register_id")
+	status := r.URL.Query().Get("status")
+
+	query := `
+		SELECT id, tenant_id, receipt_id, register_id, format, paper_width, status, error_message,
+		       created_by, created_at, completed_at
+		FROM pos_print_jobs
+		WHERE tenant_id = $1
+	`
+	args := []interface{}{tenantID}
+	argIndex := 2
+
+	if registerID != "" {
+		query += fmt.Sprintf(" AND register_id = $%d", argIndex)
+		args = append(args, registerID)
+		argIndex++
+	}
+
+	if status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, status)
+		argIndex++
+	}
+
+	query += " ORDER BY created_at, id LIMIT 100"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch print jobs", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var jobs []PrintJob
+	for rows.Next() {
+		var job PrintJob
+		err := rows.Scan(&job.ID, &job.TenantID, &job.ReceiptID, &job.RegisterID, &job.Format, &job.PaperWidth,
+			&job.Status, &job.ErrorMessage, &job.CreatedBy, &job.CreatedAt, &job.CompletedAt)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"print_jobs": jobs,
+		"count":      len(jobs),
+	})
+}
+
+// DownloadPrintJob returns a print job's raw printer payload. Each job's payload is handed out
+// once; downloading it again prints the receipt again, as an audited reprint that needs ?reason=.
+func (h *ReceiptHandler) DownloadPrintJob(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid print job ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to fetch print job", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var receiptID int
+	var payload []byte
+	var downloadedAt *time.Time
+	err = tx.QueryRow(`
+		SELECT receipt_id, payload, downloaded_at FROM pos_print_jobs
+		WHERE id = $1 AND tenant_id = $2
+		FOR UPDATE
+	`, jobID, tenantID).Scan(&receiptID, &payload, &downloadedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Print job not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch print job", http.StatusInternalServerError)
+		return
+	}
+
+	if downloadedAt != nil {
+		printed, err := h.printReceipt(tx, r, tenantID, receiptID, r.URL.Query().Get("reason"), nil, true)
+		if err != nil {
+			writePrintError(w, err, "Failed to print receipt")
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			http.Error(w, "Failed to print receipt", http.StatusInternalServerError)
+			return
+		}
+		writePrintPayload(w, printed)
+		return
+	}
+
+	if _, err = tx.Exec("UPDATE pos_print_jobs SET downloaded_at = NOW() WHERE id = $1", jobID); err != nil {
+		http.Error(w, "Failed to fetch print job", http.StatusInternalServerError)
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to fetch print job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"print-job-%d.bin\"", jobID))
+	w.Write(payload)
+}
+
+// CompletePrintJob records whether the printer accepted a job
+func (h *ReceiptHandler) CompletePrintJob(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid print job ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Success      bool    `json:"success"`
+		ErrorMessage *string `json:"error_message"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	status := "printed"
+	if !req.Success {
+		status = "failed"
+	}
+
+	var completedAt time.Time
+	err = h.db.QueryRow(`
+		UPDATE pos_print_jobs SET status = $1, error_message = $2, completed_at = NOW()
+		WHERE id = $3 AND tenant_id = $4 AND status = 'queued'
+		RETURNING completed_at
+	`, status, req.ErrorMessage, jobID, tenantID).Scan(&completedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Queued print job not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update print job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":           jobID,
+		"status":       status,
+		"completed_at": completedAt,
+		"message":      "Print job updated",
+	})
+}
+
+// =================================================================
+// EMAIL DELIVERY
+// =================================================================
+
+// EmailReceipt queues the receipt for email delivery to the customer or a given address
+func (h *ReceiptHandler) EmailReceipt(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Email *string `json:"email"` // defaults to the customer's email
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	receipt, doc, err := loadStoredReceipt(h.db, tenantID, receiptID)
+	if err != nil {
+		writeReceiptError(w, err, "Failed to fetch receipt")
+		return
+	}
+
+	recipient := ""
+	if req.Email != nil {
+		recipient = *req.Email
+	} else if doc.Customer != nil && doc.Customer.Email != nil {
+		recipient = *doc.Customer.Email
+	}
+	if recipient == "" {
+		http.Error(w, "Email address is required; the receipt has no customer email", http.StatusBadRequest)
+		return
+	}
+
+	address, err := mail.ParseAddress(recipient)
+	if err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+	subject := fmt.Sprintf("Your receipt %s", receipt.ReceiptNumber)
+
+	var deliveryID int
+	err = h.db.QueryRow(`
+		INSERT INTO pos_email_deliveries (tenant_id, receipt_id, recipient, subject, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, tenantID, receiptID, address.Address, subject, userID).Scan(&deliveryID)
+	if err != nil {
+		http.Error(w, "Failed to queue receipt email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"delivery_id": deliveryID,
+		"recipient":   address.Address,
+		"status":      "queued",
+		"message":     "Receipt email queued",
+	})
+}
+
+// GetReceiptEmailDeliveries lists the email attempts for a receipt
+func (h *ReceiptHandler) GetReceiptEmailDeliveries(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, tenant_id, receipt_id, recipient, subject, status, attempts, max_attempts, next_attempt_at,
+		       last_error, sent_at, bounced_at, created_by, created_at, updated_at
+		FROM pos_email_deliveries
+		WHERE tenant_id = $1 AND receipt_id = $2
+		ORDER BY created_at DESC
+	`, tenantID, receiptID)
+	if err != nil {
+		http.Error(w, "Failed to fetch email deliveries", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var deliveries []EmailDelivery
+	for rows.Next() {
+		var d EmailDelivery
+		err := rows.Scan(&d.ID, &d.TenantID, &d.ReceiptID, &d.Recipient, &d.Subject, &d.Status, &d.Attempts,
+			&d.MaxAttempts, &d.NextAttemptAt, &d.LastError, &d.SentAt, &d.BouncedAt, &d.CreatedBy,
+			&d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// RecordEmailBounce records a bounce reported after the mail server accepted the message
+func (h *ReceiptHandler) RecordEmailBounce(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	deliveryID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" validate:"required"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to record bounce", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var receiptID int
+	err = tx.QueryRow(`
+		UPDATE pos_email_deliveries SET status = 'bounced', bounced_at = NOW(), last_error = $1
+		WHERE id = $2 AND tenant_id = $3 AND status = 'sent'
+		RETURNING receipt_id
+	`, req.Reason, deliveryID, tenantID).Scan(&receiptID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Sent delivery not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to record bounce", http.StatusInternalServerError)
+		return
+	}
+
+	// The receipt only counts as emailed while another delivery still stands
+	_, err = tx.Exec(`
+		UPDATE pos_receipts SET email_sent = false, email_sent_at = NULL
+		WHERE id = $1 AND tenant_id = $2
+		  AND NOT EXISTS (SELECT 1 FROM pos_email_deliveries WHERE receipt_id = $1 AND status = 'sent')
+	`, receiptID, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to record bounce", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to record bounce", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      deliveryID,
+		"status":  "bounced",
+		"message": "Bounce recorded",
+	})
+}
+
+// =================================================================
+// SMS DELIVERY
+// =================================================================
+
+// SMSReceipt texts a short receipt with a link to the digital receipt
+func (h *ReceiptHandler) SMSReceipt(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Phone        *string `json:"phone"`         // defaults to the customer's phone
+		ConsentGiven bool    `json:"consent_given"` // customer agreed at the till
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to send SMS", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, doc, err := loadStoredReceipt(tx, tenantID, receiptID)
+	if err != nil {
+		writeReceiptError(w, err, "Failed to fetch receipt")
+		return
+	}
+
+	countryCode := defaultSettings["sms_default_country_code"].(string)
+	if _, err := loadTenantSetting(tx, tenantID, "sms_default_country_code", &countryCode); err != nil {
+		http.Error(w, "Failed to load SMS settings", http.StatusInternalServerError)
+		return
+	}
+
+	rawPhone := ""
+	if req.Phone != nil {
+		rawPhone = *req.Phone
+	} else if doc.Customer != nil && doc.Customer.Phone != nil {
+		rawPhone = *doc.Customer.Phone
+	}
+	if rawPhone == "" {
+		http.Error(w, "Phone number is required; the receipt has no customer phone", http.StatusBadRequest)
+		return
+	}
+
+	phone, err := normalizePhoneE164(rawPhone, countryCode)
+	if err != nil {
+		http.Error(w, "Invalid phone number", http.StatusBadRequest)
+		return
+	}
+
+	// Texts need the customer's opt-in, either on file or given at the till
+	var customerID *int
+	if doc.Customer != nil {
+		customerID = &doc.Customer.ID
+		if req.ConsentGiven {
+			_, err = tx.Exec(`
+				INSERT INTO pos_customer_sms_consent (tenant_id, customer_id, phone_e164, opted_in, source, opted_in_at, updated_by)
+				VALUES ($1, $2, $3, true, 'pos', NOW(), $4)
+				ON CONFLICT (tenant_id, customer_id) DO UPDATE SET
+					phone_e164 = EXCLUDED.phone_e164, opted_in = true, source = 'pos',
+					opted_in_at = NOW(), opted_out_at = NULL, updated_by = EXCLUDED.updated_by
+			`, tenantID, doc.Customer.ID, phone, userID)
+			if err != nil {
+				http.Error(w, "Failed to record SMS consent", http.StatusInternalServerError)
+				return
+			}
+		} else {
+			var optedIn bool
+			err = tx.QueryRow(`
+				SELECT opted_in FROM pos_customer_sms_consent
+				WHERE tenant_id = $1 AND customer_id = $2 AND phone_e164 = $3
+			`, tenantID, doc.Customer.ID, phone).Scan(&optedIn)
+			if err != nil && err != sql.ErrNoRows {
+				http.Error(w, "Failed to check SMS consent", http.StatusInternalServerError)
+				return
+			}
+			if !optedIn {
+				http.Error(w, "Customer has not opted in to SMS receipts on this number", http.StatusConflict)
+				return
+			}
+		}
+	} else if !req.ConsentGiven {
+		http.Error(w, "consent_given is required when texting a number without a customer", http.StatusBadRequest)
+		return
+	}
+
+	token, _, err := ensureReceiptViewToken(tx, tenantID, receiptID)
+	if err != nil {
+		writeReceiptError(w, err, "Failed to create receipt link")
+		return
+	}
+
+	body, err := composeReceiptSMS(doc, receiptViewURL(token))
+	if err != nil {
+		h.logger.Error("Receipt SMS does not fit one message", zap.Int("receipt_id", receiptID), zap.Error(err))
+		http.Error(w, "Receipt link is too long for an SMS", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to send SMS", http.StatusInternalServerError)
+		return
+	}
+
+	providerID, sendErr := h.sms.SendSMS(r.Context(), phone, body)
+
+	status := "sent"
+	var errorMessage, providerMessageID *string
+	if sendErr != nil {
+		status = "failed"
+		msg := sendErr.Error()
+		errorMessage = &msg
+	} else {
+		providerMessageID = &providerID
+	}
+
+	var messageID int
+	err = h.db.QueryRow(`
+		INSERT INTO pos_sms_messages (tenant_id, receipt_id, customer_id, phone_e164, body, status,
+		                              provider_message_id, error_message, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, tenantID, receiptID, customerID, phone, body, status, providerMessageID, errorMessage, userID).Scan(&messageID)
+	if err != nil {
+		h.logger.Error("Failed to record SMS message", zap.Int("receipt_id", receiptID), zap.Error(err))
+	}
+
+	if sendErr != nil {
+		h.logger.Warn("Receipt SMS failed", zap.Int("receipt_id", receiptID), zap.Error(sendErr))
+		http.Error(w, "SMS provider rejected the message", http.StatusBadGateway)
+		return
+	}
+
+	_, err = h.db.Exec("UPDATE pos_receipts SET sms_sent = true, sms_sent_at = NOW() WHERE id = $1 AND tenant_id = $2",
+		receiptID, tenantID)
+	if err != nil {
+		http.Error(w, "SMS sent but failed to update receipt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message_id":          messageID,
+		"phone":               phone,
+		"provider_message_id": providerID,
+		"status":              status,
+		"message":             "Receipt SMS sent",
+	})
+}
+
+// =================================================================
+// PUBLIC RECEIPT LINKS
+// =================================================================
+
+// CreateReceiptLink returns the receipt's public link, issuing a new one if none is active
+func (h *ReceiptHandler) CreateReceiptLink(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to create receipt link", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	token, expiresAt, err := ensureReceiptViewToken(tx, tenantID, receiptID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Receipt not found", http.StatusNotFound)
+			return
+		}
+		writeReceiptError(w, err, "Failed to create receipt link")
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to create receipt link", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"url":        receiptViewURL(token),
+		"expires_at": expiresAt,
+	})
+}
+
+// RevokeReceiptLink disables the receipt's public link; a later link gets a new token
+func (h *ReceiptHandler) RevokeReceiptLink(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	receiptID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid receipt ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec(`
+		UPDATE pos_receipts SET view_token_revoked_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND view_token IS NOT NULL AND view_token_revoked_at IS NULL
+	`, receiptID, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to revoke receipt link", http.StatusInternalServerError)
+		return
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		http.Error(w, "Receipt has no active link", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Receipt link revoked",
+	})
+}
+
+// GetPublicReceipt shows a receipt to anyone holding its signed link; no login or tenant header is needed.
+// It returns HTML, or JSON when asked with ?format=json or an application/json Accept header.
+func (h *ReceiptHandler) GetPublicReceipt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	viewToken, err := verifyReceiptLinkToken(chi.URLParam(r, "token"))
+	if err != nil {
+		http.Error(w, "Receipt not found", http.StatusNotFound)
+		return
+	}
+
+	// The token identifies the tenant; nothing from the request does
+	var receiptID int
+	var tenantID string
+	var expiresAt, revokedAt *time.Time
+	err = h.db.QueryRow(`
+		SELECT id, tenant_id, view_token_expires_at, view_token_revoked_at
+		FROM pos_receipts WHERE view_token = $1
+	`, viewToken).Scan(&receiptID, &tenantID, &expiresAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Receipt not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch receipt", http.StatusInternalServerError)
+		return
+	}
+	if revokedAt != nil || (expiresAt != nil && !expiresAt.After(time.Now())) {
+		http.Error(w, errReceiptLinkExpired.Error(), http.StatusGone)
+		return
+	}
+
+	_, doc, err := loadStoredReceipt(h.db, tenantID, receiptID)
+	if err != nil {
+		if err == errReceiptNotRendered {
+			http.Error(w, "Receipt not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch receipt", http.StatusInternalServerError)
+		return
+	}
+	doc = publicReceiptDocument(doc)
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"receipt":       doc,
+			"rendered_text": renderReceiptText(doc, defaultReceiptWidth),
+		})
+		return
+	}
+
+	page, err := renderReceiptHTML(doc)
+	if err != nil {
+		http.Error(w, "Failed to render receipt", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+// =================================================================
+// GIFT RECEIPTS
+// =================================================================
+
+// giftReceiptDocument turns a sale receipt into a gift receipt for the selected quantities per item,
+// dropping prices, payments, totals and the customer
+func giftReceiptDocument(doc *ReceiptDocument, quantities map[int]float64) *ReceiptDocument {
+	gift := &ReceiptDocument{
+		ReceiptNumber:     doc.ReceiptNumber,
+		ReceiptType:       "gift",
+		IssuedAt:          doc.IssuedAt,
+		Template:          doc.Template,
+		TransactionID:     doc.TransactionID,
+		TransactionNumber: doc.TransactionNumber,
+		TransactionType:   doc.TransactionType,
+		TransactionDate:   doc.TransactionDate,
+		Register:          doc.Register,
+		Cashier:           doc.Cashier,
+	}
+	for _, line := range doc.Lines {
+		qty, ok := quantities[line.ItemID]
+		if !ok {
+			continue
+		}
+		gift.Lines = append(gift.Lines, ReceiptLine{
+			ItemID:        line.ItemID,
+			ProductID:     line.ProductID,
+			SKU:           line.SKU,
+			Description:   line.Description,
+			Quantity:      qty,
+			UnitOfMeasure: line.UnitOfMeasure,
+		})
+	}
+	return gift
+}
+
+// CreateGiftReceipt issues a gift receipt for a sale, covering all lines or the selected ones.
+// Its number is printed as a barcode that returns resolve with LookupGiftReceipt.
+func (h *ReceiptHandler) CreateGiftReceipt(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TransactionID int `json:"transaction_id" validate:"required"`
+		Items         []struct {
+			ItemID   int      `json:"item_id"`
+			Quantity *float64 `json:"quantity"` // defaults to the full line quantity
+		} `json:"items"` // defaults to every line
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to create gift receipt", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`
+		SELECT status FROM pos_transactions WHERE id = $1 AND tenant_id = $2
+	`, req.TransactionID, tenantID).Scan(&status)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create gift receipt", http.StatusInternalServerError)
+		return
+	}
+	if status != "completed" {
+		http.Error(w, "Gift receipts can only be issued for completed sales", http.StatusConflict)
+		return
+	}
+
+	receiptNumber, err := nextDocumentNumber(tx, tenantID, "GFT")
+	if err != nil {
+		http.Error(w, "Failed to allocate receipt number", http.StatusInternalServerError)
+		return
+	}
+
+	doc, err := newReceiptDocument(tx, tenantID, req.TransactionID, receiptNumber)
+	if err != nil {
+		writeReceiptError(w, err, "Failed to build receipt")
+		return
+	}
+	if doc.ReceiptType != "sale" {
+		http.Error(w, "Gift receipts can only be issued for sales", http.StatusConflict)
+		return
+	}
+
+	lineQuantities := make(map[int]float64, len(doc.Lines))
+	for _, line := range doc.Lines {
+		lineQuantities[line.ItemID] = line.Quantity
+	}
+
+	quantities := make(map[int]float64)
+	if len(req.Items) == 0 {
+		quantities = lineQuantities
+	}
+	for _, item := range req.Items {
+		lineQty, ok := lineQuantities[item.ItemID]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Item %d is not on this transaction", item.ItemID), http.StatusBadRequest)
+			return
+		}
+		qty := lineQty
+		if item.Quantity != nil {
+			qty = *item.Quantity
+		}
+		if qty <= 0 || qty > lineQty {
+			http.Error(w, fmt.Sprintf("Quantity for item %d must be more than 0 and at most %g", item.ItemID, lineQty), http.StatusBadRequest)
+			return
+		}
+		quantities[item.ItemID] = qty
+	}
+	if len(quantities) == 0 {
+		http.Error(w, "Transaction has no items", http.StatusConflict)
+		return
+	}
+
+	gift := giftReceiptDocument(doc, quantities)
+
+	receiptData, err := json.Marshal(gift)
+	if err != nil {
+		http.Error(w, "Failed to build receipt", http.StatusInternalServerError)
+		return
+	}
+
+	paperWidth, err := registerPaperWidth(tx, tenantID, gift.Register.ID)
+	if err != nil {
+		http.Error(w, "Failed to load register settings", http.StatusInternalServerError)
+		return
+	}
+	renderedText := renderReceiptText(gift, receiptWidthForPaper(paperWidth))
+
+	var receiptID int
+	var createdAt time.Time
+	err = tx.QueryRow(`
+		INSERT INTO pos_receipts (tenant_id, transaction_id, receipt_number, receipt_type, receipt_data,
+		                          rendered_text, rendered_at)
+		VALUES ($1, $2, $3, 'gift', $4, $5, $6)
+		RETURNING id, created_at
+	`, tenantID, req.TransactionID, receiptNumber, string(receiptData), renderedText, gift.IssuedAt).Scan(&receiptID, &createdAt)
+	if err != nil {
+		http.Error(w, "Failed to create gift receipt", http.StatusInternalServerError)
+		return
+	}
+
+	for itemID, qty := range quantities {
+		_, err = tx.Exec(`
+			INSERT INTO pos_gift_receipt_items (tenant_id, receipt_id, transaction_item_id, quantity)
+			VALUES ($1, $2, $3, $4)
+		`, tenantID, receiptID, itemID, qty)
+		if err != nil {
+			http.Error(w, "Failed to create gift receipt", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err = appendFiscalEntry(tx, tenantID, fiscalEntryReceipt, receiptID); err != nil {
+		h.logger.Error("Failed to journal receipt", zap.Int("receipt_id", receiptID), zap.Error(err))
+		http.Error(w, "Failed to journal receipt", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to create gift receipt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"receipt_id":     receiptID,
+		"receipt_number": receiptNumber,
+		"receipt_type":   "gift",
+		"receipt":        gift,
+		"rendered_text":  renderedText,
+		"created_at":     createdAt,
+		"message":        "Gift receipt created successfully",
+	})
+}
+
+// netGiftReceiptLine caps a gift receipt line at what is left of the sale line after returns and
+// values it. Units already brought back, with or without the gift receipt, cannot be refunded
+// again. It reports false when nothing is left to refund.
+func netGiftReceiptLine(line *GiftReceiptLine, lineQuantity, lineTotal, returned float64) bool {
+	if remaining := lineQuantity - returned; line.Quantity > remaining {
+		line.Quantity = math.Max(remaining, 0)
+	}
+	if line.Quantity <= 0 {
+		return false
+	}
+	// The refundable value is what was paid per unit, after discounts and including tax
+	unitValue := lineTotal / lineQuantity
+	line.UnitRefundValue = math.Round(unitValue*100) / 100
+	line.RefundableValue = math.Round(unitValue*line.Quantity*100) / 100
+	return true
+}
+
+// LookupGiftReceipt resolves a scanned gift receipt code to the original sale lines and their refundable value
+func (h *ReceiptHandler) LookupGiftReceipt(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	code := chi.URLParam(r, "code")
+
+	var lookup GiftReceiptLookup
+	var status string
+	err = h.db.QueryRow(`
+		SELECT r.id, r.receipt_number, pt.id, pt.transaction_number, pt.transaction_date, pt.status
+		FROM pos_receipts r
+		JOIN pos_transactions pt ON pt.id = r.transaction_id
+		WHERE r.tenant_id = $1 AND r.receipt_number = $2 AND r.receipt_type = 'gift'
+	`, tenantID, code).Scan(&lookup.ReceiptID, &lookup.ReceiptNumber, &lookup.TransactionID,
+		&lookup.TransactionNumber, &lookup.TransactionDate, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Gift receipt not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch gift receipt", http.StatusInternalServerError)
+		return
+	}
+	if status != "completed" {
+		http.Error(w, fmt.Sprintf("The original sale is %s and has nothing left to refund", status), http.StatusConflict)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT pti.id, pti.product_id, COALESCE(p.sku, ''), COALESCE(p.name, ''), gi.quantity,
+		       pti.quantity, pti.line_total,
+		       COALESCE((SELECT SUM(ri.quantity) FROM pos_return_items ri
+		                 WHERE ri.original_item_id = pti.id AND ri.tenant_id = gi.tenant_id), 0)
+		FROM pos_gift_receipt_items gi
+		JOIN pos_transaction_items pti ON pti.id = gi.transaction_item_id
+		LEFT JOIN products p ON pti.product_id = p.id
+		WHERE gi.receipt_id = $1 AND gi.tenant_id = $2
+		ORDER BY pti.id
+	`, lookup.ReceiptID, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch gift receipt items", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line GiftReceiptLine
+		var lineQuantity, lineTotal, returned float64
+		err := rows.Scan(&line.TransactionItemID, &line.ProductID, &line.SKU, &line.Description, &line.Quantity,
+			&lineQuantity, &lineTotal, &returned)
+		if err != nil {
+			continue
+		}
+		if !netGiftReceiptLine(&line, lineQuantity, lineTotal, returned) {
+			continue
+		}
+		lookup.RefundableTotal += line.RefundableValue
+		lookup.Lines = append(lookup.Lines, line)
+	}
+	lookup.RefundableTotal = math.Round(lookup.RefundableTotal*100) / 100
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lookup)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReprintCopyNumber(t *testing.T) {
+	tests := []struct {
+		name         string
+		canReprint   bool
+		reason       string
+		reprintCount int
+		wantCopy     int
+		wantStatus   int // 0 when the reprint is allowed
+	}{
+		{"first reprint", true, "Customer lost it", 0, 1, 0},
+		{"third reprint of three", true, "Printer jam", 2, 3, 0},
+		{"over the limit", true, "Printer jam", 3, 0, http.StatusConflict},
+		{"no reason", true, "", 0, 0, http.StatusBadRequest},
+		{"no permission", false, "Customer lost it", 0, 0, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			copyNumber, err := reprintCopyNumber(tt.canReprint, tt.reason, tt.reprintCount, 3)
+			if tt.wantStatus == 0 {
+				if err != nil || copyNumber != tt.wantCopy {
+					t.Errorf("reprintCopyNumber() = %d, %v, want %d", copyNumber, err, tt.wantCopy)
+				}
+				return
+			}
+			var refused *receiptPrintRefused
+			if !errors.As(err, &refused) || refused.status != tt.wantStatus {
+				t.Errorf("reprintCopyNumber() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWritePrintError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{&receiptPrintRefused{http.StatusConflict, "Reprint limit of 3 reached for this receipt"},
+			http.StatusConflict, "Reprint limit of 3 reached for this receipt"},
+		{errReceiptNotFound, http.StatusNotFound, errReceiptNotFound.Error()},
+		{errors.New("pq: deadlock detected"), http.StatusInternalServerError, "Failed to print receipt"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writePrintError(w, tt.err, "Failed to print receipt")
+		if w.Code != tt.wantStatus {
+			t.Errorf("writePrintError(%v) status = %d, want %d", tt.err, w.Code, tt.wantStatus)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != tt.wantBody {
+			t.Errorf("writePrintError(%v) body = %q, want %q", tt.err, body, tt.wantBody)
+		}
+	}
+}
+
+func TestNetGiftReceiptLine(t *testing.T) {
+	tests := []struct {
+		name                      string
+		giftQuantity              float64
+		lineQuantity, lineTotal   float64
+		returned                  float64
+		wantOK                    bool
+		wantQuantity              float64
+		wantUnitValue, wantRefund float64
+	}{
+		{"nothing returned", 2, 3, 29.97, 0, true, 2, 9.99, 19.98},
+		{"returns beyond the gift quantity", 2, 3, 29.97, 1, true, 2, 9.99, 19.98},
+		{"returns eat into the gift quantity", 2, 3, 29.97, 2, true, 1, 9.99, 9.99},
+		{"everything returned", 2, 3, 29.97, 3, false, 0, 0, 0},
+		{"discounted line refunds what was paid", 1, 3, 25, 0, true, 1, 8.33, 8.33},
+		{"weighed line", 0.5, 0.75, 3, 0.5, true, 0.25, 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := GiftReceiptLine{Quantity: tt.giftQuantity}
+			ok := netGiftReceiptLine(&line, tt.lineQuantity, tt.lineTotal, tt.returned)
+			if ok != tt.wantOK {
+				t.Fatalf("netGiftReceiptLine() = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if line.Quantity != tt.wantQuantity || line.UnitRefundValue != tt.wantUnitValue ||
+				line.RefundableValue != tt.wantRefund {
+				t.Errorf("netGiftReceiptLine() quantity %v, unit value %v, refund %v, want %v, %v, %v", line.Quantity,
+					line.UnitRefundValue, line.RefundableValue, tt.wantQuantity, tt.wantUnitValue, tt.wantRefund)
+			}
+		})
+	}
+}
+
+func TestGiftReceiptDocument(t *testing.T) {
+	doc := &ReceiptDocument{
+		ReceiptNumber: "RCP-000042",
+		Customer:      &ReceiptCustomer{},
+		Payments:      []ReceiptPayment{{PaymentMethod: "cash"}},
+		Total:         39.96,
+		Lines: []ReceiptLine{
+			{ItemID: 1, Description: "Scarf", Quantity: 2, UnitPrice: 9.99, LineTotal: 19.98},
+			{ItemID: 2, Description: "Hat", Quantity: 1, UnitPrice: 19.98, LineTotal: 19.98},
+		},
+	}
+
+	gift := giftReceiptDocument(doc, map[int]float64{1: 1})
+	if gift.ReceiptType != "gift" || gift.Customer != nil || gift.Payments != nil || gift.Total != 0 {
+		t.Errorf("giftReceiptDocument() kept prices or the customer: %+v", gift)
+	}
+	want := []ReceiptLine{{ItemID: 1, Description: "Scarf", Quantity: 1}}
+	if !reflect.DeepEqual(gift.Lines, want) {
+		t.Errorf("giftReceiptDocument() lines = %+v, want %+v", gift.Lines, want)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	errReceiptLinkInvalid = errors.New("receipt link is not valid")
+	errReceiptLinkExpired = errors.New("receipt link has expired or was revoked")
+	errReceiptLinkNoKey   = errors.New("digital receipt links are disabled until POS_RECEIPT_LINK_SECRET is set")
+)
+
+// receiptLinkSecret returns the key public receipt links are signed with, from
+// POS_RECEIPT_LINK_SECRET; without it there are no links, so they never outlive a restart
+func receiptLinkSecret() ([]byte, bool) {
+	secret := os.Getenv("POS_RECEIPT_LINK_SECRET")
+	return []byte(secret), secret != ""
+}
+
+// receiptLinkSignature is the truncated HMAC-SHA256 of a receipt view token
+func receiptLinkSignature(secret []byte, viewToken string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("receipt-view:" + viewToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18])
+}
+
+// signReceiptViewToken builds the public token "<view token>.<signature>"
+func signReceiptViewToken(secret []byte, viewToken string) string {
+	return viewToken + "." + receiptLinkSignature(secret, viewToken)
+}
+
+// verifyReceiptLinkToken checks a public token's signature and returns the stored view token
+func verifyReceiptLinkToken(token string) (string, error) {
+	secret, ok := receiptLinkSecret()
+	if !ok {
+		return "", errReceiptLinkNoKey
+	}
+	viewToken, signature, ok := strings.Cut(token, ".")
+	if !ok || viewToken == "" {
+		return "", errReceiptLinkInvalid
+	}
+	if !hmac.Equal([]byte(signature), []byte(receiptLinkSignature(secret, viewToken))) {
+		return "", errReceiptLinkInvalid
+	}
+	return viewToken, nil
+}
+
+// ensureReceiptViewToken returns the receipt's signed public token. A missing, expired
+// or revoked token is replaced, which also invalidates any earlier link.
+func ensureReceiptViewToken(tx *sqlx.Tx, tenantID string, receiptID int) (string, *time.Time, error) {
+	secret, ok := receiptLinkSecret()
+	if !ok {
+		return "", nil, errReceiptLinkNoKey
+	}
+
+	var token *string
+	var expiresAt, revokedAt *time.Time
+	err := tx.QueryRow(`
+		SELECT view_token, view_token_expires_at, view_token_revoked_at
+		FROM pos_receipts WHERE id = $1 AND tenant_id = $2 FOR UPDATE
+	`, receiptID, tenantID).Scan(&token, &expiresAt, &revokedAt)
+	if err != nil {
+		return "", nil, err
+	}
+	if token != nil && revokedAt == nil && (expiresAt == nil || expiresAt.After(time.Now())) {
+		return signReceiptViewToken(secret, *token), expiresAt, nil
+	}
+
+	ttlDays := defaultSettings["receipt_link_ttl_days"].(int)
+	if _, err := loadTenantSetting(tx, tenantID, "receipt_link_ttl_days", &ttlDays); err != nil {
+		return "", nil, err
+	}
+	var newExpiry *time.Time
+	if ttlDays > 0 {
+		expiry := time.Now().AddDate(0, 0, ttlDays)
+		newExpiry = &expiry
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	newToken := base64.RawURLEncoding.EncodeToString(buf)
+
+	_, err = tx.Exec(`
+		UPDATE pos_receipts
+		SET view_token = $1, view_token_expires_at = $2, view_token_revoked_at = NULL
+		WHERE id = $3
+	`, newToken, newExpiry, receiptID)
+	if err != nil {
+		return "", nil, err
+	}
+	return signReceiptViewToken(secret, newToken), newExpiry, nil
+}
+
+// receiptViewURL builds the customer-facing link for a signed receipt token
+func receiptViewURL(token string) string {
+	base := strings.TrimSuffix(os.Getenv("POS_PUBLIC_BASE_URL"), "/")
+	return base + "/api/v1/pos/public/receipts/" + token
+}
+
+// publicReceiptDocument strips customer details and internal IDs from a receipt shown without login
+func publicReceiptDocument(doc *ReceiptDocument) *ReceiptDocument {
+	public := *doc
+	public.TransactionID = 0
+	public.Customer = nil
+	public.Register = ReceiptRegister{Code: doc.Register.Code, Name: doc.Register.Name}
+
+	public.Lines = make([]ReceiptLine, len(doc.Lines))
+	for i, line := range doc.Lines {
+		line.ItemID = 0
+		line.ProductID = 0
+		public.Lines[i] = line
+	}
+	return &public
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestVerifyReceiptLinkToken(t *testing.T) {
+	t.Setenv("POS_RECEIPT_LINK_SECRET", "secret")
+	secret, _ := receiptLinkSecret()
+	token := signReceiptViewToken(secret, "view-token")
+
+	if viewToken, err := verifyReceiptLinkToken(token); err != nil || viewToken != "view-token" {
+		t.Fatalf("verifyReceiptLinkToken(%q) = %q, %v, want view-token", token, viewToken, err)
+	}
+	for _, forged := range []string{
+		"view-token",
+		"view-token.",
+		"other-token" + token[len("view-token"):],
+		token + "x",
+		signReceiptViewToken([]byte("other secret"), "view-token"),
+	} {
+		if _, err := verifyReceiptLinkToken(forged); err != errReceiptLinkInvalid {
+			t.Errorf("verifyReceiptLinkToken(%q) error = %v, want %v", forged, err, errReceiptLinkInvalid)
+		}
+	}
+
+	// Links fail closed without a key instead of trusting a key that dies with the process
+	t.Setenv("POS_RECEIPT_LINK_SECRET", "")
+	if _, err := verifyReceiptLinkToken(token); err != errReceiptLinkNoKey {
+		t.Errorf("verifyReceiptLinkToken without a key error = %v, want %v", err, errReceiptLinkNoKey)
+	}
+}
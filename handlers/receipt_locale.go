@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// receiptLocale describes how numbers and dates are written in a locale
+type receiptLocale struct {
+	Language      string
+	Decimal       string
+	Group         string
+	DateFormat    string
+	SymbolAfter   bool // 12,50 € rather than €12.50
+	SymbolSpacing bool // a space between symbol and amount
+}
+
+// receiptLocales are the locales receipt templates can use
+var receiptLocales = map[string]receiptLocale{
+	"en-US": {Language: "en", Decimal: ".", Group: ",", DateFormat: "01/02/2006 15:04"},
+	"en-GB": {Language: "en", Decimal: ".", Group: ",", DateFormat: "02/01/2006 15:04"},
+	"en-CA": {Language: "en", Decimal: ".", Group: ",", DateFormat: "2006-01-02 15:04"},
+	"de-DE": {Language: "de", Decimal: ",", Group: ".", DateFormat: "02.01.2006 15:04", SymbolAfter: true, SymbolSpacing: true},
+	"de-AT": {Language: "de", Decimal: ",", Group: ".", DateFormat: "02.01.2006 15:04", SymbolAfter: true, SymbolSpacing: true},
+	"de-CH": {Language: "de", Decimal: ".", Group: "'", DateFormat: "02.01.2006 15:04", SymbolSpacing: true},
+	"fr-FR": {Language: "fr", Decimal: ",", Group: " ", DateFormat: "02/01/2006 15:04", SymbolAfter: true, SymbolSpacing: true},
+	"fr-CA": {Language: "fr", Decimal: ",", Group: " ", DateFormat: "2006-01-02 15:04", SymbolAfter: true, SymbolSpacing: true},
+	"es-ES": {Language: "es", Decimal: ",", Group: ".", DateFormat: "02/01/2006 15:04", SymbolAfter: true, SymbolSpacing: true},
+	"es-MX": {Language: "es", Decimal: ".", Group: ",", DateFormat: "02/01/2006 15:04"},
+	"it-IT": {Language: "it", Decimal: ",", Group: ".", DateFormat: "02/01/2006 15:04", SymbolAfter: true, SymbolSpacing: true},
+	"nl-NL": {Language: "nl", Decimal: ",", Group: ".", DateFormat: "02-01-2006 15:04", SymbolSpacing: true},
+	"pt-BR": {Language: "pt", Decimal: ",", Group: ".", DateFormat: "02/01/2006 15:04", SymbolSpacing: true},
+	"pt-PT": {Language: "pt", Decimal: ",", Group: " ", DateFormat: "02/01/2006 15:04", SymbolAfter: true, SymbolSpacing: true},
+}
+
+// legacyReceiptLocale keeps receipts without a template locale formatted as before
+var legacyReceiptLocale = receiptLocale{Language: "en", Decimal: ".", DateFormat: "2006-01-02 15:04"}
+
+// receiptCurrency is a currency's symbol and minor units
+type receiptCurrency struct {
+	Symbol   string
+	Decimals int
+}
+
+var receiptCurrencies = map[string]receiptCurrency{
+	"USD": {"$", 2}, "CAD": {"$", 2}, "MXN": {"$", 2}, "AUD": {"A$", 2}, "NZD": {"NZ$", 2},
+	"EUR": {"€", 2}, "GBP": {"£", 2}, "CHF": {"CHF", 2}, "BRL": {"R$", 2}, "JPY": {"¥", 0},
+	"SEK": {"kr", 2}, "NOK": {"kr", 2}, "DKK": {"kr", 2}, "PLN": {"zł", 2}, "CZK": {"Kč", 2},
+}
+
+// receiptLabels are the built-in receipt captions per language
+var receiptLabels = map[string]map[string]string{
+	"en": {
+		"receipt": "Receipt", "transaction": "Transaction", "date": "Date", "register": "Register",
+		"cashier": "Cashier", "customer": "Customer", "subtotal": "Subtotal", "discount": "Discount",
+		"tax": "Tax", "tip": "Tip", "total": "TOTAL", "change": "Change", "qty": "Qty",
+		"loyalty_balance": "Loyalty balance", "points": "pts", "return_policy": "Return policy",
+		"gift": "GIFT RECEIPT", "refund": "REFUND", "void": "VOID",
+		"serial": "S/N", "lot": "Lot",
+		"gift_notice": "Present this receipt to return or exchange these items",
+	},
+	"de": {
+		"receipt": "Beleg", "transaction": "Vorgang", "date": "Datum", "register": "Kasse",
+		"cashier": "Kassierer", "customer": "Kunde", "subtotal": "Zwischensumme", "discount": "Rabatt",
+		"tax": "MwSt.", "tip": "Trinkgeld", "total": "SUMME", "change": "Rückgeld", "qty": "Menge",
+		"loyalty_balance": "Bonuspunkte", "points": "Pkt.", "return_policy": "Rückgaberecht",
+		"gift": "GESCHENKBELEG", "refund": "ERSTATTUNG", "void": "STORNO",
+		"serial": "S/N", "lot": "Charge",
+		"gift_notice": "Mit diesem Beleg können Sie die Artikel umtauschen oder zurückgeben",
+	},
+	"fr": {
+		"receipt": "Ticket", "transaction": "Transaction", "date": "Date", "register": "Caisse",
+		"cashier": "Caissier", "customer": "Client", "subtotal": "Sous-total", "discount": "Remise",
+		"tax": "TVA", "tip": "Pourboire", "total": "TOTAL", "change": "Rendu", "qty": "Qté",
+		"loyalty_balance": "Points fidélité", "points": "pts", "return_policy": "Politique de retour",
+		"gift": "TICKET CADEAU", "refund": "REMBOURSEMENT", "void": "ANNULÉ",
+		"serial": "N° série", "lot": "Lot",
+		"gift_notice": "Présentez ce ticket pour échanger ou retourner ces articles",
+	},
+	"es": {
+		"receipt": "Ticket", "transaction": "Transacción", "date": "Fecha", "register": "Caja",
+		"cashier": "Cajero", "customer": "Cliente", "subtotal": "Subtotal", "discount": "Descuento",
+		"tax": "IVA", "tip": "Propina", "total": "TOTAL", "change": "Cambio", "qty": "Cant.",
+		"loyalty_balance": "Puntos de fidelidad", "points": "pts", "return_policy": "Política de devoluciones",
+		"gift": "TICKET REGALO", "refund": "DEVOLUCIÓN", "void": "ANULADO",
+		"serial": "N.º serie", "lot": "Lote",
+		"gift_notice": "Presente este ticket para cambiar o devolver estos artículos",
+	},
+	"it": {
+		"receipt": "Scontrino", "transaction": "Transazione", "date": "Data", "register": "Cassa",
+		"cashier": "Cassiere", "customer": "Cliente", "subtotal": "Subtotale", "discount": "Sconto",
+		"tax": "IVA", "tip": "Mancia", "total": "TOTALE", "change": "Resto", "qty": "Qtà",
+		"loyalty_balance": "Punti fedeltà", "points": "pti", "return_policy": "Politica di reso",
+		"gift": "SCONTRINO REGALO", "refund": "RIMBORSO", "void": "ANNULLATO",
+		"serial": "Matricola", "lot": "Lotto",
+		"gift_notice": "Presenta questo scontrino per cambiare o restituire gli articoli",
+	},
+	"nl": {
+		"receipt": "Bon", "transaction": "Transactie", "date": "Datum", "register": "Kassa",
+		"cashier": "Kassier", "customer": "Klant", "subtotal": "Subtotaal", "discount": "Korting",
+		"tax": "Btw", "tip": "Fooi", "total": "TOTAAL", "change": "Wisselgeld", "qty": "Aantal",
+		"loyalty_balance": "Spaarpunten", "points": "ptn", "return_policy": "Retourbeleid",
+		"gift": "CADEAUBON", "refund": "TERUGBETALING", "void": "GEANNULEERD",
+		"serial": "Serienr.", "lot": "Partij",
+		"gift_notice": "Toon deze bon om deze artikelen te ruilen of te retourneren",
+	},
+	"pt": {
+		"receipt": "Recibo", "transaction": "Transação", "date": "Data", "register": "Caixa",
+		"cashier": "Operador", "customer": "Cliente", "subtotal": "Subtotal", "discount": "Desconto",
+		"tax": "Imposto", "tip": "Gorjeta", "total": "TOTAL", "change": "Troco", "qty": "Qtd",
+		"loyalty_balance": "Pontos de fidelidade", "points": "pts", "return_policy": "Política de devolução",
+		"gift": "RECIBO PRESENTE", "refund": "REEMBOLSO", "void": "ANULADO",
+		"serial": "N.º série", "lot": "Lote",
+		"gift_notice": "Apresente este recibo para trocar ou devolver estes itens",
+	},
+}
+
+// receiptFormatter formats amounts, dates and captions for a receipt's template
+type receiptFormatter struct {
+	locale       receiptLocale
+	currency     string
+	secondary    *receiptLocale
+	translations map[string]map[string]string
+	primaryCode  string
+	secondCode   string
+}
+
+// newReceiptFormatter builds the formatter for a template; unknown or empty locales use the legacy format
+func newReceiptFormatter(tmpl ReceiptTemplate) receiptFormatter {
+	f := receiptFormatter{
+		locale:       legacyReceiptLocale,
+		currency:     tmpl.Currency,
+		translations: tmpl.Translations,
+		primaryCode:  tmpl.Locale,
+		secondCode:   tmpl.SecondaryLocale,
+	}
+	if locale, ok := receiptLocales[tmpl.Locale]; ok {
+		f.locale = locale
+	}
+	if locale, ok := receiptLocales[tmpl.SecondaryLocale]; ok && locale.Language != f.locale.Language {
+		f.secondary = &locale
+	}
+	return f
+}
+
+// labelIn looks up a caption in one locale: template translations first, then the built-in labels
+func (f receiptFormatter) labelIn(localeCode, language, key string) string {
+	if text, ok := f.translations[localeCode][key]; ok && text != "" {
+		return text
+	}
+	if text, ok := receiptLabels[language][key]; ok {
+		return text
+	}
+	return receiptLabels["en"][key]
+}
+
+// label returns a caption, followed by its translation on bilingual receipts ("TOTAL / SUMME")
+func (f receiptFormatter) label(key string) string {
+	primary := f.labelIn(f.primaryCode, f.locale.Language, key)
+	if f.secondary == nil {
+		return primary
+	}
+	if second := f.labelIn(f.secondCode, f.secondary.Language, key); second != primary {
+		return primary + " / " + second
+	}
+	return primary
+}
+
+// secondaryText returns a template text (header, footer, return_policy) in the second language, if translated
+func (f receiptFormatter) secondaryText(key string) string {
+	if f.secondary == nil {
+		return ""
+	}
+	return f.translations[f.secondCode][key]
+}
+
+// amount formats a number with the locale's separators and the currency's minor units
+func (f receiptFormatter) amount(value float64) string {
+	decimals := 2
+	if currency, ok := receiptCurrencies[f.currency]; ok {
+		decimals = currency.Decimals
+	}
+	return formatLocaleNumber(value, decimals, f.locale.Decimal, f.locale.Group)
+}
+
+// money formats an amount with the currency symbol placed for the locale
+func (f receiptFormatter) money(value float64) string {
+	if f.currency == "" {
+		return f.amount(value)
+	}
+	symbol := f.currency
+	if currency, ok := receiptCurrencies[f.currency]; ok {
+		symbol = currency.Symbol
+	}
+
+	number := f.amount(value)
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+
+	space := ""
+	if f.locale.SymbolSpacing {
+		space = " "
+	}
+	if f.locale.SymbolAfter {
+		return sign + number + space + symbol
+	}
+	return sign + symbol + space + number
+}
+
+// quantity formats a line quantity with the locale's separators, showing only the decimals it has
+func (f receiptFormatter) quantity(quantity float64, unit string) string {
+	text := strconv.FormatFloat(math.Round(quantity*1000)/1000, 'f', -1, 64)
+	_, fraction, _ := strings.Cut(text, ".")
+	text = formatLocaleNumber(quantity, len(fraction), f.locale.Decimal, f.locale.Group)
+	if unit != "" && unit != defaultUnitCode {
+		text += " " + unit
+	}
+	return text
+}
+
+// percent formats a tax rate
+func (f receiptFormatter) percent(rate float64) string {
+	return formatLocaleNumber(rate, 2, f.locale.Decimal, f.locale.Group) + "%"
+}
+
+// date formats a timestamp in the locale's date format
+func (f receiptFormatter) date(t time.Time) string {
+	return t.Format(f.locale.DateFormat)
+}
+
+// formatLocaleNumber formats value with fixed decimals and the given separators
+func formatLocaleNumber(value float64, decimals int, decimalSep, groupSep string) string {
+	s := fmt.Sprintf("%.*f", decimals, math.Abs(value))
+	whole, fraction, _ := strings.Cut(s, ".")
+
+	if groupSep != "" && len(whole) > 3 {
+		var b strings.Builder
+		lead := len(whole) % 3
+		if lead > 0 {
+			b.WriteString(whole[:lead])
+		}
+		for i := lead; i < len(whole); i += 3 {
+			if b.Len() > 0 {
+				b.WriteString(groupSep)
+			}
+			b.WriteString(whole[i : i+3])
+		}
+		whole = b.String()
+	}
+
+	out := whole
+	if fraction != "" {
+		out += decimalSep + fraction
+	}
+	if value < 0 && strings.Trim(s, "0.") != "" {
+		out = "-" + out
+	}
+	return out
+}
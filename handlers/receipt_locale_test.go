@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestFormatLocaleNumber(t *testing.T) {
+	tests := []struct {
+		value      float64
+		decimals   int
+		decimalSep string
+		groupSep   string
+		want       string
+	}{
+		{1234.5, 2, ".", ",", "1,234.50"},
+		{1234567.891, 2, ",", ".", "1.234.567,89"},
+		{1234567, 0, ",", " ", "1 234 567"},
+		{123456, 2, ".", ",", "123,456.00"},
+		{999.999, 2, ".", ",", "1,000.00"},
+		{12345.678, 3, ",", "", "12345,678"},
+		{0.5, 2, ".", ",", "0.50"},
+		{-1234.5, 2, ".", ",", "-1,234.50"},
+		{-0.001, 2, ".", ",", "0.00"},
+		{-7, 0, ".", ",", "-7"},
+		{100, 0, ".", "'", "100"},
+	}
+	for _, tt := range tests {
+		got := formatLocaleNumber(tt.value, tt.decimals, tt.decimalSep, tt.groupSep)
+		if got != tt.want {
+			t.Errorf("formatLocaleNumber(%v, %d, %q, %q) = %q, want %q", tt.value, tt.decimals, tt.decimalSep,
+				tt.groupSep, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,706 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultReceiptWidth is the column count of an 80mm slip in the printer's standard font
+const defaultReceiptWidth = 48
+
+// receiptWidthForPaper maps a paper width in millimetres to printable columns
+func receiptWidthForPaper(paperWidth int) int {
+	if paperWidth == 58 {
+		return 32
+	}
+	return defaultReceiptWidth
+}
+
+// registerPaperWidth returns the register's receipt_paper_width (mm) from its metadata,
+// falling back to the tenant's receipt_paper_width setting
+func registerPaperWidth(q rowQuerier, tenantID string, registerID int) (int, error) {
+	var paperWidth int
+	err := q.QueryRow(`
+		SELECT CASE WHEN metadata->>'receipt_paper_width' ~ '^[0-9]+$'
+		            THEN (metadata->>'receipt_paper_width')::int ELSE 0 END
+		FROM pos_registers WHERE id = $1 AND tenant_id = $2
+	`, registerID, tenantID).Scan(&paperWidth)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if paperWidth == 58 || paperWidth == 80 {
+		return paperWidth, nil
+	}
+
+	paperWidth = defaultSettings["receipt_paper_width"].(int)
+	if _, err := loadTenantSetting(q, tenantID, "receipt_paper_width", &paperWidth); err != nil {
+		return 0, err
+	}
+	return paperWidth, nil
+}
+
+var (
+	errReceiptTransactionNotFound = errors.New("transaction not found")
+	errReceiptTransactionNotFinal = errors.New("receipts can only be issued for completed, refunded or voided transactions")
+)
+
+// receiptTypeFor maps a transaction type to the receipt type issued for it
+func receiptTypeFor(transactionType string) string {
+	switch transactionType {
+	case "return":
+		return "refund"
+	case "void":
+		return "void"
+	default:
+		return "sale"
+	}
+}
+
+// loadReceiptTemplate reads the tenant's receipt header, footer, logo and legal text
+func loadReceiptTemplate(q rowQuerier, tenantID string) (ReceiptTemplate, error) {
+	tmpl := ReceiptTemplate{
+		Header:    defaultSettings["receipt_header"].(string),
+		Footer:    defaultSettings["receipt_footer"].(string),
+		LogoURL:   defaultSettings["receipt_logo_url"].(string),
+		LegalText: defaultSettings["receipt_legal_text"].(string),
+	}
+
+	fields := map[string]*string{
+		"receipt_header":     &tmpl.Header,
+		"receipt_footer":     &tmpl.Footer,
+		"receipt_logo_url":   &tmpl.LogoURL,
+		"receipt_legal_text": &tmpl.LegalText,
+	}
+	for key, dest := range fields {
+		if _, err := loadTenantSetting(q, tenantID, key, dest); err != nil {
+			return tmpl, err
+		}
+	}
+
+	return tmpl, nil
+}
+
+// sections returns the template's optional sections; receipts issued without them show the tax summary only
+func (t ReceiptTemplate) sections() ReceiptSections {
+	if t.Sections == nil {
+		return ReceiptSections{TaxSummary: true}
+	}
+	return *t.Sections
+}
+
+// resolveReceiptTemplate picks the most specific receipt template for a register: the register's own,
+// then its location's, then the tenant default. Without any, the receipt_* settings are used.
+func resolveReceiptTemplate(q rowQuerier, tenantID string, register ReceiptRegister) (ReceiptTemplate, error) {
+	var tmpl ReceiptTemplate
+	var templateID int
+	var secondaryLocale sql.NullString
+	var sections, translations []byte
+	err := q.QueryRow(`
+		SELECT id, locale, secondary_locale, currency, header, footer, logo_url, legal_text, return_policy,
+		       sections, translations
+		FROM pos_receipt_templates
+		WHERE tenant_id = $1
+		  AND (register_id = $2
+		       OR (register_id IS NULL AND location_id = $3)
+		       OR (register_id IS NULL AND location_id IS NULL))
+		ORDER BY register_id IS NULL, location_id IS NULL
+		LIMIT 1
+	`, tenantID, register.ID, register.LocationID).Scan(&templateID, &tmpl.Locale, &secondaryLocale, &tmpl.Currency,
+		&tmpl.Header, &tmpl.Footer, &tmpl.LogoURL, &tmpl.LegalText, &tmpl.ReturnPolicy, &sections, &translations)
+	if err == sql.ErrNoRows {
+		return loadReceiptTemplate(q, tenantID)
+	}
+	if err != nil {
+		return tmpl, err
+	}
+
+	tmpl.TemplateID = &templateID
+	tmpl.SecondaryLocale = secondaryLocale.String
+	tmpl.Sections = &ReceiptSections{}
+	if err := json.Unmarshal(sections, tmpl.Sections); err != nil {
+		return tmpl, err
+	}
+	if err := json.Unmarshal(translations, &tmpl.Translations); err != nil {
+		return tmpl, err
+	}
+	return tmpl, nil
+}
+
+// buildReceiptDocument assembles the canonical receipt from the recorded transaction,
+// its items, payments, customer and register
+func buildReceiptDocument(q reportQuerier, tenantID string, transactionID int) (*ReceiptDocument, error) {
+	doc := &ReceiptDocument{TransactionID: transactionID}
+
+	var status string
+	var customerID sql.NullInt64
+	var customerFirst, customerLast, companyName, email, phone sql.NullString
+	var loyaltyPoints sql.NullInt64
+	var cashierFirst, cashierLast sql.NullString
+	err := q.QueryRow(`
+		SELECT pt.transaction_number, pt.transaction_type, pt.status, pt.transaction_date,
+		       pt.subtotal, pt.discount_amount, pt.tax_amount, pt.tip_amount, pt.total_amount, pt.change_amount,
+		       pr.id, pr.code, pr.name, pr.location_id,
+		       pt.customer_id, c.first_name, c.last_name, c.company_name, c.email, c.phone, pc.loyalty_points,
+		       u.first_name, u.last_name
+		FROM pos_transactions pt
+		JOIN pos_registers pr ON pr.id = pt.register_id
+		LEFT JOIN customers c ON pt.customer_id = c.id
+		LEFT JOIN pos_customers pc ON pc.customer_id = pt.customer_id
+		LEFT JOIN users u ON pt.cashier_id = u.id
+		WHERE pt.id = $1 AND pt.tenant_id = $2
+	`, transactionID, tenantID).Scan(
+		&doc.TransactionNumber, &doc.TransactionType, &status, &doc.TransactionDate,
+		&doc.Subtotal, &doc.DiscountTotal, &doc.TaxTotal, &doc.TipAmount, &doc.Total, &doc.ChangeAmount,
+		&doc.Register.ID, &doc.Register.Code, &doc.Register.Name, &doc.Register.LocationID,
+		&customerID, &customerFirst, &customerLast, &companyName, &email, &phone, &loyaltyPoints,
+		&cashierFirst, &cashierLast,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errReceiptTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case "completed", "refunded", "void":
+	default:
+		return nil, errReceiptTransactionNotFinal
+	}
+
+	doc.ReceiptType = receiptTypeFor(doc.TransactionType)
+	doc.Cashier = strings.TrimSpace(cashierFirst.String + " " + cashierLast.String)
+
+	if customerID.Valid {
+		customer := &ReceiptCustomer{
+			ID:   int(customerID.Int64),
+			Name: strings.TrimSpace(customerFirst.String + " " + customerLast.String),
+		}
+		if companyName.Valid && companyName.String != "" {
+			customer.CompanyName = &companyName.String
+		}
+		if email.Valid && email.String != "" {
+			customer.Email = &email.String
+		}
+		if phone.Valid && phone.String != "" {
+			customer.Phone = &phone.String
+		}
+		if loyaltyPoints.Valid {
+			points := int(loyaltyPoints.Int64)
+			customer.LoyaltyPoints = &points
+		}
+		doc.Customer = customer
+	}
+
+	// Item lines
+	itemRows, err := q.Query(`
+		SELECT pti.id, pti.product_id, COALESCE(v.sku, p.sku, ''), COALESCE(p.name, ''), COALESCE(v.name, ''),
+		       pti.quantity, COALESCE(pti.unit_of_measure, ''), pti.unit_price, pti.discount_amount, pti.tax_rate,
+		       pti.tax_amount, pti.line_total
+		FROM pos_transaction_items pti
+		LEFT JOIN products p ON pti.product_id = p.id
+		LEFT JOIN pos_product_variants v ON v.id = pti.variant_id
+		WHERE pti.transaction_id = $1
+		ORDER BY pti.id
+	`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var line ReceiptLine
+		err := itemRows.Scan(&line.ItemID, &line.ProductID, &line.SKU, &line.Description, &line.Variant, &line.Quantity,
+			&line.UnitOfMeasure, &line.UnitPrice, &line.DiscountAmount, &line.TaxRate, &line.TaxAmount, &line.LineTotal)
+		if err != nil {
+			return nil, err
+		}
+		if line.Variant != "" {
+			line.Description += " - " + line.Variant
+		}
+		doc.Lines = append(doc.Lines, line)
+	}
+	if err := itemRows.Err(); err != nil {
+		return nil, err
+	}
+
+	modifiers, err := loadItemModifiers(q, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	components, err := loadItemComponents(q, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	serials, lots, err := loadItemTracking(q, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Modifiers and kit components are taxed at their own rate, so their share of a line is
+	// summarized under it
+	taxByRate := make(map[float64]*ReportTaxLine)
+	addTax := func(rate, taxable, amount float64) {
+		tax, ok := taxByRate[rate]
+		if !ok {
+			tax = &ReportTaxLine{Rate: rate}
+			taxByRate[rate] = tax
+		}
+		tax.TaxableAmount += taxable
+		tax.TaxAmount += amount
+	}
+	for i := range doc.Lines {
+		line := &doc.Lines[i]
+		taxable := line.Quantity*line.UnitPrice - line.DiscountAmount
+		taxAmount := line.TaxAmount
+		for _, m := range modifiers[line.ItemID] {
+			line.Modifiers = append(line.Modifiers, ReceiptModifier{
+				Name:      m.Name,
+				Quantity:  m.Quantity,
+				Price:     m.Price,
+				Amount:    m.Amount,
+				TaxRate:   m.TaxRate,
+				TaxAmount: m.TaxAmount,
+			})
+			addTax(m.TaxRate, m.Amount, m.TaxAmount)
+			taxable -= m.Amount
+			taxAmount -= m.TaxAmount
+		}
+		for _, c := range components[line.ItemID] {
+			line.Components = append(line.Components, ReceiptComponent{
+				Description: c.Name,
+				Quantity:    c.Quantity,
+				Amount:      c.Amount,
+				TaxRate:     c.TaxRate,
+				TaxAmount:   c.TaxAmount,
+			})
+			addTax(c.TaxRate, c.Amount, c.TaxAmount)
+			taxable -= c.Amount
+			taxAmount -= c.TaxAmount
+		}
+		if len(line.Components) == 0 {
+			addTax(line.TaxRate, taxable, taxAmount)
+		}
+
+		line.SerialNumbers = serials[line.ItemID]
+		for _, lot := range lots[line.ItemID] {
+			line.LotNumbers = append(line.LotNumbers, lot.LotNumber)
+		}
+	}
+
+	for _, tax := range taxByRate {
+		doc.TaxSummary = append(doc.TaxSummary, *tax)
+	}
+	sort.Slice(doc.TaxSummary, func(i, j int) bool { return doc.TaxSummary[i].Rate < doc.TaxSummary[j].Rate })
+
+	// Tenders
+	paymentRows, err := q.Query(`
+		SELECT payment_method, amount, card_type, reference_number
+		FROM pos_payments
+		WHERE transaction_id = $1 AND status IN ('completed', 'refunded')
+		ORDER BY id
+	`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer paymentRows.Close()
+
+	for paymentRows.Next() {
+		var payment ReceiptPayment
+		var reference sql.NullString
+		if err := paymentRows.Scan(&payment.PaymentMethod, &payment.Amount, &payment.CardType, &reference); err != nil {
+			return nil, err
+		}
+		if reference.Valid && reference.String != "" {
+			masked := maskPaymentReference(payment.PaymentMethod, reference.String)
+			payment.Reference = &masked
+		}
+		doc.AmountPaid += payment.Amount
+		doc.Payments = append(doc.Payments, payment)
+	}
+	if err := paymentRows.Err(); err != nil {
+		return nil, err
+	}
+
+	doc.Template, err = resolveReceiptTemplate(q, tenantID, doc.Register)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// newReceiptDocument builds a receipt and stamps it with its number and issue time
+func newReceiptDocument(q reportQuerier, tenantID string, transactionID int, receiptNumber string) (*ReceiptDocument, error) {
+	doc, err := buildReceiptDocument(q, tenantID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	doc.ReceiptNumber = receiptNumber
+	doc.IssuedAt = time.Now()
+	return doc, nil
+}
+
+// maskPaymentReference keeps only the last four characters of a card reference
+func maskPaymentReference(paymentMethod, reference string) string {
+	if paymentMethod != "card" || len(reference) <= 4 {
+		return reference
+	}
+	return "****" + reference[len(reference)-4:]
+}
+
+// =================================================================
+// TEXT RENDERING
+// =================================================================
+
+// receiptTextLine is one laid-out line of a receipt with its print emphasis
+type receiptTextLine struct {
+	Text string
+	Bold bool
+	Tall bool // double height
+}
+
+// layoutReceipt lays the receipt out as fixed-width lines shared by the text and ESC/POS renderers
+func layoutReceipt(doc *ReceiptDocument, width int) []receiptTextLine {
+	if width <= 0 {
+		width = defaultReceiptWidth
+	}
+
+	f := newReceiptFormatter(doc.Template)
+	sections := doc.Template.sections()
+
+	var lines []receiptTextLine
+	add := func(text string) { lines = append(lines, receiptTextLine{Text: text}) }
+	rule := strings.Repeat("-", width)
+
+	for i, line := range wrapReceiptText(doc.Template.Header, width) {
+		lines = append(lines, receiptTextLine{Text: centerReceiptText(line, width), Bold: i == 0})
+	}
+	for _, line := range wrapReceiptText(f.secondaryText("header"), width) {
+		add(centerReceiptText(line, width))
+	}
+	if doc.ReceiptType != "sale" {
+		lines = append(lines, receiptTextLine{Text: centerReceiptText(f.label(doc.ReceiptType), width), Bold: true})
+	}
+	add(rule)
+
+	add(receiptColumns(f.label("receipt"), doc.ReceiptNumber, width))
+	add(receiptColumns(f.label("transaction"), doc.TransactionNumber, width))
+	add(receiptColumns(f.label("date"), f.date(doc.TransactionDate), width))
+	add(receiptColumns(f.label("register"), doc.Register.Code, width))
+	if doc.Cashier != "" {
+		add(receiptColumns(f.label("cashier"), doc.Cashier, width))
+	}
+	if doc.Customer != nil {
+		name := doc.Customer.Name
+		if doc.Customer.CompanyName != nil {
+			name = *doc.Customer.CompanyName
+		}
+		add(receiptColumns(f.label("customer"), name, width))
+	}
+	add(rule)
+
+	// Gift receipts list items and quantities only
+	if doc.ReceiptType == "gift" {
+		for _, line := range doc.Lines {
+			add(truncateReceiptText(line.Description, width))
+			add(fmt.Sprintf("  %s %s", f.label("qty"), f.quantity(line.Quantity, line.UnitOfMeasure)))
+		}
+		add(rule)
+		for _, line := range wrapReceiptText(f.label("gift_notice"), width) {
+			add(centerReceiptText(line, width))
+		}
+		return append(lines, layoutReceiptFooter(doc, f, sections, width)...)
+	}
+
+	for _, line := range doc.Lines {
+		add(truncateReceiptText(line.Description, width))
+		for _, m := range line.Modifiers {
+			add(truncateReceiptText("  "+receiptModifierText(m, f), width))
+		}
+		for _, c := range line.Components {
+			add(truncateReceiptText("  "+f.quantity(c.Quantity, "")+" x "+c.Description, width))
+		}
+		for _, serial := range line.SerialNumbers {
+			add(truncateReceiptText("  "+f.label("serial")+" "+serial, width))
+		}
+		for _, lot := range line.LotNumbers {
+			add(truncateReceiptText("  "+f.label("lot")+" "+lot, width))
+		}
+		qty := fmt.Sprintf("  %s x %s", f.quantity(line.Quantity, line.UnitOfMeasure), f.amount(line.UnitPrice))
+		add(receiptColumns(qty, f.amount(line.Quantity*line.UnitPrice), width))
+		if line.DiscountAmount != 0 {
+			add(receiptColumns("  "+f.label("discount"), f.amount(-line.DiscountAmount), width))
+		}
+	}
+	add(rule)
+
+	add(receiptColumns(f.label("subtotal"), f.amount(doc.Subtotal), width))
+	if doc.DiscountTotal != 0 {
+		add(receiptColumns(f.label("discount"), f.amount(-doc.DiscountTotal), width))
+	}
+	if sections.TaxSummary {
+		for _, tax := range doc.TaxSummary {
+			if tax.TaxAmount == 0 {
+				continue
+			}
+			add(receiptColumns(f.label("tax")+" "+f.percent(tax.Rate), f.amount(tax.TaxAmount), width))
+		}
+	} else if doc.TaxTotal != 0 {
+		add(receiptColumns(f.label("tax"), f.amount(doc.TaxTotal), width))
+	}
+	if doc.TipAmount != 0 {
+		add(receiptColumns(f.label("tip"), f.amount(doc.TipAmount), width))
+	}
+	lines = append(lines, receiptTextLine{Text: receiptColumns(f.label("total"), f.money(doc.Total), width), Bold: true, Tall: true})
+	add(rule)
+
+	for _, payment := range doc.Payments {
+		label := strings.ToUpper(strings.ReplaceAll(payment.PaymentMethod, "_", " "))
+		if payment.Reference != nil {
+			label += " " + *payment.Reference
+		}
+		add(receiptColumns(label, f.amount(payment.Amount), width))
+	}
+	if doc.ChangeAmount != 0 {
+		add(receiptColumns(f.label("change"), f.amount(doc.ChangeAmount), width))
+	}
+
+	if sections.LoyaltyBalance && doc.Customer != nil && doc.Customer.LoyaltyPoints != nil {
+		add(rule)
+		add(receiptColumns(f.label("loyalty_balance"), fmt.Sprintf("%d %s", *doc.Customer.LoyaltyPoints, f.label("points")), width))
+	}
+
+	return append(lines, layoutReceiptFooter(doc, f, sections, width)...)
+}
+
+// layoutReceiptFooter lays out the template footer, return policy and legal text
+func layoutReceiptFooter(doc *ReceiptDocument, f receiptFormatter, sections ReceiptSections, width int) []receiptTextLine {
+	var lines []receiptTextLine
+	add := func(text string) { lines = append(lines, receiptTextLine{Text: text}) }
+
+	showPolicy := sections.ReturnPolicy && doc.Template.ReturnPolicy != ""
+	if doc.Template.Footer != "" || doc.Template.LegalText != "" || showPolicy {
+		add(strings.Repeat("-", width))
+	}
+	for _, line := range wrapReceiptText(doc.Template.Footer, width) {
+		add(centerReceiptText(line, width))
+	}
+	for _, line := range wrapReceiptText(f.secondaryText("footer"), width) {
+		add(centerReceiptText(line, width))
+	}
+	if showPolicy {
+		lines = append(lines, receiptTextLine{Text: f.label("return_policy"), Bold: true})
+		for _, line := range wrapReceiptText(doc.Template.ReturnPolicy, width) {
+			add(line)
+		}
+		for _, line := range wrapReceiptText(f.secondaryText("return_policy"), width) {
+			add(line)
+		}
+	}
+	for _, line := range wrapReceiptText(doc.Template.LegalText, width) {
+		add(line)
+	}
+	return lines
+}
+
+// renderReceiptText renders the receipt as fixed-width plain text
+func renderReceiptText(doc *ReceiptDocument, width int) string {
+	var b strings.Builder
+	for _, line := range layoutReceipt(doc, width) {
+		b.WriteString(line.Text + "\n")
+	}
+	return b.String()
+}
+
+// formatReceiptMoney formats an amount with two decimals
+func formatReceiptMoney(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// formatReceiptQuantity prints a quantity with only the decimals it has, followed by its unit
+// unless it is sold each: "2", "0.734 kg"
+func formatReceiptQuantity(quantity float64, unit string) string {
+	text := strconv.FormatFloat(math.Round(quantity*1000)/1000, 'f', -1, 64)
+	if unit != "" && unit != defaultUnitCode {
+		text += " " + unit
+	}
+	return text
+}
+
+// receiptColumns places left and right on one line, truncating the left side to fit
+func receiptColumns(left, right string, width int) string {
+	rightLen := utf8.RuneCountInString(right)
+	space := width - rightLen - 1
+	if space < 1 {
+		return truncateReceiptText(right, width)
+	}
+	left = truncateReceiptText(left, space)
+	return left + strings.Repeat(" ", width-utf8.RuneCountInString(left)-rightLen) + right
+}
+
+// centerReceiptText centers a line within the receipt width
+func centerReceiptText(text string, width int) string {
+	text = truncateReceiptText(text, width)
+	return strings.Repeat(" ", (width-utf8.RuneCountInString(text))/2) + text
+}
+
+// receiptModifierText describes a modifier under its item line, e.g. "+ 2 x Extra shot (0.50)".
+// The price is per unit of the item and is already part of the item's unit price.
+func receiptModifierText(m ReceiptModifier, f receiptFormatter) string {
+	text := "+ " + m.Name
+	if m.Quantity > 1 {
+		text = fmt.Sprintf("+ %d x %s", m.Quantity, m.Name)
+	}
+	if m.Price != 0 {
+		text += " (" + f.amount(m.Price) + ")"
+	}
+	return text
+}
+
+// truncateReceiptText cuts text to at most width characters
+func truncateReceiptText(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	return string([]rune(text)[:width])
+}
+
+// wrapReceiptText splits text on newlines and word-wraps each line to the receipt width
+func wrapReceiptText(text string, width int) []string {
+	if text == "" {
+		return nil
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		current := ""
+		for _, word := range words {
+			word = truncateReceiptText(word, width)
+			if current == "" {
+				current = word
+			} else if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= width {
+				current += " " + word
+			} else {
+				lines = append(lines, current)
+				current = word
+			}
+		}
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// =================================================================
+// HTML RENDERING
+// =================================================================
+
+// receiptHTMLFuncs are placeholders replaced per render with the receipt's formatter
+var receiptHTMLFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lines": func(text string) []string {
+		if text == "" {
+			return nil
+		}
+		return strings.Split(text, "\n")
+	},
+	"label":     func(key string) string { return key },
+	"secondary": func(key string) string { return "" },
+	"amount":    formatReceiptMoney,
+	"money":     formatReceiptMoney,
+	"percent":   func(rate float64) string { return fmt.Sprintf("%.2f%%", rate) },
+	"date":      func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"modifier":  func(m ReceiptModifier) string { return m.Name },
+	"quantity":  formatReceiptQuantity,
+}
+
+var receiptHTMLTemplate = template.Must(template.New("receipt").Funcs(receiptHTMLFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{label "receipt"}} {{.ReceiptNumber}}</title>
+</head>
+<body style="font-family: Arial, sans-serif; max-width: 480px; margin: 0 auto; color: #222;">
+{{with .Template.LogoURL}}<p style="text-align: center;"><img src="{{.}}" alt="" style="max-width: 200px;"></p>{{end}}
+<div style="text-align: center;">
+{{range lines .Template.Header}}<div>{{.}}</div>{{end}}
+{{range lines (secondary "header")}}<div>{{.}}</div>{{end}}
+{{if ne .ReceiptType "sale"}}<h3>{{label .ReceiptType}}</h3>{{end}}
+</div>
+<table style="width: 100%; font-size: 13px; margin-top: 12px;">
+<tr><td>{{label "receipt"}}</td><td style="text-align: right;">{{.ReceiptNumber}}</td></tr>
+<tr><td>{{label "transaction"}}</td><td style="text-align: right;">{{.TransactionNumber}}</td></tr>
+<tr><td>{{label "date"}}</td><td style="text-align: right;">{{date .TransactionDate}}</td></tr>
+<tr><td>{{label "register"}}</td><td style="text-align: right;">{{.Register.Code}}</td></tr>
+{{with .Cashier}}<tr><td>{{label "cashier"}}</td><td style="text-align: right;">{{.}}</td></tr>{{end}}
+{{with .Customer}}<tr><td>{{label "customer"}}</td><td style="text-align: right;">{{if .CompanyName}}{{.CompanyName}}{{else}}{{.Name}}{{end}}</td></tr>{{end}}
+</table>
+<table style="width: 100%; font-size: 13px; margin-top: 12px; border-top: 1px solid #ccc; border-bottom: 1px solid #ccc;">
+{{if eq .ReceiptType "gift"}}{{range .Lines}}<tr><td>{{.Description}}</td><td style="text-align: right;">{{label "qty"}} {{quantity .Quantity .UnitOfMeasure}}</td></tr>
+{{end}}</table>
+<p style="text-align: center; font-size: 13px;">{{label "gift_notice"}}</p>
+{{else}}{{range .Lines}}<tr><td>{{.Description}}{{range .Modifiers}}<br><small>{{modifier .}}</small>{{end}}{{range .Components}}<br><small>{{quantity .Quantity ""}} x {{.Description}}</small>{{end}}{{range .SerialNumbers}}<br><small>{{label "serial"}} {{.}}</small>{{end}}{{range .LotNumbers}}<br><small>{{label "lot"}} {{.}}</small>{{end}}<br><small>{{quantity .Quantity .UnitOfMeasure}} x {{amount .UnitPrice}}{{if .DiscountAmount}} &minus; {{amount .DiscountAmount}}{{end}}</small></td><td style="text-align: right; vertical-align: top;">{{amount .LineTotal}}</td></tr>
+{{end}}</table>
+<table style="width: 100%; font-size: 13px; margin-top: 12px;">
+<tr><td>{{label "subtotal"}}</td><td style="text-align: right;">{{amount .Subtotal}}</td></tr>
+{{if .DiscountTotal}}<tr><td>{{label "discount"}}</td><td style="text-align: right;">-{{amount .DiscountTotal}}</td></tr>{{end}}
+{{if .Sections.TaxSummary}}{{range .TaxSummary}}{{if .TaxAmount}}<tr><td>{{label "tax"}} {{percent .Rate}}</td><td style="text-align: right;">{{amount .TaxAmount}}</td></tr>{{end}}{{end}}{{else if .TaxTotal}}<tr><td>{{label "tax"}}</td><td style="text-align: right;">{{amount .TaxTotal}}</td></tr>{{end}}
+{{if .TipAmount}}<tr><td>{{label "tip"}}</td><td style="text-align: right;">{{amount .TipAmount}}</td></tr>{{end}}
+<tr><td><strong>{{label "total"}}</strong></td><td style="text-align: right;"><strong>{{money .Total}}</strong></td></tr>
+{{range .Payments}}<tr><td>{{upper .PaymentMethod}}{{with .Reference}} {{.}}{{end}}</td><td style="text-align: right;">{{amount .Amount}}</td></tr>{{end}}
+{{if .ChangeAmount}}<tr><td>{{label "change"}}</td><td style="text-align: right;">{{amount .ChangeAmount}}</td></tr>{{end}}
+{{if .Sections.LoyaltyBalance}}{{with .Customer}}{{with .LoyaltyPoints}}<tr><td>{{label "loyalty_balance"}}</td><td style="text-align: right;">{{.}} {{label "points"}}</td></tr>{{end}}{{end}}{{end}}
+</table>
+{{end}}<div style="text-align: center; margin-top: 16px;">
+{{range lines .Template.Footer}}<div>{{.}}</div>{{end}}
+{{range lines (secondary "footer")}}<div>{{.}}</div>{{end}}
+</div>
+{{if and .Sections.ReturnPolicy .Template.ReturnPolicy}}<p style="font-size: 12px;"><strong>{{label "return_policy"}}</strong><br>{{.Template.ReturnPolicy}}{{with secondary "return_policy"}}<br>{{.}}{{end}}</p>{{end}}
+{{with .Template.LegalText}}<p style="font-size: 11px; color: #666;">{{.}}</p>{{end}}
+</body>
+</html>
+`))
+
+// renderReceiptHTML renders the receipt as a standalone HTML page in the template's locale
+func renderReceiptHTML(doc *ReceiptDocument) (string, error) {
+	f := newReceiptFormatter(doc.Template)
+	tmpl, err := receiptHTMLTemplate.Clone()
+	if err != nil {
+		return "", err
+	}
+	tmpl.Funcs(template.FuncMap{
+		"label":     f.label,
+		"secondary": f.secondaryText,
+		"amount":    f.amount,
+		"money":     f.money,
+		"percent":   f.percent,
+		"date":      f.date,
+		"modifier":  func(m ReceiptModifier) string { return receiptModifierText(m, f) },
+		"quantity":  f.quantity,
+	})
+
+	data := struct {
+		*ReceiptDocument
+		Sections ReceiptSections
+	}{doc, doc.Template.sections()}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReceiptTypeFor(t *testing.T) {
+	tests := map[string]string{
+		"sale":   "sale",
+		"return": "refund",
+		"void":   "void",
+		"":       "sale",
+	}
+	for transactionType, want := range tests {
+		if got := receiptTypeFor(transactionType); got != want {
+			t.Errorf("receiptTypeFor(%q) = %q, want %q", transactionType, got, want)
+		}
+	}
+}
+
+func TestMaskPaymentReference(t *testing.T) {
+	tests := []struct {
+		method, reference string
+		want              string
+	}{
+		{"card", "4111111111111111", "****1111"},
+		{"card", "1234", "1234"},
+		{"card", "", ""},
+		{"cash", "DRAWER-12345", "DRAWER-12345"},
+	}
+	for _, tt := range tests {
+		if got := maskPaymentReference(tt.method, tt.reference); got != tt.want {
+			t.Errorf("maskPaymentReference(%q, %q) = %q, want %q", tt.method, tt.reference, got, tt.want)
+		}
+	}
+}
+
+func TestReceiptColumns(t *testing.T) {
+	tests := []struct {
+		left, right string
+		width       int
+		want        string
+	}{
+		{"Coffee", "3.50", 16, "Coffee      3.50"},
+		{"Extra large cappuccino", "4.20", 16, "Extra large 4.20"},
+		{"Café", "1.00", 10, "Café  1.00"},
+		{"Total", "1234567890", 8, "12345678"},
+	}
+	for _, tt := range tests {
+		if got := receiptColumns(tt.left, tt.right, tt.width); got != tt.want {
+			t.Errorf("receiptColumns(%q, %q, %d) = %q, want %q", tt.left, tt.right, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestCenterReceiptText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"THANK YOU", 15, "   THANK YOU"},
+		{"ÉTÉ", 7, "  ÉTÉ"},
+		{"A very long shop name", 10, "A very lon"},
+	}
+	for _, tt := range tests {
+		if got := centerReceiptText(tt.text, tt.width); got != tt.want {
+			t.Errorf("centerReceiptText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestWrapReceiptText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"", 10, nil},
+		{"Returns accepted within 30 days", 12, []string{"Returns", "accepted", "within 30", "days"}},
+		{"Line one\n\nLine two", 20, []string{"Line one", "", "Line two"}},
+		{"Supercalifragilistic word", 8, []string{"Supercal", "word"}},
+	}
+	for _, tt := range tests {
+		if got := wrapReceiptText(tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapReceiptText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ReceiptTemplateHandler manages receipt templates per tenant, location and register
+type ReceiptTemplateHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewReceiptTemplateHandler creates a new receipt template handler
+func NewReceiptTemplateHandler(db *sqlx.DB, logger *zap.Logger) *ReceiptTemplateHandler {
+	return &ReceiptTemplateHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+var (
+	errReceiptTemplateNotFound = errors.New("receipt template not found")
+	currencyCodePattern        = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// receiptTemplateRequest is the editable part of a receipt template
+type receiptTemplateRequest struct {
+	Name            string                       `json:"name" validate:"required"`
+	LocationID      *int                         `json:"location_id"`
+	RegisterID      *int                         `json:"register_id"`
+	Locale          string                       `json:"locale"`
+	SecondaryLocale *string                      `json:"secondary_locale"`
+	Currency        string                       `json:"currency"`
+	Header          string                       `json:"header"`
+	Footer          string                       `json:"footer"`
+	LogoURL         string                       `json:"logo_url"`
+	LegalText       string                       `json:"legal_text"`
+	ReturnPolicy    string                       `json:"return_policy"`
+	Sections        *ReceiptSections             `json:"sections"`
+	Translations    map[string]map[string]string `json:"translations"`
+}
+
+// validate fills defaults and checks locales and currency
+func (req *receiptTemplateRequest) validate() error {
+	if req.Name == "" {
+		return errors.New("Name is required")
+	}
+	if req.Locale == "" {
+		req.Locale = "en-US"
+	}
+	if _, ok := receiptLocales[req.Locale]; !ok {
+		return fmt.Errorf("Unsupported locale %q", req.Locale)
+	}
+	if req.SecondaryLocale != nil && *req.SecondaryLocale == "" {
+		req.SecondaryLocale = nil
+	}
+	if req.SecondaryLocale != nil {
+		if _, ok := receiptLocales[*req.SecondaryLocale]; !ok {
+			return fmt.Errorf("Unsupported secondary locale %q", *req.SecondaryLocale)
+		}
+	}
+	if req.Currency == "" {
+		req.Currency = "USD"
+	}
+	if !currencyCodePattern.MatchString(req.Currency) {
+		return errors.New("Currency must be a three-letter ISO 4217 code")
+	}
+	for locale := range req.Translations {
+		if _, ok := receiptLocales[locale]; !ok {
+			return fmt.Errorf("Unsupported translation locale %q", locale)
+		}
+	}
+	if req.Sections == nil {
+		req.Sections = &ReceiptSections{TaxSummary: true}
+	}
+	if req.Translations == nil {
+		req.Translations = map[string]map[string]string{}
+	}
+	return nil
+}
+
+// receiptTemplateColumns is the column list scanned by scanReceiptTemplate
+const receiptTemplateColumns = `id, tenant_id, name, location_id, register_id, locale, secondary_locale, currency,
+		       header, footer, logo_url, legal_text, return_policy, sections, translations, created_by,
+		       created_at, updated_at`
+
+// scanReceiptTemplate scans a pos_receipt_templates row selected with receiptTemplateColumns
+func scanReceiptTemplate(scan func(dest ...interface{}) error) (POSReceiptTemplate, error) {
+	var t POSReceiptTemplate
+	var sections, translations []byte
+	err := scan(&t.ID, &t.TenantID, &t.Name, &t.LocationID, &t.RegisterID, &t.Locale, &t.SecondaryLocale,
+		&t.Currency, &t.Header, &t.Footer, &t.LogoURL, &t.LegalText, &t.ReturnPolicy, &sections, &translations,
+		&t.CreatedBy, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(sections, &t.Sections); err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(translations, &t.Translations); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// loadReceiptTemplateByID loads one of a tenant's receipt templates
+func loadReceiptTemplateByID(q rowQuerier, tenantID string, templateID int) (POSReceiptTemplate, error) {
+	row := q.QueryRow("SELECT "+receiptTemplateColumns+" FROM pos_receipt_templates WHERE id = $1 AND tenant_id = $2",
+		templateID, tenantID)
+	t, err := scanReceiptTemplate(row.Scan)
+	if err == sql.ErrNoRows {
+		return t, errReceiptTemplateNotFound
+	}
+	return t, err
+}
+
+// receiptTemplate converts a stored template to the snapshot embedded in receipts
+func (t POSReceiptTemplate) receiptTemplate() ReceiptTemplate {
+	id := t.ID
+	sections := t.Sections
+	tmpl := ReceiptTemplate{
+		TemplateID:   &id,
+		Header:       t.Header,
+		Footer:       t.Footer,
+		LogoURL:      t.LogoURL,
+		LegalText:    t.LegalText,
+		ReturnPolicy: t.ReturnPolicy,
+		Locale:       t.Locale,
+		Currency:     t.Currency,
+		Sections:     &sections,
+		Translations: t.Translations,
+	}
+	if t.SecondaryLocale != nil {
+		tmpl.SecondaryLocale = *t.SecondaryLocale
+	}
+	return tmpl
+}
+
+// checkTemplateRegister verifies a template's register belongs to the tenant
+func (h *ReceiptTemplateHandler) checkTemplateRegister(w http.ResponseWriter, tenantID string, registerID *int) bool {
+	if registerID == nil {
+		return true
+	}
+	var exists bool
+	err := h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM pos_registers WHERE id = $1 AND tenant_id = $2)",
+		*registerID, tenantID).Scan(&exists)
+	if err != nil {
+		http.Error(w, "Failed to verify register", http.StatusInternalServerError)
+		return false
+	}
+	if !exists {
+		http.Error(w, "Register not found", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// GetReceiptTemplates lists the tenant's receipt templates
+func (h *ReceiptTemplateHandler) GetReceiptTemplates(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	locationID := r.URL.Query().Get("location_id")
+	registerID := r.URL.Query().Get("register_id")
+
+	query := "SELECT " + receiptTemplateColumns + " FROM pos_receipt_templates WHERE tenant_id = $1"
+	args := []interface{}{tenantID}
+	argIndex := 2
+
+	if locationID != "" {
+		query += fmt.Sprintf(" AND location_id = $%d", argIndex)
+		args = append(args, locationID)
+		argIndex++
+	}
+
+	if registerID != "" {
+		query += fmt.Sprintf(" AND register_id = $%d", argIndex)
+		args = append(args, registerID)
+		argIndex++
+	}
+
+	query += " ORDER BY location_id NULLS FIRST, register_id NULLS FIRST, name"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch receipt templates", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var templates []POSReceiptTemplate
+	for rows.Next() {
+		t, err := scanReceiptTemplate(rows.Scan)
+		if err != nil {
+			continue
+		}
+		templates = append(templates, t)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// GetReceiptTemplate retrieves a receipt template
+func (h *ReceiptTemplateHandler) GetReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	templateID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	t, err := loadReceiptTemplateByID(h.db, tenantID, templateID)
+	if err != nil {
+		if err == errReceiptTemplateNotFound {
+			http.Error(w, "Receipt template not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch receipt template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+// CreateReceiptTemplate creates a template for the tenant, a location or a register
+func (h *ReceiptTemplateHandler) CreateReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req receiptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.checkTemplateRegister(w, tenantID, req.RegisterID) {
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	sections, _ := json.Marshal(req.Sections)
+	translations, _ := json.Marshal(req.Translations)
+
+	var id int
+	var createdAt time.Time
+	err = h.db.QueryRow(`
+		INSERT INTO pos_receipt_templates (tenant_id, name, location_id, register_id, locale, secondary_locale,
+		                                   currency, header, footer, logo_url, legal_text, return_policy,
+		                                   sections, translations, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id, created_at
+	`, tenantID, req.Name, req.LocationID, req.RegisterID, req.Locale, req.SecondaryLocale, req.Currency,
+		req.Header, req.Footer, req.LogoURL, req.LegalText, req.ReturnPolicy, sections, translations,
+		userID).Scan(&id, &createdAt)
+	if err != nil {
+		if isUniqueViolation(err, "") {
+			http.Error(w, "Receipt template already exists; each register, location and the tenant can have only one", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to create receipt template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"created_at": createdAt,
+		"message":    "Receipt template created successfully",
+	})
+}
+
+// UpdateReceiptTemplate replaces a receipt template's settings; issued receipts keep their snapshot
+func (h *ReceiptTemplateHandler) UpdateReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	templateID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	var req receiptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.checkTemplateRegister(w, tenantID, req.RegisterID) {
+		return
+	}
+
+	sections, _ := json.Marshal(req.Sections)
+	translations, _ := json.Marshal(req.Translations)
+
+	var updatedAt time.Time
+	err = h.db.QueryRow(`
+		UPDATE pos_receipt_templates
+		SET name = $1, location_id = $2, register_id = $3, locale = $4, secondary_locale = $5, currency = $6,
+		    header = $7, footer = $8, logo_url = $9, legal_text = $10, return_policy = $11,
+		    sections = $12, translations = $13
+		WHERE id = $14 AND tenant_id = $15
+		RETURNING updated_at
+	`, req.Name, req.LocationID, req.RegisterID, req.Locale, req.SecondaryLocale, req.Currency, req.Header,
+		req.Footer, req.LogoURL, req.LegalText, req.ReturnPolicy, sections, translations,
+		templateID, tenantID).Scan(&updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Receipt template not found", http.StatusNotFound)
+			return
+		}
+		if isUniqueViolation(err, "") {
+			http.Error(w, "Receipt template already exists; each register, location and the tenant can have only one", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update receipt template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         templateID,
+		"updated_at": updatedAt,
+		"message":    "Receipt template updated successfully",
+	})
+}
+
+// DeleteReceiptTemplate deletes a receipt template; the next less specific one applies instead
+func (h *ReceiptTemplateHandler) DeleteReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	templateID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.db.Exec("DELETE FROM pos_receipt_templates WHERE id = $1 AND tenant_id = $2", templateID, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to delete receipt template", http.StatusInternalServerError)
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		http.Error(w, "Receipt template not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Receipt template deleted successfully",
+	})
+}
+
+// PreviewReceiptTemplate renders a template against a real transaction, or sample data without one
+func (h *ReceiptTemplateHandler) PreviewReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	templateID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		TransactionID *int   `json:"transaction_id"`
+		PaperWidth    int    `json:"paper_width"`  // 58 or 80
+		ReceiptType   string `json:"receipt_type"` // sample data only: sale, refund or gift
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	t, err := loadReceiptTemplateByID(h.db, tenantID, templateID)
+	if err != nil {
+		if err == errReceiptTemplateNotFound {
+			http.Error(w, "Receipt template not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch receipt template", http.StatusInternalServerError)
+		return
+	}
+
+	var doc *ReceiptDocument
+	if req.TransactionID != nil {
+		doc, err = buildReceiptDocument(h.db, tenantID, *req.TransactionID)
+		if err != nil {
+			writeReceiptError(w, err, "Failed to build receipt")
+			return
+		}
+	} else {
+		doc = sampleReceiptDocument(req.ReceiptType)
+	}
+	doc.ReceiptNumber = "PREVIEW"
+	doc.IssuedAt = time.Now()
+	doc.Template = t.receiptTemplate()
+
+	paperWidth := req.PaperWidth
+	if paperWidth != 58 {
+		paperWidth = 80
+	}
+
+	page, err := renderReceiptHTML(doc)
+	if err != nil {
+		http.Error(w, "Failed to render receipt", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"receipt":       doc,
+		"paper_width":   paperWidth,
+		"rendered_text": renderReceiptText(doc, receiptWidthForPaper(paperWidth)),
+		"rendered_html": page,
+	})
+}
+
+// sampleReceiptDocument is the made-up sale used to preview templates without a transaction
+func sampleReceiptDocument(receiptType string) *ReceiptDocument {
+	loyaltyPoints := 240
+	reference := "****4242"
+	cardType := "visa"
+	doc := &ReceiptDocument{
+		ReceiptType:       "sale",
+		TransactionNumber: "POS-SAMPLE-0001",
+		TransactionType:   "sale",
+		TransactionDate:   time.Now(),
+		Register:          ReceiptRegister{Code: "REG-01", Name: "Front Counter"},
+		Cashier:           "Sam Cashier",
+		Customer:          &ReceiptCustomer{Name: "Alex Sample", LoyaltyPoints: &loyaltyPoints},
+		Lines: []ReceiptLine{
+			{ItemID: 1, SKU: "COF-1KG", Description: "Coffee beans 1kg", Quantity: 2, UnitPrice: 18.50,
+				TaxRate: 7, TaxAmount: 2.59, LineTotal: 39.59},
+			{ItemID: 2, SKU: "MUG-CER", Description: "Ceramic mug", Quantity: 1, UnitPrice: 12.00,
+				DiscountAmount: 2.00, TaxRate: 19, TaxAmount: 1.90, LineTotal: 11.90},
+		},
+		TaxSummary: []ReportTaxLine{
+			{Rate: 7, TaxableAmount: 37.00, TaxAmount: 2.59},
+			{Rate: 19, TaxableAmount: 10.00, TaxAmount: 1.90},
+		},
+		Payments: []ReceiptPayment{
+			{PaymentMethod: "card", Amount: 40.00, CardType: &cardType, Reference: &reference},
+			{PaymentMethod: "cash", Amount: 20.00},
+		},
+		Subtotal:      49.00,
+		DiscountTotal: 2.00,
+		TaxTotal:      4.49,
+		Total:         51.49,
+		AmountPaid:    60.00,
+		ChangeAmount:  8.51,
+	}
+
+	switch receiptType {
+	case "refund":
+		doc.ReceiptType = "refund"
+		doc.TransactionType = "return"
+	case "gift":
+		quantities := map[int]float64{}
+		for _, line := range doc.Lines {
+			quantities[line.ItemID] = line.Quantity
+		}
+		doc = giftReceiptDocument(doc, quantities)
+	}
+	return doc
+}
@@ -0,0 +1,729 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// RegisterHandler handles register lifecycle and cash movements
+type RegisterHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewRegisterHandler creates a new register handler
+func NewRegisterHandler(db *sqlx.DB, logger *zap.Logger) *RegisterHandler {
+	return &RegisterHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// =================================================================
+// REGISTER STATE MACHINE
+// =================================================================
+
+// registerTransitions lists the statuses a register may move to from each status
+var registerTransitions = map[string][]string{
+	"closed":      {"open", "maintenance"},
+	"open":        {"closed", "suspended"},
+	"suspended":   {"open"},
+	"maintenance": {"closed"},
+}
+
+var (
+	errRegisterNotFound  = errors.New("register not found")
+	errRegisterNotOpen   = errors.New("register is not open")
+	errRegisterSuspended = errors.New("register is suspended")
+)
+
+// canTransitionRegister reports whether a register may move from one status to another
+func canTransitionRegister(from, to string) bool {
+	for _, allowed := range registerTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// cashMovementSign returns +1 for movements that add cash to the drawer and -1 for those that remove it
+func cashMovementSign(transactionType string) float64 {
+	switch transactionType {
+	case "cash_out", "cash_drop", "payout", "refund_cash":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// lockRegister loads a register's status and expected balance, locking the row
+func lockRegister(q rowQuerier, tenantID string, registerID int) (status string, expectedBalance float64, err error) {
+	err = q.QueryRow(`
+		SELECT status, COALESCE(expected_balance, 0) FROM pos_registers
+		WHERE id = $1 AND tenant_id = $2 AND is_active = true
+		FOR UPDATE
+	`, registerID, tenantID).Scan(&status, &expectedBalance)
+	if err == sql.ErrNoRows {
+		return "", 0, errRegisterNotFound
+	}
+	return status, expectedBalance, err
+}
+
+// requireRegisterOpen returns an error unless the register is open for business
+func requireRegisterOpen(q rowQuerier, tenantID string, registerID int) error {
+	var status string
+	err := q.QueryRow("SELECT status FROM pos_registers WHERE id = $1 AND tenant_id = $2 AND is_active = true",
+		registerID, tenantID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return errRegisterNotFound
+	}
+	if err != nil {
+		return err
+	}
+	switch status {
+	case "open":
+		return nil
+	case "suspended":
+		return errRegisterSuspended
+	default:
+		return errRegisterNotOpen
+	}
+}
+
+// recordCashMovement writes a register_transactions row and moves the register balances by the signed amount
+func recordCashMovement(tx *sqlx.Tx, tenantID string, registerID int, transactionType string, amount, balanceBefore float64,
+	reason, notes, referenceNumber *string, userID int) (RegisterTransaction, error) {
+	movement := RegisterTransaction{
+		TenantID:        tenantID,
+		RegisterID:      registerID,
+		TransactionType: transactionType,
+		Amount:          amount,
+		BalanceBefore:   balanceBefore,
+		BalanceAfter:    balanceBefore + cashMovementSign(transactionType)*amount,
+		Reason:          reason,
+		Notes:           notes,
+		ReferenceNumber: referenceNumber,
+		CreatedBy:       &userID,
+	}
+
+	if shiftID, err := getOpenShiftID(tx, tenantID, registerID); err == nil {
+		movement.ShiftID = &shiftID
+	} else if err != errNoOpenShift {
+		return movement, err
+	}
+
+	err := tx.QueryRow(`
+		INSERT INTO register_transactions (tenant_id, register_id, shift_id, transaction_type, amount,
+		                                   balance_before, balance_after, reason, notes, reference_number, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`, tenantID, registerID, movement.ShiftID, transactionType, amount, movement.BalanceBefore, movement.BalanceAfter,
+		reason, notes, referenceNumber, userID).Scan(&movement.ID, &movement.CreatedAt)
+	if err != nil {
+		return movement, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pos_registers SET current_balance = $1, expected_balance = $1 WHERE id = $2
+	`, movement.BalanceAfter, registerID)
+	return movement, err
+}
+
+// transitionRegister locks a register and checks it may move to the given status.
+// It returns the current status and expected balance; the caller applies the update and commits.
+func (h *RegisterHandler) transitionRegister(w http.ResponseWriter, tx *sqlx.Tx, tenantID string, registerID int, to string) (string, float64, bool) {
+	from, expectedBalance, err := lockRegister(tx, tenantID, registerID)
+	if err != nil {
+		if err == errRegisterNotFound {
+			http.Error(w, "Register not found", http.StatusNotFound)
+			return "", 0, false
+		}
+		http.Error(w, "Failed to fetch register", http.StatusInternalServerError)
+		return "", 0, false
+	}
+
+	if !canTransitionRegister(from, to) {
+		http.Error(w, fmt.Sprintf("Cannot move register from %s to %s", from, to), http.StatusConflict)
+		return "", 0, false
+	}
+
+	return from, expectedBalance, true
+}
+
+// registerIDParam parses the {id} route parameter
+func registerIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	registerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid register ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return registerID, true
+}
+
+// OpenRegister opens a closed register with a starting float
+func (h *RegisterHandler) OpenRegister(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.registers.open") {
+		http.Error(w, "Opening a register requires the pos.registers.open permission", http.StatusForbidden)
+		return
+	}
+
+	registerID, ok := registerIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		OpeningBalance float64 `json:"opening_balance"`
+		Notes          *string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.OpeningBalance < 0 {
+		http.Error(w, "Opening balance cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to open register", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	from, _, ok := h.transitionRegister(w, tx, tenantID, registerID, "open")
+	if !ok {
+		return
+	}
+
+	// Suspended registers keep their float and go through ResumeRegister
+	if from != "closed" {
+		http.Error(w, "Use the resume endpoint to reopen a suspended register", http.StatusConflict)
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pos_registers
+		SET status = 'open', opening_balance = $1, current_balance = 0, expected_balance = 0,
+		    opened_at = $2, opened_by = $3, closed_at = NULL, closed_by = NULL
+		WHERE id = $4
+	`, req.OpeningBalance, time.Now(), userID, registerID)
+	if err == nil {
+		_, err = recordCashMovement(tx, tenantID, registerID, "opening", req.OpeningBalance, 0, nil, req.Notes, nil, userID)
+	}
+	if err != nil {
+		http.Error(w, "Failed to open register", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to open register", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"register_id":     registerID,
+		"opening_balance": req.OpeningBalance,
+		"status":          "open",
+		"message":         "Register opened successfully",
+	})
+}
+
+// CloseRegister closes an open register and records the counted cash
+func (h *RegisterHandler) CloseRegister(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.registers.close") {
+		http.Error(w, "Closing a register requires the pos.registers.close permission", http.StatusForbidden)
+		return
+	}
+
+	registerID, ok := registerIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CountedAmount float64 `json:"counted_amount"`
+		Notes         *string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to close register", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, expectedBalance, ok := h.transitionRegister(w, tx, tenantID, registerID, "closed")
+	if !ok {
+		return
+	}
+
+	// Sessions and shifts must be wrapped up before the register closes
+	var activeSessions, openShifts int
+	err = tx.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM pos_sessions WHERE tenant_id = $1 AND register_id = $2 AND status = 'active'),
+			(SELECT COUNT(*) FROM register_shifts WHERE tenant_id = $1 AND register_id = $2 AND status = 'open')
+	`, tenantID, registerID).Scan(&activeSessions, &openShifts)
+	if err != nil {
+		http.Error(w, "Failed to close register", http.StatusInternalServerError)
+		return
+	}
+	if activeSessions > 0 {
+		http.Error(w, "Register has an active session that must be closed first", http.StatusConflict)
+		return
+	}
+	if openShifts > 0 {
+		http.Error(w, "Register has an open shift that must be closed first", http.StatusConflict)
+		return
+	}
+
+	variance := req.CountedAmount - expectedBalance
+
+	_, err = tx.Exec(`
+		INSERT INTO register_transactions (tenant_id, register_id, transaction_type, amount,
+		                                   balance_before, balance_after, notes, created_by)
+		VALUES ($1, $2, 'closing', $3, $4, $3, $5, $6)
+	`, tenantID, registerID, req.CountedAmount, expectedBalance, req.Notes, userID)
+	if err != nil {
+		http.Error(w, "Failed to record closing count", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pos_registers
+		SET status = 'closed', current_balance = $1, closed_at = $2, closed_by = $3
+		WHERE id = $4
+	`, req.CountedAmount, time.Now(), userID, registerID)
+	if err != nil {
+		http.Error(w, "Failed to close register", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to close register", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"register_id":      registerID,
+		"status":           "closed",
+		"counted_amount":   req.CountedAmount,
+		"expected_balance": expectedBalance,
+		"variance":         variance,
+		"message":          "Register closed successfully",
+	})
+}
+
+// SuspendRegister temporarily stops sales on an open register
+func (h *RegisterHandler) SuspendRegister(w http.ResponseWriter, r *http.Request) {
+	h.setRegisterStatus(w, r, "suspended", "pos.registers.suspend", "Register suspended successfully")
+}
+
+// ResumeRegister reopens a suspended register
+func (h *RegisterHandler) ResumeRegister(w http.ResponseWriter, r *http.Request) {
+	h.setRegisterStatus(w, r, "open", "pos.registers.suspend", "Register resumed successfully")
+}
+
+// StartRegisterMaintenance takes a closed register out of service
+func (h *RegisterHandler) StartRegisterMaintenance(w http.ResponseWriter, r *http.Request) {
+	h.setRegisterStatus(w, r, "maintenance", "pos.registers.edit", "Register placed in maintenance")
+}
+
+// EndRegisterMaintenance returns a register from maintenance to closed
+func (h *RegisterHandler) EndRegisterMaintenance(w http.ResponseWriter, r *http.Request) {
+	h.setRegisterStatus(w, r, "closed", "pos.registers.edit", "Register returned from maintenance")
+}
+
+// setRegisterStatus performs a status-only transition, gated on permission, and logs the reason in metadata
+func (h *RegisterHandler) setRegisterStatus(w http.ResponseWriter, r *http.Request, to, permission, message string) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, permission) {
+		http.Error(w, "This status change requires the "+permission+" permission", http.StatusForbidden)
+		return
+	}
+
+	registerID, ok := registerIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason *string `json:"reason"`
+	}
+	// Reason is optional, so an empty body is fine
+	json.NewDecoder(r.Body).Decode(&req)
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to update register", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	from, _, ok := h.transitionRegister(w, tx, tenantID, registerID, to)
+	if !ok {
+		return
+	}
+
+	// Resuming is only valid from suspended; opening a closed register goes through OpenRegister
+	if to == "open" && from != "suspended" {
+		http.Error(w, "Only a suspended register can be resumed", http.StatusConflict)
+		return
+	}
+	// Closing via this path is only for leaving maintenance; open registers use CloseRegister
+	if to == "closed" && from != "maintenance" {
+		http.Error(w, "Use the close endpoint to close an open register", http.StatusConflict)
+		return
+	}
+
+	statusChange, _ := json.Marshal(map[string]interface{}{
+		"last_status_change": map[string]interface{}{
+			"from":       from,
+			"to":         to,
+			"reason":     req.Reason,
+			"changed_by": userID,
+			"changed_at": time.Now(),
+		},
+	})
+
+	_, err = tx.Exec(`
+		UPDATE pos_registers SET status = $1, metadata = COALESCE(metadata, '{}'::jsonb) || $2::jsonb
+		WHERE id = $3
+	`, to, string(statusChange), registerID)
+	if err != nil {
+		http.Error(w, "Failed to update register", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to update register", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"register_id":     registerID,
+		"previous_status": from,
+		"status":          to,
+		"message":         message,
+	})
+}
+
+// =================================================================
+// REGISTER MAINTENANCE
+// =================================================================
+
+// UpdatePOSRegister updates a register's descriptive fields
+func (h *RegisterHandler) UpdatePOSRegister(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	registerID, ok := registerIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name         *string  `json:"name"`
+		Code         *string  `json:"code"`
+		LocationID   *int     `json:"location_id"`
+		RegisterType *string  `json:"register_type"`
+		CompanyID    *string  `json:"company_id"`
+		Metadata     Metadata `json:"metadata"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		UPDATE pos_registers
+		SET name = COALESCE($1, name),
+		    code = COALESCE($2, code),
+		    location_id = COALESCE($3, location_id),
+		    register_type = COALESCE($4, register_type),
+		    company_id = COALESCE($5, company_id),
+		    metadata = COALESCE(metadata, '{}'::jsonb) || COALESCE($6::jsonb, '{}'::jsonb)
+		WHERE id = $7 AND tenant_id = $8 AND is_active = true
+		RETURNING updated_at
+	`
+
+	var metadataJSON *string
+	if req.Metadata != nil {
+		b, _ := json.Marshal(req.Metadata)
+		str := string(b)
+		metadataJSON = &str
+	}
+
+	var updatedAt time.Time
+	err = h.db.QueryRow(query, req.Name, req.Code, req.LocationID, req.RegisterType, req.CompanyID,
+		metadataJSON, registerID, tenantID).Scan(&updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Register not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to update POS register", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         registerID,
+		"updated_at": updatedAt,
+		"message":    "POS register updated successfully",
+	})
+}
+
+// DeactivatePOSRegister soft-deletes a closed register
+func (h *RegisterHandler) DeactivatePOSRegister(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	registerID, ok := registerIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var status string
+	err = h.db.QueryRow(`
+		UPDATE pos_registers SET is_active = false
+		WHERE id = $1 AND tenant_id = $2 AND is_active = true AND status IN ('closed', 'maintenance')
+		RETURNING status
+	`, registerID, tenantID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Distinguish a missing register from one that is still trading
+			var current string
+			lookupErr := h.db.QueryRow("SELECT status FROM pos_registers WHERE id = $1 AND tenant_id = $2 AND is_active = true",
+				registerID, tenantID).Scan(&current)
+			if lookupErr == nil {
+				http.Error(w, fmt.Sprintf("Register is %s; close it before deactivating", current), http.StatusConflict)
+				return
+			}
+			http.Error(w, "Register not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to deactivate POS register", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      registerID,
+		"message": "POS register deactivated successfully",
+	})
+}
+
+// =================================================================
+// CASH MOVEMENTS
+// =================================================================
+
+// GetRegisterTransactions lists cash movements for a register
+func (h *RegisterHandler) GetRegisterTransactions(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	registerID, ok := registerIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	shiftID := r.URL.Query().Get("shift_id")
+	transactionType := r.URL.Query().Get("transaction_type")
+
+	query := `
+		SELECT id, tenant_id, register_id, shift_id, transaction_type, amount, balance_before, balance_after,
+		       reason, notes, reference_number, created_at, created_by
+		FROM register_transactions
+		WHERE tenant_id = $1 AND register_id = $2
+	`
+	args := []interface{}{tenantID, registerID}
+	argIndex := 3
+
+	if shiftID != "" {
+		query += fmt.Sprintf(" AND shift_id = $%d", argIndex)
+		args = append(args, shiftID)
+		argIndex++
+	}
+
+	if transactionType != "" {
+		query += fmt.Sprintf(" AND transaction_type = $%d", argIndex)
+		args = append(args, transactionType)
+		argIndex++
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch register transactions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var movements []RegisterTransaction
+	for rows.Next() {
+		var m RegisterTransaction
+		err := rows.Scan(&m.ID, &m.TenantID, &m.RegisterID, &m.ShiftID, &m.TransactionType, &m.Amount,
+			&m.BalanceBefore, &m.BalanceAfter, &m.Reason, &m.Notes, &m.ReferenceNumber, &m.CreatedAt, &m.CreatedBy)
+		if err != nil {
+			continue
+		}
+		movements = append(movements, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"transactions": movements,
+		"count":        len(movements),
+	})
+}
+
+// CreateCashMovement records cash added to or removed from an open register
+func (h *RegisterHandler) CreateCashMovement(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.cash_movements.create") {
+		http.Error(w, "Recording cash movements requires the pos.cash_movements.create permission", http.StatusForbidden)
+		return
+	}
+
+	registerID, ok := registerIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TransactionType string  `json:"transaction_type" validate:"required"` // cash_in, cash_out, payout, refund_cash
+		Amount          float64 `json:"amount" validate:"required"`
+		Reason          *string `json:"reason"`
+		Notes           *string `json:"notes"`
+		ReferenceNumber *string `json:"reference_number"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch req.TransactionType {
+	case "cash_in", "cash_out", "payout", "refund_cash":
+	case "cash_drop":
+		http.Error(w, "Cash drops must go to a safe; use POST /safes/{id}/drops", http.StatusBadRequest)
+		return
+	default:
+		http.Error(w, "Invalid transaction type", http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount <= 0 {
+		http.Error(w, "Amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to record cash movement", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	status, balance, err := lockRegister(tx, tenantID, registerID)
+	if err != nil {
+		if err == errRegisterNotFound {
+			http.Error(w, "Register not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch register", http.StatusInternalServerError)
+		return
+	}
+
+	if status != "open" {
+		http.Error(w, fmt.Sprintf("Register is %s; cash movements require an open register", status), http.StatusConflict)
+		return
+	}
+
+	if cashMovementSign(req.TransactionType) < 0 && req.Amount > balance {
+		http.Error(w, "Amount exceeds cash in register", http.StatusBadRequest)
+		return
+	}
+
+	movement, err := recordCashMovement(tx, tenantID, registerID, req.TransactionType, req.Amount, balance,
+		req.Reason, req.Notes, req.ReferenceNumber, userID)
+	if err != nil {
+		http.Error(w, "Failed to record cash movement", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to record cash movement", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestCanTransitionRegister(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"closed", "open", true},
+		{"closed", "maintenance", true},
+		{"closed", "suspended", false},
+		{"open", "closed", true},
+		{"open", "suspended", true},
+		{"open", "maintenance", false},
+		{"suspended", "open", true},
+		{"suspended", "closed", false},
+		{"maintenance", "closed", true},
+		{"maintenance", "open", false},
+		{"open", "open", false},
+		{"unknown", "open", false},
+	}
+	for _, tt := range tests {
+		if got := canTransitionRegister(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransitionRegister(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCashMovementSign(t *testing.T) {
+	tests := []struct {
+		transactionType string
+		want            float64
+	}{
+		{"opening", 1},
+		{"cash_in", 1},
+		{"cash_out", -1},
+		{"cash_drop", -1},
+		{"payout", -1},
+		{"refund_cash", -1},
+	}
+	for _, tt := range tests {
+		if got := cashMovementSign(tt.transactionType); got != tt.want {
+			t.Errorf("cashMovementSign(%q) = %v, want %v", tt.transactionType, got, tt.want)
+		}
+	}
+}
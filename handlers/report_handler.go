@@ -0,0 +1,746 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ReportHandler handles X-report and Z-report generation
+type ReportHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(db *sqlx.DB, logger *zap.Logger) *ReportHandler {
+	return &ReportHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// reportQuerier is satisfied by both *sqlx.DB and *sqlx.Tx
+type reportQuerier interface {
+	rowQuerier
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+var (
+	errReportTargetNotFound = errors.New("report target not found")
+	errReportTargetOpen     = errors.New("session, shift or register must be closed before a Z-report")
+	errReportAlreadyTaken   = errors.New("a Z-report has already been generated for this period")
+)
+
+// reportScope identifies what a report covers and the period it spans
+type reportScope struct {
+	Scope        string // register, session, shift
+	RegisterID   int
+	SessionID    *int
+	ShiftID      *int
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	OpeningFloat float64
+	CountedCash  *float64
+	Closed       bool
+}
+
+// transactionFilter returns the WHERE fragment selecting pos_transactions (aliased pt) in scope.
+// Placeholders start at $2; $1 is always the tenant ID.
+func (s reportScope) transactionFilter() (string, []interface{}) {
+	switch s.Scope {
+	case "session":
+		return "pt.session_id = $2", []interface{}{*s.SessionID}
+	case "shift":
+		return "pt.shift_id = $2", []interface{}{*s.ShiftID}
+	default:
+		return "pt.register_id = $2 AND pt.transaction_date > $3 AND pt.transaction_date <= $4",
+			[]interface{}{s.RegisterID, s.PeriodStart, s.PeriodEnd}
+	}
+}
+
+// cashMovementFilter returns the WHERE fragment selecting register_transactions in scope
+func (s reportScope) cashMovementFilter() (string, []interface{}) {
+	if s.Scope == "shift" {
+		return "shift_id = $2", []interface{}{*s.ShiftID}
+	}
+	return "register_id = $2 AND created_at > $3 AND created_at <= $4",
+		[]interface{}{s.RegisterID, s.PeriodStart, s.PeriodEnd}
+}
+
+// resolveReportScope loads the session, shift or register a report is requested for
+func resolveReportScope(q rowQuerier, tenantID string, registerID, sessionID, shiftID *int) (reportScope, error) {
+	now := time.Now()
+
+	switch {
+	case sessionID != nil:
+		scope := reportScope{Scope: "session", SessionID: sessionID}
+		var sessionEnd sql.NullTime
+		var closingAmount sql.NullFloat64
+		var status string
+		err := q.QueryRow(`
+			SELECT register_id, shift_id, session_start, session_end, COALESCE(opening_amount, 0), closing_amount, status
+			FROM pos_sessions WHERE id = $1 AND tenant_id = $2
+		`, *sessionID, tenantID).Scan(&scope.RegisterID, &scope.ShiftID, &scope.PeriodStart, &sessionEnd,
+			&scope.OpeningFloat, &closingAmount, &status)
+		if err == sql.ErrNoRows {
+			return scope, errReportTargetNotFound
+		}
+		if err != nil {
+			return scope, err
+		}
+		scope.PeriodEnd = now
+		if sessionEnd.Valid {
+			scope.PeriodEnd = sessionEnd.Time
+		}
+		if closingAmount.Valid {
+			scope.CountedCash = &closingAmount.Float64
+		}
+		scope.Closed = status == "closed"
+		return scope, nil
+
+	case shiftID != nil:
+		scope := reportScope{Scope: "shift", ShiftID: shiftID}
+		var closedAt sql.NullTime
+		var closingBalance sql.NullFloat64
+		var status string
+		err := q.QueryRow(`
+			SELECT register_id, opened_at, closed_at, opening_balance, closing_balance, status
+			FROM register_shifts WHERE id = $1 AND tenant_id = $2
+		`, *shiftID, tenantID).Scan(&scope.RegisterID, &scope.PeriodStart, &closedAt,
+			&scope.OpeningFloat, &closingBalance, &status)
+		if err == sql.ErrNoRows {
+			return scope, errReportTargetNotFound
+		}
+		if err != nil {
+			return scope, err
+		}
+		scope.PeriodEnd = now
+		if closedAt.Valid {
+			scope.PeriodEnd = closedAt.Time
+		}
+		if closingBalance.Valid {
+			scope.CountedCash = &closingBalance.Float64
+		}
+		scope.Closed = status != "open"
+		return scope, nil
+
+	case registerID != nil:
+		scope := reportScope{Scope: "register", RegisterID: *registerID, PeriodEnd: now}
+		var status string
+		var closedAt sql.NullTime
+		err := q.QueryRow(`
+			SELECT status, COALESCE(opening_balance, 0), closed_at
+			FROM pos_registers WHERE id = $1 AND tenant_id = $2
+		`, *registerID, tenantID).Scan(&status, &scope.OpeningFloat, &closedAt)
+		if err == sql.ErrNoRows {
+			return scope, errReportTargetNotFound
+		}
+		if err != nil {
+			return scope, err
+		}
+		scope.Closed = status == "closed" || status == "maintenance"
+		if scope.Closed && closedAt.Valid {
+			scope.PeriodEnd = closedAt.Time
+		}
+
+		// A register-level report starts where the previous register Z-report ended
+		var lastEnd sql.NullTime
+		err = q.QueryRow(`
+			SELECT MAX(period_end) FROM pos_z_reports
+			WHERE tenant_id = $1 AND register_id = $2 AND scope = 'register'
+		`, tenantID, *registerID).Scan(&lastEnd)
+		if err != nil {
+			return scope, err
+		}
+		if lastEnd.Valid {
+			scope.PeriodStart = lastEnd.Time
+		}
+
+		// The counted cash is the closing count taken when the register was closed
+		if scope.Closed {
+			var counted float64
+			err = q.QueryRow(`
+				SELECT amount FROM register_transactions
+				WHERE tenant_id = $1 AND register_id = $2 AND transaction_type = 'closing'
+				  AND created_at > $3 AND created_at <= $4
+				ORDER BY created_at DESC LIMIT 1
+			`, tenantID, *registerID, scope.PeriodStart, scope.PeriodEnd).Scan(&counted)
+			if err == nil {
+				scope.CountedCash = &counted
+			} else if err != sql.ErrNoRows {
+				return scope, err
+			}
+		}
+		return scope, nil
+	}
+
+	return reportScope{}, errReportTargetNotFound
+}
+
+// buildRegisterReport aggregates sales, tenders, tax and cash activity for a scope
+func buildRegisterReport(q reportQuerier, tenantID string, scope reportScope, reportType string, userID int) (*RegisterReport, error) {
+	report := &RegisterReport{
+		ReportType:    reportType,
+		Scope:         scope.Scope,
+		RegisterID:    scope.RegisterID,
+		SessionID:     scope.SessionID,
+		ShiftID:       scope.ShiftID,
+		PeriodStart:   scope.PeriodStart,
+		PeriodEnd:     scope.PeriodEnd,
+		GeneratedAt:   time.Now(),
+		GeneratedBy:   userID,
+		OpeningFloat:  scope.OpeningFloat,
+		CountedCash:   scope.CountedCash,
+		TaxByRate:     []ReportTaxLine{},
+		Tenders:       []ReportTenderLine{},
+		CashMovements: []ReportCashLine{},
+	}
+
+	filter, filterArgs := scope.transactionFilter()
+	args := append([]interface{}{tenantID}, filterArgs...)
+
+	// Sales, returns and voids
+	summaryQuery := fmt.Sprintf(`
+		SELECT
+			COUNT(*) FILTER (WHERE pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded')),
+			COALESCE(SUM(pt.subtotal) FILTER (WHERE pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded')), 0),
+			COALESCE(SUM(pt.discount_amount) FILTER (WHERE pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded')), 0),
+			COALESCE(SUM(pt.tax_amount) FILTER (WHERE pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded')), 0),
+			COALESCE(SUM(pt.tip_amount) FILTER (WHERE pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded')), 0),
+			COUNT(*) FILTER (WHERE pt.transaction_type = 'return' AND pt.status <> 'void'),
+			COALESCE(SUM(pt.total_amount) FILTER (WHERE pt.transaction_type = 'return' AND pt.status <> 'void'), 0),
+			COUNT(*) FILTER (WHERE pt.status = 'void' OR pt.transaction_type = 'void'),
+			COALESCE(SUM(pt.total_amount) FILTER (WHERE pt.status = 'void' OR pt.transaction_type = 'void'), 0),
+			COALESCE(SUM(pt.change_amount) FILTER (WHERE pt.status <> 'void'), 0),
+			COUNT(*),
+			(ARRAY_AGG(pt.transaction_number ORDER BY pt.transaction_date, pt.id))[1],
+			(ARRAY_AGG(pt.transaction_number ORDER BY pt.transaction_date DESC, pt.id DESC))[1]
+		FROM pos_transactions pt
+		WHERE pt.tenant_id = $1 AND %s
+	`, filter)
+
+	var firstNumber, lastNumber sql.NullString
+	err := q.QueryRow(summaryQuery, args...).Scan(
+		&report.SalesCount, &report.GrossSales, &report.Discounts, &report.TaxTotal, &report.Tips,
+		&report.ReturnCount, &report.Returns, &report.VoidCount, &report.VoidAmount,
+		&report.ChangeGiven, &report.TransactionCount, &firstNumber, &lastNumber,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if firstNumber.Valid {
+		report.FirstTransactionNumber = &firstNumber.String
+	}
+	if lastNumber.Valid {
+		report.LastTransactionNumber = &lastNumber.String
+	}
+	report.NetSales = report.GrossSales - report.Discounts - report.Returns
+
+	// Tax by rate; modifiers are part of their line but taxed at their own rate, and a kit line
+	// is taxed entirely through its components
+	taxRows, err := q.Query(fmt.Sprintf(`
+		WITH lines AS (
+			SELECT pti.id, pti.tax_rate, pti.quantity * pti.unit_price - pti.discount_amount AS taxable,
+			       pti.tax_amount
+			FROM pos_transaction_items pti
+			JOIN pos_transactions pt ON pt.id = pti.transaction_id
+			WHERE pt.tenant_id = $1 AND %s AND pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded')
+		), parts AS (
+			SELECT l.tax_rate, l.taxable - COALESCE(SUM(m.amount), 0) AS taxable,
+			       l.tax_amount - COALESCE(SUM(m.tax_amount), 0) AS tax_amount
+			FROM lines l
+			LEFT JOIN pos_transaction_item_modifiers m ON m.transaction_item_id = l.id
+			WHERE NOT EXISTS (SELECT 1 FROM pos_transaction_item_components c WHERE c.transaction_item_id = l.id)
+			GROUP BY l.id, l.tax_rate, l.taxable, l.tax_amount
+			UNION ALL
+			SELECT m.tax_rate, m.amount, m.tax_amount
+			FROM pos_transaction_item_modifiers m
+			JOIN lines l ON l.id = m.transaction_item_id
+			UNION ALL
+			SELECT c.tax_rate, c.amount, c.tax_amount
+			FROM pos_transaction_item_components c
+			JOIN lines l ON l.id = c.transaction_item_id
+		)
+		SELECT tax_rate, COALESCE(SUM(taxable), 0), COALESCE(SUM(tax_amount), 0)
+		FROM parts
+		GROUP BY tax_rate
+		ORDER BY tax_rate
+	`, filter), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer taxRows.Close()
+	for taxRows.Next() {
+		var line ReportTaxLine
+		if err := taxRows.Scan(&line.Rate, &line.TaxableAmount, &line.TaxAmount); err != nil {
+			return nil, err
+		}
+		report.TaxByRate = append(report.TaxByRate, line)
+	}
+
+	// Tender breakdown; refunds on returns count against the tender
+	tenderRows, err := q.Query(fmt.Sprintf(`
+		SELECT pp.payment_method, COUNT(*),
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'return' THEN -pp.amount ELSE pp.amount END), 0)
+		FROM pos_payments pp
+		JOIN pos_transactions pt ON pt.id = pp.transaction_id
+		WHERE pt.tenant_id = $1 AND %s AND pt.status <> 'void' AND pp.status = 'completed'
+		GROUP BY pp.payment_method
+		ORDER BY pp.payment_method
+	`, filter), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer tenderRows.Close()
+	for tenderRows.Next() {
+		var line ReportTenderLine
+		if err := tenderRows.Scan(&line.PaymentMethod, &line.Count, &line.Amount); err != nil {
+			return nil, err
+		}
+		if line.PaymentMethod == "cash" {
+			report.CashTendered = line.Amount
+		}
+		report.Tenders = append(report.Tenders, line)
+	}
+
+	// Cash movements other than the opening float and closing count
+	movementFilter, movementArgs := scope.cashMovementFilter()
+	movementRows, err := q.Query(fmt.Sprintf(`
+		SELECT transaction_type, COUNT(*), COALESCE(SUM(amount), 0)
+		FROM register_transactions
+		WHERE tenant_id = $1 AND %s AND transaction_type NOT IN ('opening', 'closing')
+		GROUP BY transaction_type
+		ORDER BY transaction_type
+	`, movementFilter), append([]interface{}{tenantID}, movementArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer movementRows.Close()
+
+	netMovements := 0.0
+	for movementRows.Next() {
+		var line ReportCashLine
+		if err := movementRows.Scan(&line.TransactionType, &line.Count, &line.Amount); err != nil {
+			return nil, err
+		}
+		netMovements += cashMovementSign(line.TransactionType) * line.Amount
+		report.CashMovements = append(report.CashMovements, line)
+	}
+
+	// Cash handed back on voids and returns leaves the drawer as refund_cash movements, so the
+	// drawer is expected to hold the cash taken on every sale, voided or not, plus the movements
+	var salesCash float64
+	err = q.QueryRow(fmt.Sprintf(`
+		SELECT COALESCE(SUM(pp.amount) FILTER (WHERE pp.payment_method = 'cash' AND pp.status IN ('completed', 'void')), 0) -
+		       COALESCE((SELECT SUM(pt.change_amount) FROM pos_transactions pt
+		                 WHERE pt.tenant_id = $1 AND %s AND pt.transaction_type = 'sale'), 0)
+		FROM pos_payments pp
+		JOIN pos_transactions pt ON pt.id = pp.transaction_id
+		WHERE pt.tenant_id = $1 AND %s AND pt.transaction_type = 'sale'
+	`, filter, filter), args...).Scan(&salesCash)
+	if err != nil {
+		return nil, err
+	}
+
+	report.ExpectedCash = report.OpeningFloat + salesCash + netMovements
+	if report.CountedCash != nil {
+		variance := *report.CountedCash - report.ExpectedCash
+		report.CashVariance = &variance
+	}
+
+	return report, nil
+}
+
+// reportScopeFromQuery reads register_id, session_id or shift_id from query parameters
+func reportScopeFromQuery(r *http.Request) (registerID, sessionID, shiftID *int, err error) {
+	parse := func(name string) (*int, error) {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			return nil, nil
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s", name)
+		}
+		return &id, nil
+	}
+	if registerID, err = parse("register_id"); err != nil {
+		return
+	}
+	if sessionID, err = parse("session_id"); err != nil {
+		return
+	}
+	shiftID, err = parse("shift_id")
+	return
+}
+
+// reportErrorStatus maps report errors to HTTP status codes
+func reportErrorStatus(err error) int {
+	switch err {
+	case errReportTargetNotFound:
+		return http.StatusNotFound
+	case errReportTargetOpen, errReportAlreadyTaken:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetXReport returns a read-only snapshot for a register, session or shift
+func (h *ReportHandler) GetXReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	registerID, sessionID, shiftID, err := reportScopeFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if registerID == nil && sessionID == nil && shiftID == nil {
+		http.Error(w, "register_id, session_id or shift_id is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	scope, err := resolveReportScope(h.db, tenantID, registerID, sessionID, shiftID)
+	if err != nil {
+		if status := reportErrorStatus(err); status != http.StatusInternalServerError {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		http.Error(w, "Failed to generate X-report", http.StatusInternalServerError)
+		return
+	}
+
+	report, err := buildRegisterReport(h.db, tenantID, scope, "X", userID)
+	if err != nil {
+		h.logger.Error("Failed to build X-report", zap.Error(err))
+		http.Error(w, "Failed to generate X-report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// itemSalesBreakdown totals completed sales and their returns by product and variant, and by
+// modifier. filter is a WHERE fragment on pos_transactions (aliased pt); $1 is always the tenant ID.
+func itemSalesBreakdown(q reportQuerier, filter string, args []interface{}) ([]ReportItemLine, []ReportModifierLine, error) {
+	items := []ReportItemLine{}
+	modifiers := []ReportModifierLine{}
+
+	itemRows, err := q.Query(fmt.Sprintf(`
+		SELECT pti.product_id, COALESCE(p.name, ''), pti.variant_id, v.name,
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'sale' THEN pti.quantity END), 0),
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'return' THEN pti.quantity END), 0),
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'sale'
+		                         THEN pti.quantity * pti.unit_price - pti.discount_amount END), 0),
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'return'
+		                         THEN pti.quantity * pti.unit_price - pti.discount_amount END), 0)
+		FROM pos_transaction_items pti
+		JOIN pos_transactions pt ON pt.id = pti.transaction_id
+		LEFT JOIN products p ON p.id = pti.product_id
+		LEFT JOIN pos_product_variants v ON v.id = pti.variant_id
+		WHERE %s
+		  AND ((pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded'))
+		       OR (pt.transaction_type = 'return' AND pt.status <> 'void'))
+		GROUP BY pti.product_id, p.name, pti.variant_id, v.name
+		ORDER BY 7 DESC, pti.product_id
+	`, filter), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer itemRows.Close()
+	for itemRows.Next() {
+		var line ReportItemLine
+		if err := itemRows.Scan(&line.ProductID, &line.ProductName, &line.VariantID, &line.VariantName,
+			&line.QuantitySold, &line.QuantityReturned, &line.Sales, &line.Returns); err != nil {
+			return nil, nil, err
+		}
+		line.NetSales = line.Sales - line.Returns
+		items = append(items, line)
+	}
+	if err := itemRows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	modifierRows, err := q.Query(fmt.Sprintf(`
+		SELECT m.modifier_id, m.group_id, COALESCE(g.name, ''), m.name,
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'sale' THEN m.quantity * pti.quantity END), 0),
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'return' THEN m.quantity * pti.quantity END), 0),
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'sale' THEN m.amount END), 0),
+		       COALESCE(SUM(CASE WHEN pt.transaction_type = 'return' THEN m.amount END), 0)
+		FROM pos_transaction_item_modifiers m
+		JOIN pos_transaction_items pti ON pti.id = m.transaction_item_id
+		JOIN pos_transactions pt ON pt.id = pti.transaction_id
+		LEFT JOIN pos_modifier_groups g ON g.id = m.group_id
+		WHERE %s
+		  AND ((pt.transaction_type = 'sale' AND pt.status IN ('completed', 'refunded'))
+		       OR (pt.transaction_type = 'return' AND pt.status <> 'void'))
+		GROUP BY m.modifier_id, m.group_id, g.name, m.name
+		ORDER BY 5 DESC, m.modifier_id
+	`, filter), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer modifierRows.Close()
+	for modifierRows.Next() {
+		var line ReportModifierLine
+		if err := modifierRows.Scan(&line.ModifierID, &line.GroupID, &line.GroupName, &line.Name,
+			&line.QuantitySold, &line.QuantityReturned, &line.Sales, &line.Returns); err != nil {
+			return nil, nil, err
+		}
+		line.NetSales = line.Sales - line.Returns
+		modifiers = append(modifiers, line)
+	}
+	if err := modifierRows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return items, modifiers, nil
+}
+
+// CreateZReport generates, numbers and stores the closing report for a closed register, session or shift
+func (h *ReportHandler) CreateZReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		RegisterID *int `json:"register_id"`
+		SessionID  *int `json:"session_id"`
+		ShiftID    *int `json:"shift_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.RegisterID == nil && req.SessionID == nil && req.ShiftID == nil {
+		http.Error(w, "register_id, session_id or shift_id is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to generate Z-report", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	stored, err := createZReport(tx, tenantID, req.RegisterID, req.SessionID, req.ShiftID, userID)
+	if err != nil {
+		if status := reportErrorStatus(err); status != http.StatusInternalServerError {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		h.logger.Error("Failed to build Z-report", zap.Error(err))
+		http.Error(w, "Failed to generate Z-report", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to generate Z-report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stored)
+}
+
+// createZReport builds and persists a Z-report inside the caller's transaction.
+// The register row is locked so Z numbers are allocated without gaps or duplicates.
+func createZReport(tx *sqlx.Tx, tenantID string, registerID, sessionID, shiftID *int, userID int) (*ZReport, error) {
+	scope, err := resolveReportScope(tx, tenantID, registerID, sessionID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.Closed {
+		return nil, errReportTargetOpen
+	}
+
+	var lockedID int
+	err = tx.QueryRow("SELECT id FROM pos_registers WHERE id = $1 AND tenant_id = $2 FOR UPDATE",
+		scope.RegisterID, tenantID).Scan(&lockedID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sessions and shifts may only be Z'd once; register reports must cover new activity
+	var existing int
+	switch scope.Scope {
+	case "session":
+		err = tx.QueryRow("SELECT COUNT(*) FROM pos_z_reports WHERE scope = 'session' AND session_id = $1", *scope.SessionID).Scan(&existing)
+	case "shift":
+		err = tx.QueryRow("SELECT COUNT(*) FROM pos_z_reports WHERE scope = 'shift' AND shift_id = $1", *scope.ShiftID).Scan(&existing)
+	default:
+		err = tx.QueryRow(`
+			SELECT COUNT(*) FROM pos_z_reports
+			WHERE tenant_id = $1 AND register_id = $2 AND scope = 'register' AND period_end >= $3
+		`, tenantID, scope.RegisterID, scope.PeriodEnd).Scan(&existing)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, errReportAlreadyTaken
+	}
+
+	var zNumber int
+	err = tx.QueryRow("SELECT COALESCE(MAX(z_number), 0) + 1 FROM pos_z_reports WHERE tenant_id = $1 AND register_id = $2",
+		tenantID, scope.RegisterID).Scan(&zNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := buildRegisterReport(tx, tenantID, scope, "Z", userID)
+	if err != nil {
+		return nil, err
+	}
+	report.ZNumber = &zNumber
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &ZReport{
+		TenantID:         tenantID,
+		RegisterID:       scope.RegisterID,
+		SessionID:        scope.SessionID,
+		ShiftID:          scope.ShiftID,
+		Scope:            scope.Scope,
+		ZNumber:          zNumber,
+		PeriodStart:      scope.PeriodStart,
+		PeriodEnd:        scope.PeriodEnd,
+		TransactionCount: report.TransactionCount,
+		NetSales:         report.NetSales,
+		ReportData:       reportJSON,
+		GeneratedBy:      &userID,
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO pos_z_reports (tenant_id, register_id, session_id, shift_id, scope, z_number,
+		                           period_start, period_end, transaction_count, net_sales, report_data, generated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at
+	`, tenantID, stored.RegisterID, stored.SessionID, stored.ShiftID, stored.Scope, zNumber,
+		stored.PeriodStart, stored.PeriodEnd, stored.TransactionCount, stored.NetSales, string(reportJSON), userID).
+		Scan(&stored.ID, &stored.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// GetZReports lists stored Z-reports
+func (h *ReportHandler) GetZReports(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	registerID := r.URL.Query().Get("register_id")
+	scope := r.URL.Query().Get("scope")
+
+	query := `
+		SELECT id, tenant_id, register_id, session_id, shift_id, scope, z_number, period_start, period_end,
+		       transaction_count, net_sales, generated_by, created_at
+		FROM pos_z_reports
+		WHERE tenant_id = $1
+	`
+	args := []interface{}{tenantID}
+	argIndex := 2
+
+	if registerID != "" {
+		query += fmt.Sprintf(" AND register_id = $%d", argIndex)
+		args = append(args, registerID)
+		argIndex++
+	}
+
+	if scope != "" {
+		query += fmt.Sprintf(" AND scope = $%d", argIndex)
+		args = append(args, scope)
+		argIndex++
+	}
+
+	query += " ORDER BY register_id, z_number DESC"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch Z-reports", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var reports []ZReport
+	for rows.Next() {
+		var report ZReport
+		err := rows.Scan(&report.ID, &report.TenantID, &report.RegisterID, &report.SessionID, &report.ShiftID,
+			&report.Scope, &report.ZNumber, &report.PeriodStart, &report.PeriodEnd, &report.TransactionCount,
+			&report.NetSales, &report.GeneratedBy, &report.CreatedAt)
+		if err != nil {
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reports": reports,
+		"count":   len(reports),
+	})
+}
+
+// GetZReport returns a stored Z-report exactly as it was generated, for reprinting
+func (h *ReportHandler) GetZReport(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	reportID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	var reportData []byte
+	err = h.db.QueryRow("SELECT report_data FROM pos_z_reports WHERE id = $1 AND tenant_id = $2",
+		reportID, tenantID).Scan(&reportData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Z-report not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch Z-report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reportData)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReportScopeFilters(t *testing.T) {
+	sessionID, shiftID := 7, 9
+	start := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+
+	tests := []struct {
+		name         string
+		scope        reportScope
+		wantTx       string
+		wantTxArgs   []interface{}
+		wantCash     string
+		wantCashArgs []interface{}
+	}{
+		{"session",
+			reportScope{Scope: "session", RegisterID: 3, SessionID: &sessionID, PeriodStart: start, PeriodEnd: end},
+			"pt.session_id = $2", []interface{}{7},
+			"register_id = $2 AND created_at > $3 AND created_at <= $4", []interface{}{3, start, end}},
+		{"shift",
+			reportScope{Scope: "shift", RegisterID: 3, ShiftID: &shiftID, PeriodStart: start, PeriodEnd: end},
+			"pt.shift_id = $2", []interface{}{9},
+			"shift_id = $2", []interface{}{9}},
+		{"register",
+			reportScope{Scope: "register", RegisterID: 3, PeriodStart: start, PeriodEnd: end},
+			"pt.register_id = $2 AND pt.transaction_date > $3 AND pt.transaction_date <= $4", []interface{}{3, start, end},
+			"register_id = $2 AND created_at > $3 AND created_at <= $4", []interface{}{3, start, end}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, args := tt.scope.transactionFilter()
+			if filter != tt.wantTx || !reflect.DeepEqual(args, tt.wantTxArgs) {
+				t.Errorf("transactionFilter() = %q %v, want %q %v", filter, args, tt.wantTx, tt.wantTxArgs)
+			}
+			filter, args = tt.scope.cashMovementFilter()
+			if filter != tt.wantCash || !reflect.DeepEqual(args, tt.wantCashArgs) {
+				t.Errorf("cashMovementFilter() = %q %v, want %q %v", filter, args, tt.wantCash, tt.wantCashArgs)
+			}
+		})
+	}
+}
+
+func TestReportScopeFromQuery(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	tests := []struct {
+		query                                string
+		wantRegister, wantSession, wantShift *int
+		wantErr                              bool
+	}{
+		{"", nil, nil, nil, false},
+		{"register_id=3", intPtr(3), nil, nil, false},
+		{"session_id=7&shift_id=9", nil, intPtr(7), intPtr(9), false},
+		{"register_id=abc", nil, nil, nil, true},
+		{"shift_id=", nil, nil, nil, false},
+		{"shift_id=x", nil, nil, nil, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/reports/x?"+tt.query, nil)
+		registerID, sessionID, shiftID, err := reportScopeFromQuery(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("reportScopeFromQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if !reflect.DeepEqual(registerID, tt.wantRegister) || !reflect.DeepEqual(sessionID, tt.wantSession) ||
+			!reflect.DeepEqual(shiftID, tt.wantShift) {
+			t.Errorf("reportScopeFromQuery(%q) = %v, %v, %v, want %v, %v, %v", tt.query,
+				registerID, sessionID, shiftID, tt.wantRegister, tt.wantSession, tt.wantShift)
+		}
+	}
+}
@@ -0,0 +1,639 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ReturnHandler handles voids and returns of completed sales
+type ReturnHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewReturnHandler creates a new return handler
+func NewReturnHandler(db *sqlx.DB, logger *zap.Logger) *ReturnHandler {
+	return &ReturnHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+var (
+	errSaleNotFound      = errors.New("transaction not found")
+	errSaleNotReversible = errors.New("only completed sales can be voided or returned")
+	errSaleHasReturns    = errors.New("sales with returns cannot be voided")
+)
+
+// originalSale is the part of a sale needed to reverse it
+type originalSale struct {
+	SessionID  int
+	RegisterID int
+	CustomerID *int
+	Total      float64
+}
+
+// lockOriginalSale loads a completed sale for reversal, locking it against concurrent voids and returns
+func lockOriginalSale(tx *sqlx.Tx, tenantID string, transactionID int) (*originalSale, error) {
+	var sale originalSale
+	var transactionType, status string
+	err := tx.QueryRow(`
+		SELECT session_id, register_id, customer_id, transaction_type, COALESCE(status, 'completed'),
+		       COALESCE(total_amount, 0)
+		FROM pos_transactions
+		WHERE id = $1 AND tenant_id = $2
+		FOR UPDATE
+	`, transactionID, tenantID).Scan(&sale.SessionID, &sale.RegisterID, &sale.CustomerID, &transactionType,
+		&status, &sale.Total)
+	if err == sql.ErrNoRows {
+		return nil, errSaleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if transactionType != "sale" || status != "completed" {
+		return nil, errSaleNotReversible
+	}
+	return &sale, nil
+}
+
+// reversalErrorStatus maps a void or return error to an HTTP status
+func reversalErrorStatus(err error) int {
+	switch err {
+	case errSaleNotFound:
+		return http.StatusNotFound
+	case errSaleNotReversible, errSaleHasReturns:
+		return http.StatusConflict
+	}
+	return saleContextErrorStatus(err)
+}
+
+// payOutCash records cash handed back to the customer as a refund_cash movement on the register.
+// It writes the error response and returns false when the drawer cannot cover the amount.
+func (h *ReturnHandler) payOutCash(w http.ResponseWriter, tx *sqlx.Tx, tenantID string, registerID int, amount float64,
+	reason string, notes, referenceNumber *string, userID int) bool {
+	_, balance, err := lockRegister(tx, tenantID, registerID)
+	if err != nil {
+		http.Error(w, "Failed to fetch register", http.StatusInternalServerError)
+		return false
+	}
+	if amount > balance {
+		http.Error(w, "Refund exceeds cash in register", http.StatusConflict)
+		return false
+	}
+	if _, err = recordCashMovement(tx, tenantID, registerID, "refund_cash", amount, balance, &reason, notes,
+		referenceNumber, userID); err != nil {
+		http.Error(w, "Failed to record cash movement", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// VoidTransaction cancels a completed sale while its session is still active. Totals, the
+// register's cash and stock are reversed; the sale stays on record with status void.
+func (h *ReturnHandler) VoidTransaction(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.transactions.void") {
+		http.Error(w, "Voiding a sale requires the pos.transactions.void permission", http.StatusForbidden)
+		return
+	}
+
+	transactionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason      string `json:"reason" validate:"required"`
+		Disposition string `json:"disposition"` // restock (default), damaged or write_off
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Reason == "" {
+		http.Error(w, "A reason is required to void a sale", http.StatusBadRequest)
+		return
+	}
+	if req.Disposition == "" {
+		req.Disposition = "restock"
+	} else if !stockDispositions[req.Disposition] {
+		http.Error(w, "Disposition must be restock, damaged or write_off", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to void transaction", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	sale, err := lockOriginalSale(tx, tenantID, transactionID)
+	if err == nil {
+		var hasReturns bool
+		err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM pos_return_items WHERE original_transaction_id = $1)",
+			transactionID).Scan(&hasReturns)
+		if err == nil && hasReturns {
+			err = errSaleHasReturns
+		}
+	}
+	var shiftID int
+	if err == nil {
+		shiftID, err = validateSaleContext(tx, tenantID, sale.SessionID, sale.RegisterID)
+	}
+	if err != nil {
+		status := reversalErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			http.Error(w, "Failed to void transaction", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Cash and card taken on the sale, net of change, leave the shift again
+	var cashSales, cardSales float64
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(pp.amount) FILTER (WHERE pp.payment_method = 'cash'), 0) - COALESCE(pt.change_amount, 0),
+		       COALESCE(SUM(pp.amount) FILTER (WHERE pp.payment_method = 'card'), 0)
+		FROM pos_transactions pt
+		LEFT JOIN pos_payments pp ON pp.transaction_id = pt.id AND pp.status = 'completed'
+		WHERE pt.id = $1
+		GROUP BY pt.change_amount
+	`, transactionID).Scan(&cashSales, &cardSales)
+	if err != nil {
+		http.Error(w, "Failed to void transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pos_transactions
+		SET status = 'void',
+		    custom_fields = COALESCE(custom_fields, '{}') || jsonb_build_object('void_reason', $1::text, 'voided_by', $2::int)
+		WHERE id = $3
+	`, req.Reason, userID, transactionID)
+	if err != nil {
+		http.Error(w, "Failed to void transaction", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("UPDATE pos_payments SET status = 'void' WHERE transaction_id = $1 AND status = 'completed'", transactionID)
+	if err != nil {
+		http.Error(w, "Failed to void payments", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec("UPDATE pos_sessions SET total_sales = total_sales - $1 WHERE id = $2", sale.Total, sale.SessionID)
+	if err != nil {
+		http.Error(w, "Failed to update session", http.StatusInternalServerError)
+		return
+	}
+
+	// Cash handed back leaves the drawer as a refund_cash movement, which the shift's expected
+	// balance already subtracts, so only card takings come off the shift totals
+	_, err = tx.Exec(`
+		UPDATE register_shifts
+		SET total_sales = total_sales - $1, total_card_sales = total_card_sales - $2
+		WHERE id = $3
+	`, sale.Total, cardSales, shiftID)
+	if err != nil {
+		http.Error(w, "Failed to update shift", http.StatusInternalServerError)
+		return
+	}
+
+	if cashSales > 0 {
+		reason := "Void of " + strconv.Itoa(transactionID)
+		if !h.payOutCash(w, tx, tenantID, sale.RegisterID, cashSales, reason, &req.Reason, nil, userID) {
+			return
+		}
+	}
+
+	// Nothing left the store, so every item comes back with the chosen disposition
+	if err = reverseVoidedSale(tx, tenantID, transactionID, userID, req.Disposition); err != nil {
+		h.logger.Error("Failed to record stock movements", zap.Int("transaction_id", transactionID), zap.Error(err))
+		http.Error(w, "Failed to update inventory", http.StatusInternalServerError)
+		return
+	}
+	if err = releaseVoidedTracking(tx, transactionID, req.Disposition); err != nil {
+		http.Error(w, "Failed to release serial and lot numbers", http.StatusInternalServerError)
+		return
+	}
+	if err = releaseVoidedBundleUses(tx, transactionID); err != nil {
+		http.Error(w, "Failed to release bundle price uses", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to void transaction", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Transaction voided",
+		zap.String("tenant_id", tenantID),
+		zap.Int("transaction_id", transactionID),
+		zap.Int("user_id", userID),
+		zap.String("reason", req.Reason),
+		zap.String("disposition", req.Disposition))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"transaction_id": transactionID,
+		"status":         "void",
+		"disposition":    req.Disposition,
+		"message":        "Transaction voided successfully",
+	})
+}
+
+// returnLine is an original sale line with what is still returnable
+type returnLine struct {
+	ProductID        int
+	VariantID        *int
+	UnitOfMeasure    *string
+	Quantity         float64
+	Returned         float64
+	UnitPrice        float64
+	DiscountAmount   float64
+	TaxRate          float64
+	TaxAmount        float64
+	RefundedDiscount float64
+	RefundedTax      float64
+}
+
+// prorateReturn returns the discount and tax given back for returning quantity more of the line
+// and counts it as returned. Each return refunds the cumulative share up to that point less what
+// was refunded before, so partial returns never drift by a cent and the last one gets the rest.
+func (line *returnLine) prorateReturn(quantity float64) (discount, tax float64) {
+	line.Returned += quantity
+	cumulativeDiscount, cumulativeTax := line.DiscountAmount, line.TaxAmount
+	if math.Round(line.Returned*1000) < math.Round(line.Quantity*1000) {
+		ratio := line.Returned / line.Quantity
+		cumulativeDiscount = math.Round(line.DiscountAmount*ratio*100) / 100
+		cumulativeTax = math.Round(line.TaxAmount*ratio*100) / 100
+	}
+	discount = math.Round((cumulativeDiscount-line.RefundedDiscount)*100) / 100
+	tax = math.Round((cumulativeTax-line.RefundedTax)*100) / 100
+	line.RefundedDiscount += discount
+	line.RefundedTax += tax
+	return discount, tax
+}
+
+// ReturnTransaction takes items of a completed sale back at the current register. It records a
+// return transaction with prorated discount and tax, refunds it, and restocks each item or
+// books it as damaged.
+func (h *ReturnHandler) ReturnTransaction(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.returns.create") {
+		http.Error(w, "Taking a return requires the pos.returns.create permission", http.StatusForbidden)
+		return
+	}
+
+	originalID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		SessionID  int     `json:"session_id" validate:"required"`
+		RegisterID int     `json:"register_id" validate:"required"`
+		Reason     *string `json:"reason"`
+		// RefundMethod defaults to cash
+		RefundMethod string `json:"refund_method"`
+		Items        []struct {
+			ItemID        int       `json:"item_id"`
+			Quantity      float64   `json:"quantity"`
+			Disposition   string    `json:"disposition"`    // restock (default), damaged or write_off
+			SerialNumbers []string  `json:"serial_numbers"` // required for serialized items
+			Lots          []ItemLot `json:"lots"`           // defaults to the line's only lot
+		} `json:"items" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) == 0 {
+		http.Error(w, "At least one item is required", http.StatusBadRequest)
+		return
+	}
+	switch req.RefundMethod {
+	case "":
+		req.RefundMethod = "cash"
+	case "cash", "card":
+	default:
+		http.Error(w, "Refund method must be cash or card", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to create return", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	sale, err := lockOriginalSale(tx, tenantID, originalID)
+	var shiftID int
+	if err == nil {
+		shiftID, err = validateSaleContext(tx, tenantID, req.SessionID, req.RegisterID)
+	}
+	if err != nil {
+		status := reversalErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			http.Error(w, "Failed to create return", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	rows, err := tx.Query(`
+		SELECT pti.id, pti.product_id, pti.variant_id, pti.unit_of_measure, pti.quantity, pti.unit_price,
+		       COALESCE(pti.discount_amount, 0),
+		       COALESCE(pti.tax_rate, 0), COALESCE(pti.tax_amount, 0),
+		       COALESCE(returned.quantity, 0), COALESCE(returned.discount_amount, 0),
+		       COALESCE(returned.tax_amount, 0)
+		FROM pos_transaction_items pti
+		LEFT JOIN LATERAL (
+			SELECT SUM(ri.quantity) AS quantity, SUM(rti.discount_amount) AS discount_amount,
+			       SUM(rti.tax_amount) AS tax_amount
+			FROM pos_return_items ri
+			JOIN pos_transaction_items rti ON rti.id = ri.return_item_id
+			WHERE ri.original_item_id = pti.id
+		) returned ON true
+		WHERE pti.transaction_id = $1
+	`, originalID)
+	if err != nil {
+		http.Error(w, "Failed to load sale items", http.StatusInternalServerError)
+		return
+	}
+	lines := make(map[int]*returnLine)
+	for rows.Next() {
+		var itemID int
+		var line returnLine
+		if err := rows.Scan(&itemID, &line.ProductID, &line.VariantID, &line.UnitOfMeasure, &line.Quantity, &line.UnitPrice,
+			&line.DiscountAmount, &line.TaxRate, &line.TaxAmount, &line.Returned, &line.RefundedDiscount,
+			&line.RefundedTax); err != nil {
+			rows.Close()
+			http.Error(w, "Failed to load sale items", http.StatusInternalServerError)
+			return
+		}
+		lines[itemID] = &line
+	}
+	rows.Close()
+
+	var subtotal, discountTotal, taxTotal float64
+	discounts := make([]float64, len(req.Items))
+	taxes := make([]float64, len(req.Items))
+	for i, item := range req.Items {
+		line, ok := lines[item.ItemID]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Item %d is not on this transaction", item.ItemID), http.StatusBadRequest)
+			return
+		}
+		if item.Disposition == "" {
+			req.Items[i].Disposition = "restock"
+		} else if !stockDispositions[item.Disposition] {
+			http.Error(w, "Disposition must be restock, damaged or write_off", http.StatusBadRequest)
+			return
+		}
+		// Weighed lines return fractions; compare at the quantity column's precision
+		returnable := math.Round((line.Quantity-line.Returned)*1000) / 1000
+		if item.Quantity <= 0 || item.Quantity > returnable {
+			http.Error(w, fmt.Sprintf("Quantity for item %d must be more than 0 and at most %g", item.ItemID, returnable), http.StatusConflict)
+			return
+		}
+		// Count the line as returned now, so the same item listed twice cannot exceed it
+		discounts[i], taxes[i] = line.prorateReturn(item.Quantity)
+
+		subtotal += item.Quantity * line.UnitPrice
+		discountTotal += discounts[i]
+		taxTotal += taxes[i]
+	}
+	discountTotal = math.Round(discountTotal*100) / 100
+	taxTotal = math.Round(taxTotal*100) / 100
+	subtotal = math.Round(subtotal*100) / 100
+	total := math.Round((subtotal-discountTotal+taxTotal)*100) / 100
+
+	returnNumber := fmt.Sprintf("RTN-%d", time.Now().UnixMilli())
+	customFieldsJSON, _ := json.Marshal(map[string]interface{}{
+		"original_transaction_id": originalID,
+		"return_reason":           req.Reason,
+	})
+
+	var returnID int
+	err = tx.QueryRow(`
+		INSERT INTO pos_transactions (tenant_id, transaction_number, session_id, register_id, shift_id, customer_id,
+		                             transaction_type, subtotal, tax_amount, discount_amount, total_amount,
+		                             cashier_id, notes, custom_fields)
+		VALUES ($1, $2, $3, $4, $5, $6, 'return', $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`, tenantID, returnNumber, req.SessionID, req.RegisterID, shiftID, sale.CustomerID,
+		subtotal, taxTotal, discountTotal, total, userID, req.Reason, customFieldsJSON).Scan(&returnID)
+	if err != nil {
+		http.Error(w, "Failed to create return", http.StatusInternalServerError)
+		return
+	}
+
+	adapter, err := tenantInventoryAdapter(tx, tenantID)
+	if err != nil {
+		h.logger.Error("Failed to load inventory adapter", zap.Error(err))
+		http.Error(w, "Failed to update inventory", http.StatusInternalServerError)
+		return
+	}
+	locationID, err := registerLocationID(tx, tenantID, req.RegisterID)
+	if err != nil {
+		http.Error(w, "Failed to update inventory", http.StatusInternalServerError)
+		return
+	}
+
+	for i, item := range req.Items {
+		line := lines[item.ItemID]
+		ratio := item.Quantity / line.Quantity
+
+		var returnItemID int
+		err = tx.QueryRow(`
+			INSERT INTO pos_transaction_items (transaction_id, product_id, variant_id, quantity, unit_price,
+			                                   discount_amount, tax_rate, tax_amount, unit_of_measure)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, returnID, line.ProductID, line.VariantID, item.Quantity, line.UnitPrice,
+			discounts[i], line.TaxRate, taxes[i], line.UnitOfMeasure).Scan(&returnItemID)
+		if err != nil {
+			http.Error(w, "Failed to create return item", http.StatusInternalServerError)
+			return
+		}
+
+		if err = copyItemModifiers(tx, item.ItemID, returnItemID, ratio); err == nil {
+			err = copyItemComponents(tx, item.ItemID, returnItemID, ratio)
+		}
+		if err != nil {
+			http.Error(w, "Failed to create return item", http.StatusInternalServerError)
+			return
+		}
+
+		err = returnItemTracking(tx, item.ItemID, returnItemID, item.Quantity, item.SerialNumbers, item.Lots,
+			item.Disposition)
+		if err != nil {
+			var optionErr *itemOptionError
+			if errors.As(err, &optionErr) {
+				http.Error(w, optionErr.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to record returned serial and lot numbers", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO pos_return_items (tenant_id, return_transaction_id, return_item_id, original_transaction_id,
+			                              original_item_id, quantity, disposition, reason)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, tenantID, returnID, returnItemID, originalID, item.ItemID, item.Quantity, item.Disposition,
+			req.Reason)
+		if err != nil {
+			http.Error(w, "Failed to create return item", http.StatusInternalServerError)
+			return
+		}
+
+		if adapter != nil {
+			// A returned kit puts its components back
+			parts, err := itemStockParts(tx, returnItemID, line.ProductID, item.Quantity)
+			for i := 0; err == nil && i < len(parts); i++ {
+				err = recordStockMovement(tx, adapter, StockMovement{
+					TenantID:          tenantID,
+					TransactionID:     returnID,
+					TransactionItemID: returnItemID,
+					ProductID:         parts[i].ProductID,
+					LocationID:        locationID,
+					Quantity:          parts[i].Quantity,
+					MovementType:      "return",
+					Disposition:       item.Disposition,
+					CreatedBy:         userID,
+				})
+			}
+			if err != nil {
+				h.logger.Error("Failed to record stock movement", zap.Int("transaction_id", returnID), zap.Error(err))
+				http.Error(w, "Failed to update inventory", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO pos_payments (transaction_id, payment_method, amount, status, notes)
+		VALUES ($1, $2, $3, 'completed', $4)
+	`, returnID, req.RefundMethod, total, "Refund for "+strconv.Itoa(originalID))
+	if err != nil {
+		http.Error(w, "Failed to create refund", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE pos_sessions
+		SET total_refunds = total_refunds + $1, total_transactions = total_transactions + 1
+		WHERE id = $2
+	`, total, req.SessionID)
+	if err != nil {
+		http.Error(w, "Failed to update session", http.StatusInternalServerError)
+		return
+	}
+
+	// Card refunds reduce the shift's card takings; cash refunds leave the drawer as a
+	// refund_cash movement, which the shift's expected balance already subtracts
+	var cashRefund, cardRefund float64
+	switch req.RefundMethod {
+	case "cash":
+		cashRefund = total
+	case "card":
+		cardRefund = total
+	}
+	_, err = tx.Exec(`
+		UPDATE register_shifts
+		SET total_returns = total_returns + $1, total_card_sales = total_card_sales - $2,
+		    transaction_count = transaction_count + 1
+		WHERE id = $3
+	`, total, cardRefund, shiftID)
+	if err != nil {
+		http.Error(w, "Failed to update shift", http.StatusInternalServerError)
+		return
+	}
+
+	if cashRefund > 0 {
+		reason := "Refund for " + strconv.Itoa(originalID)
+		if !h.payOutCash(w, tx, tenantID, req.RegisterID, cashRefund, reason, req.Reason, &returnNumber, userID) {
+			return
+		}
+	}
+
+	// Once every line has come back the original sale is refunded and cannot be returned again
+	fullyReturned := true
+	for _, line := range lines {
+		if math.Round(line.Returned*1000) < math.Round(line.Quantity*1000) {
+			fullyReturned = false
+			break
+		}
+	}
+	originalStatus := "completed"
+	if fullyReturned {
+		originalStatus = "refunded"
+		_, err = tx.Exec("UPDATE pos_transactions SET status = 'refunded' WHERE id = $1", originalID)
+		if err != nil {
+			http.Error(w, "Failed to update original transaction", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err = appendFiscalEntry(tx, tenantID, fiscalEntryTransaction, returnID); err != nil {
+		h.logger.Error("Failed to journal transaction", zap.Int("transaction_id", returnID), zap.Error(err))
+		http.Error(w, "Failed to journal transaction", http.StatusInternalServerError)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to create return", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"transaction_id":          returnID,
+		"transaction_number":      returnNumber,
+		"original_transaction_id": originalID,
+		"original_status":         originalStatus,
+		"shift_id":                shiftID,
+		"subtotal":                subtotal,
+		"discount_amount":         discountTotal,
+		"tax_amount":              taxTotal,
+		"total_amount":            total,
+		"refund_method":           req.RefundMethod,
+		"message":                 "Return created successfully",
+	})
+}
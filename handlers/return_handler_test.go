@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestProrateReturn(t *testing.T) {
+	type step struct {
+		quantity              float64
+		wantDiscount, wantTax float64
+	}
+	tests := []struct {
+		name  string
+		line  returnLine
+		steps []step
+	}{
+		{"thirds add up to the line",
+			returnLine{Quantity: 3, DiscountAmount: 1, TaxAmount: 2},
+			[]step{{1, 0.33, 0.67}, {1, 0.34, 0.66}, {1, 0.33, 0.67}}},
+		{"whole line at once",
+			returnLine{Quantity: 2, DiscountAmount: 0.5, TaxAmount: 1.99},
+			[]step{{2, 0.5, 1.99}}},
+		{"after an earlier return",
+			returnLine{Quantity: 4, DiscountAmount: 2, TaxAmount: 4, Returned: 1, RefundedDiscount: 0.5, RefundedTax: 1},
+			[]step{{3, 1.5, 3}}},
+		{"weighed fractions",
+			returnLine{Quantity: 0.75, DiscountAmount: 0.1, TaxAmount: 0.25},
+			[]step{{0.25, 0.03, 0.08}, {0.25, 0.04, 0.09}, {0.25, 0.03, 0.08}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := tt.line
+			for i, s := range tt.steps {
+				discount, tax := line.prorateReturn(s.quantity)
+				if math.Abs(discount-s.wantDiscount) > 1e-9 || math.Abs(tax-s.wantTax) > 1e-9 {
+					t.Errorf("return %d: prorateReturn(%g) = %v, %v, want %v, %v", i+1, s.quantity, discount, tax,
+						s.wantDiscount, s.wantTax)
+				}
+			}
+			if math.Abs(line.RefundedDiscount-line.DiscountAmount) > 1e-9 || math.Abs(line.RefundedTax-line.TaxAmount) > 1e-9 {
+				t.Errorf("refunded %v discount and %v tax, want the line's %v and %v", line.RefundedDiscount,
+					line.RefundedTax, line.DiscountAmount, line.TaxAmount)
+			}
+		})
+	}
+}
+
+func TestReversalErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errSaleNotFound, http.StatusNotFound},
+		{errSaleNotReversible, http.StatusConflict},
+		{errSaleHasReturns, http.StatusConflict},
+		{errSessionNotActive, http.StatusConflict},
+		{errSessionWrongRegister, http.StatusBadRequest},
+		{errors.New("connection reset"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := reversalErrorStatus(tt.err); got != tt.want {
+			t.Errorf("reversalErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,763 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// SafeHandler handles the location safe ledger and bank deposits
+type SafeHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewSafeHandler creates a new safe handler
+func NewSafeHandler(db *sqlx.DB, logger *zap.Logger) *SafeHandler {
+	return &SafeHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+var (
+	errSafeNotFound         = errors.New("safe not found")
+	errSafeInsufficientCash = errors.New("not enough cash in safe")
+	errSafeWrongLocation    = errors.New("register is not at the safe's location")
+)
+
+// =================================================================
+// SAFE LEDGER HELPERS
+// =================================================================
+
+// lockSafe loads an active safe and locks it for a balance change
+func lockSafe(tx *sqlx.Tx, tenantID string, safeID int) (Safe, error) {
+	var safe Safe
+	err := tx.QueryRow(`
+		SELECT id, tenant_id, location_id, name, current_balance, is_active, created_at, updated_at
+		FROM pos_safes
+		WHERE id = $1 AND tenant_id = $2 AND is_active = true
+		FOR UPDATE
+	`, safeID, tenantID).Scan(&safe.ID, &safe.TenantID, &safe.LocationID, &safe.Name,
+		&safe.CurrentBalance, &safe.IsActive, &safe.CreatedAt, &safe.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return safe, errSafeNotFound
+	}
+	return safe, err
+}
+
+// postSafeEntry writes a ledger entry and applies the signed amount to the safe balance
+func postSafeEntry(tx *sqlx.Tx, safe *Safe, entry SafeTransaction, signedAmount float64) (SafeTransaction, error) {
+	if safe.CurrentBalance+signedAmount < 0 {
+		return entry, errSafeInsufficientCash
+	}
+
+	entry.TenantID = safe.TenantID
+	entry.SafeID = safe.ID
+	entry.BalanceBefore = safe.CurrentBalance
+	entry.BalanceAfter = safe.CurrentBalance + signedAmount
+
+	err := tx.QueryRow(`
+		INSERT INTO pos_safe_transactions (tenant_id, safe_id, transaction_type, amount, balance_before, balance_after,
+		                                   register_id, register_transaction_id, deposit_id, reference_number, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at
+	`, entry.TenantID, entry.SafeID, entry.TransactionType, entry.Amount, entry.BalanceBefore, entry.BalanceAfter,
+		entry.RegisterID, entry.RegisterTransactionID, entry.DepositID, entry.ReferenceNumber, entry.Notes, entry.CreatedBy).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return entry, err
+	}
+
+	_, err = tx.Exec("UPDATE pos_safes SET current_balance = $1 WHERE id = $2", entry.BalanceAfter, safe.ID)
+	if err != nil {
+		return entry, err
+	}
+
+	safe.CurrentBalance = entry.BalanceAfter
+	return entry, nil
+}
+
+// checkRegisterAtSafeLocation ensures a register belongs to the same location as the safe
+func checkRegisterAtSafeLocation(tx *sqlx.Tx, tenantID string, registerID int, safe Safe) error {
+	var sameLocation bool
+	err := tx.QueryRow(`
+		SELECT location_id IS NOT DISTINCT FROM $1 FROM pos_registers
+		WHERE id = $2 AND tenant_id = $3 AND is_active = true
+	`, safe.LocationID, registerID, tenantID).Scan(&sameLocation)
+	if err == sql.ErrNoRows {
+		return errRegisterNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !sameLocation {
+		return errSafeWrongLocation
+	}
+	return nil
+}
+
+// safeErrorStatus maps safe and register errors to HTTP status codes
+func safeErrorStatus(err error) int {
+	switch err {
+	case errSafeNotFound, errRegisterNotFound:
+		return http.StatusNotFound
+	case errSafeWrongLocation:
+		return http.StatusBadRequest
+	case errSafeInsufficientCash, errRegisterNotOpen, errRegisterSuspended:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeSafeError writes a mapped error, hiding internal error details
+func writeSafeError(w http.ResponseWriter, err error, fallback string) {
+	status := safeErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		http.Error(w, fallback, status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// =================================================================
+// SAFES
+// =================================================================
+
+// GetSafes retrieves the tenant's safes
+func (h *SafeHandler) GetSafes(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query := `
+		SELECT id, tenant_id, location_id, name, current_balance, is_active, created_at, updated_at
+		FROM pos_safes
+		WHERE tenant_id = $1 AND is_active = true
+	`
+	args := []interface{}{tenantID}
+
+	if locationID := r.URL.Query().Get("location_id"); locationID != "" {
+		query += " AND location_id = $2"
+		args = append(args, locationID)
+	}
+
+	query += " ORDER BY name"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch safes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var safes []Safe
+	for rows.Next() {
+		var safe Safe
+		err := rows.Scan(&safe.ID, &safe.TenantID, &safe.LocationID, &safe.Name, &safe.CurrentBalance,
+			&safe.IsActive, &safe.CreatedAt, &safe.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		safes = append(safes, safe)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"safes": safes,
+		"count": len(safes),
+	})
+}
+
+// CreateSafe creates the safe for a location
+func (h *SafeHandler) CreateSafe(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.safes.manage") {
+		http.Error(w, "Creating a safe requires the pos.safes.manage permission", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Name       string `json:"name" validate:"required"`
+		LocationID *int   `json:"location_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	var id int
+	var createdAt time.Time
+	err = h.db.QueryRow(`
+		INSERT INTO pos_safes (tenant_id, location_id, name)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, tenantID, req.LocationID, req.Name).Scan(&id, &createdAt)
+	if err != nil {
+		http.Error(w, "Failed to create safe; a location can only have one active safe", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"created_at": createdAt,
+		"message":    "Safe created successfully",
+	})
+}
+
+// GetSafeTransactions lists a safe's ledger
+func (h *SafeHandler) GetSafeTransactions(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	safeID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid safe ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT id, tenant_id, safe_id, transaction_type, amount, balance_before, balance_after, register_id,
+		       register_transaction_id, deposit_id, reference_number, notes, created_at, created_by
+		FROM pos_safe_transactions
+		WHERE tenant_id = $1 AND safe_id = $2
+		ORDER BY created_at DESC, id DESC
+	`, tenantID, safeID)
+	if err != nil {
+		http.Error(w, "Failed to fetch safe transactions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var entries []SafeTransaction
+	for rows.Next() {
+		var e SafeTransaction
+		err := rows.Scan(&e.ID, &e.TenantID, &e.SafeID, &e.TransactionType, &e.Amount, &e.BalanceBefore,
+			&e.BalanceAfter, &e.RegisterID, &e.RegisterTransactionID, &e.DepositID, &e.ReferenceNumber,
+			&e.Notes, &e.CreatedAt, &e.CreatedBy)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"transactions": entries,
+		"count":        len(entries),
+	})
+}
+
+// =================================================================
+// REGISTER DROPS AND CHANGE FUNDS
+// =================================================================
+
+// CreateSafeDrop moves cash from an open register into the location safe
+func (h *SafeHandler) CreateSafeDrop(w http.ResponseWriter, r *http.Request) {
+	h.moveRegisterCash(w, r, "cash_drop", "register_drop", "Cash drop recorded successfully")
+}
+
+// CreateChangeFund moves cash from the safe into an open register
+func (h *SafeHandler) CreateChangeFund(w http.ResponseWriter, r *http.Request) {
+	h.moveRegisterCash(w, r, "cash_in", "change_fund", "Change fund issued successfully")
+}
+
+// moveRegisterCash records the register side and the safe side of a transfer in one transaction
+func (h *SafeHandler) moveRegisterCash(w http.ResponseWriter, r *http.Request, registerType, safeType, message string) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.safes.manage") {
+		http.Error(w, "Moving cash between a register and a safe requires the pos.safes.manage permission", http.StatusForbidden)
+		return
+	}
+
+	safeID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid safe ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		RegisterID      int     `json:"register_id" validate:"required"`
+		Amount          float64 `json:"amount" validate:"required"`
+		ReferenceNumber *string `json:"reference_number"`
+		Notes           *string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount <= 0 {
+		http.Error(w, "Amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to move cash", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	safe, err := lockSafe(tx, tenantID, safeID)
+	if err != nil {
+		writeSafeError(w, err, "Failed to fetch safe")
+		return
+	}
+
+	if err := checkRegisterAtSafeLocation(tx, tenantID, req.RegisterID, safe); err != nil {
+		writeSafeError(w, err, "Failed to fetch register")
+		return
+	}
+
+	status, registerBalance, err := lockRegister(tx, tenantID, req.RegisterID)
+	if err != nil {
+		writeSafeError(w, err, "Failed to fetch register")
+		return
+	}
+	if status != "open" {
+		http.Error(w, fmt.Sprintf("Register is %s; cash movements require an open register", status), http.StatusConflict)
+		return
+	}
+
+	// Drops leave the register; change funds leave the safe
+	safeAmount := req.Amount
+	if registerType == "cash_drop" {
+		if req.Amount > registerBalance {
+			http.Error(w, "Amount exceeds cash in register", http.StatusBadRequest)
+			return
+		}
+	} else {
+		safeAmount = -req.Amount
+	}
+
+	reason := fmt.Sprintf("Safe %s", safe.Name)
+	movement, err := recordCashMovement(tx, tenantID, req.RegisterID, registerType, req.Amount, registerBalance,
+		&reason, req.Notes, req.ReferenceNumber, userID)
+	if err != nil {
+		http.Error(w, "Failed to record register movement", http.StatusInternalServerError)
+		return
+	}
+
+	entry, err := postSafeEntry(tx, &safe, SafeTransaction{
+		TransactionType:       safeType,
+		Amount:                req.Amount,
+		RegisterID:            &req.RegisterID,
+		RegisterTransactionID: &movement.ID,
+		ReferenceNumber:       req.ReferenceNumber,
+		Notes:                 req.Notes,
+		CreatedBy:             &userID,
+	}, safeAmount)
+	if err != nil {
+		writeSafeError(w, err, "Failed to update safe")
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to move cash", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"safe_transaction":     entry,
+		"register_transaction": movement,
+		"safe_balance":         safe.CurrentBalance,
+		"message":              message,
+	})
+}
+
+// =================================================================
+// BANK DEPOSITS
+// =================================================================
+
+// GetBankDeposits lists bank deposits
+func (h *SafeHandler) GetBankDeposits(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	safeID := r.URL.Query().Get("safe_id")
+	status := r.URL.Query().Get("status")
+
+	query := `SELECT * FROM pos_bank_deposits WHERE tenant_id = $1`
+	args := []interface{}{tenantID}
+	argIndex := 2
+
+	if safeID != "" {
+		query += fmt.Sprintf(" AND safe_id = $%d", argIndex)
+		args = append(args, safeID)
+		argIndex++
+	}
+
+	if status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, status)
+		argIndex++
+	}
+
+	query += " ORDER BY prepared_at DESC"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch bank deposits", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var deposits []BankDeposit
+	for rows.Next() {
+		var d BankDeposit
+		err := rows.Scan(&d.ID, &d.TenantID, &d.SafeID, &d.DepositNumber, &d.SlipNumber, &d.BagID,
+			&d.ExpectedAmount, &d.ConfirmedAmount, &d.Variance, &d.Status, &d.BankReference, &d.Notes,
+			&d.PreparedBy, &d.PreparedAt, &d.DispatchedBy, &d.DispatchedAt, &d.ConfirmedBy, &d.ConfirmedAt,
+			&d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		deposits = append(deposits, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deposits": deposits,
+		"count":    len(deposits),
+	})
+}
+
+// CreateBankDeposit prepares a deposit batch and takes its cash out of the safe
+func (h *SafeHandler) CreateBankDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.deposits.create") {
+		http.Error(w, "Preparing a bank deposit requires the pos.deposits.create permission", http.StatusForbidden)
+		return
+	}
+
+	safeID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid safe ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Amount     *float64 `json:"amount"` // defaults to the full safe balance
+		SlipNumber *string  `json:"slip_number"`
+		BagID      *string  `json:"bag_id"`
+		Notes      *string  `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to create bank deposit", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	safe, err := lockSafe(tx, tenantID, safeID)
+	if err != nil {
+		writeSafeError(w, err, "Failed to fetch safe")
+		return
+	}
+
+	amount := safe.CurrentBalance
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if amount <= 0 {
+		http.Error(w, "Deposit amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	depositNumber, err := nextDocumentNumber(tx, tenantID, "DEP")
+	if err != nil {
+		http.Error(w, "Failed to allocate deposit number", http.StatusInternalServerError)
+		return
+	}
+
+	var deposit BankDeposit
+	err = tx.QueryRow(`
+		INSERT INTO pos_bank_deposits (tenant_id, safe_id, deposit_number, slip_number, bag_id, expected_amount,
+		                               notes, prepared_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, prepared_at
+	`, tenantID, safeID, depositNumber, req.SlipNumber, req.BagID, amount, req.Notes, userID).
+		Scan(&deposit.ID, &deposit.PreparedAt)
+	if err != nil {
+		http.Error(w, "Failed to create bank deposit", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = postSafeEntry(tx, &safe, SafeTransaction{
+		TransactionType: "bank_deposit",
+		Amount:          amount,
+		DepositID:       &deposit.ID,
+		ReferenceNumber: req.SlipNumber,
+		Notes:           req.Notes,
+		CreatedBy:       &userID,
+	}, -amount)
+	if err != nil {
+		writeSafeError(w, err, "Failed to update safe")
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to create bank deposit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":              deposit.ID,
+		"deposit_number":  depositNumber,
+		"expected_amount": amount,
+		"safe_balance":    safe.CurrentBalance,
+		"prepared_at":     deposit.PreparedAt,
+		"message":         "Bank deposit prepared successfully",
+	})
+}
+
+// DispatchBankDeposit marks a prepared deposit as handed to the courier or bank
+func (h *SafeHandler) DispatchBankDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.deposits.create") {
+		http.Error(w, "Dispatching a bank deposit requires the pos.deposits.create permission", http.StatusForbidden)
+		return
+	}
+
+	depositID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid deposit ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		BagID *string `json:"bag_id"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	var dispatchedAt time.Time
+	err = h.db.QueryRow(`
+		UPDATE pos_bank_deposits
+		SET status = 'in_transit', bag_id = COALESCE($1, bag_id), dispatched_by = $2, dispatched_at = NOW()
+		WHERE id = $3 AND tenant_id = $4 AND status = 'prepared'
+		RETURNING dispatched_at
+	`, req.BagID, userID, depositID, tenantID).Scan(&dispatchedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Prepared deposit not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to dispatch deposit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":            depositID,
+		"status":        "in_transit",
+		"dispatched_at": dispatchedAt,
+		"message":       "Bank deposit dispatched successfully",
+	})
+}
+
+// ReconcileBankDeposit records the bank-confirmed amount and flags any discrepancy
+func (h *SafeHandler) ReconcileBankDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.deposits.reconcile") {
+		http.Error(w, "Reconciling a bank deposit requires the pos.deposits.reconcile permission", http.StatusForbidden)
+		return
+	}
+
+	depositID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid deposit ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		ConfirmedAmount float64 `json:"confirmed_amount" validate:"required"`
+		BankReference   *string `json:"bank_reference"`
+		Notes           *string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	var expected float64
+	var status string
+	var variance float64
+	err = h.db.QueryRow(`
+		UPDATE pos_bank_deposits
+		SET confirmed_amount = $1,
+		    status = CASE WHEN $1 = expected_amount THEN 'confirmed' ELSE 'discrepancy' END,
+		    bank_reference = COALESCE($2, bank_reference),
+		    notes = COALESCE($3, notes),
+		    confirmed_by = $4, confirmed_at = NOW()
+		WHERE id = $5 AND tenant_id = $6 AND status IN ('prepared', 'in_transit', 'discrepancy')
+		RETURNING expected_amount, status, variance
+	`, req.ConfirmedAmount, req.BankReference, req.Notes, userID, depositID, tenantID).Scan(&expected, &status, &variance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Open deposit not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to reconcile deposit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":               depositID,
+		"expected_amount":  expected,
+		"confirmed_amount": req.ConfirmedAmount,
+		"variance":         variance,
+		"status":           status,
+		"message":          "Bank deposit reconciled",
+	})
+}
+
+// CancelBankDeposit cancels a deposit that has not left the store and returns its cash to the safe
+func (h *SafeHandler) CancelBankDeposit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.deposits.create") {
+		http.Error(w, "Cancelling a bank deposit requires the pos.deposits.create permission", http.StatusForbidden)
+		return
+	}
+
+	depositID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid deposit ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to cancel deposit", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var safeID int
+	var amount float64
+	var depositNumber string
+	err = tx.QueryRow(`
+		UPDATE pos_bank_deposits SET status = 'cancelled'
+		WHERE id = $1 AND tenant_id = $2 AND status = 'prepared'
+		RETURNING safe_id, expected_amount, deposit_number
+	`, depositID, tenantID).Scan(&safeID, &amount, &depositNumber)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Only prepared deposits can be cancelled", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to cancel deposit", http.StatusInternalServerError)
+		return
+	}
+
+	safe, err := lockSafe(tx, tenantID, safeID)
+	if err != nil {
+		writeSafeError(w, err, "Failed to fetch safe")
+		return
+	}
+
+	_, err = postSafeEntry(tx, &safe, SafeTransaction{
+		TransactionType: "deposit_cancelled",
+		Amount:          amount,
+		DepositID:       &depositID,
+		ReferenceNumber: &depositNumber,
+		CreatedBy:       &userID,
+	}, amount)
+	if err != nil {
+		writeSafeError(w, err, "Failed to update safe")
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to cancel deposit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":           depositID,
+		"status":       "cancelled",
+		"safe_balance": safe.CurrentBalance,
+		"message":      "Bank deposit cancelled and returned to safe",
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteSafeError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{errSafeNotFound, http.StatusNotFound, "safe not found"},
+		{errRegisterNotFound, http.StatusNotFound, "register not found"},
+		{errSafeWrongLocation, http.StatusBadRequest, "register is not at the safe's location"},
+		{errSafeInsufficientCash, http.StatusConflict, "not enough cash in safe"},
+		{errRegisterNotOpen, http.StatusConflict, "register is not open"},
+		{errRegisterSuspended, http.StatusConflict, "register is suspended"},
+		{errors.New("pq: deadlock detected"), http.StatusInternalServerError, "Failed to record drop"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writeSafeError(w, tt.err, "Failed to record drop")
+		if w.Code != tt.wantStatus {
+			t.Errorf("writeSafeError(%v) status = %d, want %d", tt.err, w.Code, tt.wantStatus)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != tt.wantBody {
+			t.Errorf("writeSafeError(%v) body = %q, want %q", tt.err, body, tt.wantBody)
+		}
+	}
+}
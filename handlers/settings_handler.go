@@ -0,0 +1,281 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// SettingsHandler handles per-tenant module settings
+type SettingsHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(db *sqlx.DB, logger *zap.Logger) *SettingsHandler {
+	return &SettingsHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// defaultSettings mirrors the defaults declared in module.yml
+var defaultSettings = map[string]interface{}{
+	"default_payment_method":      "cash",
+	"auto_print_receipts":         true,
+	"receipt_header":              "",
+	"receipt_footer":              "Thank you for shopping with us",
+	"receipt_logo_url":            "",
+	"receipt_legal_text":          "",
+	"receipt_paper_width":         80,
+	"receipt_code_type":           "barcode",
+	"sms_default_country_code":    "1",
+	"receipt_link_ttl_days":       90,
+	"max_receipt_reprints":        2,
+	"invoice_seller_name":         "",
+	"invoice_seller_address":      "",
+	"invoice_seller_tax_id":       "",
+	"invoice_number_prefix":       "INV-",
+	"invoice_paper_size":          "A4",
+	"invoice_currency":            "USD",
+	"require_customer_for_sale":   false,
+	"enable_discounts":            true,
+	"enable_tips":                 true,
+	"default_tip_percentage":      15,
+	"enable_loyalty_program":      false,
+	"loyalty_points_per_dollar":   1,
+	"enable_inventory_tracking":   true,
+	"inventory_adapter":           "local",
+	"embedded_price_prefixes":     "20,21,22",
+	"embedded_weight_prefixes":    "23,24,25",
+	"embedded_weight_decimals":    3,
+	"product_search_sales_days":   90,
+	"enable_multi_location":       false,
+	"session_timeout_minutes":     480,
+	"shift_timeout_minutes":       720,
+	"stale_session_action":        "auto_close",
+	"require_manager_override":    true,
+	"max_refund_percentage":       100,
+	"enable_cash_drawer_tracking": true,
+	"cash_drawer_opening_amount":  100,
+}
+
+// settingLimits bound the numeric settings; whole ones are read into integers
+var settingLimits = map[string]struct {
+	min, max float64
+	whole    bool
+}{
+	"receipt_link_ttl_days":      {1, 3650, true},
+	"max_receipt_reprints":       {0, 100, true},
+	"default_tip_percentage":     {0, 100, false},
+	"loyalty_points_per_dollar":  {0, 1000, false},
+	"embedded_weight_decimals":   {0, 5, true},
+	"product_search_sales_days":  {1, 3650, true},
+	"session_timeout_minutes":    {0, 43200, true}, // 0 never times out
+	"shift_timeout_minutes":      {0, 43200, true},
+	"max_refund_percentage":      {0, 100, false},
+	"cash_drawer_opening_amount": {0, 1000000, false},
+}
+
+// settingOptions are the values the select settings accept
+var settingOptions = map[string][]interface{}{
+	"default_payment_method": {"cash", "card", "check", "gift_card"},
+	"receipt_paper_width":    {58.0, 80.0},
+	"receipt_code_type":      {"barcode", "qr", "none"},
+	"invoice_paper_size":     {"A4", "Letter"},
+	"inventory_adapter":      {"local", "core"},
+	"stale_session_action":   {"auto_close", "flag"},
+}
+
+// settingPatterns are the formats text settings must match
+var settingPatterns = map[string]*regexp.Regexp{
+	"sms_default_country_code": regexp.MustCompile(`^[0-9]{1,3}$`),
+	"invoice_currency":         regexp.MustCompile(`^[A-Z]{3}$`),
+	"embedded_price_prefixes":  regexp.MustCompile(`^([0-9]{1,3}(\s*,\s*[0-9]{1,3})*)?$`),
+	"embedded_weight_prefixes": regexp.MustCompile(`^([0-9]{1,3}(\s*,\s*[0-9]{1,3})*)?$`),
+}
+
+// validateSetting checks a setting sent by a client against its default's type and the
+// setting's range, options or format
+func validateSetting(key string, raw json.RawMessage) error {
+	def, ok := defaultSettings[key]
+	if !ok {
+		return fmt.Errorf("Unknown setting %q", key)
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("Setting %q is not valid JSON", key)
+	}
+
+	switch def.(type) {
+	case bool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("Setting %q must be true or false", key)
+		}
+	case string:
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("Setting %q must be text", key)
+		}
+		if pattern, ok := settingPatterns[key]; ok && !pattern.MatchString(text) {
+			return fmt.Errorf("Setting %q has an invalid format", key)
+		}
+	case int:
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("Setting %q must be a number", key)
+		}
+		if limit, ok := settingLimits[key]; ok {
+			if limit.whole && n != math.Trunc(n) {
+				return fmt.Errorf("Setting %q must be a whole number", key)
+			}
+			if n < limit.min || n > limit.max {
+				return fmt.Errorf("Setting %q must be between %g and %g", key, limit.min, limit.max)
+			}
+		}
+	}
+
+	if options, ok := settingOptions[key]; ok {
+		for _, option := range options {
+			if value == option {
+				return nil
+			}
+		}
+		names := make([]string, len(options))
+		for i, option := range options {
+			names[i] = fmt.Sprint(option)
+		}
+		return fmt.Errorf("Setting %q must be one of %s", key, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// loadTenantSetting decodes a tenant's setting into dest, leaving dest untouched when unset.
+// It reports whether the tenant has an override stored.
+func loadTenantSetting(q rowQuerier, tenantID, key string, dest interface{}) (bool, error) {
+	var value []byte
+	err := q.QueryRow("SELECT setting_value FROM pos_settings WHERE tenant_id = $1 AND setting_key = $2",
+		tenantID, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(value, dest)
+}
+
+// GetSettings returns the tenant's settings merged over the module defaults
+func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	settings := make(map[string]interface{}, len(defaultSettings))
+	for key, value := range defaultSettings {
+		settings[key] = value
+	}
+
+	rows, err := h.db.Query("SELECT setting_key, setting_value FROM pos_settings WHERE tenant_id = $1", tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch settings", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			continue
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(value, &decoded); err != nil {
+			continue
+		}
+		settings[key] = decoded
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": settings,
+	})
+}
+
+// UpdateSettings stores tenant overrides for one or more settings
+func (h *SettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if !h.baseHandler.hasPermission(r, "pos.settings.edit") {
+		http.Error(w, "Changing settings requires the pos.settings.edit permission", http.StatusForbidden)
+		return
+	}
+
+	var req map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(req) == 0 {
+		http.Error(w, "At least one setting is required", http.StatusBadRequest)
+		return
+	}
+	for key, value := range req {
+		if err := validateSetting(key, value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for key, value := range req {
+		_, err = tx.Exec(`
+			INSERT INTO pos_settings (tenant_id, setting_key, setting_value, updated_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (tenant_id, setting_key) DO UPDATE SET
+				setting_value = EXCLUDED.setting_value,
+				updated_by = EXCLUDED.updated_by,
+				updated_at = NOW()
+		`, tenantID, key, string(value), userID)
+		if err != nil {
+			http.Error(w, "Failed to update setting "+key, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"updated": len(req),
+		"message": "Settings updated successfully",
+	})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateSetting(t *testing.T) {
+	tests := []struct {
+		key     string
+		raw     string
+		wantErr bool
+	}{
+		{"session_timeout_minutes", `60`, false},
+		{"session_timeout_minutes", `0`, false},
+		{"session_timeout_minutes", `-5`, true},
+		{"session_timeout_minutes", `43201`, true},
+		{"shift_timeout_minutes", `12.5`, true},
+		{"shift_timeout_minutes", `"720"`, true},
+		{"stale_session_action", `"flag"`, false},
+		{"stale_session_action", `"delete"`, true},
+		{"stale_session_action", `true`, true},
+		{"sms_default_country_code", `"44"`, false},
+		{"sms_default_country_code", `"+44"`, true},
+		{"no_such_setting", `1`, true},
+		{"session_timeout_minutes", `{`, true},
+	}
+	for _, tt := range tests {
+		err := validateSetting(tt.key, json.RawMessage(tt.raw))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateSetting(%q, %s) error = %v, want error %v", tt.key, tt.raw, err, tt.wantErr)
+		}
+	}
+}
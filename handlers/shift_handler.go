@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -29,6 +30,119 @@ func NewShiftHandler(db *sqlx.DB, logger *zap.Logger) *ShiftHandler {
 	}
 }
 
+// =================================================================
+// SHIFT LINKAGE HELPERS
+// =================================================================
+
+// register_shifts is the canonical shift table; sessions and transactions
+// reference it through shift_id.
+
+var (
+	errNoOpenShift          = errors.New("no open shift for this register")
+	errSessionNotFound      = errors.New("session not found")
+	errSessionNotActive     = errors.New("session is not active")
+	errSessionWrongRegister = errors.New("session does not belong to this register")
+)
+
+// rowQuerier is satisfied by both *sqlx.DB and *sqlx.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// shiftExpectedBalance returns the cash a shift's drawer should hold: the opening float plus cash
+// sales and the shift's cash movements. The shift must be locked so no sale changes it meanwhile.
+func shiftExpectedBalance(q rowQuerier, shift RegisterShift) (float64, error) {
+	var netCashMovements float64
+	err := q.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN transaction_type IN ('cash_out', 'cash_drop', 'payout', 'refund_cash')
+		                         THEN -amount ELSE amount END), 0)
+		FROM register_transactions
+		WHERE shift_id = $1 AND transaction_type NOT IN ('opening', 'closing')
+	`, shift.ID).Scan(&netCashMovements)
+	if err != nil {
+		return 0, err
+	}
+	return shift.OpeningBalance + shift.TotalCashSales + netCashMovements, nil
+}
+
+// getOpenShiftID returns the ID of the register's open shift, share-locked so that a
+// concurrent close waits for the caller's transaction
+func getOpenShiftID(q rowQuerier, tenantID string, registerID int) (int, error) {
+	var shiftID int
+	err := q.QueryRow(`
+		SELECT id FROM register_shifts
+		WHERE tenant_id = $1 AND register_id = $2 AND status = 'open'
+		FOR SHARE
+	`, tenantID, registerID).Scan(&shiftID)
+	if err == sql.ErrNoRows {
+		return 0, errNoOpenShift
+	}
+	return shiftID, err
+}
+
+// validateSaleContext checks that a sale is posted to an active session on an
+// open shift of the same register, and returns the shift ID to record. The shift is
+// share-locked, so a close waits for the sale and counts it.
+// The session row is locked so it cannot be closed while the sale is written.
+func validateSaleContext(q rowQuerier, tenantID string, sessionID, registerID int) (int, error) {
+	var sessionRegisterID int
+	var status string
+	var shiftID sql.NullInt64
+	err := q.QueryRow(`
+		SELECT register_id, status, shift_id FROM pos_sessions
+		WHERE id = $1 AND tenant_id = $2
+		FOR UPDATE
+	`, sessionID, tenantID).Scan(&sessionRegisterID, &status, &shiftID)
+	if err == sql.ErrNoRows {
+		return 0, errSessionNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if sessionRegisterID != registerID {
+		return 0, errSessionWrongRegister
+	}
+	if err := requireRegisterOpen(q, tenantID, registerID); err != nil {
+		return 0, err
+	}
+	if status != "active" {
+		return 0, errSessionNotActive
+	}
+	if !shiftID.Valid {
+		return 0, errNoOpenShift
+	}
+
+	var shiftOpen bool
+	err = q.QueryRow(`
+		SELECT status = 'open' FROM register_shifts
+		WHERE id = $1 AND tenant_id = $2 AND register_id = $3
+		FOR SHARE
+	`, shiftID.Int64, tenantID, registerID).Scan(&shiftOpen)
+	if err == sql.ErrNoRows || (err == nil && !shiftOpen) {
+		return 0, errNoOpenShift
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return int(shiftID.Int64), nil
+}
+
+// saleContextErrorStatus maps a validateSaleContext error to an HTTP status
+func saleContextErrorStatus(err error) int {
+	switch err {
+	case errSessionNotFound, errRegisterNotFound:
+		return http.StatusNotFound
+	case errSessionWrongRegister:
+		return http.StatusBadRequest
+	case errSessionNotActive, errNoOpenShift, errRegisterNotOpen, errRegisterSuspended:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // GetPOSShifts retrieves all shifts
 func (h *ShiftHandler) GetPOSShifts(w http.ResponseWriter, r *http.Request) {
 	tenantID, err := h.baseHandler.getTenantID(r)
@@ -107,6 +221,26 @@ func (h *ShiftHandler) CreatePOSShift(w http.ResponseWriter, r *http.Request) {
 
 	userID, _ := h.baseHandler.getUserID(r)
 
+	// Shifts run on an open register
+	if err := requireRegisterOpen(h.db, tenantID, req.RegisterID); err != nil {
+		status := saleContextErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			http.Error(w, "Failed to create shift", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Only one shift may be open per register
+	if _, err := getOpenShiftID(h.db, tenantID, req.RegisterID); err == nil {
+		http.Error(w, "There is already an open shift for this register", http.StatusConflict)
+		return
+	} else if err != errNoOpenShift {
+		http.Error(w, "Failed to create shift", http.StatusInternalServerError)
+		return
+	}
+
 	// Generate shift number
 	shiftNumber := fmt.Sprintf("SHIFT-%d", time.Now().Unix())
 
@@ -121,6 +255,11 @@ func (h *ShiftHandler) CreatePOSShift(w http.ResponseWriter, r *http.Request) {
 
 	err = h.db.QueryRow(query, tenantID, req.RegisterID, shiftNumber, userID, req.OpeningBalance).
 		Scan(&id, &createdAt)
+	if isUniqueViolation(err, "idx_register_shifts_one_open") {
+		// Another shift was opened on the register since the check above
+		http.Error(w, "There is already an open shift for this register", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to create shift", http.StatusInternalServerError)
 		return
@@ -169,14 +308,13 @@ func (h *ShiftHandler) ClosePOSShift(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	// Get shift details
+	// Lock the shift so sales and sessions still writing to it finish before it is settled
 	var shift RegisterShift
-	err = tx.QueryRow("SELECT * FROM register_shifts WHERE id = $1 AND tenant_id = $2 AND status = 'open'",
-		shiftID, tenantID).Scan(&shift.ID, &shift.TenantID, &shift.RegisterID, &shift.ShiftNumber,
-		&shift.CashierID, &shift.OpeningBalance, &shift.ClosingBalance, &shift.ExpectedBalance,
-		&shift.Variance, &shift.TotalSales, &shift.TotalCashSales, &shift.TotalCardSales,
-		&shift.TotalReturns, &shift.TransactionCount, &shift.OpenedAt, &shift.ClosedAt,
-		&shift.Status, &shift.Notes, &shift.CreatedAt)
+	err = tx.QueryRow(`
+		SELECT id, opening_balance, total_cash_sales FROM register_shifts
+		WHERE id = $1 AND tenant_id = $2 AND status = 'open'
+		FOR UPDATE
+	`, shiftID, tenantID).Scan(&shift.ID, &shift.OpeningBalance, &shift.TotalCashSales)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -187,16 +325,32 @@ func (h *ShiftHandler) ClosePOSShift(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Calculate expected balance
-	expectedBalance := shift.OpeningBalance + shift.TotalSales
+	// Sessions must be closed before their shift
+	var activeSessions int
+	err = tx.QueryRow("SELECT COUNT(*) FROM pos_sessions WHERE shift_id = $1 AND status = 'active'", shiftID).
+		Scan(&activeSessions)
+	if err != nil {
+		http.Error(w, "Failed to close shift", http.StatusInternalServerError)
+		return
+	}
+	if activeSessions > 0 {
+		http.Error(w, "Shift has active sessions that must be closed first", http.StatusConflict)
+		return
+	}
+
+	expectedBalance, err := shiftExpectedBalance(tx, shift)
+	if err != nil {
+		http.Error(w, "Failed to close shift", http.StatusInternalServerError)
+		return
+	}
 	variance := req.ClosingAmount - expectedBalance
 
-	// Close shift
+	// Close shift (variance is a generated column)
 	_, err = tx.Exec(`
 		UPDATE register_shifts 
-		SET closing_balance = $1, expected_balance = $2, variance = $3, closed_at = $4, status = 'closed', notes = $5
-		WHERE id = $6
-	`, req.ClosingAmount, expectedBalance, variance, time.Now(), req.Notes, shiftID)
+		SET closing_balance = $1, expected_balance = $2, closed_at = $3, status = 'closed', notes = $4
+		WHERE id = $5
+	`, req.ClosingAmount, expectedBalance, time.Now(), req.Notes, shiftID)
 
 	if err != nil {
 		http.Error(w, "Failed to close shift", http.StatusInternalServerError)
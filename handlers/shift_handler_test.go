@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestSaleContextErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errSessionNotFound, http.StatusNotFound},
+		{errRegisterNotFound, http.StatusNotFound},
+		{errSessionWrongRegister, http.StatusBadRequest},
+		{errSessionNotActive, http.StatusConflict},
+		{errNoOpenShift, http.StatusConflict},
+		{errRegisterNotOpen, http.StatusConflict},
+		{errRegisterSuspended, http.StatusConflict},
+		{errors.New("connection reset"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := saleContextErrorStatus(tt.err); got != tt.want {
+			t.Errorf("saleContextErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
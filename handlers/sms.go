@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// smsMaxLength keeps receipt texts to a single GSM segment
+const smsMaxLength = 160
+
+var (
+	errInvalidPhoneNumber = errors.New("invalid phone number")
+	errSMSTooLong         = errors.New("receipt text does not fit in one SMS")
+	e164Pattern           = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+)
+
+// SMSNotifier sends text messages through an SMS provider
+type SMSNotifier interface {
+	SendSMS(ctx context.Context, to, body string) (providerMessageID string, err error)
+}
+
+// logSMSNotifier is the local stub provider; it logs messages instead of sending them
+type logSMSNotifier struct {
+	logger *zap.Logger
+}
+
+// SendSMS logs the message and returns a synthetic provider ID
+func (n *logSMSNotifier) SendSMS(ctx context.Context, to, body string) (string, error) {
+	n.logger.Info("SMS (stub provider)", zap.String("to", to), zap.String("body", body))
+	return fmt.Sprintf("stub-%d", time.Now().UnixNano()), nil
+}
+
+// normalizePhoneE164 converts a locally typed phone number to E.164 using the tenant's default country code
+func normalizePhoneE164(raw, defaultCountryCode string) (string, error) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", errInvalidPhoneNumber
+		}
+	}
+	number := b.String()
+
+	switch {
+	case strings.HasPrefix(number, "+"):
+	case strings.HasPrefix(number, "00"):
+		number = "+" + number[2:]
+	default:
+		countryCode := strings.TrimPrefix(defaultCountryCode, "+")
+		if countryCode == "" {
+			return "", errInvalidPhoneNumber
+		}
+		// A trunk prefix is dropped when the country code is added
+		number = "+" + countryCode + strings.TrimPrefix(number, "0")
+	}
+
+	if !e164Pattern.MatchString(number) {
+		return "", errInvalidPhoneNumber
+	}
+	return number, nil
+}
+
+// composeReceiptSMS writes a short receipt text that fits one message, dropping the store name and
+// then the receipt number when needed; errSMSTooLong means even the shortest form does not fit
+func composeReceiptSMS(doc *ReceiptDocument, link string) (string, error) {
+	store := ""
+	if lines := wrapReceiptText(doc.Template.Header, smsMaxLength); len(lines) > 0 {
+		store = lines[0] + ": "
+	}
+
+	total := formatReceiptMoney(doc.Total)
+	for _, body := range []string{
+		fmt.Sprintf("%sReceipt %s, total %s. View: %s", store, doc.ReceiptNumber, total, link),
+		fmt.Sprintf("Receipt %s, total %s. View: %s", doc.ReceiptNumber, total, link),
+		fmt.Sprintf("Receipt total %s: %s", total, link),
+	} {
+		if len(body) <= smsMaxLength {
+			return body, nil
+		}
+	}
+	return "", errSMSTooLong
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizePhoneE164(t *testing.T) {
+	tests := []struct {
+		raw, countryCode string
+		want             string
+		wantErr          bool
+	}{
+		{"+1 (555) 123-4567", "44", "+15551234567", false},
+		{"0044 20 7946 0958", "1", "+442079460958", false},
+		{"020 7946 0958", "44", "+442079460958", false},
+		{"020.7946.0958", "+44", "+442079460958", false},
+		{"5551234567", "1", "+15551234567", false},
+		{"020 7946 0958", "", "", true},
+		{"555-CALL-NOW", "1", "", true},
+		{"+44 +20 7946 0958", "44", "", true},
+		{"12345", "1", "", true},
+		{"+0 20 7946 0958", "44", "", true},
+		{"", "44", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizePhoneE164(tt.raw, tt.countryCode)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizePhoneE164(%q, %q) error = %v, want error %v", tt.raw, tt.countryCode, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizePhoneE164(%q, %q) = %q, want %q", tt.raw, tt.countryCode, got, tt.want)
+		}
+	}
+}
+
+func TestComposeReceiptSMS(t *testing.T) {
+	link := "https://shop.example/api/v1/pos/public/receipts/abc123"
+	doc := func(header string) *ReceiptDocument {
+		return &ReceiptDocument{ReceiptNumber: "R-000042", Total: 12.5, Template: ReceiptTemplate{Header: header}}
+	}
+
+	tests := []struct {
+		name    string
+		doc     *ReceiptDocument
+		link    string
+		want    string
+		wantErr error
+	}{
+		{"with store name", doc("Corner Shop\n1 Main St"), link,
+			"Corner Shop: Receipt R-000042, total 12.50. View: " + link, nil},
+		{"without header", doc(""), link, "Receipt R-000042, total 12.50. View: " + link, nil},
+		{"long store name is dropped", doc(strings.Repeat("x", 100)), link,
+			"Receipt R-000042, total 12.50. View: " + link, nil},
+		{"long link drops the receipt number", doc(""), strings.Repeat("l", 130),
+			"Receipt total 12.50: " + strings.Repeat("l", 130), nil},
+		{"link too long for any form", doc(""), strings.Repeat("l", 150), "", errSMSTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := composeReceiptSMS(tt.doc, tt.link)
+			if err != tt.wantErr {
+				t.Fatalf("composeReceiptSMS error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("composeReceiptSMS = %q, want %q", got, tt.want)
+			}
+			if len(got) > smsMaxLength {
+				t.Errorf("composeReceiptSMS is %d characters, over %d", len(got), smsMaxLength)
+			}
+		})
+	}
+}
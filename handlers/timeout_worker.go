@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// timeoutCheckInterval is how often the worker looks for stale sessions and shifts
+const timeoutCheckInterval = 5 * time.Minute
+
+// TimeoutEvent describes a session or shift that ran past its tenant's timeout
+type TimeoutEvent struct {
+	ID             int        `json:"id" db:"id"`
+	TenantID       string     `json:"tenant_id" db:"tenant_id"`
+	EntityType     string     `json:"entity_type" db:"entity_type"` // session, shift
+	EntityID       int        `json:"entity_id" db:"entity_id"`
+	RegisterID     int        `json:"register_id" db:"register_id"`
+	UserID         *int       `json:"user_id" db:"user_id"`
+	Action         string     `json:"action" db:"action"` // flagged, auto_closed
+	Reason         string     `json:"reason" db:"reason"`
+	LastActivityAt time.Time  `json:"last_activity_at" db:"last_activity_at"`
+	TimeoutMinutes int        `json:"timeout_minutes" db:"timeout_minutes"`
+	NotifiedAt     *time.Time `json:"notified_at" db:"notified_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ManagerNotifier delivers timeout alerts to a tenant's managers
+type ManagerNotifier interface {
+	NotifyManagers(ctx context.Context, event TimeoutEvent) error
+}
+
+// logNotifier writes manager alerts to the module log
+type logNotifier struct {
+	logger *zap.Logger
+}
+
+// NotifyManagers logs the event at warn level
+func (n *logNotifier) NotifyManagers(ctx context.Context, event TimeoutEvent) error {
+	n.logger.Warn("POS timeout requires manager attention",
+		zap.String("tenant_id", event.TenantID),
+		zap.String("entity_type", event.EntityType),
+		zap.Int("entity_id", event.EntityID),
+		zap.Int("register_id", event.RegisterID),
+		zap.String("action", event.Action),
+		zap.String("reason", event.Reason),
+	)
+	return nil
+}
+
+// TimeoutWorker enforces session_timeout_minutes and shift_timeout_minutes in the background
+type TimeoutWorker struct {
+	db       *sqlx.DB
+	logger   *zap.Logger
+	notifier ManagerNotifier
+	interval time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTimeoutWorker creates a timeout worker that alerts managers through the log
+func NewTimeoutWorker(db *sqlx.DB, logger *zap.Logger) *TimeoutWorker {
+	return &TimeoutWorker{
+		db:       db,
+		logger:   logger,
+		notifier: &logNotifier{logger: logger},
+		interval: timeoutCheckInterval,
+	}
+}
+
+// Start runs the worker until Stop is called
+func (w *TimeoutWorker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.runOnce(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop signals the worker to exit and waits for the current pass to finish
+func (w *TimeoutWorker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+// runOnce checks every tenant that has an active session or open shift
+func (w *TimeoutWorker) runOnce(ctx context.Context) {
+	rows, err := w.db.QueryContext(ctx, `
+		SELECT tenant_id FROM pos_sessions WHERE status = 'active'
+		UNION
+		SELECT tenant_id FROM register_shifts WHERE status = 'open'
+	`)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("Failed to list tenants for timeout check", zap.Error(err))
+		}
+		return
+	}
+
+	var tenants []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err == nil {
+			tenants = append(tenants, tenantID)
+		}
+	}
+	rows.Close()
+
+	for _, tenantID := range tenants {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := w.checkTenant(ctx, tenantID); err != nil {
+			w.logger.Error("Timeout check failed", zap.String("tenant_id", tenantID), zap.Error(err))
+		}
+	}
+}
+
+// timeoutPolicy is a tenant's timeout configuration
+type timeoutPolicy struct {
+	SessionTimeoutMinutes int
+	ShiftTimeoutMinutes   int
+	Action                string // auto_close, flag
+}
+
+// loadTimeoutPolicy reads the tenant's timeout settings, falling back to module defaults
+func loadTimeoutPolicy(q rowQuerier, tenantID string) (timeoutPolicy, error) {
+	policy := timeoutPolicy{
+		SessionTimeoutMinutes: defaultSettings["session_timeout_minutes"].(int),
+		ShiftTimeoutMinutes:   defaultSettings["shift_timeout_minutes"].(int),
+		Action:                defaultSettings["stale_session_action"].(string),
+	}
+	if _, err := loadTenantSetting(q, tenantID, "session_timeout_minutes", &policy.SessionTimeoutMinutes); err != nil {
+		return policy, err
+	}
+	if _, err := loadTenantSetting(q, tenantID, "shift_timeout_minutes", &policy.ShiftTimeoutMinutes); err != nil {
+		return policy, err
+	}
+	if _, err := loadTenantSetting(q, tenantID, "stale_session_action", &policy.Action); err != nil {
+		return policy, err
+	}
+	return policy, nil
+}
+
+// staleSessionDecision returns the event action and reason for a session past its timeout
+func (p timeoutPolicy) staleSessionDecision() (action, reason string) {
+	if p.Action == "auto_close" {
+		return "auto_closed", fmt.Sprintf("Session auto-closed after %d minutes without activity", p.SessionTimeoutMinutes)
+	}
+	return "flagged", fmt.Sprintf("Session has had no activity for over %d minutes", p.SessionTimeoutMinutes)
+}
+
+// staleShiftDecision returns the event action and reason for a shift past its timeout.
+// A shift that still has an active session is flagged rather than closed.
+func (p timeoutPolicy) staleShiftDecision(activeSessions int) (action, reason string) {
+	if p.Action == "auto_close" && activeSessions == 0 {
+		return "auto_closed", fmt.Sprintf("Shift auto-closed after running longer than %d minutes", p.ShiftTimeoutMinutes)
+	}
+	reason = fmt.Sprintf("Shift has been open for longer than %d minutes", p.ShiftTimeoutMinutes)
+	if activeSessions > 0 {
+		reason += " and still has an active session"
+	}
+	return "flagged", reason
+}
+
+// staleEntity is a session or shift found past its timeout
+type staleEntity struct {
+	ID           int
+	RegisterID   int
+	UserID       int
+	LastActivity time.Time
+	DrawerID     *int
+}
+
+// checkTenant flags or closes the tenant's stale sessions, then its stale shifts.
+// Sessions go first so that shifts they were holding open can be closed in the same pass.
+func (w *TimeoutWorker) checkTenant(ctx context.Context, tenantID string) error {
+	policy, err := loadTimeoutPolicy(w.db, tenantID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	if policy.SessionTimeoutMinutes > 0 {
+		cutoff := now.Add(-time.Duration(policy.SessionTimeoutMinutes) * time.Minute)
+
+		// A session is idle from its last sale, or from when it started if nothing was sold
+		rows, err := w.db.QueryContext(ctx, `
+			SELECT ps.id, ps.register_id, ps.user_id, ps.cash_drawer_id,
+			       COALESCE(MAX(pt.transaction_date), ps.session_start) AS last_activity
+			FROM pos_sessions ps
+			LEFT JOIN pos_transactions pt ON pt.session_id = ps.id
+			WHERE ps.tenant_id = $1 AND ps.status = 'active'
+			GROUP BY ps.id
+			HAVING COALESCE(MAX(pt.transaction_date), ps.session_start) < $2
+		`, tenantID, cutoff)
+		if err != nil {
+			return err
+		}
+		var sessions []staleEntity
+		for rows.Next() {
+			var s staleEntity
+			if err := rows.Scan(&s.ID, &s.RegisterID, &s.UserID, &s.DrawerID, &s.LastActivity); err == nil {
+				sessions = append(sessions, s)
+			}
+		}
+		rows.Close()
+
+		for _, s := range sessions {
+			if err := w.handleStaleSession(ctx, tenantID, policy, s); err != nil {
+				w.logger.Error("Failed to handle stale session", zap.Int("session_id", s.ID), zap.Error(err))
+			}
+		}
+	}
+
+	if policy.ShiftTimeoutMinutes > 0 {
+		cutoff := now.Add(-time.Duration(policy.ShiftTimeoutMinutes) * time.Minute)
+
+		rows, err := w.db.QueryContext(ctx, `
+			SELECT id, register_id, cashier_id, opened_at
+			FROM register_shifts
+			WHERE tenant_id = $1 AND status = 'open' AND opened_at < $2
+		`, tenantID, cutoff)
+		if err != nil {
+			return err
+		}
+		var shifts []staleEntity
+		for rows.Next() {
+			var s staleEntity
+			if err := rows.Scan(&s.ID, &s.RegisterID, &s.UserID, &s.LastActivity); err == nil {
+				shifts = append(shifts, s)
+			}
+		}
+		rows.Close()
+
+		for _, s := range shifts {
+			if err := w.handleStaleShift(ctx, tenantID, policy, s); err != nil {
+				w.logger.Error("Failed to handle stale shift", zap.Int("shift_id", s.ID), zap.Error(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// handleStaleSession closes or flags one session and alerts managers
+func (w *TimeoutWorker) handleStaleSession(ctx context.Context, tenantID string, policy timeoutPolicy, s staleEntity) error {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	event := TimeoutEvent{
+		TenantID:       tenantID,
+		EntityType:     "session",
+		EntityID:       s.ID,
+		RegisterID:     s.RegisterID,
+		UserID:         &s.UserID,
+		LastActivityAt: s.LastActivity,
+		TimeoutMinutes: policy.SessionTimeoutMinutes,
+	}
+
+	event.Action, event.Reason = policy.staleSessionDecision()
+	if event.Action == "auto_closed" {
+		// Re-check the status so a session closed by a cashier in the meantime is left alone
+		res, err := tx.Exec(`
+			UPDATE pos_sessions
+			SET status = 'closed', session_end = NOW(),
+			    notes = COALESCE(notes || E'\n', '') || $1,
+			    metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('auto_closed', true, 'close_reason', $1::text)
+			WHERE id = $2 AND status = 'active'
+		`, event.Reason, s.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+
+		if s.DrawerID != nil {
+			_, err = tx.Exec(`
+				UPDATE pos_cash_drawers SET status = 'closed', closed_at = NOW()
+				WHERE id = $1 AND status = 'open'
+			`, *s.DrawerID)
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		_, err = tx.Exec(`
+			UPDATE pos_sessions
+			SET metadata = COALESCE(metadata, '{}'::jsonb) || jsonb_build_object('stale', true, 'stale_reason', $1::text)
+			WHERE id = $2 AND status = 'active'
+		`, event.Reason, s.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.recordAndNotify(ctx, tx, event)
+}
+
+// handleStaleShift closes or flags one shift and alerts managers.
+// A shift that still has an active session is flagged rather than closed.
+func (w *TimeoutWorker) handleStaleShift(ctx context.Context, tenantID string, policy timeoutPolicy, s staleEntity) error {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	event := TimeoutEvent{
+		TenantID:       tenantID,
+		EntityType:     "shift",
+		EntityID:       s.ID,
+		RegisterID:     s.RegisterID,
+		UserID:         &s.UserID,
+		LastActivityAt: s.LastActivity,
+		TimeoutMinutes: policy.ShiftTimeoutMinutes,
+	}
+
+	// Lock the shift as ClosePOSShift does, so sales still writing to it finish first
+	shift := RegisterShift{ID: s.ID}
+	err = tx.QueryRow(`
+		SELECT opening_balance, total_cash_sales FROM register_shifts
+		WHERE id = $1 AND status = 'open'
+		FOR UPDATE
+	`, s.ID).Scan(&shift.OpeningBalance, &shift.TotalCashSales)
+	if err == sql.ErrNoRows {
+		// Closed since it was found stale
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var activeSessions int
+	err = tx.QueryRow("SELECT COUNT(*) FROM pos_sessions WHERE shift_id = $1 AND status = 'active'", s.ID).Scan(&activeSessions)
+	if err != nil {
+		return err
+	}
+
+	event.Action, event.Reason = policy.staleShiftDecision(activeSessions)
+	if event.Action == "auto_closed" {
+		// Nobody counted the drawer, so the closing balance and variance stay empty
+		expectedBalance, err := shiftExpectedBalance(tx, shift)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE register_shifts
+			SET status = 'closed', closed_at = NOW(), expected_balance = $1,
+			    notes = COALESCE(notes || E'\n', '') || $2
+			WHERE id = $3
+		`, expectedBalance, event.Reason, s.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.recordAndNotify(ctx, tx, event)
+}
+
+// recordAndNotify stores the event, commits, and alerts managers once per entity and action
+func (w *TimeoutWorker) recordAndNotify(ctx context.Context, tx *sqlx.Tx, event TimeoutEvent) error {
+	err := tx.QueryRow(`
+		INSERT INTO pos_timeout_events (tenant_id, entity_type, entity_id, register_id, user_id, action,
+		                                reason, last_activity_at, timeout_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (entity_type, entity_id, action) DO NOTHING
+		RETURNING id, created_at
+	`, event.TenantID, event.EntityType, event.EntityID, event.RegisterID, event.UserID, event.Action,
+		event.Reason, event.LastActivityAt, event.TimeoutMinutes).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		// Already recorded on an earlier pass; nothing new to report
+		if err == sql.ErrNoRows {
+			return tx.Commit()
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := w.notifier.NotifyManagers(ctx, event); err != nil {
+		return err
+	}
+
+	_, err = w.db.ExecContext(ctx, "UPDATE pos_timeout_events SET notified_at = NOW() WHERE id = $1", event.ID)
+	return err
+}
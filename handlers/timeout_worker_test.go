@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestStaleSessionDecision(t *testing.T) {
+	tests := []struct {
+		action     string
+		wantAction string
+		wantReason string
+	}{
+		{"auto_close", "auto_closed", "Session auto-closed after 30 minutes without activity"},
+		{"flag", "flagged", "Session has had no activity for over 30 minutes"},
+	}
+	for _, tt := range tests {
+		policy := timeoutPolicy{SessionTimeoutMinutes: 30, Action: tt.action}
+		action, reason := policy.staleSessionDecision()
+		if action != tt.wantAction || reason != tt.wantReason {
+			t.Errorf("staleSessionDecision() with %q = %q, %q, want %q, %q", tt.action, action, reason,
+				tt.wantAction, tt.wantReason)
+		}
+	}
+}
+
+func TestStaleShiftDecision(t *testing.T) {
+	tests := []struct {
+		action         string
+		activeSessions int
+		wantAction     string
+		wantReason     string
+	}{
+		{"auto_close", 0, "auto_closed", "Shift auto-closed after running longer than 720 minutes"},
+		{"auto_close", 1, "flagged", "Shift has been open for longer than 720 minutes and still has an active session"},
+		{"flag", 0, "flagged", "Shift has been open for longer than 720 minutes"},
+		{"flag", 2, "flagged", "Shift has been open for longer than 720 minutes and still has an active session"},
+	}
+	for _, tt := range tests {
+		policy := timeoutPolicy{ShiftTimeoutMinutes: 720, Action: tt.action}
+		action, reason := policy.staleShiftDecision(tt.activeSessions)
+		if action != tt.wantAction || reason != tt.wantReason {
+			t.Errorf("staleShiftDecision(%d) with %q = %q, %q, want %q, %q", tt.activeSessions, tt.action,
+				action, reason, tt.wantAction, tt.wantReason)
+		}
+	}
+}
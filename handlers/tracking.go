@@ -0,0 +1,460 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// loadProductTracking returns how a product is tracked, or nil when its serials and lots are not captured
+func loadProductTracking(q rowQuerier, tenantID string, productID int) (*ProductTracking, error) {
+	t := ProductTracking{TenantID: tenantID, ProductID: productID}
+	err := q.QueryRow(`
+		SELECT tracking, warranty_days, updated_at
+		FROM pos_product_tracking
+		WHERE tenant_id = $1 AND product_id = $2
+	`, tenantID, productID).Scan(&t.Tracking, &t.WarrantyDays, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// normalizeSerialNumbers trims the serials captured on a line and rejects blanks and repeats
+func normalizeSerialNumbers(serials []string) ([]string, error) {
+	seen := make(map[string]bool, len(serials))
+	normalized := make([]string, 0, len(serials))
+	for _, serial := range serials {
+		serial = strings.TrimSpace(serial)
+		if serial == "" {
+			return nil, &itemOptionError{"Serial numbers cannot be blank"}
+		}
+		if seen[serial] {
+			return nil, &itemOptionError{fmt.Sprintf("Serial %s is listed more than once", serial)}
+		}
+		seen[serial] = true
+		normalized = append(normalized, serial)
+	}
+	return normalized, nil
+}
+
+// captureItemTracking records the serials or lots a sale line was sold from. Serialized products
+// need one known, in-stock serial per unit; lot-tracked products need lots that cover the
+// quantity, are not past expiry and have enough left. Serials are marked sold and lots drawn down.
+// A kit line's serials and lots are those of its components, each matched to the component
+// product that holds it.
+func captureItemTracking(tx *sqlx.Tx, tenantID string, itemID int, item *POSTransactionItem) error {
+	if len(item.Components) == 0 {
+		return captureProductTracking(tx, tenantID, itemID, item.ProductID, item.Quantity, &item.SerialNumbers,
+			&item.Lots)
+	}
+
+	serials, err := normalizeSerialNumbers(item.SerialNumbers)
+	if err != nil {
+		return err
+	}
+	for i := range item.Lots {
+		item.Lots[i].LotNumber = strings.TrimSpace(item.Lots[i].LotNumber)
+	}
+
+	// A product listed on several components is captured once for their combined quantity
+	var productIDs []int
+	quantities := make(map[int]float64)
+	for _, c := range item.Components {
+		if _, ok := quantities[c.ProductID]; !ok {
+			productIDs = append(productIDs, c.ProductID)
+		}
+		quantities[c.ProductID] += c.Quantity
+	}
+
+	serialTaken := make([]bool, len(serials))
+	lotTaken := make([]bool, len(item.Lots))
+	var captured []ItemLot
+	for _, productID := range productIDs {
+		var ownSerials []string
+		for i, serial := range serials {
+			if serialTaken[i] {
+				continue
+			}
+			var held bool
+			err := tx.QueryRow(`
+				SELECT EXISTS (SELECT 1 FROM pos_serial_numbers WHERE tenant_id = $1 AND product_id = $2 AND serial_number = $3)
+			`, tenantID, productID, serial).Scan(&held)
+			if err != nil {
+				return err
+			}
+			if held {
+				serialTaken[i] = true
+				ownSerials = append(ownSerials, serial)
+			}
+		}
+		var ownLots []ItemLot
+		for i, lot := range item.Lots {
+			if lotTaken[i] {
+				continue
+			}
+			var held bool
+			err := tx.QueryRow(`
+				SELECT EXISTS (SELECT 1 FROM pos_lots WHERE tenant_id = $1 AND product_id = $2 AND lot_number = $3)
+			`, tenantID, productID, lot.LotNumber).Scan(&held)
+			if err != nil {
+				return err
+			}
+			if held {
+				lotTaken[i] = true
+				ownLots = append(ownLots, lot)
+			}
+		}
+
+		quantity := math.Round(quantities[productID]*1000) / 1000
+		if err := captureProductTracking(tx, tenantID, itemID, productID, quantity, &ownSerials, &ownLots); err != nil {
+			return err
+		}
+		captured = append(captured, ownLots...)
+	}
+
+	for i, serial := range serials {
+		if !serialTaken[i] {
+			return &itemOptionError{fmt.Sprintf("Serial %s is not in stock for any component of kit %d", serial,
+				item.ProductID)}
+		}
+	}
+	for i, lot := range item.Lots {
+		if !lotTaken[i] {
+			return &itemOptionError{fmt.Sprintf("Lot %s is not in stock for any component of kit %d", lot.LotNumber,
+				item.ProductID)}
+		}
+	}
+	item.SerialNumbers = serials
+	item.Lots = captured
+	return nil
+}
+
+// captureProductTracking captures the serials or lots of quantity units of one product on a line
+func captureProductTracking(tx *sqlx.Tx, tenantID string, itemID, productID int, quantity float64, serials *[]string,
+	lots *[]ItemLot) error {
+	tracking, err := loadProductTracking(tx, tenantID, productID)
+	if err != nil {
+		return err
+	}
+	if tracking == nil || tracking.Tracking != "serial" {
+		if len(*serials) > 0 {
+			return &itemOptionError{fmt.Sprintf("Product %d is not serialized", productID)}
+		}
+	}
+	if tracking == nil || tracking.Tracking != "lot" {
+		if len(*lots) > 0 {
+			return &itemOptionError{fmt.Sprintf("Product %d is not lot-tracked", productID)}
+		}
+	}
+	if tracking == nil {
+		return nil
+	}
+
+	if tracking.Tracking == "serial" {
+		normalized, err := normalizeSerialNumbers(*serials)
+		if err != nil {
+			return err
+		}
+		if float64(len(normalized)) != quantity {
+			return &itemOptionError{fmt.Sprintf("Product %d needs one serial number per unit: %g given for %g",
+				productID, float64(len(normalized)), quantity)}
+		}
+		for _, serial := range normalized {
+			var serialID int
+			var status string
+			err := tx.QueryRow(`
+				SELECT id, status FROM pos_serial_numbers
+				WHERE tenant_id = $1 AND product_id = $2 AND serial_number = $3
+				FOR UPDATE
+			`, tenantID, productID, serial).Scan(&serialID, &status)
+			if err == sql.ErrNoRows {
+				return &itemOptionError{fmt.Sprintf("Serial %s is not in stock for product %d", serial, productID)}
+			}
+			if err != nil {
+				return err
+			}
+			if status != "in_stock" {
+				return &itemOptionError{fmt.Sprintf("Serial %s is %s", serial, strings.ReplaceAll(status, "_", " "))}
+			}
+
+			_, err = tx.Exec("UPDATE pos_serial_numbers SET status = 'sold', sold_item_id = $1 WHERE id = $2",
+				itemID, serialID)
+			if err == nil {
+				_, err = tx.Exec(`
+					INSERT INTO pos_transaction_item_tracking (transaction_item_id, serial_id, serial_number, quantity)
+					VALUES ($1, $2, $3, 1)
+				`, itemID, serialID, serial)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		*serials = normalized
+		return nil
+	}
+
+	if len(*lots) == 0 {
+		return &itemOptionError{fmt.Sprintf("Product %d needs a lot number", productID)}
+	}
+	var covered float64
+	for i := range *lots {
+		lot := &(*lots)[i]
+		lot.LotNumber = strings.TrimSpace(lot.LotNumber)
+		if lot.Quantity <= 0 {
+			return &itemOptionError{fmt.Sprintf("Quantity from lot %s must be positive", lot.LotNumber)}
+		}
+		covered += lot.Quantity
+
+		var lotID int
+		var onHand float64
+		err := tx.QueryRow(`
+			SELECT id, expiry_date, quantity_on_hand FROM pos_lots
+			WHERE tenant_id = $1 AND product_id = $2 AND lot_number = $3
+			FOR UPDATE
+		`, tenantID, productID, lot.LotNumber).Scan(&lotID, &lot.ExpiryDate, &onHand)
+		if err == sql.ErrNoRows {
+			return &itemOptionError{fmt.Sprintf("Lot %s is not in stock for product %d", lot.LotNumber, productID)}
+		}
+		if err != nil {
+			return err
+		}
+		if lot.ExpiryDate != nil && lot.ExpiryDate.Before(time.Now().Truncate(24*time.Hour)) {
+			return &itemOptionError{fmt.Sprintf("Lot %s expired on %s", lot.LotNumber, lot.ExpiryDate.Format("2006-01-02"))}
+		}
+		if lot.Quantity > onHand {
+			return &itemOptionError{fmt.Sprintf("Lot %s has only %g left", lot.LotNumber, onHand)}
+		}
+
+		_, err = tx.Exec("UPDATE pos_lots SET quantity_on_hand = quantity_on_hand - $1 WHERE id = $2",
+			lot.Quantity, lotID)
+		if err == nil {
+			_, err = tx.Exec(`
+				INSERT INTO pos_transaction_item_tracking (transaction_item_id, lot_id, lot_number, quantity)
+				VALUES ($1, $2, $3, $4)
+			`, itemID, lotID, lot.LotNumber, lot.Quantity)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if math.Round(covered*1000) != math.Round(quantity*1000) {
+		return &itemOptionError{fmt.Sprintf("Lots of product %d cover %g of %g", productID, covered, quantity)}
+	}
+	return nil
+}
+
+// releaseVoidedTracking puts the serials and lots of a voided sale back in stock. Serials that
+// are not restocked are marked damaged and their lot quantities stay off hand.
+func releaseVoidedTracking(tx *sqlx.Tx, transactionID int, disposition string) error {
+	status := "in_stock"
+	if disposition != "restock" {
+		status = "damaged"
+	}
+	_, err := tx.Exec(`
+		UPDATE pos_serial_numbers s SET status = $2
+		FROM pos_transaction_item_tracking t
+		JOIN pos_transaction_items pti ON pti.id = t.transaction_item_id
+		WHERE t.serial_id = s.id AND pti.transaction_id = $1 AND s.sold_item_id = pti.id
+	`, transactionID, status)
+	if err != nil || disposition != "restock" {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE pos_lots l SET quantity_on_hand = l.quantity_on_hand + returned.quantity
+		FROM (
+			SELECT t.lot_id, SUM(t.quantity) AS quantity
+			FROM pos_transaction_item_tracking t
+			JOIN pos_transaction_items pti ON pti.id = t.transaction_item_id
+			WHERE pti.transaction_id = $1 AND t.lot_id IS NOT NULL
+			GROUP BY t.lot_id
+		) returned
+		WHERE l.id = returned.lot_id
+	`, transactionID)
+	return err
+}
+
+// returnItemTracking records which serials or lots of a sold line come back on a return line.
+// Serials must have been sold on that line and not returned since; lots default to the line's
+// only lot and cannot return more than was sold from them. Restocked units go back on hand;
+// damaged or written-off serials are marked damaged and their lot quantities are not put back. A kit line
+// returns its components' serials and lots in proportion to the kits returned.
+func returnItemTracking(tx *sqlx.Tx, originalItemID, returnItemID int, quantity float64, serials []string,
+	lots []ItemLot, disposition string) error {
+	var soldSerials, soldLots int
+	var soldLotQuantity, lineQuantity float64
+	err := tx.QueryRow(`
+		SELECT COUNT(t.serial_id), COUNT(t.lot_id), COALESCE(SUM(t.quantity) FILTER (WHERE t.lot_id IS NOT NULL), 0),
+		       pti.quantity
+		FROM pos_transaction_items pti
+		LEFT JOIN pos_transaction_item_tracking t ON t.transaction_item_id = pti.id
+		WHERE pti.id = $1
+		GROUP BY pti.quantity
+	`, originalItemID).Scan(&soldSerials, &soldLots, &soldLotQuantity, &lineQuantity)
+	if err != nil {
+		return err
+	}
+	// Serials and lot quantities expected back; for a plain line these equal the quantity returned
+	share := quantity / lineQuantity
+	serialCount := math.Round(float64(soldSerials) * share)
+	lotQuantity := math.Round(soldLotQuantity*share*1000) / 1000
+	if soldSerials == 0 && len(serials) > 0 {
+		return &itemOptionError{fmt.Sprintf("Item %d was not sold with serial numbers", originalItemID)}
+	}
+	if soldLots == 0 && len(lots) > 0 {
+		return &itemOptionError{fmt.Sprintf("Item %d was not sold from lots", originalItemID)}
+	}
+
+	if soldSerials > 0 {
+		serials, err = normalizeSerialNumbers(serials)
+		if err != nil {
+			return err
+		}
+		if float64(len(serials)) != serialCount {
+			return &itemOptionError{fmt.Sprintf("Returning %g of item %d needs %g serial numbers", quantity,
+				originalItemID, serialCount)}
+		}
+		status := "in_stock"
+		if disposition != "restock" {
+			status = "damaged"
+		}
+		for _, serial := range serials {
+			var serialID int
+			err := tx.QueryRow(`
+				SELECT s.id FROM pos_serial_numbers s
+				JOIN pos_transaction_item_tracking t ON t.serial_id = s.id AND t.transaction_item_id = $1
+				WHERE t.serial_number = $2 AND s.status = 'sold' AND s.sold_item_id = $1
+				FOR UPDATE OF s
+			`, originalItemID, serial).Scan(&serialID)
+			if err == sql.ErrNoRows {
+				return &itemOptionError{fmt.Sprintf("Serial %s was not sold on item %d or is already returned",
+					serial, originalItemID)}
+			}
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec("UPDATE pos_serial_numbers SET status = $1 WHERE id = $2", status, serialID)
+			if err == nil {
+				_, err = tx.Exec(`
+					INSERT INTO pos_transaction_item_tracking (transaction_item_id, serial_id, serial_number, quantity)
+					VALUES ($1, $2, $3, 1)
+				`, returnItemID, serialID, serial)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// A kit may hold both serialized and lot-tracked components
+	if soldLots == 0 {
+		return nil
+	}
+	if len(lots) == 0 {
+		if soldLots > 1 {
+			return &itemOptionError{fmt.Sprintf("Item %d was sold from several lots; say which lots come back", originalItemID)}
+		}
+		var lot ItemLot
+		err := tx.QueryRow(`
+			SELECT lot_number FROM pos_transaction_item_tracking WHERE transaction_item_id = $1 AND lot_id IS NOT NULL
+		`, originalItemID).Scan(&lot.LotNumber)
+		if err != nil {
+			return err
+		}
+		lot.Quantity = lotQuantity
+		lots = []ItemLot{lot}
+	}
+
+	var covered float64
+	for _, lot := range lots {
+		lotNumber := strings.TrimSpace(lot.LotNumber)
+		if lot.Quantity <= 0 {
+			return &itemOptionError{fmt.Sprintf("Quantity from lot %s must be positive", lotNumber)}
+		}
+		covered += lot.Quantity
+
+		// What is left to return from the lot: sold on the line less returned against it before
+		var lotID int
+		var returnable float64
+		err := tx.QueryRow(`
+			SELECT t.lot_id, SUM(t.quantity) - COALESCE((
+				SELECT SUM(rt.quantity)
+				FROM pos_return_items ri
+				JOIN pos_transaction_item_tracking rt ON rt.transaction_item_id = ri.return_item_id
+				WHERE ri.original_item_id = $1 AND rt.lot_id = t.lot_id
+			), 0)
+			FROM pos_transaction_item_tracking t
+			WHERE t.transaction_item_id = $1 AND t.lot_number = $2
+			GROUP BY t.lot_id
+		`, originalItemID, lotNumber).Scan(&lotID, &returnable)
+		if err == sql.ErrNoRows {
+			return &itemOptionError{fmt.Sprintf("Lot %s was not sold on item %d", lotNumber, originalItemID)}
+		}
+		if err != nil {
+			return err
+		}
+		if math.Round(lot.Quantity*1000) > math.Round(returnable*1000) {
+			return &itemOptionError{fmt.Sprintf("Only %g of lot %s can still be returned", returnable, lotNumber)}
+		}
+
+		if disposition == "restock" {
+			_, err = tx.Exec("UPDATE pos_lots SET quantity_on_hand = quantity_on_hand + $1 WHERE id = $2",
+				lot.Quantity, lotID)
+		}
+		if err == nil {
+			_, err = tx.Exec(`
+				INSERT INTO pos_transaction_item_tracking (transaction_item_id, lot_id, lot_number, quantity)
+				VALUES ($1, $2, $3, $4)
+			`, returnItemID, lotID, lotNumber, lot.Quantity)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if math.Round(covered*1000) != math.Round(lotQuantity*1000) {
+		return &itemOptionError{fmt.Sprintf("Lots returned on item %d cover %g of %g", originalItemID, covered,
+			lotQuantity)}
+	}
+	return nil
+}
+
+// loadItemTracking returns the serials and lots captured on every line of a transaction, keyed by line
+func loadItemTracking(q reportQuerier, transactionID int) (map[int][]string, map[int][]ItemLot, error) {
+	rows, err := q.Query(`
+		SELECT t.transaction_item_id, t.serial_number, t.lot_number, t.quantity, l.expiry_date
+		FROM pos_transaction_item_tracking t
+		JOIN pos_transaction_items pti ON pti.id = t.transaction_item_id
+		LEFT JOIN pos_lots l ON l.id = t.lot_id
+		WHERE pti.transaction_id = $1
+		ORDER BY t.transaction_item_id, t.id
+	`, transactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	serials := make(map[int][]string)
+	lots := make(map[int][]ItemLot)
+	for rows.Next() {
+		var itemID int
+		var serialNumber, lotNumber sql.NullString
+		var lot ItemLot
+		if err := rows.Scan(&itemID, &serialNumber, &lotNumber, &lot.Quantity, &lot.ExpiryDate); err != nil {
+			return nil, nil, err
+		}
+		if serialNumber.Valid {
+			serials[itemID] = append(serials[itemID], serialNumber.String)
+		} else {
+			lot.LotNumber = lotNumber.String
+			lots[itemID] = append(lots[itemID], lot)
+		}
+	}
+	return serials, lots, rows.Err()
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// TrackingHandler manages serialized and lot-tracked products, the serials and lots received into
+// stock, and serial lookups for warranty returns
+type TrackingHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewTrackingHandler creates a new tracking handler
+func NewTrackingHandler(db *sqlx.DB, logger *zap.Logger) *TrackingHandler {
+	return &TrackingHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// GetProductTracking returns whether a product's serials or lots are captured at sale
+func (h *TrackingHandler) GetProductTracking(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	tracking, err := loadProductTracking(h.db, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to fetch product tracking", http.StatusInternalServerError)
+		return
+	}
+	if tracking == nil {
+		tracking = &ProductTracking{TenantID: tenantID, ProductID: productID, Tracking: "none"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tracking)
+}
+
+// SetProductTracking makes a product serialized or lot-tracked, or stops tracking it with "none".
+// Serials and lots already received are kept.
+func (h *TrackingHandler) SetProductTracking(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Tracking     string `json:"tracking" validate:"required"` // serial, lot, none
+		WarrantyDays *int   `json:"warranty_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.WarrantyDays != nil && *req.WarrantyDays < 0 {
+		http.Error(w, "Warranty days cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	tracking := ProductTracking{TenantID: tenantID, ProductID: productID, Tracking: req.Tracking,
+		WarrantyDays: req.WarrantyDays}
+	switch req.Tracking {
+	case "none":
+		_, err = h.db.Exec("DELETE FROM pos_product_tracking WHERE tenant_id = $1 AND product_id = $2",
+			tenantID, productID)
+		tracking.WarrantyDays = nil
+		tracking.UpdatedAt = time.Now()
+	case "serial", "lot":
+		err = h.db.QueryRow(`
+			INSERT INTO pos_product_tracking (tenant_id, product_id, tracking, warranty_days)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (tenant_id, product_id) DO UPDATE SET
+				tracking = EXCLUDED.tracking, warranty_days = EXCLUDED.warranty_days
+			RETURNING updated_at
+		`, tenantID, productID, req.Tracking, req.WarrantyDays).Scan(&tracking.UpdatedAt)
+	default:
+		http.Error(w, "Tracking must be serial, lot or none", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update product tracking", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tracking)
+}
+
+// GetProductSerials lists a product's serial numbers, optionally filtered by ?status=
+func (h *TrackingHandler) GetProductSerials(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT id, tenant_id, product_id, serial_number, status, sold_item_id, received_by, received_at, updated_at
+		FROM pos_serial_numbers
+		WHERE tenant_id = $1 AND product_id = $2
+	`
+	args := []interface{}{tenantID, productID}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " AND status = $3"
+		args = append(args, status)
+	}
+	query += " ORDER BY serial_number"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to fetch serial numbers", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	serials := []SerialNumber{}
+	for rows.Next() {
+		var s SerialNumber
+		err := rows.Scan(&s.ID, &s.TenantID, &s.ProductID, &s.SerialNumber, &s.Status, &s.SoldItemID,
+			&s.ReceivedBy, &s.ReceivedAt, &s.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		serials = append(serials, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"serials": serials,
+		"count":   len(serials),
+	})
+}
+
+// ReceiveProductSerials adds serial numbers to a product's stock
+func (h *TrackingHandler) ReceiveProductSerials(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		SerialNumbers []string `json:"serial_numbers" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	serials, err := normalizeSerialNumbers(req.SerialNumbers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(serials) == 0 {
+		http.Error(w, "At least one serial number is required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.baseHandler.getUserID(r)
+
+	tx, err := h.db.Beginx()
+	if err != nil {
+		http.Error(w, "Failed to receive serial numbers", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, serial := range serials {
+		_, err = tx.Exec(`
+			INSERT INTO pos_serial_numbers (tenant_id, product_id, serial_number, received_by)
+			VALUES ($1, $2, $3, $4)
+		`, tenantID, productID, serial, userID)
+		if err != nil {
+			http.Error(w, "Failed to receive serial numbers; "+serial+" is already recorded for this product",
+				http.StatusConflict)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to receive serial numbers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"product_id": productID,
+		"count":      len(serials),
+		"message":    "Serial numbers received successfully",
+	})
+}
+
+// GetProductLots lists a product's lots, soonest expiry first
+func (h *TrackingHandler) GetProductLots(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT id, tenant_id, product_id, lot_number, expiry_date, quantity_on_hand, created_at, updated_at
+		FROM pos_lots
+		WHERE tenant_id = $1 AND product_id = $2
+	`
+	if r.URL.Query().Get("available") == "true" {
+		query += " AND quantity_on_hand > 0 AND (expiry_date IS NULL OR expiry_date >= CURRENT_DATE)"
+	}
+	query += " ORDER BY expiry_date NULLS LAST, lot_number"
+
+	rows, err := h.db.Query(query, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to fetch lots", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	lots := []Lot{}
+	for rows.Next() {
+		var l Lot
+		err := rows.Scan(&l.ID, &l.TenantID, &l.ProductID, &l.LotNumber, &l.ExpiryDate, &l.QuantityOnHand,
+			&l.CreatedAt, &l.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		lots = append(lots, l)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"lots":  lots,
+		"count": len(lots),
+	})
+}
+
+// ReceiveProductLot adds a quantity of a lot to a product's stock, creating the lot on first receipt
+func (h *TrackingHandler) ReceiveProductLot(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		LotNumber  string  `json:"lot_number" validate:"required"`
+		ExpiryDate *string `json:"expiry_date"` // YYYY-MM-DD
+		Quantity   float64 `json:"quantity" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.LotNumber = strings.TrimSpace(req.LotNumber)
+	if req.LotNumber == "" {
+		http.Error(w, "Lot number is required", http.StatusBadRequest)
+		return
+	}
+	if req.Quantity <= 0 {
+		http.Error(w, "Quantity must be positive", http.StatusBadRequest)
+		return
+	}
+	var expiryDate *time.Time
+	if req.ExpiryDate != nil {
+		date, err := time.Parse("2006-01-02", *req.ExpiryDate)
+		if err != nil {
+			http.Error(w, "Invalid expiry date; use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		expiryDate = &date
+	}
+
+	// A later receipt of the same lot adds to it; an expiry date given again replaces the recorded one
+	var lot Lot
+	err = h.db.QueryRow(`
+		INSERT INTO pos_lots (tenant_id, product_id, lot_number, expiry_date, quantity_on_hand)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, product_id, lot_number) DO UPDATE SET
+			quantity_on_hand = pos_lots.quantity_on_hand + EXCLUDED.quantity_on_hand,
+			expiry_date = COALESCE(EXCLUDED.expiry_date, pos_lots.expiry_date)
+		RETURNING id, tenant_id, product_id, lot_number, expiry_date, quantity_on_hand, created_at, updated_at
+	`, tenantID, productID, req.LotNumber, expiryDate, req.Quantity).Scan(&lot.ID, &lot.TenantID, &lot.ProductID,
+		&lot.LotNumber, &lot.ExpiryDate, &lot.QuantityOnHand, &lot.CreatedAt, &lot.UpdatedAt)
+	if err != nil {
+		http.Error(w, "Failed to receive lot", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(lot)
+}
+
+// LookupSerial finds a serial number and the sale it left the store on, with its warranty, so a
+// warranty return can be taken against the original sale. The same serial may exist for several
+// products; ?product_id= narrows the lookup.
+func (h *TrackingHandler) LookupSerial(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	serial := strings.TrimSpace(chi.URLParam(r, "serial"))
+	query := `
+		SELECT s.id, s.tenant_id, s.product_id, s.serial_number, s.status, s.sold_item_id, s.received_by,
+		       s.received_at, s.updated_at, COALESCE(p.name, ''), pt.warranty_days
+		FROM pos_serial_numbers s
+		LEFT JOIN products p ON p.id = s.product_id
+		LEFT JOIN pos_product_tracking pt ON pt.tenant_id = s.tenant_id AND pt.product_id = s.product_id
+		WHERE s.tenant_id = $1 AND s.serial_number = $2
+	`
+	args := []interface{}{tenantID, serial}
+	if productID := r.URL.Query().Get("product_id"); productID != "" {
+		query += " AND s.product_id = $3"
+		args = append(args, productID)
+	}
+	query += " ORDER BY s.product_id"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to look up serial number", http.StatusInternalServerError)
+		return
+	}
+	var lookups []SerialLookup
+	var warrantyDays []*int
+	for rows.Next() {
+		var l SerialLookup
+		var days *int
+		s := &l.Serial
+		err := rows.Scan(&s.ID, &s.TenantID, &s.ProductID, &s.SerialNumber, &s.Status, &s.SoldItemID,
+			&s.ReceivedBy, &s.ReceivedAt, &s.UpdatedAt, &l.ProductName, &days)
+		if err != nil {
+			continue
+		}
+		l.Transactions = []SerialTransaction{}
+		lookups = append(lookups, l)
+		warrantyDays = append(warrantyDays, days)
+	}
+	rows.Close()
+	if len(lookups) == 0 {
+		http.Error(w, "Serial number not found", http.StatusNotFound)
+		return
+	}
+
+	for i := range lookups {
+		l := &lookups[i]
+		txRows, err := h.db.Query(`
+			SELECT pt.id, pt.transaction_number, pt.transaction_type, COALESCE(pt.status, 'completed'),
+			       pt.transaction_date, pti.id, pt.register_id, pt.customer_id
+			FROM pos_transaction_item_tracking t
+			JOIN pos_transaction_items pti ON pti.id = t.transaction_item_id
+			JOIN pos_transactions pt ON pt.id = pti.transaction_id
+			WHERE t.serial_id = $1 AND pt.tenant_id = $2
+			ORDER BY pt.transaction_date, pt.id
+		`, l.Serial.ID, tenantID)
+		if err != nil {
+			http.Error(w, "Failed to look up serial number", http.StatusInternalServerError)
+			return
+		}
+		for txRows.Next() {
+			var t SerialTransaction
+			err := txRows.Scan(&t.TransactionID, &t.TransactionNumber, &t.TransactionType, &t.Status,
+				&t.TransactionDate, &t.TransactionItemID, &t.RegisterID, &t.CustomerID)
+			if err != nil {
+				continue
+			}
+			l.Transactions = append(l.Transactions, t)
+			if t.TransactionType == "sale" && t.Status == "completed" {
+				sale := t
+				l.OriginalSale = &sale
+			}
+		}
+		txRows.Close()
+
+		if l.OriginalSale != nil && warrantyDays[i] != nil {
+			expires := l.OriginalSale.TransactionDate.AddDate(0, 0, *warrantyDays[i])
+			inWarranty := time.Now().Before(expires)
+			l.WarrantyExpiresAt = &expires
+			l.InWarranty = &inWarranty
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"serials": lookups,
+		"count":   len(lookups),
+	})
+}
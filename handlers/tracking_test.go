@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSerialNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		serials []string
+		want    []string
+		wantErr bool
+	}{
+		{"trimmed in order", []string{" SN-2 ", "SN-1\t"}, []string{"SN-2", "SN-1"}, false},
+		{"none", nil, []string{}, false},
+		{"blank", []string{"SN-1", "  "}, nil, true},
+		{"repeated", []string{"SN-1", "SN-2", "SN-1"}, nil, true},
+		{"repeated after trimming", []string{"SN-1", " SN-1"}, nil, true},
+		{"case matters", []string{"sn-1", "SN-1"}, []string{"sn-1", "SN-1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeSerialNumbers(tt.serials)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeSerialNumbers(%q) error = %v, want error %v", tt.serials, err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*itemOptionError); !ok {
+					t.Errorf("normalizeSerialNumbers(%q) error = %#v, want an itemOptionError", tt.serials, err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeSerialNumbers(%q) = %q, want %q", tt.serials, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// UnitHandler manages units of measure, the unit each product is sold in, and prices lines
+// from scale readings
+type UnitHandler struct {
+	db          *sqlx.DB
+	logger      *zap.Logger
+	baseHandler *POSHandler
+}
+
+// NewUnitHandler creates a new unit handler
+func NewUnitHandler(db *sqlx.DB, logger *zap.Logger) *UnitHandler {
+	return &UnitHandler{
+		db:          db,
+		logger:      logger,
+		baseHandler: NewPOSHandler(db, logger),
+	}
+}
+
+// unitRequest is the body of a unit of measure create or update
+type unitRequest struct {
+	Name      string `json:"name" validate:"required"`
+	Decimals  int    `json:"decimals"`
+	Rounding  string `json:"rounding"`
+	IsWeighed bool   `json:"is_weighed"`
+}
+
+func (req *unitRequest) validate() error {
+	if req.Name == "" {
+		return errors.New("Unit name is required")
+	}
+	if req.Decimals < 0 || req.Decimals > 3 {
+		return errors.New("Decimals must be between 0 and 3")
+	}
+	if req.Rounding == "" {
+		req.Rounding = "half_up"
+	}
+	if !unitRoundings[req.Rounding] {
+		return errors.New("Rounding must be half_up, down or up")
+	}
+	return nil
+}
+
+// GetUnits lists the units of measure available to the tenant
+func (h *UnitHandler) GetUnits(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	units, err := loadUnitsOfMeasure(h.db, tenantID)
+	if err != nil {
+		http.Error(w, "Failed to fetch units", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"units": units,
+		"count": len(units),
+	})
+}
+
+// SetUnit creates a tenant unit of measure, or overrides the name, precision and rounding of a
+// built-in one. A built-in unit keeps its conversions, so a weighed built-in stays weighed.
+func (h *UnitHandler) SetUnit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	code := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "code")))
+	if code == "" || len(code) > 20 {
+		http.Error(w, "Invalid unit code", http.StatusBadRequest)
+		return
+	}
+
+	var req unitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if builtin, ok := builtinUnits[code]; ok {
+		req.IsWeighed = builtin.IsWeighed
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO pos_units_of_measure (tenant_id, code, name, decimals, rounding, is_weighed)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id, code) DO UPDATE SET
+			name = EXCLUDED.name, decimals = EXCLUDED.decimals, rounding = EXCLUDED.rounding,
+			is_weighed = EXCLUDED.is_weighed
+	`, tenantID, code, req.Name, req.Decimals, req.Rounding, req.IsWeighed)
+	if err != nil {
+		http.Error(w, "Failed to save unit", http.StatusInternalServerError)
+		return
+	}
+
+	_, builtIn := builtinUnits[code]
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UnitOfMeasure{Code: code, Name: req.Name, Decimals: req.Decimals,
+		Rounding: req.Rounding, IsWeighed: req.IsWeighed, BuiltIn: builtIn})
+}
+
+// GetProductUnit returns the unit a product is sold in
+func (h *UnitHandler) GetProductUnit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	unit, err := loadProductUnit(h.db, tenantID, productID)
+	if err != nil {
+		http.Error(w, "Failed to fetch product unit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(unit)
+}
+
+// SetProductUnit sets the unit a product is sold in, the tare of its container and whether its
+// quantity must come from the scale
+func (h *UnitHandler) SetProductUnit(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		UnitCode      string  `json:"unit_code" validate:"required"`
+		TareWeight    float64 `json:"tare_weight"` // in unit_code
+		RequiresScale bool    `json:"requires_scale"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TareWeight < 0 {
+		http.Error(w, "Tare weight cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	unit, err := loadUnitOfMeasure(h.db, tenantID, req.UnitCode)
+	if err != nil {
+		http.Error(w, "Failed to fetch unit", http.StatusInternalServerError)
+		return
+	}
+	if unit == nil {
+		http.Error(w, "Unit not found", http.StatusNotFound)
+		return
+	}
+	if !unit.IsWeighed && (req.TareWeight > 0 || req.RequiresScale) {
+		http.Error(w, "Tare weight and requires_scale apply only to weighed units", http.StatusBadRequest)
+		return
+	}
+
+	var updatedAt time.Time
+	err = h.db.QueryRow(`
+		INSERT INTO pos_product_units (tenant_id, product_id, unit_code, tare_weight, requires_scale)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, product_id) DO UPDATE SET
+			unit_code = EXCLUDED.unit_code, tare_weight = EXCLUDED.tare_weight,
+			requires_scale = EXCLUDED.requires_scale
+		RETURNING updated_at
+	`, tenantID, productID, unit.Code, req.TareWeight, req.RequiresScale).Scan(&updatedAt)
+	if err != nil {
+		http.Error(w, "Failed to save product unit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ProductUnit{TenantID: tenantID, ProductID: productID, UnitCode: unit.Code,
+		TareWeight: req.TareWeight, RequiresScale: req.RequiresScale, UpdatedAt: updatedAt})
+}
+
+// PriceLineItem prices a line the way a sale would record it, so the terminal can show a
+// weighed item's net weight, a kit's components and bundle price, and the discount, tax and
+// total before adding it to the cart. ?register_id and ?customer_id select the bundle rules
+// and component prices that apply.
+func (h *UnitHandler) PriceLineItem(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := h.baseHandler.getTenantID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	ids := map[string]*int{}
+	for _, param := range []string{"register_id", "customer_id"} {
+		if value := r.URL.Query().Get(param); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			ids[param] = &id
+		}
+	}
+
+	var item POSTransactionItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	priceCtx, err := loadPriceContext(h.db, tenantID, ids["register_id"], ids["customer_id"])
+	if err != nil {
+		http.Error(w, "Failed to price item", http.StatusInternalServerError)
+		return
+	}
+	if err = resolveLineQuantity(h.db, tenantID, &item); err == nil {
+		priceLineItem(&item)
+		err = applyItemOptions(h.db, tenantID, &item)
+	}
+	if err == nil {
+		err = explodeKitLine(h.db, tenantID, priceCtx, &item, time.Now())
+	}
+	if err != nil {
+		var optionErr *itemOptionError
+		if errors.As(err, &optionErr) {
+			http.Error(w, optionErr.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to price item", http.StatusInternalServerError)
+		return
+	}
+	item.LineTotal = math.Round((item.Quantity*item.UnitPrice-item.DiscountAmount+item.TaxAmount)*100) / 100
+	item.Scale = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
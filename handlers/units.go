@@ -0,0 +1,218 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const defaultUnitCode = "ea"
+
+// builtinUnit is a unit every tenant has. Units of the same dimension convert through factor,
+// the size of the unit in the dimension's base unit (kg, l, m).
+type builtinUnit struct {
+	UnitOfMeasure
+	dimension string
+	factor    float64
+}
+
+var builtinUnits = map[string]builtinUnit{
+	"ea": {UnitOfMeasure{Code: "ea", Name: "Each", Decimals: 0, Rounding: "half_up"}, "count", 1},
+	"kg": {UnitOfMeasure{Code: "kg", Name: "Kilogram", Decimals: 3, Rounding: "half_up", IsWeighed: true}, "mass", 1},
+	"g":  {UnitOfMeasure{Code: "g", Name: "Gram", Decimals: 0, Rounding: "half_up", IsWeighed: true}, "mass", 0.001},
+	"lb": {UnitOfMeasure{Code: "lb", Name: "Pound", Decimals: 3, Rounding: "half_up", IsWeighed: true}, "mass", 0.45359237},
+	"oz": {UnitOfMeasure{Code: "oz", Name: "Ounce", Decimals: 2, Rounding: "half_up", IsWeighed: true}, "mass", 0.028349523125},
+	"l":  {UnitOfMeasure{Code: "l", Name: "Litre", Decimals: 3, Rounding: "half_up"}, "volume", 1},
+	"ml": {UnitOfMeasure{Code: "ml", Name: "Millilitre", Decimals: 0, Rounding: "half_up"}, "volume", 0.001},
+	"m":  {UnitOfMeasure{Code: "m", Name: "Metre", Decimals: 2, Rounding: "half_up"}, "length", 1},
+}
+
+var unitRoundings = map[string]bool{"half_up": true, "down": true, "up": true}
+
+// loadUnitsOfMeasure returns the built-in units with the tenant's overrides applied, followed by
+// the units the tenant added
+func loadUnitsOfMeasure(q reportQuerier, tenantID string) ([]UnitOfMeasure, error) {
+	units := make(map[string]UnitOfMeasure, len(builtinUnits))
+	for code, u := range builtinUnits {
+		u.BuiltIn = true
+		units[code] = u.UnitOfMeasure
+	}
+
+	rows, err := q.Query(`
+		SELECT code, name, decimals, rounding, is_weighed
+		FROM pos_units_of_measure
+		WHERE tenant_id = $1
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u UnitOfMeasure
+		if err := rows.Scan(&u.Code, &u.Name, &u.Decimals, &u.Rounding, &u.IsWeighed); err != nil {
+			return nil, err
+		}
+		_, u.BuiltIn = builtinUnits[u.Code]
+		units[u.Code] = u
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	list := make([]UnitOfMeasure, 0, len(units))
+	for _, u := range units {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].BuiltIn != list[j].BuiltIn {
+			return list[i].BuiltIn
+		}
+		return list[i].Code < list[j].Code
+	})
+	return list, nil
+}
+
+// loadUnitOfMeasure returns one of the tenant's units, or nil when the code is unknown
+func loadUnitOfMeasure(q rowQuerier, tenantID, code string) (*UnitOfMeasure, error) {
+	var u UnitOfMeasure
+	err := q.QueryRow(`
+		SELECT code, name, decimals, rounding, is_weighed
+		FROM pos_units_of_measure
+		WHERE tenant_id = $1 AND code = $2
+	`, tenantID, code).Scan(&u.Code, &u.Name, &u.Decimals, &u.Rounding, &u.IsWeighed)
+	if err == sql.ErrNoRows {
+		builtin, ok := builtinUnits[code]
+		if !ok {
+			return nil, nil
+		}
+		u = builtin.UnitOfMeasure
+	} else if err != nil {
+		return nil, err
+	}
+	_, u.BuiltIn = builtinUnits[code]
+	return &u, nil
+}
+
+// round brings a quantity to the unit's precision. The small epsilon keeps binary fractions
+// such as 0.734*1000 = 733.9999... from rounding the wrong way under down and up.
+func (u *UnitOfMeasure) round(quantity float64) float64 {
+	scale := math.Pow10(u.Decimals)
+	scaled := quantity * scale
+	switch u.Rounding {
+	case "down":
+		scaled = math.Floor(scaled + 1e-9)
+	case "up":
+		scaled = math.Ceil(scaled - 1e-9)
+	default:
+		scaled = math.Round(scaled)
+	}
+	return scaled / scale
+}
+
+// convertQuantity converts between built-in units of the same dimension
+func convertQuantity(quantity float64, from, to string) (float64, error) {
+	if from == to {
+		return quantity, nil
+	}
+	f, fromOK := builtinUnits[from]
+	t, toOK := builtinUnits[to]
+	if !fromOK || !toOK || f.dimension != t.dimension {
+		return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+	return quantity * f.factor / t.factor, nil
+}
+
+// loadProductUnit returns the unit a product is sold in; products without one are sold each
+func loadProductUnit(q rowQuerier, tenantID string, productID int) (*ProductUnit, error) {
+	pu := ProductUnit{TenantID: tenantID, ProductID: productID}
+	err := q.QueryRow(`
+		SELECT unit_code, tare_weight, requires_scale, updated_at
+		FROM pos_product_units
+		WHERE tenant_id = $1 AND product_id = $2
+	`, tenantID, productID).Scan(&pu.UnitCode, &pu.TareWeight, &pu.RequiresScale, &pu.UpdatedAt)
+	if err == sql.ErrNoRows {
+		pu.UnitCode = defaultUnitCode
+		return &pu, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pu, nil
+}
+
+// resolveLineQuantity settles a line's quantity in the product's unit of measure. A scale
+// reading replaces the quantity with the net weight: the gross reading, converted to the
+// product's unit, less the product's tare and any tare taken on the scale. The quantity is then
+// rounded by the unit's rule; units without decimals refuse fractional quantities rather than
+// round them. When the quantity differs from what the terminal sent, the line is repriced.
+func resolveLineQuantity(q rowQuerier, tenantID string, item *POSTransactionItem) error {
+	pu, err := loadProductUnit(q, tenantID, item.ProductID)
+	if err != nil {
+		return err
+	}
+	unit, err := loadUnitOfMeasure(q, tenantID, pu.UnitCode)
+	if err != nil {
+		return err
+	}
+	if unit == nil {
+		return fmt.Errorf("product %d is sold in unknown unit %q", item.ProductID, pu.UnitCode)
+	}
+
+	quantity := item.Quantity
+	if reading := item.Scale; reading != nil {
+		if !unit.IsWeighed {
+			return &itemOptionError{fmt.Sprintf("Product %d is sold by %s, not by weight", item.ProductID, unit.Name)}
+		}
+		if reading.Stable != nil && !*reading.Stable {
+			return &itemOptionError{fmt.Sprintf("Scale reading for product %d has not settled", item.ProductID)}
+		}
+		from := reading.Unit
+		if from == "" {
+			from = unit.Code
+		}
+		gross, err := convertQuantity(reading.GrossWeight, from, unit.Code)
+		if err != nil {
+			return &itemOptionError{fmt.Sprintf("Scale reading for product %d: %v", item.ProductID, err)}
+		}
+		scaleTare, _ := convertQuantity(reading.Tare, from, unit.Code)
+		tare := pu.TareWeight + scaleTare
+
+		quantity = unit.round(gross - tare)
+		if quantity <= 0 {
+			return &itemOptionError{fmt.Sprintf("Scale reading for product %d is not more than its tare", item.ProductID)}
+		}
+		gross, tare = math.Round(gross*1000)/1000, math.Round(tare*1000)/1000
+		item.GrossWeight = &gross
+		item.TareWeight = &tare
+	} else {
+		if pu.RequiresScale {
+			return &itemOptionError{fmt.Sprintf("Product %d must be weighed on the scale", item.ProductID)}
+		}
+		if unit.Decimals == 0 && quantity != math.Trunc(quantity) {
+			return &itemOptionError{fmt.Sprintf("Product %d is sold in whole %s", item.ProductID, unit.Name)}
+		}
+		quantity = unit.round(quantity)
+		if item.Quantity > 0 && quantity == 0 {
+			return &itemOptionError{fmt.Sprintf("Quantity of product %d is below the smallest %s", item.ProductID, unit.Name)}
+		}
+	}
+
+	code := unit.Code
+	item.UnitOfMeasure = &code
+	if quantity != item.Quantity {
+		item.Quantity = quantity
+		priceLineItem(item)
+	}
+	return nil
+}
+
+// priceLineItem recomputes a line's discount and tax from its quantity, unit price and rates.
+// A percentage discount follows the quantity; a fixed discount amount is kept as given.
+func priceLineItem(item *POSTransactionItem) {
+	extended := item.Quantity * item.UnitPrice
+	if item.DiscountPercent > 0 {
+		item.DiscountAmount = math.Round(extended*item.DiscountPercent) / 100
+	}
+	item.TaxAmount = math.Round((extended-item.DiscountAmount)*item.TaxRate) / 100
+}
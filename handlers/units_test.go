@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnitOfMeasureRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		decimals int
+		rounding string
+		quantity float64
+		want     float64
+	}{
+		{"half up to grams", 3, "half_up", 1.2346, 1.235},
+		{"half up keeps exact weights", 3, "half_up", 0.734, 0.734},
+		{"down truncates", 3, "down", 1.2349, 1.234},
+		{"down keeps binary fractions", 3, "down", 0.734, 0.734},
+		{"up raises", 3, "up", 1.2341, 1.235},
+		{"up keeps binary fractions", 3, "up", 0.734, 0.734},
+		{"whole units half up", 0, "half_up", 2.5, 3},
+		{"whole units down", 0, "down", 2.9, 2},
+		{"whole units up", 0, "up", 2.1, 3},
+		{"up ignores float noise", 2, "up", 0.1 + 0.2, 0.3},
+		{"unknown rounding rounds half up", 1, "", 0.25, 0.3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := UnitOfMeasure{Decimals: tt.decimals, Rounding: tt.rounding}
+			if got := u.round(tt.quantity); got != tt.want {
+				t.Errorf("round(%v) with %d decimals %s = %v, want %v", tt.quantity, tt.decimals, tt.rounding,
+					got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertQuantity(t *testing.T) {
+	tests := []struct {
+		quantity float64
+		from, to string
+		want     float64
+		wantErr  bool
+	}{
+		{1500, "g", "kg", 1.5, false},
+		{1, "lb", "kg", 0.45359237, false},
+		{1, "kg", "lb", 2.2046226218, false},
+		{16, "oz", "lb", 1, false},
+		{250, "ml", "l", 0.25, false},
+		{3, "ea", "ea", 3, false},
+		{2, "box", "box", 2, false}, // same unit needs no conversion, even a tenant's own
+		{1, "kg", "l", 0, true},
+		{1, "ea", "kg", 0, true},
+		{1, "box", "ea", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := convertQuantity(tt.quantity, tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("convertQuantity(%v, %s, %s) error = %v, want error %v", tt.quantity, tt.from, tt.to, err, tt.wantErr)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("convertQuantity(%v, %s, %s) = %v, want %v", tt.quantity, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestPriceLineItem(t *testing.T) {
+	tests := []struct {
+		name         string
+		item         POSTransactionItem
+		wantDiscount float64
+		wantTax      float64
+	}{
+		{"percentage discount follows the quantity",
+			POSTransactionItem{Quantity: 1.25, UnitPrice: 8, DiscountPercent: 10, TaxRate: 20}, 1, 1.8},
+		{"fixed discount is kept",
+			POSTransactionItem{Quantity: 0.5, UnitPrice: 10, DiscountAmount: 1, TaxRate: 10}, 1, 0.4},
+		{"no discount",
+			POSTransactionItem{Quantity: 0.734, UnitPrice: 12.99, TaxRate: 8}, 0, 0.76},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := tt.item
+			priceLineItem(&item)
+			if item.DiscountAmount != tt.wantDiscount || item.TaxAmount != tt.wantTax {
+				t.Errorf("priceLineItem = discount %v tax %v, want %v and %v", item.DiscountAmount, item.TaxAmount,
+					tt.wantDiscount, tt.wantTax)
+			}
+		})
+	}
+}
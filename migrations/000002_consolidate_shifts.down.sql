@@ -0,0 +1,31 @@
+-- Restore the pos_shifts compatibility table
+
+DROP INDEX IF EXISTS idx_pos_sessions_one_active;
+DROP INDEX IF EXISTS idx_register_shifts_one_open;
+
+CREATE TABLE IF NOT EXISTS pos_shifts (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    register_id INTEGER NOT NULL REFERENCES pos_registers(id),
+    shift_number VARCHAR(50) NOT NULL,
+    user_id INTEGER NOT NULL,
+    shift_start TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    shift_end TIMESTAMP,
+    opening_amount DECIMAL(15,2) DEFAULT 0.00,
+    closing_amount DECIMAL(15,2),
+    expected_balance DECIMAL(15,2),
+    variance DECIMAL(15,2),
+    total_sales DECIMAL(15,2) DEFAULT 0.00,
+    total_cash_sales DECIMAL(15,2) DEFAULT 0.00,
+    total_card_sales DECIMAL(15,2) DEFAULT 0.00,
+    total_returns DECIMAL(15,2) DEFAULT 0.00,
+    total_transactions INTEGER DEFAULT 0,
+    status VARCHAR(20) DEFAULT 'active', -- active, closed, reconciled
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, shift_number),
+    CONSTRAINT chk_pos_shift_status CHECK (status IN ('active', 'closed', 'reconciled'))
+);
+
+CREATE TRIGGER update_pos_shifts_updated_at BEFORE UPDATE ON pos_shifts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
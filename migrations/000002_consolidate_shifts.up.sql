@@ -0,0 +1,77 @@
+-- Consolidate shift tracking onto register_shifts
+-- pos_shifts was a duplicate kept "for compatibility"; sessions and transactions
+-- already reference register_shifts, so it becomes the single shift table.
+
+-- Carry over any rows that only exist in pos_shifts
+INSERT INTO register_shifts (tenant_id, register_id, shift_number, cashier_id, opening_balance,
+                             closing_balance, expected_balance, total_sales, total_cash_sales,
+                             total_card_sales, total_returns, transaction_count, opened_at,
+                             closed_at, status, notes, created_at)
+SELECT ps.tenant_id, ps.register_id, ps.shift_number, ps.user_id, COALESCE(ps.opening_amount, 0),
+       ps.closing_amount, ps.expected_balance, COALESCE(ps.total_sales, 0), COALESCE(ps.total_cash_sales, 0),
+       COALESCE(ps.total_card_sales, 0), COALESCE(ps.total_returns, 0), COALESCE(ps.total_transactions, 0),
+       COALESCE(ps.shift_start, ps.created_at, CURRENT_TIMESTAMP),
+       ps.shift_end,
+       CASE ps.status WHEN 'active' THEN 'open' ELSE ps.status END,
+       ps.notes, COALESCE(ps.created_at, CURRENT_TIMESTAMP)
+FROM pos_shifts ps
+WHERE NOT EXISTS (
+    SELECT 1 FROM register_shifts rs
+    WHERE rs.tenant_id = ps.tenant_id AND rs.shift_number = ps.shift_number
+);
+
+DROP TABLE IF EXISTS pos_shifts CASCADE;
+
+-- Registers left with several open shifts keep the latest one; the others are closed
+UPDATE register_shifts rs
+SET status = 'closed',
+    closed_at = COALESCE(rs.closed_at, CURRENT_TIMESTAMP),
+    notes = CONCAT_WS(E'\n', rs.notes, 'Closed by migration: another shift was open on the register')
+WHERE rs.status = 'open'
+  AND EXISTS (
+    SELECT 1 FROM register_shifts newer
+    WHERE newer.tenant_id = rs.tenant_id AND newer.register_id = rs.register_id AND newer.status = 'open'
+      AND (newer.opened_at, newer.id) > (rs.opened_at, rs.id)
+  );
+
+-- Only one open shift per register
+CREATE UNIQUE INDEX IF NOT EXISTS idx_register_shifts_one_open
+    ON register_shifts(tenant_id, register_id) WHERE status = 'open';
+
+-- Sales need the session's shift to be open: active sessions without one take the register's open shift
+UPDATE pos_sessions ps
+SET shift_id = rs.id
+FROM register_shifts rs
+WHERE ps.status = 'active'
+  AND rs.tenant_id = ps.tenant_id AND rs.register_id = ps.register_id AND rs.status = 'open'
+  AND (ps.shift_id IS NULL OR NOT EXISTS (
+    SELECT 1 FROM register_shifts own
+    WHERE own.id = ps.shift_id AND own.register_id = ps.register_id AND own.status = 'open'
+  ));
+
+-- Active sessions on a register with no open shift could not sell; close them so a new session can open
+UPDATE pos_sessions ps
+SET status = 'closed',
+    session_end = COALESCE(ps.session_end, CURRENT_TIMESTAMP),
+    notes = CONCAT_WS(E'\n', ps.notes, 'Closed by migration: no open shift on the register')
+WHERE ps.status = 'active'
+  AND NOT EXISTS (
+    SELECT 1 FROM register_shifts rs
+    WHERE rs.id = ps.shift_id AND rs.register_id = ps.register_id AND rs.status = 'open'
+  );
+
+-- Likewise for sessions: the latest active session per register stays active
+UPDATE pos_sessions ps
+SET status = 'closed',
+    session_end = COALESCE(ps.session_end, CURRENT_TIMESTAMP),
+    notes = CONCAT_WS(E'\n', ps.notes, 'Closed by migration: another session was active on the register')
+WHERE ps.status = 'active'
+  AND EXISTS (
+    SELECT 1 FROM pos_sessions newer
+    WHERE newer.tenant_id = ps.tenant_id AND newer.register_id = ps.register_id AND newer.status = 'active'
+      AND (COALESCE(newer.session_start, 'epoch'), newer.id) > (COALESCE(ps.session_start, 'epoch'), ps.id)
+  );
+
+-- Only one active session per register
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_sessions_one_active
+    ON pos_sessions(tenant_id, register_id) WHERE status = 'active';
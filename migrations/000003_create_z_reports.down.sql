@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pos_z_reports CASCADE;
@@ -0,0 +1,29 @@
+-- Z-Reports (end-of-day closing reports)
+-- Each Z-report is numbered per register and stores the exact figures that were
+-- printed so it can be reprinted without being recalculated.
+
+CREATE TABLE IF NOT EXISTS pos_z_reports (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    register_id INTEGER NOT NULL REFERENCES pos_registers(id),
+    session_id INTEGER REFERENCES pos_sessions(id),
+    shift_id INTEGER REFERENCES register_shifts(id),
+    scope VARCHAR(20) NOT NULL, -- register, session, shift
+    z_number INTEGER NOT NULL,
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    transaction_count INTEGER NOT NULL DEFAULT 0,
+    net_sales DECIMAL(15,2) NOT NULL DEFAULT 0,
+    report_data JSONB NOT NULL,
+    generated_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, register_id, z_number),
+    CONSTRAINT chk_z_report_scope CHECK (scope IN ('register', 'session', 'shift'))
+);
+
+-- A session or shift can only be Z'd once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_z_reports_session ON pos_z_reports(session_id) WHERE scope = 'session';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_z_reports_shift ON pos_z_reports(shift_id) WHERE scope = 'shift';
+
+CREATE INDEX IF NOT EXISTS idx_pos_z_reports_tenant ON pos_z_reports(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_pos_z_reports_register ON pos_z_reports(register_id, period_end DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pos_timeout_events CASCADE;
+DROP TABLE IF EXISTS pos_settings CASCADE;
@@ -0,0 +1,35 @@
+-- POS Settings (per-tenant overrides of module.yml settings)
+CREATE TABLE IF NOT EXISTS pos_settings (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    setting_key VARCHAR(100) NOT NULL,
+    setting_value JSONB NOT NULL,
+    updated_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, setting_key)
+);
+
+-- Timeout Events (stale sessions and shifts found by the timeout worker)
+CREATE TABLE IF NOT EXISTS pos_timeout_events (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    entity_type VARCHAR(20) NOT NULL, -- session, shift
+    entity_id INTEGER NOT NULL,
+    register_id INTEGER NOT NULL REFERENCES pos_registers(id),
+    user_id INTEGER, -- cashier on the session or shift
+    action VARCHAR(20) NOT NULL, -- flagged, auto_closed
+    reason TEXT NOT NULL,
+    last_activity_at TIMESTAMP NOT NULL,
+    timeout_minutes INTEGER NOT NULL,
+    notified_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(entity_type, entity_id, action),
+    CONSTRAINT chk_timeout_entity_type CHECK (entity_type IN ('session', 'shift')),
+    CONSTRAINT chk_timeout_action CHECK (action IN ('flagged', 'auto_closed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_settings_tenant ON pos_settings(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_pos_timeout_events_tenant ON pos_timeout_events(tenant_id, created_at DESC);
+
+CREATE TRIGGER update_pos_settings_updated_at BEFORE UPDATE ON pos_settings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pos_safe_transactions CASCADE;
+DROP TABLE IF EXISTS pos_bank_deposits CASCADE;
+DROP TABLE IF EXISTS pos_safes CASCADE;
@@ -0,0 +1,69 @@
+-- Safes (one back-office safe per location)
+CREATE TABLE IF NOT EXISTS pos_safes (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    location_id INTEGER, -- references locations table
+    name VARCHAR(100) NOT NULL,
+    current_balance DECIMAL(15,2) NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_safe_balance CHECK (current_balance >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_safes_location
+    ON pos_safes(tenant_id, COALESCE(location_id, 0)) WHERE is_active = true;
+
+-- Bank Deposits (cash sent from a safe to the bank)
+CREATE TABLE IF NOT EXISTS pos_bank_deposits (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    safe_id INTEGER NOT NULL REFERENCES pos_safes(id),
+    deposit_number VARCHAR(50) NOT NULL,
+    slip_number VARCHAR(100),
+    bag_id VARCHAR(100),
+    expected_amount DECIMAL(15,2) NOT NULL,
+    confirmed_amount DECIMAL(15,2),
+    variance DECIMAL(15,2) GENERATED ALWAYS AS (confirmed_amount - expected_amount) STORED,
+    status VARCHAR(20) NOT NULL DEFAULT 'prepared', -- prepared, in_transit, confirmed, discrepancy, cancelled
+    bank_reference VARCHAR(100),
+    notes TEXT,
+    prepared_by INTEGER, -- references users table
+    prepared_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_by INTEGER, -- references users table
+    dispatched_at TIMESTAMP,
+    confirmed_by INTEGER, -- references users table
+    confirmed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, deposit_number),
+    CONSTRAINT chk_deposit_status CHECK (status IN ('prepared', 'in_transit', 'confirmed', 'discrepancy', 'cancelled'))
+);
+
+-- Safe Transactions (safe ledger)
+CREATE TABLE IF NOT EXISTS pos_safe_transactions (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    safe_id INTEGER NOT NULL REFERENCES pos_safes(id),
+    transaction_type VARCHAR(50) NOT NULL, -- register_drop, change_fund, bank_deposit, deposit_cancelled, adjustment
+    amount DECIMAL(15,2) NOT NULL,
+    balance_before DECIMAL(15,2) NOT NULL,
+    balance_after DECIMAL(15,2) NOT NULL,
+    register_id INTEGER REFERENCES pos_registers(id),
+    register_transaction_id INTEGER REFERENCES register_transactions(id),
+    deposit_id INTEGER REFERENCES pos_bank_deposits(id),
+    reference_number VARCHAR(100),
+    notes TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by INTEGER, -- references users table
+    CONSTRAINT chk_safe_txn_type CHECK (transaction_type IN ('register_drop', 'change_fund', 'bank_deposit', 'deposit_cancelled', 'adjustment'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_safes_tenant ON pos_safes(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_pos_safe_txn_safe ON pos_safe_transactions(safe_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_pos_bank_deposits_tenant ON pos_bank_deposits(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_pos_bank_deposits_safe ON pos_bank_deposits(safe_id);
+CREATE INDEX IF NOT EXISTS idx_pos_bank_deposits_status ON pos_bank_deposits(status);
+
+CREATE TRIGGER update_pos_safes_updated_at BEFORE UPDATE ON pos_safes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_pos_bank_deposits_updated_at BEFORE UPDATE ON pos_bank_deposits FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_pos_receipts_transaction;
+
+ALTER TABLE pos_receipts DROP COLUMN IF EXISTS rendered_at;
+ALTER TABLE pos_receipts DROP COLUMN IF EXISTS rendered_text;
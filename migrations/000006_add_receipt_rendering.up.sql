@@ -0,0 +1,6 @@
+-- Receipts are rendered on the server from the recorded transaction.
+-- receipt_data holds the canonical structured receipt (JSON); rendered_text the plain-text slip.
+ALTER TABLE pos_receipts ADD COLUMN IF NOT EXISTS rendered_text TEXT;
+ALTER TABLE pos_receipts ADD COLUMN IF NOT EXISTS rendered_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_pos_receipts_transaction ON pos_receipts(transaction_id);
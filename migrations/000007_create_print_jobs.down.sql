@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pos_print_jobs CASCADE;
@@ -0,0 +1,20 @@
+-- Print Jobs (rendered receipts waiting for a register's printer)
+CREATE TABLE IF NOT EXISTS pos_print_jobs (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    receipt_id INTEGER NOT NULL REFERENCES pos_receipts(id),
+    register_id INTEGER NOT NULL REFERENCES pos_registers(id),
+    format VARCHAR(20) NOT NULL DEFAULT 'escpos',
+    paper_width INTEGER NOT NULL DEFAULT 80, -- millimetres: 58 or 80
+    payload BYTEA NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued', -- queued, printed, failed
+    error_message TEXT,
+    created_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    CONSTRAINT chk_print_job_status CHECK (status IN ('queued', 'printed', 'failed')),
+    CONSTRAINT chk_print_job_width CHECK (paper_width IN (58, 80))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_print_jobs_register ON pos_print_jobs(tenant_id, register_id, status);
+CREATE INDEX IF NOT EXISTS idx_pos_print_jobs_receipt ON pos_print_jobs(receipt_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pos_email_deliveries CASCADE;
@@ -0,0 +1,24 @@
+-- Email Deliveries (queued receipt emails with retry state)
+CREATE TABLE IF NOT EXISTS pos_email_deliveries (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    receipt_id INTEGER NOT NULL REFERENCES pos_receipts(id),
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued', -- queued, sending, sent, failed, bounced
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    sent_at TIMESTAMP,
+    bounced_at TIMESTAMP,
+    created_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_email_delivery_status CHECK (status IN ('queued', 'sending', 'sent', 'failed', 'bounced'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_email_deliveries_due ON pos_email_deliveries(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_pos_email_deliveries_receipt ON pos_email_deliveries(receipt_id);
+
+CREATE TRIGGER update_pos_email_deliveries_updated_at BEFORE UPDATE ON pos_email_deliveries FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
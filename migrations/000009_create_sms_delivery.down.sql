@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS pos_sms_messages CASCADE;
+DROP TABLE IF EXISTS pos_customer_sms_consent CASCADE;
+
+DROP INDEX IF EXISTS idx_pos_receipts_view_token;
+ALTER TABLE pos_receipts DROP COLUMN IF EXISTS view_token;
@@ -0,0 +1,39 @@
+-- Token embedded in links to the customer's digital receipt
+ALTER TABLE pos_receipts ADD COLUMN IF NOT EXISTS view_token VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_receipts_view_token ON pos_receipts(view_token) WHERE view_token IS NOT NULL;
+
+-- SMS Consent (per-customer opt-in for receipt texts)
+CREATE TABLE IF NOT EXISTS pos_customer_sms_consent (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    customer_id INTEGER NOT NULL, -- references customers table
+    phone_e164 VARCHAR(20) NOT NULL,
+    opted_in BOOLEAN NOT NULL DEFAULT false,
+    source VARCHAR(50), -- pos, web, import
+    opted_in_at TIMESTAMP,
+    opted_out_at TIMESTAMP,
+    updated_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, customer_id)
+);
+
+-- SMS Messages (receipt texts sent through the SMS provider)
+CREATE TABLE IF NOT EXISTS pos_sms_messages (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    receipt_id INTEGER NOT NULL REFERENCES pos_receipts(id),
+    customer_id INTEGER, -- references customers table
+    phone_e164 VARCHAR(20) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL, -- sent, failed
+    provider_message_id VARCHAR(100),
+    error_message TEXT,
+    created_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_sms_status CHECK (status IN ('sent', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_sms_messages_receipt ON pos_sms_messages(receipt_id);
+
+CREATE TRIGGER update_pos_customer_sms_consent_updated_at BEFORE UPDATE ON pos_customer_sms_consent FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
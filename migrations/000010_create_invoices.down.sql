@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pos_invoices CASCADE;
+DROP TABLE IF EXISTS pos_invoice_sequences CASCADE;
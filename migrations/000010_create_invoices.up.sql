@@ -0,0 +1,26 @@
+-- Invoice Sequences (gapless per-tenant invoice numbering)
+CREATE TABLE IF NOT EXISTS pos_invoice_sequences (
+    tenant_id VARCHAR(255) PRIMARY KEY,
+    last_number INTEGER NOT NULL DEFAULT 0
+);
+
+-- Invoices (tax invoices for business customers)
+CREATE TABLE IF NOT EXISTS pos_invoices (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    transaction_id INTEGER NOT NULL REFERENCES pos_transactions(id),
+    sequence_number INTEGER NOT NULL,
+    invoice_number VARCHAR(50) NOT NULL,
+    customer_id INTEGER, -- references customers table
+    buyer_name VARCHAR(255) NOT NULL,
+    buyer_tax_id VARCHAR(100),
+    total_amount DECIMAL(15,2) NOT NULL,
+    invoice_data JSONB NOT NULL, -- the invoice as issued, so downloads always match
+    issued_by INTEGER, -- references users table
+    issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, transaction_id),
+    UNIQUE(tenant_id, sequence_number),
+    UNIQUE(tenant_id, invoice_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_invoices_customer ON pos_invoices(tenant_id, customer_id);
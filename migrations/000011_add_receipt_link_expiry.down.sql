@@ -0,0 +1,2 @@
+ALTER TABLE pos_receipts DROP COLUMN IF EXISTS view_token_revoked_at;
+ALTER TABLE pos_receipts DROP COLUMN IF EXISTS view_token_expires_at;
@@ -0,0 +1,3 @@
+-- Expiry and revocation for public receipt links
+ALTER TABLE pos_receipts ADD COLUMN IF NOT EXISTS view_token_expires_at TIMESTAMP;
+ALTER TABLE pos_receipts ADD COLUMN IF NOT EXISTS view_token_revoked_at TIMESTAMP;
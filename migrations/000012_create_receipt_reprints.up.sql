@@ -0,0 +1,14 @@
+-- Receipt Reprints (audit of every copy printed after the original)
+CREATE TABLE IF NOT EXISTS pos_receipt_reprints (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    receipt_id INTEGER NOT NULL REFERENCES pos_receipts(id),
+    copy_number INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    print_job_id INTEGER REFERENCES pos_print_jobs(id),
+    printed_by INTEGER, -- references users table
+    printed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(receipt_id, copy_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_receipt_reprints_receipt ON pos_receipt_reprints(tenant_id, receipt_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pos_gift_receipt_items CASCADE;
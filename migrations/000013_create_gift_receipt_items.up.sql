@@ -0,0 +1,13 @@
+-- Gift Receipt Items (the original lines, and how many units of each, a gift receipt covers)
+CREATE TABLE IF NOT EXISTS pos_gift_receipt_items (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    receipt_id INTEGER NOT NULL REFERENCES pos_receipts(id) ON DELETE CASCADE,
+    transaction_item_id INTEGER NOT NULL REFERENCES pos_transaction_items(id),
+    quantity INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(receipt_id, transaction_item_id),
+    CONSTRAINT chk_gift_receipt_quantity CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_gift_receipt_items_item ON pos_gift_receipt_items(transaction_item_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pos_receipt_templates CASCADE;
@@ -0,0 +1,26 @@
+-- Receipt Templates (per tenant, location or register; the most specific one applies)
+CREATE TABLE IF NOT EXISTS pos_receipt_templates (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    location_id INTEGER, -- references locations table; NULL with register_id NULL is the tenant default
+    register_id INTEGER REFERENCES pos_registers(id) ON DELETE CASCADE,
+    locale VARCHAR(10) NOT NULL DEFAULT 'en-US',
+    secondary_locale VARCHAR(10), -- second language printed alongside the first
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    header TEXT NOT NULL DEFAULT '',
+    footer TEXT NOT NULL DEFAULT '',
+    logo_url TEXT NOT NULL DEFAULT '',
+    legal_text TEXT NOT NULL DEFAULT '',
+    return_policy TEXT NOT NULL DEFAULT '',
+    sections JSONB NOT NULL DEFAULT '{"tax_summary": true, "loyalty_balance": false, "return_policy": false}',
+    translations JSONB NOT NULL DEFAULT '{}', -- {"de-DE": {"footer": "...", "total": "..."}}
+    created_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_receipt_templates_scope
+    ON pos_receipt_templates(tenant_id, COALESCE(location_id, 0), COALESCE(register_id, 0));
+
+CREATE TRIGGER update_pos_receipt_templates_updated_at BEFORE UPDATE ON pos_receipt_templates FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
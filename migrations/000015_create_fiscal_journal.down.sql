@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pos_fiscal_journal CASCADE;
+DROP FUNCTION IF EXISTS pos_fiscal_journal_immutable();
+DROP TABLE IF EXISTS pos_fiscal_keys CASCADE;
@@ -0,0 +1,42 @@
+-- Fiscal Signing Keys (public half of each tenant signing key; the private key is derived, never stored)
+CREATE TABLE IF NOT EXISTS pos_fiscal_keys (
+    tenant_id VARCHAR(255) NOT NULL,
+    key_version INTEGER NOT NULL,
+    algorithm VARCHAR(20) NOT NULL DEFAULT 'ed25519',
+    public_key TEXT NOT NULL, -- base64
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, key_version)
+);
+
+-- Fiscal Journal (append-only, hash-chained record of completed transactions and receipts per register)
+CREATE TABLE IF NOT EXISTS pos_fiscal_journal (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    register_id INTEGER NOT NULL REFERENCES pos_registers(id),
+    sequence_number BIGINT NOT NULL,
+    entry_type VARCHAR(20) NOT NULL, -- transaction, receipt
+    entity_id INTEGER NOT NULL,
+    payload TEXT NOT NULL, -- canonical JSON snapshot of the record as journaled
+    payload_hash VARCHAR(64) NOT NULL,
+    previous_hash VARCHAR(64) NOT NULL,
+    entry_hash VARCHAR(64) NOT NULL,
+    signature TEXT, -- NULL when no signing key is configured
+    key_version INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(register_id, sequence_number),
+    UNIQUE(tenant_id, entry_type, entity_id),
+    CONSTRAINT chk_fiscal_entry_type CHECK (entry_type IN ('transaction', 'receipt'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_fiscal_journal_register ON pos_fiscal_journal(tenant_id, register_id, sequence_number);
+
+-- Journal entries can never be changed or removed
+CREATE OR REPLACE FUNCTION pos_fiscal_journal_immutable()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'pos_fiscal_journal is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER pos_fiscal_journal_no_update BEFORE UPDATE OR DELETE ON pos_fiscal_journal FOR EACH ROW EXECUTE FUNCTION pos_fiscal_journal_immutable();
+CREATE TRIGGER pos_fiscal_journal_no_truncate BEFORE TRUNCATE ON pos_fiscal_journal FOR EACH STATEMENT EXECUTE FUNCTION pos_fiscal_journal_immutable();
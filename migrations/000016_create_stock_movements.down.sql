@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pos_return_items CASCADE;
+DROP TABLE IF EXISTS pos_stock_levels CASCADE;
+DROP TABLE IF EXISTS pos_stock_movements CASCADE;
@@ -0,0 +1,51 @@
+-- Stock Movements (every stock change made by the POS, per transaction item)
+CREATE TABLE IF NOT EXISTS pos_stock_movements (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    transaction_id INTEGER NOT NULL REFERENCES pos_transactions(id),
+    transaction_item_id INTEGER NOT NULL REFERENCES pos_transaction_items(id),
+    product_id INTEGER NOT NULL, -- references products table
+    location_id INTEGER, -- references locations table; the register's location
+    quantity DECIMAL(15,3) NOT NULL, -- negative leaves stock, positive comes back
+    movement_type VARCHAR(20) NOT NULL, -- sale, void, return
+    disposition VARCHAR(20), -- restock, damaged (voids and returns)
+    adapter VARCHAR(20) NOT NULL, -- inventory store the movement was applied to
+    created_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_stock_movement_type CHECK (movement_type IN ('sale', 'void', 'return')),
+    CONSTRAINT chk_stock_disposition CHECK (disposition IS NULL OR disposition IN ('restock', 'damaged'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_stock_movements_transaction ON pos_stock_movements(tenant_id, transaction_id);
+CREATE INDEX IF NOT EXISTS idx_pos_stock_movements_product ON pos_stock_movements(tenant_id, product_id, location_id);
+
+-- Stock Levels (on-hand stock kept by the local inventory adapter when the ERP inventory is not used)
+CREATE TABLE IF NOT EXISTS pos_stock_levels (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    location_id INTEGER, -- references locations table
+    quantity_on_hand DECIMAL(15,3) NOT NULL DEFAULT 0,
+    quantity_damaged DECIMAL(15,3) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_stock_levels_product
+    ON pos_stock_levels(tenant_id, product_id, COALESCE(location_id, 0));
+
+-- Return Items (original sale lines taken back by each return transaction)
+CREATE TABLE IF NOT EXISTS pos_return_items (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    return_transaction_id INTEGER NOT NULL REFERENCES pos_transactions(id),
+    return_item_id INTEGER NOT NULL REFERENCES pos_transaction_items(id),
+    original_transaction_id INTEGER NOT NULL REFERENCES pos_transactions(id),
+    original_item_id INTEGER NOT NULL REFERENCES pos_transaction_items(id),
+    quantity INTEGER NOT NULL,
+    disposition VARCHAR(20) NOT NULL, -- restock, damaged
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_return_disposition CHECK (disposition IN ('restock', 'damaged'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_return_items_original ON pos_return_items(tenant_id, original_item_id);
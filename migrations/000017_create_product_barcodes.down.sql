@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pos_product_barcodes CASCADE;
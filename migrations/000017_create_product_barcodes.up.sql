@@ -0,0 +1,16 @@
+-- Product Barcodes (alternate barcodes per product, e.g. case packs or supplier codes)
+CREATE TABLE IF NOT EXISTS pos_product_barcodes (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    barcode VARCHAR(80) NOT NULL, -- normalized: UPC-A is stored as its EAN-13 form
+    symbology VARCHAR(20) NOT NULL, -- upc_a, ean_13, ean_8, code128
+    quantity INTEGER NOT NULL DEFAULT 1, -- units one scan adds
+    description VARCHAR(100),
+    created_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, barcode),
+    CONSTRAINT chk_product_barcode_quantity CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_product_barcodes_product ON pos_product_barcodes(tenant_id, product_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS pos_transaction_item_modifiers CASCADE;
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS variant_id;
+DROP TABLE IF EXISTS pos_product_modifier_groups CASCADE;
+DROP TABLE IF EXISTS pos_modifiers CASCADE;
+DROP TABLE IF EXISTS pos_modifier_groups CASCADE;
+DROP TABLE IF EXISTS pos_product_variants CASCADE;
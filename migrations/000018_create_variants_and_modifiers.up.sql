@@ -0,0 +1,87 @@
+-- Product Variants (e.g. size and colour of an apparel item)
+CREATE TABLE IF NOT EXISTS pos_product_variants (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    name VARCHAR(100) NOT NULL, -- e.g. "M / Red"
+    sku VARCHAR(100),
+    barcode VARCHAR(80), -- normalized like pos_product_barcodes.barcode
+    attributes JSONB DEFAULT '{}', -- e.g. {"size": "M", "color": "Red"}
+    price DECIMAL(15,2), -- NULL sells at the product's price
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, product_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_product_variants_product ON pos_product_variants(tenant_id, product_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_product_variants_sku ON pos_product_variants(tenant_id, sku) WHERE sku IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_product_variants_barcode ON pos_product_variants(tenant_id, barcode) WHERE barcode IS NOT NULL;
+
+-- Modifier Groups (e.g. "Milk", "Extra shots") and their modifiers
+CREATE TABLE IF NOT EXISTS pos_modifier_groups (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    min_selections INTEGER NOT NULL DEFAULT 0,
+    max_selections INTEGER NOT NULL DEFAULT 1, -- 0 means no limit
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, name),
+    CONSTRAINT chk_modifier_group_selections CHECK (min_selections >= 0 AND max_selections >= 0
+        AND (max_selections = 0 OR max_selections >= min_selections))
+);
+
+CREATE TABLE IF NOT EXISTS pos_modifiers (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    group_id INTEGER NOT NULL REFERENCES pos_modifier_groups(id),
+    name VARCHAR(100) NOT NULL,
+    price DECIMAL(15,2) NOT NULL DEFAULT 0.00, -- added to the item's unit price; may be negative
+    tax_rate DECIMAL(5,2), -- NULL is taxed at the item's rate
+    sort_order INTEGER DEFAULT 0,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(group_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_modifiers_group ON pos_modifiers(group_id);
+
+-- Modifier groups offered on a product, with per-product selection rules
+CREATE TABLE IF NOT EXISTS pos_product_modifier_groups (
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    group_id INTEGER NOT NULL REFERENCES pos_modifier_groups(id),
+    min_selections INTEGER, -- NULL uses the group's rule
+    max_selections INTEGER, -- NULL uses the group's rule; 0 means no limit
+    sort_order INTEGER DEFAULT 0,
+    PRIMARY KEY (tenant_id, product_id, group_id)
+);
+
+-- Variant sold on a line; NULL for products without variants
+ALTER TABLE pos_transaction_items ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES pos_product_variants(id);
+
+-- Modifiers chosen on a line. The line's unit_price and tax_amount already include them;
+-- these rows break them out for receipts, tax by rate and analytics.
+CREATE TABLE IF NOT EXISTS pos_transaction_item_modifiers (
+    id SERIAL PRIMARY KEY,
+    transaction_item_id INTEGER NOT NULL REFERENCES pos_transaction_items(id),
+    modifier_id INTEGER NOT NULL REFERENCES pos_modifiers(id),
+    group_id INTEGER NOT NULL REFERENCES pos_modifier_groups(id),
+    name VARCHAR(100) NOT NULL, -- as sold
+    quantity INTEGER NOT NULL DEFAULT 1, -- per unit of the line
+    price DECIMAL(15,2) NOT NULL, -- per modifier, as sold
+    amount DECIMAL(15,2) NOT NULL, -- price x quantity x line quantity
+    tax_rate DECIMAL(5,2) NOT NULL DEFAULT 0.00,
+    tax_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    CONSTRAINT chk_item_modifier_quantity CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_transaction_item_modifiers_item ON pos_transaction_item_modifiers(transaction_item_id);
+CREATE INDEX IF NOT EXISTS idx_pos_transaction_item_modifiers_modifier ON pos_transaction_item_modifiers(modifier_id);
+
+CREATE TRIGGER update_pos_product_variants_updated_at BEFORE UPDATE ON pos_product_variants FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_pos_modifier_groups_updated_at BEFORE UPDATE ON pos_modifier_groups FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_pos_modifiers_updated_at BEFORE UPDATE ON pos_modifiers FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
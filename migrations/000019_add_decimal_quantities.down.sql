@@ -0,0 +1,25 @@
+-- Whole-unit quantities cannot hold weighed sales; refuse to roll back rather than change them
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pos_transaction_items WHERE quantity <> TRUNC(quantity))
+       OR EXISTS (SELECT 1 FROM pos_return_items WHERE quantity <> TRUNC(quantity))
+       OR EXISTS (SELECT 1 FROM pos_gift_receipt_items WHERE quantity <> TRUNC(quantity)) THEN
+        RAISE EXCEPTION 'fractional quantities have been recorded; they cannot be converted to whole units';
+    END IF;
+END;
+$$;
+
+DROP TABLE IF EXISTS pos_product_units CASCADE;
+DROP TABLE IF EXISTS pos_units_of_measure CASCADE;
+
+ALTER TABLE pos_gift_receipt_items ALTER COLUMN quantity TYPE INTEGER USING quantity::integer;
+ALTER TABLE pos_return_items ALTER COLUMN quantity TYPE INTEGER USING quantity::integer;
+
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS tare_weight;
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS gross_weight;
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS unit_of_measure;
+
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS line_total;
+ALTER TABLE pos_transaction_items ALTER COLUMN quantity TYPE INTEGER USING quantity::integer;
+ALTER TABLE pos_transaction_items ADD COLUMN line_total DECIMAL(15,2)
+    GENERATED ALWAYS AS (quantity * unit_price - discount_amount + tax_amount) STORED;
@@ -0,0 +1,44 @@
+-- Fractional quantities for weighed and measured items. line_total is generated from quantity,
+-- so it is dropped around the type change and rebuilt with the same expression.
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS line_total;
+ALTER TABLE pos_transaction_items ALTER COLUMN quantity TYPE DECIMAL(15,3);
+ALTER TABLE pos_transaction_items ADD COLUMN line_total DECIMAL(15,2)
+    GENERATED ALWAYS AS (quantity * unit_price - discount_amount + tax_amount) STORED;
+
+ALTER TABLE pos_transaction_items ADD COLUMN IF NOT EXISTS unit_of_measure VARCHAR(20); -- NULL for lines sold before units existed
+ALTER TABLE pos_transaction_items ADD COLUMN IF NOT EXISTS gross_weight DECIMAL(15,3); -- scale reading, in unit_of_measure
+ALTER TABLE pos_transaction_items ADD COLUMN IF NOT EXISTS tare_weight DECIMAL(15,3);
+
+ALTER TABLE pos_return_items ALTER COLUMN quantity TYPE DECIMAL(15,3);
+ALTER TABLE pos_gift_receipt_items ALTER COLUMN quantity TYPE DECIMAL(15,3);
+
+-- Units of measure a tenant adds, or built-in units whose rounding it overrides
+CREATE TABLE IF NOT EXISTS pos_units_of_measure (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    code VARCHAR(20) NOT NULL, -- e.g. kg, lb, ea
+    name VARCHAR(50) NOT NULL,
+    decimals INTEGER NOT NULL DEFAULT 0, -- quantity precision; 0 sells whole units only
+    rounding VARCHAR(10) NOT NULL DEFAULT 'half_up', -- half_up, down, up
+    is_weighed BOOLEAN NOT NULL DEFAULT false, -- quantities may come from a scale
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, code),
+    CONSTRAINT chk_unit_decimals CHECK (decimals BETWEEN 0 AND 3),
+    CONSTRAINT chk_unit_rounding CHECK (rounding IN ('half_up', 'down', 'up'))
+);
+
+-- The unit a product is sold in, and the tare of its container on the scale
+CREATE TABLE IF NOT EXISTS pos_product_units (
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    unit_code VARCHAR(20) NOT NULL,
+    tare_weight DECIMAL(15,3) NOT NULL DEFAULT 0, -- in unit_code
+    requires_scale BOOLEAN NOT NULL DEFAULT false, -- the quantity must come from a scale reading
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, product_id),
+    CONSTRAINT chk_product_unit_tare CHECK (tare_weight >= 0)
+);
+
+CREATE TRIGGER update_pos_units_of_measure_updated_at BEFORE UPDATE ON pos_units_of_measure FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_pos_product_units_updated_at BEFORE UPDATE ON pos_product_units FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
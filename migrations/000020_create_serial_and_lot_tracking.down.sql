@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS pos_transaction_item_tracking CASCADE;
+DROP TABLE IF EXISTS pos_lots CASCADE;
+DROP TABLE IF EXISTS pos_serial_numbers CASCADE;
+DROP TABLE IF EXISTS pos_product_tracking CASCADE;
@@ -0,0 +1,63 @@
+-- Products that must have a serial or lot number captured when sold
+CREATE TABLE IF NOT EXISTS pos_product_tracking (
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    tracking VARCHAR(10) NOT NULL, -- serial, lot
+    warranty_days INTEGER, -- from the sale date, for serial lookups on warranty returns
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, product_id),
+    CONSTRAINT chk_product_tracking CHECK (tracking IN ('serial', 'lot')),
+    CONSTRAINT chk_product_warranty_days CHECK (warranty_days IS NULL OR warranty_days >= 0)
+);
+
+-- Serial numbers received into stock, and whether each is still on hand
+CREATE TABLE IF NOT EXISTS pos_serial_numbers (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    serial_number VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_stock', -- in_stock, sold, damaged
+    sold_item_id INTEGER REFERENCES pos_transaction_items(id), -- the sale line it last left on
+    received_by INTEGER, -- references users table
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, product_id, serial_number),
+    CONSTRAINT chk_serial_status CHECK (status IN ('in_stock', 'sold', 'damaged'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_serial_numbers_serial ON pos_serial_numbers(tenant_id, serial_number);
+
+-- Lots received into stock with their expiry and the quantity left
+CREATE TABLE IF NOT EXISTS pos_lots (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    lot_number VARCHAR(100) NOT NULL,
+    expiry_date DATE, -- lots past expiry cannot be sold
+    quantity_on_hand DECIMAL(15,3) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, product_id, lot_number),
+    CONSTRAINT chk_lot_quantity CHECK (quantity_on_hand >= 0)
+);
+
+-- Serials and lots captured on each sale and return line
+CREATE TABLE IF NOT EXISTS pos_transaction_item_tracking (
+    id SERIAL PRIMARY KEY,
+    transaction_item_id INTEGER NOT NULL REFERENCES pos_transaction_items(id) ON DELETE CASCADE,
+    serial_id INTEGER REFERENCES pos_serial_numbers(id),
+    lot_id INTEGER REFERENCES pos_lots(id),
+    serial_number VARCHAR(100),
+    lot_number VARCHAR(100),
+    quantity DECIMAL(15,3) NOT NULL, -- 1 for a serial
+    CONSTRAINT chk_item_tracking_target CHECK ((serial_id IS NULL) <> (lot_id IS NULL)),
+    CONSTRAINT chk_item_tracking_quantity CHECK (quantity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_item_tracking_item ON pos_transaction_item_tracking(transaction_item_id);
+CREATE INDEX IF NOT EXISTS idx_pos_item_tracking_serial ON pos_transaction_item_tracking(serial_id);
+CREATE INDEX IF NOT EXISTS idx_pos_item_tracking_lot ON pos_transaction_item_tracking(lot_id);
+
+CREATE TRIGGER update_pos_product_tracking_updated_at BEFORE UPDATE ON pos_product_tracking FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_pos_serial_numbers_updated_at BEFORE UPDATE ON pos_serial_numbers FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_pos_lots_updated_at BEFORE UPDATE ON pos_lots FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
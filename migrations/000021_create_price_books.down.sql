@@ -0,0 +1,5 @@
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS price_book_id;
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS list_price;
+DROP TABLE IF EXISTS pos_price_book_publications CASCADE;
+DROP TABLE IF EXISTS pos_price_book_entries CASCADE;
+DROP TABLE IF EXISTS pos_price_books CASCADE;
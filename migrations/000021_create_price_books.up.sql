@@ -0,0 +1,60 @@
+-- Price Books (prices scoped to every register, a location, a register or a customer group)
+CREATE TABLE IF NOT EXISTS pos_price_books (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    scope_type VARCHAR(20) NOT NULL DEFAULT 'all', -- all, location, register, customer_group
+    scope_id INTEGER, -- location, register or customer group; NULL for all
+    priority INTEGER NOT NULL DEFAULT 0, -- breaks ties between books of the same scope; higher wins
+    valid_from TIMESTAMP,
+    valid_to TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    version INTEGER NOT NULL DEFAULT 0, -- last published version
+    published_at TIMESTAMP,
+    created_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, name),
+    CONSTRAINT chk_price_book_scope CHECK (
+        (scope_type = 'all' AND scope_id IS NULL) OR
+        (scope_type IN ('location', 'register', 'customer_group') AND scope_id IS NOT NULL)
+    ),
+    CONSTRAINT chk_price_book_validity CHECK (valid_to IS NULL OR valid_from IS NULL OR valid_to > valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_price_books_scope ON pos_price_books(tenant_id, scope_type, scope_id);
+
+-- Price Book Entries (a price for a product or variant from a date; later entries schedule price changes)
+CREATE TABLE IF NOT EXISTS pos_price_book_entries (
+    id SERIAL PRIMARY KEY,
+    price_book_id INTEGER NOT NULL REFERENCES pos_price_books(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL, -- references products table
+    variant_id INTEGER REFERENCES pos_product_variants(id), -- NULL prices every variant without its own entry
+    price DECIMAL(15,2) NOT NULL,
+    effective_from TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    effective_to TIMESTAMP, -- ends a temporary price; the entry before it applies again
+    created_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_price_book_entry_price CHECK (price >= 0),
+    CONSTRAINT chk_price_book_entry_dates CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_price_book_entries_product ON pos_price_book_entries(price_book_id, product_id, effective_from);
+
+-- Price Book Publications (versioned snapshots terminals download and cache)
+CREATE TABLE IF NOT EXISTS pos_price_book_publications (
+    id SERIAL PRIMARY KEY,
+    price_book_id INTEGER NOT NULL REFERENCES pos_price_books(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    snapshot JSONB NOT NULL, -- the book and all its current and scheduled entries
+    snapshot_hash VARCHAR(64) NOT NULL, -- SHA-256 of snapshot, served as the ETag
+    published_by INTEGER, -- references users table
+    published_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(price_book_id, version)
+);
+
+-- The price each line would have been charged from the price books, to show cashier overrides
+ALTER TABLE pos_transaction_items ADD COLUMN IF NOT EXISTS list_price DECIMAL(15,2);
+ALTER TABLE pos_transaction_items ADD COLUMN IF NOT EXISTS price_book_id INTEGER REFERENCES pos_price_books(id);
+
+CREATE TRIGGER update_pos_price_books_updated_at BEFORE UPDATE ON pos_price_books FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_pos_transaction_items_transaction_product;
+DROP INDEX IF EXISTS idx_pos_transactions_tenant_date;
+DROP INDEX IF EXISTS idx_pos_products_product;
+DROP INDEX IF EXISTS idx_products_sku_trgm;
+DROP INDEX IF EXISTS idx_products_name_trgm;
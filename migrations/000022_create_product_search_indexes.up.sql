@@ -0,0 +1,10 @@
+-- Trigram matching for terminal product search by partial or misspelled name and SKU
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (sku gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_pos_products_product ON pos_products(product_id, register_id);
+
+-- Sales frequency ranking counts a tenant's recent sales per product
+CREATE INDEX IF NOT EXISTS idx_pos_transactions_tenant_date ON pos_transactions(tenant_id, transaction_date);
+CREATE INDEX IF NOT EXISTS idx_pos_transaction_items_transaction_product ON pos_transaction_items(transaction_id, product_id);
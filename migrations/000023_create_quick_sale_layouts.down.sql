@@ -0,0 +1,14 @@
+ALTER TABLE quick_sale_items DROP CONSTRAINT IF EXISTS quick_sale_items_category_id_fkey;
+ALTER TABLE quick_sale_items ADD CONSTRAINT quick_sale_items_category_id_fkey
+    FOREIGN KEY (category_id) REFERENCES quick_sale_categories(id);
+
+DROP INDEX IF EXISTS idx_quick_sale_items_layout_product;
+DROP INDEX IF EXISTS idx_quick_sale_categories_layout_code;
+
+DELETE FROM quick_sale_items WHERE register_id IS NOT NULL;
+DELETE FROM quick_sale_categories WHERE register_id IS NOT NULL;
+ALTER TABLE quick_sale_items DROP COLUMN IF EXISTS register_id;
+ALTER TABLE quick_sale_categories DROP COLUMN IF EXISTS register_id;
+
+ALTER TABLE quick_sale_items ADD CONSTRAINT quick_sale_items_tenant_id_product_id_location_id_key UNIQUE (tenant_id, product_id, location_id);
+ALTER TABLE quick_sale_categories ADD CONSTRAINT quick_sale_categories_tenant_id_category_code_key UNIQUE (tenant_id, category_code);
@@ -0,0 +1,17 @@
+-- Quick sale layouts: a tenant default (no location), one per location, and optional per-register overrides.
+-- Category codes and product buttons are unique within a layout instead of across the tenant.
+ALTER TABLE quick_sale_categories ADD COLUMN IF NOT EXISTS register_id INTEGER REFERENCES pos_registers(id);
+ALTER TABLE quick_sale_items ADD COLUMN IF NOT EXISTS register_id INTEGER REFERENCES pos_registers(id);
+
+ALTER TABLE quick_sale_categories DROP CONSTRAINT IF EXISTS quick_sale_categories_tenant_id_category_code_key;
+ALTER TABLE quick_sale_items DROP CONSTRAINT IF EXISTS quick_sale_items_tenant_id_product_id_location_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_quick_sale_categories_layout_code
+    ON quick_sale_categories(tenant_id, (COALESCE(location_id, 0)), (COALESCE(register_id, 0)), category_code);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_quick_sale_items_layout_product
+    ON quick_sale_items(tenant_id, (COALESCE(location_id, 0)), (COALESCE(register_id, 0)), product_id);
+
+-- Deleting a category deletes its buttons
+ALTER TABLE quick_sale_items DROP CONSTRAINT IF EXISTS quick_sale_items_category_id_fkey;
+ALTER TABLE quick_sale_items ADD CONSTRAINT quick_sale_items_category_id_fkey
+    FOREIGN KEY (category_id) REFERENCES quick_sale_categories(id) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+ALTER TABLE pos_transaction_items DROP COLUMN IF EXISTS discount_rule_id;
+DROP TABLE IF EXISTS pos_transaction_item_components CASCADE;
+DROP TABLE IF EXISTS pos_kit_components CASCADE;
+DROP TABLE IF EXISTS pos_kits CASCADE;
@@ -0,0 +1,48 @@
+-- Kits (a product sold as one line that takes its component products out of stock)
+CREATE TABLE IF NOT EXISTS pos_kits (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- the kit's own product; scanned and priced like any other
+    allocation_method VARCHAR(20) NOT NULL DEFAULT 'list_price', -- list_price, quantity, fixed
+    is_active BOOLEAN NOT NULL DEFAULT true, -- an inactive kit sells as a plain product
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, product_id),
+    CONSTRAINT chk_kit_allocation_method CHECK (allocation_method IN ('list_price', 'quantity', 'fixed'))
+);
+
+CREATE TABLE IF NOT EXISTS pos_kit_components (
+    id SERIAL PRIMARY KEY,
+    kit_id INTEGER NOT NULL REFERENCES pos_kits(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL, -- references products table
+    variant_id INTEGER REFERENCES pos_product_variants(id),
+    quantity DECIMAL(15,3) NOT NULL DEFAULT 1, -- per kit
+    allocated_price DECIMAL(15,2), -- per unit; weights the fixed allocation method
+    tax_rate DECIMAL(5,2), -- NULL is taxed at the kit line's rate
+    sort_order INTEGER DEFAULT 0,
+    CONSTRAINT chk_kit_component_quantity CHECK (quantity > 0),
+    CONSTRAINT chk_kit_component_price CHECK (allocated_price IS NULL OR allocated_price >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_kit_components_kit ON pos_kit_components(kit_id);
+
+-- Components of a sold kit line. The line's amount and tax are split across them, so stock,
+-- tax by rate and returns work on the components while the line stays one item to the customer.
+CREATE TABLE IF NOT EXISTS pos_transaction_item_components (
+    id SERIAL PRIMARY KEY,
+    transaction_item_id INTEGER NOT NULL REFERENCES pos_transaction_items(id),
+    product_id INTEGER NOT NULL, -- references products table
+    variant_id INTEGER REFERENCES pos_product_variants(id),
+    name VARCHAR(255) NOT NULL, -- as sold
+    quantity DECIMAL(15,3) NOT NULL, -- per kit x line quantity
+    amount DECIMAL(15,2) NOT NULL, -- share of the line's discounted amount
+    tax_rate DECIMAL(5,2) NOT NULL DEFAULT 0.00,
+    tax_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_transaction_item_components_item ON pos_transaction_item_components(transaction_item_id);
+
+-- The bundle rule that priced a kit line
+ALTER TABLE pos_transaction_items ADD COLUMN IF NOT EXISTS discount_rule_id INTEGER REFERENCES discount_rules(id);
+
+CREATE TRIGGER update_pos_kits_updated_at BEFORE UPDATE ON pos_kits FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
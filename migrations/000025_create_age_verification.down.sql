@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pos_age_verifications CASCADE;
+DROP TABLE IF EXISTS pos_age_sale_restrictions CASCADE;
+DROP TABLE IF EXISTS pos_product_age_restrictions CASCADE;
@@ -0,0 +1,57 @@
+-- Minimum customer age for products such as alcohol and tobacco
+CREATE TABLE IF NOT EXISTS pos_product_age_restrictions (
+    tenant_id VARCHAR(255) NOT NULL,
+    product_id INTEGER NOT NULL, -- references products table
+    minimum_age INTEGER NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, product_id),
+    CONSTRAINT chk_product_minimum_age CHECK (minimum_age BETWEEN 1 AND 99)
+);
+
+-- Hours when age-restricted products may not be sold, per location or everywhere
+CREATE TABLE IF NOT EXISTS pos_age_sale_restrictions (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    location_id INTEGER, -- references locations table; NULL for every location
+    name VARCHAR(100) NOT NULL,
+    minimum_age INTEGER, -- applies to products restricted to at least this age; NULL for all
+    day_of_week INTEGER, -- 0 = Sunday; NULL for every day
+    time_from TIME NOT NULL, -- start of the blocked window, local time
+    time_to TIME NOT NULL, -- end of the window; earlier than time_from runs past midnight, equal blocks the whole day
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA zone of the location, e.g. America/Chicago
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_age_sale_restriction_day CHECK (day_of_week IS NULL OR day_of_week BETWEEN 0 AND 6),
+    CONSTRAINT chk_age_sale_restriction_age CHECK (minimum_age IS NULL OR minimum_age BETWEEN 1 AND 99)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_age_sale_restrictions_location ON pos_age_sale_restrictions(tenant_id, location_id);
+
+-- The age check recorded on a sale of age-restricted products, kept for compliance audits
+CREATE TABLE IF NOT EXISTS pos_age_verifications (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    transaction_id INTEGER NOT NULL REFERENCES pos_transactions(id),
+    method VARCHAR(20) NOT NULL, -- dob_entered, id_scanned, manager_override
+    required_age INTEGER NOT NULL, -- highest minimum age of the products sold
+    date_of_birth DATE,
+    customer_age INTEGER, -- on the sale date, from date_of_birth
+    id_type VARCHAR(30), -- e.g. drivers_license, passport
+    id_expiry DATE,
+    manager_id INTEGER, -- references users table; approves an override
+    override_reason TEXT,
+    verified_by INTEGER NOT NULL, -- references users table
+    verified_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(transaction_id),
+    CONSTRAINT chk_age_verification_method CHECK (method IN ('dob_entered', 'id_scanned', 'manager_override')),
+    CONSTRAINT chk_age_verification_evidence CHECK (
+        (method = 'manager_override' AND manager_id IS NOT NULL AND override_reason IS NOT NULL) OR
+        (method IN ('dob_entered', 'id_scanned') AND date_of_birth IS NOT NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_pos_age_verifications_tenant ON pos_age_verifications(tenant_id, verified_at);
+
+CREATE TRIGGER update_pos_product_age_restrictions_updated_at BEFORE UPDATE ON pos_product_age_restrictions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_pos_age_sale_restrictions_updated_at BEFORE UPDATE ON pos_age_sale_restrictions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1 @@
+ALTER TABLE pos_print_jobs DROP COLUMN IF EXISTS downloaded_at;
@@ -0,0 +1,5 @@
+-- When a print job's payload was handed out; a later download of the same job is a reprint
+ALTER TABLE pos_print_jobs ADD COLUMN IF NOT EXISTS downloaded_at TIMESTAMP;
+
+-- Jobs printed before downloads were tracked have been handed out already
+UPDATE pos_print_jobs SET downloaded_at = COALESCE(completed_at, created_at) WHERE status <> 'queued';
@@ -0,0 +1,16 @@
+-- Written-off movements have no place in the narrower constraints; refuse to roll back rather than change them
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pos_stock_movements WHERE disposition = 'write_off')
+       OR EXISTS (SELECT 1 FROM pos_return_items WHERE disposition = 'write_off') THEN
+        RAISE EXCEPTION 'written-off stock movements exist; they cannot be rolled back';
+    END IF;
+END;
+$$;
+
+ALTER TABLE pos_return_items DROP CONSTRAINT IF EXISTS chk_return_disposition;
+ALTER TABLE pos_return_items ADD CONSTRAINT chk_return_disposition CHECK (disposition IN ('restock', 'damaged'));
+
+ALTER TABLE pos_stock_movements DROP CONSTRAINT IF EXISTS chk_stock_disposition;
+ALTER TABLE pos_stock_movements ADD CONSTRAINT chk_stock_disposition
+    CHECK (disposition IS NULL OR disposition IN ('restock', 'damaged'));
@@ -0,0 +1,8 @@
+-- Voids and returns can write stock off instead of restocking it or keeping it as damaged
+ALTER TABLE pos_stock_movements DROP CONSTRAINT IF EXISTS chk_stock_disposition;
+ALTER TABLE pos_stock_movements ADD CONSTRAINT chk_stock_disposition
+    CHECK (disposition IS NULL OR disposition IN ('restock', 'damaged', 'write_off'));
+
+ALTER TABLE pos_return_items DROP CONSTRAINT IF EXISTS chk_return_disposition;
+ALTER TABLE pos_return_items ADD CONSTRAINT chk_return_disposition
+    CHECK (disposition IN ('restock', 'damaged', 'write_off'));
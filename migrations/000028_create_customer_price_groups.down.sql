@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pos_customer_price_groups CASCADE;
@@ -0,0 +1,13 @@
+-- Customer Price Groups (the customer group whose price books and bundle rules apply to a customer)
+CREATE TABLE IF NOT EXISTS pos_customer_price_groups (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    customer_id INTEGER NOT NULL, -- references customers table
+    customer_group_id INTEGER NOT NULL, -- scope_id of customer_group price books
+    updated_by INTEGER, -- references users table
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, customer_id)
+);
+
+CREATE TRIGGER update_pos_customer_price_groups_updated_at BEFORE UPDATE ON pos_customer_price_groups FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
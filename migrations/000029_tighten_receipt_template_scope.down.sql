@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_pos_receipt_templates_location;
+DROP INDEX IF EXISTS idx_pos_receipt_templates_register;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_receipt_templates_scope
+    ON pos_receipt_templates(tenant_id, COALESCE(location_id, 0), COALESCE(register_id, 0));
@@ -0,0 +1,14 @@
+-- A register's template applies whatever location it names, so templates are unique per register,
+-- and per location (or tenant default) among templates without a register
+DELETE FROM pos_receipt_templates t
+USING pos_receipt_templates newer
+WHERE t.tenant_id = newer.tenant_id AND t.register_id = newer.register_id
+  AND (t.updated_at, t.id) < (newer.updated_at, newer.id);
+
+DROP INDEX IF EXISTS idx_pos_receipt_templates_scope;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_receipt_templates_register
+    ON pos_receipt_templates(tenant_id, register_id) WHERE register_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pos_receipt_templates_location
+    ON pos_receipt_templates(tenant_id, COALESCE(location_id, 0)) WHERE register_id IS NULL;
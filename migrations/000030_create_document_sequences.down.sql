@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pos_document_sequences CASCADE;
@@ -0,0 +1,7 @@
+-- Document Sequences (per-tenant counters for deposit and receipt numbers)
+CREATE TABLE IF NOT EXISTS pos_document_sequences (
+    tenant_id VARCHAR(255) NOT NULL,
+    document_type VARCHAR(20) NOT NULL, -- number prefix, e.g. DEP, RCP
+    last_number INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, document_type)
+);